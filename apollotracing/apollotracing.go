@@ -0,0 +1,161 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package apollotracing provides a graphql.Tracer that reports resolver
+// timings in the Apollo Tracing format described in
+// https://github.com/apollographql/apollo-tracing.
+package apollotracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// ExtensionKey is the key of the response extension that holds the trace.
+const ExtensionKey = "tracing"
+
+// Tracer is a graphql.Tracer that adds an Apollo Tracing trace to the
+// extensions of each response. The zero value is ready to use.
+type Tracer struct {
+	// now is used to obtain the current time. If nil, time.Now is used.
+	now func() time.Time
+}
+
+// Trace is the Apollo Tracing format. Durations and offsets are in
+// nanoseconds.
+type Trace struct {
+	Version    int       `json:"version"`
+	StartTime  time.Time `json:"startTime"`
+	EndTime    time.Time `json:"endTime"`
+	Duration   int64     `json:"duration"`
+	Parsing    Phase     `json:"parsing"`
+	Validation Phase     `json:"validation"`
+	Execution  Execution `json:"execution"`
+}
+
+// Phase is the timing of a phase of the request.
+type Phase struct {
+	StartOffset int64 `json:"startOffset"`
+	Duration    int64 `json:"duration"`
+}
+
+// Execution holds the timings of the execution phase.
+type Execution struct {
+	Resolvers []*Resolver `json:"resolvers"`
+}
+
+// Resolver is the timing of a single resolver call.
+type Resolver struct {
+	Path        []graphql.PathSegment `json:"path"`
+	ParentType  string                `json:"parentType"`
+	FieldName   string                `json:"fieldName"`
+	ReturnType  string                `json:"returnType"`
+	StartOffset int64                 `json:"startOffset"`
+	Duration    int64                 `json:"duration"`
+}
+
+// requestTrace is the state of a single request's trace.
+type requestTrace struct {
+	start time.Time
+
+	mu    sync.Mutex
+	trace Trace
+}
+
+type requestTraceContextKey struct{}
+
+func (t *Tracer) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+// TraceExecution starts a new trace.
+func (t *Tracer) TraceExecution(ctx context.Context, req graphql.Request) (context.Context, func(*graphql.Response)) {
+	rt := &requestTrace{start: t.clock()}
+	rt.trace.Version = 1
+	rt.trace.StartTime = rt.start.UTC()
+	rt.trace.Execution.Resolvers = []*Resolver{}
+	ctx = context.WithValue(ctx, requestTraceContextKey{}, rt)
+	return ctx, func(resp *graphql.Response) {
+		end := t.clock()
+		rt.mu.Lock()
+		defer rt.mu.Unlock()
+		rt.trace.EndTime = end.UTC()
+		rt.trace.Duration = int64(end.Sub(rt.start))
+		if resp.Extensions == nil {
+			resp.Extensions = make(map[string]interface{})
+		}
+		resp.Extensions[ExtensionKey] = &rt.trace
+	}
+}
+
+// TraceParse records the timing of the parsing phase.
+func (t *Tracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*graphql.ResponseError)) {
+	rt, _ := ctx.Value(requestTraceContextKey{}).(*requestTrace)
+	if rt == nil {
+		return ctx, nil
+	}
+	start := t.clock()
+	return ctx, func([]*graphql.ResponseError) {
+		rt.setPhase(&rt.trace.Parsing, start, t.clock())
+	}
+}
+
+// TraceValidation records the timing of the validation phase.
+func (t *Tracer) TraceValidation(ctx context.Context) (context.Context, func(*graphql.ValidatedQuery, []*graphql.ResponseError)) {
+	rt, _ := ctx.Value(requestTraceContextKey{}).(*requestTrace)
+	if rt == nil {
+		return ctx, nil
+	}
+	start := t.clock()
+	return ctx, func(*graphql.ValidatedQuery, []*graphql.ResponseError) {
+		rt.setPhase(&rt.trace.Validation, start, t.clock())
+	}
+}
+
+// TraceField records the timing of a resolver.
+func (t *Tracer) TraceField(ctx context.Context, info *graphql.FieldInfo) (context.Context, func(interface{}, error)) {
+	rt, _ := ctx.Value(requestTraceContextKey{}).(*requestTrace)
+	if rt == nil {
+		return ctx, nil
+	}
+	start := t.clock()
+	r := &Resolver{
+		Path:        append([]graphql.PathSegment(nil), info.Path...),
+		ParentType:  info.ParentType,
+		FieldName:   info.Request.Name,
+		ReturnType:  info.ReturnType,
+		StartOffset: int64(start.Sub(rt.start)),
+	}
+	return ctx, func(interface{}, error) {
+		r.Duration = int64(t.clock().Sub(start))
+		rt.mu.Lock()
+		rt.trace.Execution.Resolvers = append(rt.trace.Execution.Resolvers, r)
+		rt.mu.Unlock()
+	}
+}
+
+func (rt *requestTrace) setPhase(phase *Phase, start, end time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	phase.StartOffset = int64(start.Sub(rt.start))
+	phase.Duration = int64(end.Sub(start))
+}
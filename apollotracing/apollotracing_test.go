@@ -0,0 +1,198 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apollotracing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type testQuery struct{}
+
+func (testQuery) Greet(args map[string]graphql.Value) string {
+	return "Hello, " + args["name"].Scalar() + "!"
+}
+
+func (testQuery) Names() []*testName {
+	return []*testName{{Value: "Alice"}, {Value: "Bob"}}
+}
+
+type testName struct {
+	Value string
+}
+
+func newTestServer(tb testing.TB, tracer *Tracer) *graphql.Server {
+	tb.Helper()
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greet(name: String!): String!
+			names: [Name!]
+		}
+
+		type Name {
+			value: String!
+		}
+	`, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := graphql.NewServerWithOptions(schema, testQuery{}, nil, &graphql.ServerOptions{
+		Tracer: tracer,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+// fakeClock returns a function that advances by one millisecond every call.
+func fakeClock(start time.Time) func() time.Time {
+	t := start
+	return func() time.Time {
+		now := t
+		t = t.Add(time.Millisecond)
+		return now
+	}
+}
+
+func TestTracer(t *testing.T) {
+	start := time.Date(2019, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tracer := &Tracer{now: fakeClock(start)}
+	srv := newTestServer(t, tracer)
+	resp := srv.Execute(context.Background(), graphql.Request{
+		Query: `{ greet(name: "World") names { value } }`,
+	})
+	for _, err := range resp.Errors {
+		t.Errorf("Response error: %v", err)
+	}
+	got, ok := resp.Extensions[ExtensionKey].(*Trace)
+	if !ok {
+		t.Fatalf("resp.Extensions[%q] = %#v; want *Trace", ExtensionKey, resp.Extensions[ExtensionKey])
+	}
+	const ms = int64(time.Millisecond)
+	want := &Trace{
+		Version:    1,
+		StartTime:  start,
+		EndTime:    start.Add(13 * time.Millisecond),
+		Duration:   13 * ms,
+		Parsing:    Phase{StartOffset: 1 * ms, Duration: 1 * ms},
+		Validation: Phase{StartOffset: 3 * ms, Duration: 1 * ms},
+		Execution: Execution{
+			Resolvers: []*Resolver{
+				{
+					Path:        []graphql.PathSegment{{Field: "greet"}},
+					ParentType:  "Query",
+					FieldName:   "greet",
+					ReturnType:  "String!",
+					StartOffset: 5 * ms,
+					Duration:    1 * ms,
+				},
+				{
+					Path:        []graphql.PathSegment{{Field: "names"}},
+					ParentType:  "Query",
+					FieldName:   "names",
+					ReturnType:  "[Name!]",
+					StartOffset: 7 * ms,
+					Duration:    1 * ms,
+				},
+				{
+					Path:        []graphql.PathSegment{{Field: "names"}, {ListIndex: 0}, {Field: "value"}},
+					ParentType:  "Name",
+					FieldName:   "value",
+					ReturnType:  "String!",
+					StartOffset: 9 * ms,
+					Duration:    1 * ms,
+				},
+				{
+					Path:        []graphql.PathSegment{{Field: "names"}, {ListIndex: 1}, {Field: "value"}},
+					ParentType:  "Name",
+					FieldName:   "value",
+					ReturnType:  "String!",
+					StartOffset: 11 * ms,
+					Duration:    1 * ms,
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("trace (-want +got):\n%s", diff)
+	}
+}
+
+func TestTraceJSON(t *testing.T) {
+	start := time.Date(2019, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tracer := &Tracer{now: fakeClock(start)}
+	srv := newTestServer(t, tracer)
+	resp := srv.Execute(context.Background(), graphql.Request{
+		Query: `{ names { value } }`,
+	})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Extensions struct {
+			Tracing map[string]interface{} `json:"tracing"`
+		} `json:"extensions"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	tracing := got.Extensions.Tracing
+	if tracing["version"] != 1.0 {
+		t.Errorf("version = %v; want 1", tracing["version"])
+	}
+	if want := "2019-06-01T12:00:00Z"; tracing["startTime"] != want {
+		t.Errorf("startTime = %v; want %q", tracing["startTime"], want)
+	}
+	resolvers, _ := tracing["execution"].(map[string]interface{})["resolvers"].([]interface{})
+	if len(resolvers) != 3 {
+		t.Fatalf("len(execution.resolvers) = %d; want 3", len(resolvers))
+	}
+	wantPath := []interface{}{"names", 1.0, "value"}
+	if diff := cmp.Diff(wantPath, resolvers[2].(map[string]interface{})["path"]); diff != "" {
+		t.Errorf("execution.resolvers[2].path (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidatedQuery(t *testing.T) {
+	start := time.Date(2019, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tracer := &Tracer{now: fakeClock(start)}
+	srv := newTestServer(t, tracer)
+	query, errs := srv.Schema().Validate(`{ greet(name: "World") }`)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	resp := srv.Execute(context.Background(), graphql.Request{
+		ValidatedQuery: query,
+	})
+	got, ok := resp.Extensions[ExtensionKey].(*Trace)
+	if !ok {
+		t.Fatalf("resp.Extensions[%q] = %#v; want *Trace", ExtensionKey, resp.Extensions[ExtensionKey])
+	}
+	if got.Parsing != (Phase{}) || got.Validation != (Phase{}) {
+		t.Errorf("parsing = %+v, validation = %+v; want zero", got.Parsing, got.Validation)
+	}
+	if len(got.Execution.Resolvers) != 1 {
+		t.Errorf("len(execution.resolvers) = %d; want 1", len(got.Execution.Resolvers))
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apollotracing_test
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/apollotracing"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type Query struct{}
+
+func (Query) Hello() string {
+	return "Hello, World!"
+}
+
+func ExampleTracer() {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			hello: String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	server, err := graphql.NewServerWithOptions(schema, Query{}, nil, &graphql.ServerOptions{
+		Tracer: new(apollotracing.Tracer),
+	})
+	if err != nil {
+		// handle error
+	}
+
+	response := server.Execute(context.Background(), graphql.Request{
+		Query: "{ hello }",
+	})
+	trace := response.Extensions[apollotracing.ExtensionKey].(*apollotracing.Trace)
+	for _, r := range trace.Execution.Resolvers {
+		fmt.Printf("%s.%s: %s\n", r.ParentType, r.FieldName, r.ReturnType)
+	}
+	// Output:
+	// Query.hello: String!
+}
@@ -14,8 +14,8 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
-// Package gqlang provides a parser for the GraphQL language.
-package gqlang
+// Package ast provides a parser and syntax tree for the GraphQL language.
+package ast
 
 import (
 	"fmt"
@@ -60,6 +60,7 @@ type Definition struct {
 	Operation *Operation
 	Fragment  *FragmentDefinition
 	Type      *TypeDefinition
+	Directive *DirectiveDefinition
 }
 
 // Start returns the position of the definition's first token.
@@ -71,6 +72,8 @@ func (defn *Definition) Start() Pos {
 		return defn.Fragment.Keyword
 	case defn.Type != nil:
 		return defn.Type.Start()
+	case defn.Directive != nil:
+		return defn.Directive.Keyword
 	default:
 		panic("unknown definition")
 	}
@@ -723,6 +726,16 @@ func (nn *NonNullType) String() string {
 // Directives annotate parts of a GraphQL document.
 type Directives []*Directive
 
+// ByName returns the first directive with the given name or nil if not found.
+func (ds Directives) ByName(name string) *Directive {
+	for _, d := range ds {
+		if d.Name.Value == name {
+			return d
+		}
+	}
+	return nil
+}
+
 // A Directive is a single annotation.
 type Directive struct {
 	At        Pos
@@ -887,6 +900,26 @@ func (defn *TypeDefinition) Name() *Name {
 	}
 }
 
+// Directives returns the type definition's directives.
+func (defn *TypeDefinition) Directives() Directives {
+	switch {
+	case defn == nil:
+		return nil
+	case defn.Scalar != nil:
+		return defn.Scalar.Directives
+	case defn.Object != nil:
+		return defn.Object.Directives
+	case defn.Union != nil:
+		return defn.Union.Directives
+	case defn.Enum != nil:
+		return defn.Enum.Directives
+	case defn.InputObject != nil:
+		return defn.InputObject.Directives
+	default:
+		return nil
+	}
+}
+
 func (defn *TypeDefinition) asDefinition() *Definition {
 	return &Definition{Type: defn}
 }
@@ -897,6 +930,7 @@ type ScalarTypeDefinition struct {
 	Description *Description
 	Keyword     Pos
 	Name        *Name
+	Directives  Directives
 }
 
 func (defn *ScalarTypeDefinition) asTypeDefinition() *TypeDefinition {
@@ -909,6 +943,7 @@ type ObjectTypeDefinition struct {
 	Description *Description
 	Keyword     Pos
 	Name        *Name
+	Directives  Directives
 	Fields      *FieldsDefinition
 }
 
@@ -949,6 +984,7 @@ type UnionTypeDefinition struct {
 	Description *Description
 	Keyword     Pos
 	Name        *Name
+	Directives  Directives
 	MemberTypes []*Name
 }
 
@@ -962,6 +998,7 @@ type EnumTypeDefinition struct {
 	Description *Description
 	Keyword     Pos
 	Name        *Name
+	Directives  Directives
 	Values      *EnumValuesDefinition
 }
 
@@ -991,6 +1028,7 @@ type InputObjectTypeDefinition struct {
 	Description *Description
 	Keyword     Pos
 	Name        *Name
+	Directives  Directives
 	Fields      *InputFieldsDefinition
 }
 
@@ -1015,4 +1053,27 @@ type InputValueDefinition struct {
 	Colon       Pos
 	Type        *TypeRef
 	Default     *DefaultValue
+	Directives  Directives
+}
+
+// DirectiveDefinition declares a directive that can be used in a schema.
+// https://graphql.github.io/graphql-spec/June2018/#DirectiveDefinition
+type DirectiveDefinition struct {
+	Description *Description
+	Keyword     Pos
+	At          Pos
+	Name        *Name
+	Args        *ArgumentsDefinition
+	// Repeatable is true if the definition includes the "repeatable" keyword,
+	// as proposed in the draft specification.
+	Repeatable bool
+	On         Pos
+	Locations  []*Name
+}
+
+func (defn *DirectiveDefinition) asDefinition() *Definition {
+	if defn == nil {
+		return nil
+	}
+	return &Definition{Directive: defn}
 }
@@ -16,7 +16,7 @@
 
 // +build gofuzz
 
-package gqlang
+package ast
 
 func Fuzz(data []byte) int {
 	_, errs := Parse(string(data))
@@ -14,7 +14,7 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
-package gqlang
+package ast
 
 import (
 	"fmt"
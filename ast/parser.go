@@ -14,7 +14,7 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
-package gqlang
+package ast
 
 import "golang.org/x/xerrors"
 
@@ -135,6 +135,9 @@ func (p *parser) definition(depth int) (*Definition, []error) {
 	case "input":
 		def, errs := p.inputObjectTypeDefinition(depth + 1)
 		return def.asTypeDefinition().asDefinition(), errs
+	case "directive":
+		def, errs := p.directiveDefinition(depth + 1)
+		return def.asDefinition(), errs
 	default:
 		return nil, []error{&posError{
 			pos: keywordTok.start,
@@ -958,7 +961,12 @@ func (p *parser) scalarTypeDefinition(depth int) (*ScalarTypeDefinition, []error
 	if err != nil {
 		return def, []error{xerrors.Errorf("scalar type definition: %w", err)}
 	}
-	return def, nil
+	var errs []error
+	def.Directives, errs = p.directives(depth+1, true)
+	for i := range errs {
+		errs[i] = xerrors.Errorf("scalar type definition %s: %w", def.Name.Value, errs[i])
+	}
+	return def, errs
 }
 
 func (p *parser) objectTypeDefinition(depth int) (*ObjectTypeDefinition, []error) {
@@ -986,7 +994,10 @@ func (p *parser) objectTypeDefinition(depth int) (*ObjectTypeDefinition, []error
 		return def, []error{xerrors.Errorf("object type definition: %w", err)}
 	}
 	var errs []error
-	def.Fields, errs = p.fieldsDefinition(depth + 1)
+	def.Directives, errs = p.directives(depth+1, true)
+	var fieldsErrs []error
+	def.Fields, fieldsErrs = p.fieldsDefinition(depth + 1)
+	errs = append(errs, fieldsErrs...)
 	for i := range errs {
 		errs[i] = xerrors.Errorf("object type definition %s: %w", def.Name.Value, errs[i])
 	}
@@ -1121,35 +1132,40 @@ func (p *parser) unionTypeDefinition(depth int) (*UnionTypeDefinition, []error)
 	if err != nil {
 		return defn, []error{xerrors.Errorf("union type definition: %w", err)}
 	}
+	var errs []error
+	defn.Directives, errs = p.directives(depth+1, true)
+	for i := range errs {
+		errs[i] = xerrors.Errorf("union type definition %s: %w", defn.Name.Value, errs[i])
+	}
 
 	// Member types.
 	if len(p.tokens) == 0 || p.tokens[0].kind != equals {
-		return defn, nil
+		return defn, errs
 	}
 	p.next()
 	if len(p.tokens) == 0 {
-		return defn, []error{&posError{
+		return defn, append(errs, &posError{
 			pos: p.eofPos,
 			err: xerrors.New("union type definition: expected '|' or name, got EOF"),
-		}}
+		})
 	}
 	if p.tokens[0].kind == or {
 		p.next()
 	}
 	firstTypeName, err := p.name()
 	if err != nil {
-		return defn, []error{xerrors.Errorf("union type definition: %w", err)}
+		return defn, append(errs, xerrors.Errorf("union type definition: %w", err))
 	}
 	defn.MemberTypes = append(defn.MemberTypes, firstTypeName)
 	for len(p.tokens) > 0 && p.tokens[0].kind == or {
 		p.next()
 		nextTypeName, err := p.name()
 		if err != nil {
-			return defn, []error{xerrors.Errorf("union type definition: %w", err)}
+			return defn, append(errs, xerrors.Errorf("union type definition: %w", err))
 		}
 		defn.MemberTypes = append(defn.MemberTypes, nextTypeName)
 	}
-	return defn, nil
+	return defn, errs
 }
 
 func (p *parser) enumTypeDefinition(depth int) (*EnumTypeDefinition, []error) {
@@ -1177,7 +1193,10 @@ func (p *parser) enumTypeDefinition(depth int) (*EnumTypeDefinition, []error) {
 		return defn, []error{xerrors.Errorf("enum type definition: %w", err)}
 	}
 	var errs []error
-	defn.Values, errs = p.enumValuesDefinition(depth + 1)
+	defn.Directives, errs = p.directives(depth+1, true)
+	var valuesErrs []error
+	defn.Values, valuesErrs = p.enumValuesDefinition(depth + 1)
+	errs = append(errs, valuesErrs...)
 	for i, err := range errs {
 		errs[i] = xerrors.Errorf("enum type definition %s: %w", defn.Name.Value, err)
 	}
@@ -1246,7 +1265,10 @@ func (p *parser) inputObjectTypeDefinition(depth int) (*InputObjectTypeDefinitio
 		return def, []error{xerrors.Errorf("input object type definition: %w", err)}
 	}
 	var errs []error
-	def.Fields, errs = p.inputFieldsDefinition(depth + 1)
+	def.Directives, errs = p.directives(depth+1, true)
+	var fieldsErrs []error
+	def.Fields, fieldsErrs = p.inputFieldsDefinition(depth + 1)
+	errs = append(errs, fieldsErrs...)
 	for i := range errs {
 		errs[i] = xerrors.Errorf("input object type definition %s: %w", def.Name.Value, errs[i])
 	}
@@ -1315,9 +1337,93 @@ func (p *parser) inputValueDefinition(depth int) (*InputValueDefinition, []error
 		return field, errs
 	}
 	field.Default, errs = p.optionalDefaultValue(depth + 1)
+	if len(errs) > 0 {
+		return field, errs
+	}
+	field.Directives, errs = p.directives(depth+1, true)
 	return field, errs
 }
 
+func (p *parser) directiveDefinition(depth int) (*DirectiveDefinition, []error) {
+	if depth > maxParseDepth {
+		return nil, []error{errTooDeep}
+	}
+	defn := new(DirectiveDefinition)
+	defn.Description = p.optionalDescription()
+	if len(p.tokens) == 0 {
+		return nil, []error{&posError{
+			pos: p.eofPos,
+			err: xerrors.New("directive definition: expected 'directive', got EOF"),
+		}}
+	}
+	if p.tokens[0].kind != name || p.tokens[0].source != "directive" {
+		return nil, []error{&posError{
+			pos: p.tokens[0].start,
+			err: xerrors.Errorf("directive definition: expected 'directive', found %q", p.tokens[0]),
+		}}
+	}
+	defn.Keyword = p.next().start
+	if len(p.tokens) == 0 {
+		return defn, []error{&posError{
+			pos: p.eofPos,
+			err: xerrors.New("directive definition: expected '@', got EOF"),
+		}}
+	}
+	if p.tokens[0].kind != atSign {
+		return defn, []error{&posError{
+			pos: p.tokens[0].start,
+			err: xerrors.Errorf("directive definition: expected '@', found %q", p.tokens[0]),
+		}}
+	}
+	defn.At = p.next().start
+	var err error
+	defn.Name, err = p.name()
+	if err != nil {
+		return defn, []error{xerrors.Errorf("directive definition: %w", err)}
+	}
+	var errs []error
+	if len(p.tokens) > 0 && p.tokens[0].kind == lparen {
+		defn.Args, errs = p.argumentsDefinition(depth + 1)
+		for i := range errs {
+			errs[i] = xerrors.Errorf("directive definition @%s: %w", defn.Name.Value, errs[i])
+		}
+	}
+	if len(p.tokens) > 0 && p.tokens[0].kind == name && p.tokens[0].source == "repeatable" {
+		p.next()
+		defn.Repeatable = true
+	}
+	if len(p.tokens) == 0 {
+		return defn, append(errs, &posError{
+			pos: p.eofPos,
+			err: xerrors.Errorf("directive definition @%s: expected 'on', got EOF", defn.Name.Value),
+		})
+	}
+	if p.tokens[0].kind != name || p.tokens[0].source != "on" {
+		return defn, append(errs, &posError{
+			pos: p.tokens[0].start,
+			err: xerrors.Errorf("directive definition @%s: expected 'on', found %q", defn.Name.Value, p.tokens[0]),
+		})
+	}
+	defn.On = p.next().start
+	if len(p.tokens) > 0 && p.tokens[0].kind == or {
+		p.next()
+	}
+	loc, err := p.name()
+	if err != nil {
+		return defn, append(errs, xerrors.Errorf("directive definition @%s: %w", defn.Name.Value, err))
+	}
+	defn.Locations = append(defn.Locations, loc)
+	for len(p.tokens) > 0 && p.tokens[0].kind == or {
+		p.next()
+		loc, err := p.name()
+		if err != nil {
+			return defn, append(errs, xerrors.Errorf("directive definition @%s: %w", defn.Name.Value, err))
+		}
+		defn.Locations = append(defn.Locations, loc)
+	}
+	return defn, errs
+}
+
 // group parses a list of the given rule started and ended by the given token kind.
 func (p *parser) group(ldelim, rdelim tokenKind, ruleName string, rule func() []error) (start, end Pos, _ []error) {
 	if len(p.tokens) == 0 {
@@ -14,7 +14,7 @@
 //
 // SPDX-License-Identifier: Apache-2.0
 
-package gqlang
+package ast
 
 import (
 	"strings"
@@ -512,6 +512,121 @@ input ItemInput {
 				},
 			},
 		},
+		{
+			name:  "TypeDirectives",
+			input: `scalar Date @example(value: "2019")`,
+			want: &Document{
+				Definitions: []*Definition{
+					{Type: &TypeDefinition{Scalar: &ScalarTypeDefinition{
+						Keyword: 0,
+						Name:    &Name{Value: "Date", Start: 7},
+						Directives: Directives{
+							{
+								At:   12,
+								Name: &Name{Value: "example", Start: 13},
+								Arguments: &Arguments{
+									LParen: 20,
+									RParen: 34,
+									Args: []*Argument{
+										{
+											Name:  &Name{Value: "value", Start: 21},
+											Colon: 26,
+											Value: &InputValue{Scalar: &ScalarValue{
+												Start: 28,
+												Type:  StringScalar,
+												Raw:   `"2019"`,
+											}},
+										},
+									},
+								},
+							},
+						},
+					}}},
+				},
+			},
+		},
+		{
+			name:  "InputValueDirectives",
+			input: `input In { x: Int = 1 @c }`,
+			want: &Document{
+				Definitions: []*Definition{
+					{Type: &TypeDefinition{InputObject: &InputObjectTypeDefinition{
+						Keyword: 0,
+						Name:    &Name{Value: "In", Start: 6},
+						Fields: &InputFieldsDefinition{
+							LBrace: 9,
+							RBrace: 25,
+							Defs: []*InputValueDefinition{
+								{
+									Name:  &Name{Value: "x", Start: 11},
+									Colon: 12,
+									Type:  &TypeRef{Named: &Name{Value: "Int", Start: 14}},
+									Default: &DefaultValue{
+										Eq: 18,
+										Value: &InputValue{Scalar: &ScalarValue{
+											Start: 20,
+											Type:  IntScalar,
+											Raw:   "1",
+										}},
+									},
+									Directives: Directives{
+										{At: 22, Name: &Name{Value: "c", Start: 23}},
+									},
+								},
+							},
+						},
+					}}},
+				},
+			},
+		},
+		{
+			name:  "DirectiveDefinition",
+			input: `directive @example(value: String!) repeatable on FIELD_DEFINITION | SCALAR`,
+			want: &Document{
+				Definitions: []*Definition{
+					{Directive: &DirectiveDefinition{
+						Keyword: 0,
+						At:      10,
+						Name:    &Name{Value: "example", Start: 11},
+						Args: &ArgumentsDefinition{
+							LParen: 18,
+							RParen: 33,
+							Args: []*InputValueDefinition{
+								{
+									Name:  &Name{Value: "value", Start: 19},
+									Colon: 24,
+									Type: &TypeRef{NonNull: &NonNullType{
+										Named: &Name{Value: "String", Start: 26},
+										Pos:   32,
+									}},
+								},
+							},
+						},
+						Repeatable: true,
+						On:         46,
+						Locations: []*Name{
+							{Value: "FIELD_DEFINITION", Start: 49},
+							{Value: "SCALAR", Start: 68},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name:  "DirectiveDefinitionMissingLocations",
+			input: `directive @foo on`,
+			want: &Document{
+				Definitions: []*Definition{
+					{Directive: &DirectiveDefinition{
+						Keyword: 0,
+						At:      10,
+						Name:    &Name{Value: "foo", Start: 11},
+						On:      15,
+					}},
+				},
+			},
+			wantErrs: posSet{17: {}},
+		},
 		{
 			name: "InputObjectLiteral",
 			input: `{
@@ -0,0 +1,417 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Node is implemented by all the types in the syntax tree. The concrete types
+// are always pointers to the structs declared in this package.
+type Node interface {
+	node()
+}
+
+func (*Document) node()                  {}
+func (*Definition) node()                {}
+func (*Operation) node()                 {}
+func (*SelectionSet) node()              {}
+func (*Selection) node()                 {}
+func (*Field) node()                     {}
+func (*Arguments) node()                 {}
+func (*Argument) node()                  {}
+func (*InputValue) node()                {}
+func (*ScalarValue) node()               {}
+func (*Variable) node()                  {}
+func (*ListValue) node()                 {}
+func (*InputObjectValue) node()          {}
+func (*InputObjectField) node()          {}
+func (*DefaultValue) node()              {}
+func (*VariableDefinitions) node()       {}
+func (*VariableDefinition) node()        {}
+func (*Name) node()                      {}
+func (*TypeRef) node()                   {}
+func (*ListType) node()                  {}
+func (*NonNullType) node()               {}
+func (*Directive) node()                 {}
+func (*FragmentSpread) node()            {}
+func (*InlineFragment) node()            {}
+func (*FragmentDefinition) node()        {}
+func (*TypeCondition) node()             {}
+func (*Description) node()               {}
+func (*TypeDefinition) node()            {}
+func (*ScalarTypeDefinition) node()      {}
+func (*ObjectTypeDefinition) node()      {}
+func (*FieldsDefinition) node()          {}
+func (*FieldDefinition) node()           {}
+func (*ArgumentsDefinition) node()       {}
+func (*UnionTypeDefinition) node()       {}
+func (*EnumTypeDefinition) node()        {}
+func (*EnumValuesDefinition) node()      {}
+func (*EnumValueDefinition) node()       {}
+func (*InputObjectTypeDefinition) node() {}
+func (*InputFieldsDefinition) node()     {}
+func (*InputValueDefinition) node()      {}
+func (*DirectiveDefinition) node()       {}
+
+// An ApplyFunc is invoked by Apply for each non-nil node before and/or after
+// the node's children, using a Cursor describing the current node and providing
+// operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a syntax tree recursively, starting with root, and calling
+// enter and leave for each non-nil node as described below. Apply returns the
+// syntax tree, possibly modified.
+//
+// If enter is not nil, it is called for each node before the node's children
+// are traversed (pre-order). If enter returns false, no children are traversed,
+// and leave is not called for that node.
+//
+// If leave is not nil, and a prior call of enter didn't return false, leave is
+// called for each node after its children are traversed (post-order). If leave
+// returns false, traversal is terminated and Apply returns immediately.
+//
+// Only fields that refer to syntax tree nodes are considered children; i.e.,
+// token positions and identifier strings are not traversed. Children are
+// traversed in the order in which they appear in the respective node's struct
+// definition.
+//
+// Replace and Delete modify the tree in place, so they must not be used on a
+// tree that is shared with others, like the document returned by
+// graphql.ValidatedQuery.Document. Parse the source again to obtain a private
+// copy that is safe to modify.
+func Apply(root Node, enter, leave ApplyFunc) (result Node) {
+	parent := &rootNode{Node: root}
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+		result = parent.Node
+	}()
+	a := &application{enter: enter, leave: leave}
+	a.apply(parent, "Node", nil, root)
+	return
+}
+
+var abort = new(int) // singleton, to signal termination of Apply
+
+// rootNode is the parent of the root node passed to Apply.
+type rootNode struct {
+	Node Node
+}
+
+func (*rootNode) node() {}
+
+// A Cursor describes a node encountered during Apply. Information about the
+// node and its parent is available from the Node, Parent, Name, and Index
+// methods.
+//
+// If p is a variable of type and value of the current parent node c.Parent(),
+// and f is the field identifier with name c.Name(), the following invariants
+// hold:
+//
+//	p.f            == c.Node()  if c.Index() <  0
+//	p.f[c.Index()] == c.Node()  if c.Index() >= 0
+//
+// The methods Replace and Delete can be used to change the syntax tree as it
+// is traversed by Apply.
+type Cursor struct {
+	parent Node
+	name   string
+	iter   *iterator // valid if non-nil
+	node   Node
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+// Parent returns the parent of the current node. Parent returns nil for the
+// root node passed to Apply.
+func (c *Cursor) Parent() Node {
+	if _, isRoot := c.parent.(*rootNode); isRoot {
+		return nil
+	}
+	return c.parent
+}
+
+// Name returns the name of the parent node field that contains the current
+// node. If the parent is a *Document and the current node is one of its
+// definitions, for example, Name returns "Definitions".
+func (c *Cursor) Name() string {
+	return c.name
+}
+
+// Index reports the index >= 0 of the current node in the slice of nodes that
+// contains it, or a value < 0 if the current node is not part of a slice.
+// The index of the current node changes if Delete is called on a preceding
+// element of the slice.
+func (c *Cursor) Index() int {
+	if c.iter != nil {
+		return c.iter.index
+	}
+	return -1
+}
+
+// field returns the current node's parent field value.
+func (c *Cursor) field() reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(c.parent)).FieldByName(c.name)
+}
+
+// Replace replaces the current node with n. The replacement node is not
+// traversed by Apply, but its children are if Replace is called from enter.
+// Replace panics if n is not assignable to the parent's field.
+func (c *Cursor) Replace(n Node) {
+	v := c.field()
+	if i := c.Index(); i >= 0 {
+		v = v.Index(i)
+	}
+	nv := reflect.Zero(v.Type())
+	if n != nil {
+		nv = reflect.ValueOf(n)
+		if !nv.Type().AssignableTo(v.Type()) {
+			panic(fmt.Sprintf("ast: cannot replace %v with %T", v.Type(), n))
+		}
+	}
+	v.Set(nv)
+	c.node = n
+}
+
+// Delete deletes the current node from its containing slice. If the current
+// node is not part of a slice, Delete panics.
+func (c *Cursor) Delete() {
+	i := c.Index()
+	if i < 0 {
+		panic("ast: Delete node not contained in slice")
+	}
+	v := c.field()
+	l := v.Len()
+	reflect.Copy(v.Slice(i, l), v.Slice(i+1, l))
+	v.Index(l - 1).Set(reflect.Zero(v.Type().Elem()))
+	v.SetLen(l - 1)
+	c.iter.step--
+	c.node = nil
+}
+
+type application struct {
+	enter, leave ApplyFunc
+	cursor       Cursor
+	iter         iterator
+}
+
+type iterator struct {
+	index, step int
+}
+
+func (a *application) apply(parent Node, name string, iter *iterator, n Node) {
+	// Convert typed nil into untyped nil.
+	if v := reflect.ValueOf(n); v.Kind() == reflect.Ptr && v.IsNil() {
+		n = nil
+	}
+	if n == nil {
+		return
+	}
+
+	saved := a.cursor
+	a.cursor.parent = parent
+	a.cursor.name = name
+	a.cursor.iter = iter
+	a.cursor.node = n
+
+	if a.enter != nil && !a.enter(&a.cursor) || a.cursor.node == nil {
+		// Either skipped or deleted/replaced with nil by enter.
+		a.cursor = saved
+		return
+	}
+
+	// Walk children. The node may have been replaced by enter.
+	switch n := a.cursor.node.(type) {
+	case *Document:
+		a.applyList(n, "Definitions")
+	case *Definition:
+		a.apply(n, "Operation", nil, n.Operation)
+		a.apply(n, "Fragment", nil, n.Fragment)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Directive", nil, n.Directive)
+	case *Operation:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "VariableDefinitions", nil, n.VariableDefinitions)
+		a.apply(n, "SelectionSet", nil, n.SelectionSet)
+	case *SelectionSet:
+		a.applyList(n, "Sel")
+	case *Selection:
+		a.apply(n, "Field", nil, n.Field)
+		a.apply(n, "FragmentSpread", nil, n.FragmentSpread)
+		a.apply(n, "InlineFragment", nil, n.InlineFragment)
+	case *Field:
+		a.apply(n, "Alias", nil, n.Alias)
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Arguments", nil, n.Arguments)
+		a.apply(n, "SelectionSet", nil, n.SelectionSet)
+	case *Arguments:
+		a.applyList(n, "Args")
+	case *Argument:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Value", nil, n.Value)
+	case *InputValue:
+		a.apply(n, "Null", nil, n.Null)
+		a.apply(n, "Scalar", nil, n.Scalar)
+		a.apply(n, "VariableRef", nil, n.VariableRef)
+		a.apply(n, "List", nil, n.List)
+		a.apply(n, "InputObject", nil, n.InputObject)
+	case *ScalarValue, *Name, *Description:
+		// Leaves.
+	case *Variable:
+		a.apply(n, "Name", nil, n.Name)
+	case *ListValue:
+		a.applyList(n, "Values")
+	case *InputObjectValue:
+		a.applyList(n, "Fields")
+	case *InputObjectField:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Value", nil, n.Value)
+	case *DefaultValue:
+		a.apply(n, "Value", nil, n.Value)
+	case *VariableDefinitions:
+		a.applyList(n, "Defs")
+	case *VariableDefinition:
+		a.apply(n, "Var", nil, n.Var)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Default", nil, n.Default)
+	case *TypeRef:
+		a.apply(n, "Named", nil, n.Named)
+		a.apply(n, "List", nil, n.List)
+		a.apply(n, "NonNull", nil, n.NonNull)
+	case *ListType:
+		a.apply(n, "Type", nil, n.Type)
+	case *NonNullType:
+		a.apply(n, "Named", nil, n.Named)
+		a.apply(n, "List", nil, n.List)
+	case *Directive:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Arguments", nil, n.Arguments)
+	case *FragmentSpread:
+		a.apply(n, "Name", nil, n.Name)
+	case *InlineFragment:
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "SelectionSet", nil, n.SelectionSet)
+	case *FragmentDefinition:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "SelectionSet", nil, n.SelectionSet)
+	case *TypeCondition:
+		a.apply(n, "Name", nil, n.Name)
+	case *TypeDefinition:
+		a.apply(n, "Scalar", nil, n.Scalar)
+		a.apply(n, "Object", nil, n.Object)
+		a.apply(n, "Union", nil, n.Union)
+		a.apply(n, "Enum", nil, n.Enum)
+		a.apply(n, "InputObject", nil, n.InputObject)
+	case *ScalarTypeDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.applyList(n, "Directives")
+	case *ObjectTypeDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.applyList(n, "Directives")
+		a.apply(n, "Fields", nil, n.Fields)
+	case *FieldsDefinition:
+		a.applyList(n, "Defs")
+	case *FieldDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Args", nil, n.Args)
+		a.apply(n, "Type", nil, n.Type)
+		a.applyList(n, "Directives")
+	case *ArgumentsDefinition:
+		a.applyList(n, "Args")
+	case *UnionTypeDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.applyList(n, "Directives")
+		a.applyList(n, "MemberTypes")
+	case *EnumTypeDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.applyList(n, "Directives")
+		a.apply(n, "Values", nil, n.Values)
+	case *EnumValuesDefinition:
+		a.applyList(n, "Values")
+	case *EnumValueDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Value", nil, n.Value)
+		a.applyList(n, "Directives")
+	case *InputObjectTypeDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.applyList(n, "Directives")
+		a.apply(n, "Fields", nil, n.Fields)
+	case *InputFieldsDefinition:
+		a.applyList(n, "Defs")
+	case *InputValueDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Type", nil, n.Type)
+		a.apply(n, "Default", nil, n.Default)
+		a.applyList(n, "Directives")
+	case *DirectiveDefinition:
+		a.apply(n, "Description", nil, n.Description)
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "Args", nil, n.Args)
+		a.applyList(n, "Locations")
+	default:
+		panic(fmt.Sprintf("ast: Apply: unexpected node type %T", n))
+	}
+
+	if a.leave != nil && !a.leave(&a.cursor) {
+		panic(abort)
+	}
+
+	a.cursor = saved
+}
+
+func (a *application) applyList(parent Node, name string) {
+	// Avoid heap-allocating a new iterator for each applyList call; reuse
+	// a.iter instead.
+	saved := a.iter
+	a.iter.index = 0
+	for {
+		// Must reload parent.name each time, since cursor modifications might
+		// change it.
+		v := reflect.Indirect(reflect.ValueOf(parent)).FieldByName(name)
+		if a.iter.index >= v.Len() {
+			break
+		}
+
+		// element x may be nil in a bad syntax tree; be cautious
+		var x Node
+		if e := v.Index(a.iter.index); e.IsValid() && !e.IsNil() {
+			x = e.Interface().(Node)
+		}
+
+		a.iter.step = 1
+		a.apply(parent, name, &a.iter, x)
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ast
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApply(t *testing.T) {
+	const source = `
+		query Foo($x: Int = 42) {
+			a(arg: $x) { b c }
+			...Frag
+			... on Bar { d }
+		}
+
+		fragment Frag on Query { e }
+	`
+	parse := func(t *testing.T) *Document {
+		t.Helper()
+		doc, errs := Parse(source)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return doc
+	}
+	fieldNames := func(n Node) []string {
+		var names []string
+		Apply(n, func(c *Cursor) bool {
+			if f, ok := c.Node().(*Field); ok {
+				names = append(names, f.Name.Value)
+			}
+			return true
+		}, nil)
+		return names
+	}
+
+	t.Run("Order", func(t *testing.T) {
+		var got []string
+		Apply(parse(t), func(c *Cursor) bool {
+			got = append(got, fmt.Sprintf("enter %T", c.Node()))
+			return true
+		}, func(c *Cursor) bool {
+			if _, ok := c.Node().(*Name); !ok {
+				got = append(got, fmt.Sprintf("leave %T", c.Node()))
+			}
+			return true
+		})
+		want := []string{
+			"enter *ast.Document",
+			"enter *ast.Definition",
+			"enter *ast.Operation",
+			"enter *ast.Name",
+			"enter *ast.VariableDefinitions",
+			"enter *ast.VariableDefinition",
+			"enter *ast.Variable",
+			"enter *ast.Name",
+			"leave *ast.Variable",
+			"enter *ast.TypeRef",
+			"enter *ast.Name",
+			"leave *ast.TypeRef",
+			"enter *ast.DefaultValue",
+			"enter *ast.InputValue",
+			"enter *ast.ScalarValue",
+			"leave *ast.ScalarValue",
+			"leave *ast.InputValue",
+			"leave *ast.DefaultValue",
+			"leave *ast.VariableDefinition",
+			"leave *ast.VariableDefinitions",
+			"enter *ast.SelectionSet",
+		}
+		if len(got) > len(want) {
+			got = got[:len(want)]
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Fields", func(t *testing.T) {
+		got := fieldNames(parse(t))
+		want := []string{"a", "b", "c", "d", "e"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fields (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Skip", func(t *testing.T) {
+		var got []string
+		Apply(parse(t), func(c *Cursor) bool {
+			switch n := c.Node().(type) {
+			case *Field:
+				got = append(got, n.Name.Value)
+			case *InlineFragment, *FragmentDefinition:
+				return false
+			}
+			return true
+		}, nil)
+		want := []string{"a", "b", "c"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fields (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Abort", func(t *testing.T) {
+		var got []string
+		Apply(parse(t), nil, func(c *Cursor) bool {
+			if f, ok := c.Node().(*Field); ok {
+				got = append(got, f.Name.Value)
+				return f.Name.Value != "c"
+			}
+			return true
+		})
+		want := []string{"b", "c"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fields (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Replace", func(t *testing.T) {
+		doc := parse(t)
+		Apply(doc, func(c *Cursor) bool {
+			if n, ok := c.Node().(*Name); ok && n.Value == "b" {
+				c.Replace(&Name{Value: "renamed", Start: n.Start})
+			}
+			return true
+		}, nil)
+		got := fieldNames(doc)
+		want := []string{"a", "renamed", "c", "d", "e"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fields (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("ReplaceRoot", func(t *testing.T) {
+		replacement := &Document{}
+		got := Apply(parse(t), func(c *Cursor) bool {
+			if c.Parent() == nil {
+				c.Replace(replacement)
+			}
+			return true
+		}, nil)
+		if got != replacement {
+			t.Errorf("Apply(...) = %v; want %v", got, replacement)
+		}
+	})
+	t.Run("ReplaceWrongType", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Replace did not panic")
+			}
+		}()
+		Apply(parse(t), func(c *Cursor) bool {
+			if _, ok := c.Node().(*Name); ok {
+				c.Replace(&Field{})
+			}
+			return true
+		}, nil)
+	})
+	t.Run("Delete", func(t *testing.T) {
+		doc := parse(t)
+		Apply(doc, func(c *Cursor) bool {
+			sel, ok := c.Node().(*Selection)
+			if ok && (sel.FragmentSpread != nil || sel.Field != nil && sel.Field.Name.Value == "b") {
+				c.Delete()
+			}
+			return true
+		}, nil)
+		got := fieldNames(doc)
+		want := []string{"a", "c", "d", "e"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("fields (-want +got):\n%s", diff)
+		}
+		if n := len(doc.FindOperation("Foo").SelectionSet.Sel); n != 2 {
+			t.Errorf("len(Foo.SelectionSet.Sel) = %d; want 2", n)
+		}
+	})
+}
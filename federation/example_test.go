@@ -0,0 +1,94 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package federation_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/federation"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type Query struct{}
+
+func (Query) Me() *User {
+	return users["1"]
+}
+
+type User struct {
+	ID   string
+	Name string
+}
+
+var users = map[string]*User{
+	"1": {ID: "1", Name: "Alice"},
+	"2": {ID: "2", Name: "Bob"},
+}
+
+func ExampleSubgraph() {
+	sg, err := federation.NewSubgraph(`
+		type Query {
+			me: User
+		}
+
+		type User @key(fields: "id") {
+			id: ID!
+			name: String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	err = sg.SetEntityResolver("User", func(ctx context.Context, rep map[string]graphql.Value) (interface{}, error) {
+		u := users[rep["id"].Scalar()]
+		if u == nil {
+			return nil, nil
+		}
+		return u, nil
+	})
+	if err != nil {
+		// handle error
+	}
+	srv, err := sg.NewServer(Query{}, nil)
+	if err != nil {
+		// handle error
+	}
+
+	// A gateway sends queries like this to fetch fields of entities
+	// referenced from other subgraphs.
+	response := srv.Execute(context.Background(), graphql.Request{
+		Query: `query ($representations: [_Any!]!) {
+			_entities(representations: $representations) {
+				... on User { name }
+			}
+		}`,
+		Variables: map[string]graphql.Input{
+			"representations": graphql.ListInput([]graphql.Input{
+				graphql.InputObject(map[string]graphql.Input{
+					"__typename": graphql.ScalarInput("User"),
+					"id":         graphql.ScalarInput("2"),
+				}),
+			}),
+		},
+	})
+	output, _ := json.Marshal(response)
+	fmt.Println(string(output))
+	// Output:
+	// {"data":{"_entities":[{"name":"Bob"}]}}
+}
@@ -0,0 +1,364 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package federation serves a schema as an Apollo Federation subgraph.
+// See https://www.apollographql.com/docs/federation/subgraph-spec/ for the
+// specification.
+//
+// A subgraph schema is written like any other schema, but may use the
+// federation directives (@key, @external, @requires, @provides, and
+// @shareable) without declaring them:
+//
+//	type Query {
+//	  me: User
+//	}
+//
+//	type User @key(fields: "id") {
+//	  id: ID!
+//	  name: String!
+//	}
+//
+// NewSubgraph adds the directive definitions along with the _service and
+// _entities query fields that a gateway uses to fetch the subgraph's SDL and
+// resolve references to entities.
+package federation
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/ast"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// directiveDefinitions declares the federation directives.
+const directiveDefinitions = `
+scalar _FieldSet
+directive @key(fields: _FieldSet!, resolvable: Boolean = true) repeatable on OBJECT
+directive @external on FIELD_DEFINITION | OBJECT
+directive @requires(fields: _FieldSet!) on FIELD_DEFINITION
+directive @provides(fields: _FieldSet!) on FIELD_DEFINITION
+directive @shareable repeatable on OBJECT | FIELD_DEFINITION
+`
+
+// Subgraph is a parsed subgraph schema along with the functions used to
+// resolve its entities.
+type Subgraph struct {
+	sdl string
+	// base is the schema without the _service and _entities fields. It is
+	// used to check and resolve the application's query object.
+	base   *graphql.Schema
+	schema *graphql.Schema
+
+	entityOrder []string
+	entities    map[string]*entity
+}
+
+type entity struct {
+	resolvable bool
+	resolve    EntityResolver
+}
+
+// EntityResolver fetches an entity from its representation: the fields named
+// in one of the type's @key directives. It should return nil if the entity
+// does not exist.
+type EntityResolver func(ctx context.Context, representation map[string]graphql.Value) (interface{}, error)
+
+// NewSubgraph parses a subgraph schema. Object types with a @key directive are
+// entities, which need a resolver set with SetEntityResolver unless the
+// directive has the resolvable: false argument.
+func NewSubgraph(source string, opts *graphql.SchemaOptions) (*Subgraph, error) {
+	baseSource := source + "\n" + directiveDefinitions
+	base, err := graphql.ParseSchema(baseSource, opts)
+	if err != nil {
+		return nil, xerrors.Errorf("new subgraph: %w", err)
+	}
+	sg := &Subgraph{
+		sdl:      source,
+		base:     base,
+		entities: make(map[string]*entity),
+	}
+	for _, typ := range base.Types() {
+		if typ.Kind() != graphql.ObjectKind {
+			continue
+		}
+		var keys []*graphql.Directive
+		for _, d := range typ.Directives() {
+			if d.Name == "key" {
+				keys = append(keys, d)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		e := new(entity)
+		for _, key := range keys {
+			if err := checkFieldSet(typ, key.Args["fields"].Scalar()); err != nil {
+				return nil, xerrors.Errorf("new subgraph: %s @key: %w", typ.Name(), err)
+			}
+			e.resolvable = e.resolvable || key.Args["resolvable"].Boolean()
+		}
+		sg.entityOrder = append(sg.entityOrder, typ.Name())
+		sg.entities[typ.Name()] = e
+	}
+
+	fedSource, err := sg.federatedSource(baseSource)
+	if err != nil {
+		return nil, xerrors.Errorf("new subgraph: %w", err)
+	}
+	fedOpts := new(graphql.SchemaOptions)
+	if opts != nil {
+		*fedOpts = *opts
+	}
+	fedOpts.StructuredScalars = append(fedOpts.StructuredScalars[:len(fedOpts.StructuredScalars):len(fedOpts.StructuredScalars)], "_Any")
+	sg.schema, err = graphql.ParseSchema(fedSource, fedOpts)
+	if err != nil {
+		return nil, xerrors.Errorf("new subgraph: %w", err)
+	}
+	return sg, nil
+}
+
+// federatedSource adds the federation types and fields to the schema source.
+func (sg *Subgraph) federatedSource(baseSource string) (string, error) {
+	doc, errs := ast.Parse(baseSource)
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+	var query *ast.ObjectTypeDefinition
+	for _, defn := range doc.Definitions {
+		if defn.Type != nil && defn.Type.Object != nil && defn.Type.Object.Name.Value == "Query" {
+			query = defn.Type.Object
+			break
+		}
+	}
+	if query == nil || query.Fields == nil {
+		return "", xerrors.New("could not find Query type")
+	}
+	sb := new(strings.Builder)
+	end := int(query.Fields.RBrace)
+	sb.WriteString(baseSource[:end])
+	sb.WriteString("\n_service: _Service!\n")
+	if len(sg.entityOrder) > 0 {
+		sb.WriteString("_entities(representations: [_Any!]!): [_Entity]!\n")
+	}
+	sb.WriteString(baseSource[end:])
+	sb.WriteString("\nscalar _Any\ntype _Service { sdl: String }\n")
+	if len(sg.entityOrder) > 0 {
+		sb.WriteString("union _Entity = ")
+		sb.WriteString(strings.Join(sg.entityOrder, " | "))
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// checkFieldSet returns an error if the field set is not a valid selection of
+// fields on the given type.
+func checkFieldSet(typ *graphql.Type, fields string) error {
+	doc, errs := ast.Parse("{" + fields + "}")
+	if len(errs) > 0 {
+		return xerrors.Errorf("invalid field set %q", fields)
+	}
+	if len(doc.Definitions) != 1 || doc.Definitions[0].Operation == nil {
+		return xerrors.Errorf("invalid field set %q", fields)
+	}
+	return checkSelectionSet(typ, doc.Definitions[0].Operation.SelectionSet)
+}
+
+func checkSelectionSet(typ *graphql.Type, set *ast.SelectionSet) error {
+	for _, sel := range set.Sel {
+		if sel.Field == nil {
+			return xerrors.Errorf("fragments not allowed in field set")
+		}
+		name := sel.Field.Name.Value
+		f := typ.Field(name)
+		if f == nil {
+			return xerrors.Errorf("%s has no field %s", typ.Name(), name)
+		}
+		if len(f.Args) > 0 {
+			return xerrors.Errorf("field %s.%s in field set must not have arguments", typ.Name(), name)
+		}
+		fieldType := f.Type
+		for fieldType.OfType() != nil {
+			fieldType = fieldType.OfType()
+		}
+		hasSub := sel.Field.SelectionSet != nil
+		switch {
+		case fieldType.Kind() == graphql.ObjectKind && !hasSub:
+			return xerrors.Errorf("field %s.%s in field set must have a selection", typ.Name(), name)
+		case fieldType.Kind() != graphql.ObjectKind && hasSub:
+			return xerrors.Errorf("field %s.%s in field set must not have a selection", typ.Name(), name)
+		case hasSub:
+			if err := checkSelectionSet(fieldType, sel.Field.SelectionSet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Schema returns the subgraph's schema, including the federation types and
+// fields.
+func (sg *Subgraph) Schema() *graphql.Schema {
+	return sg.schema
+}
+
+// SDL returns the schema source passed to NewSubgraph, which is served by the
+// _service field.
+func (sg *Subgraph) SDL() string {
+	return sg.sdl
+}
+
+// EntityTypes returns the names of the subgraph's entity types in the order
+// they were defined.
+func (sg *Subgraph) EntityTypes() []string {
+	return append([]string(nil), sg.entityOrder...)
+}
+
+// SetEntityResolver sets the function used to resolve references to the named
+// entity type. It returns an error if the type is not an entity.
+// SetEntityResolver must not be called concurrently with NewServer.
+func (sg *Subgraph) SetEntityResolver(typeName string, resolve EntityResolver) error {
+	e := sg.entities[typeName]
+	if e == nil {
+		return xerrors.Errorf("set entity resolver: %s is not an entity type", typeName)
+	}
+	e.resolve = resolve
+	return nil
+}
+
+// NewServer returns a new server for the subgraph. query and mutation follow
+// the same rules as graphql.NewServer, except that query must not be a
+// function. The _service and _entities fields are resolved by the server, so
+// query must not provide them.
+func (sg *Subgraph) NewServer(query, mutation interface{}) (*graphql.Server, error) {
+	resolvers := make(map[string]EntityResolver)
+	for _, name := range sg.entityOrder {
+		e := sg.entities[name]
+		if e.resolve == nil {
+			if e.resolvable {
+				return nil, xerrors.Errorf("new subgraph server: no entity resolver for %s", name)
+			}
+			continue
+		}
+		resolvers[name] = e.resolve
+	}
+	if query != nil && reflect.ValueOf(query).Kind() == reflect.Func {
+		return nil, xerrors.New("new subgraph server: query must not be a function")
+	}
+	// Check the root objects against the schema they will be resolved with.
+	if _, err := graphql.NewServer(sg.base, query, mutation); err != nil {
+		return nil, xerrors.Errorf("new subgraph server: %w", err)
+	}
+	r := &root{
+		base:      sg.base,
+		query:     query,
+		service:   &service{SDL: sg.sdl},
+		resolvers: resolvers,
+	}
+	srv, err := graphql.NewServer(sg.schema, r, mutation)
+	if err != nil {
+		return nil, xerrors.Errorf("new subgraph server: %w", err)
+	}
+	return srv, nil
+}
+
+// root is the query object of a subgraph server.
+type root struct {
+	base      *graphql.Schema
+	query     interface{}
+	service   *service
+	resolvers map[string]EntityResolver
+}
+
+// service is the Go representation of the _Service type.
+type service struct {
+	SDL string
+}
+
+// ResolveField resolves the federation fields and delegates all others to the
+// application's query object.
+func (r *root) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	switch req.Name {
+	case "_service":
+		return r.service, nil
+	case "_entities":
+		return r.resolveEntities(ctx, req.Args["representations"])
+	default:
+		return r.base.ResolveField(ctx, r.query, "Query", req)
+	}
+}
+
+func (r *root) resolveEntities(ctx context.Context, reps graphql.Value) ([]interface{}, error) {
+	entities := make([]interface{}, reps.Len())
+	for i := range entities {
+		var rep map[string]graphql.Value
+		if err := reps.At(i).Convert(&rep); err != nil {
+			return nil, xerrors.Errorf("representations[%d]: not an object", i)
+		}
+		typeName := rep["__typename"].Scalar()
+		delete(rep, "__typename")
+		resolve := r.resolvers[typeName]
+		if resolve == nil {
+			return nil, xerrors.Errorf("representations[%d]: cannot resolve entity type %q", i, typeName)
+		}
+		e, err := resolve(ctx, rep)
+		if err != nil {
+			return nil, xerrors.Errorf("representations[%d]: %w", i, err)
+		}
+		if isNil(e) {
+			continue
+		}
+		entities[i] = &entityValue{
+			base:     r.base,
+			typeName: typeName,
+			value:    e,
+		}
+	}
+	return entities, nil
+}
+
+// entityValue is an entity returned from an EntityResolver. It ensures that
+// the entity resolves to the type named in its representation.
+type entityValue struct {
+	base     *graphql.Schema
+	typeName string
+	value    interface{}
+}
+
+// GraphQLType returns the entity's type name.
+func (e *entityValue) GraphQLType() string {
+	return e.typeName
+}
+
+// ResolveField resolves a field on the entity.
+func (e *entityValue) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	return e.base.ResolveField(ctx, e.value, e.typeName, req)
+}
+
+func isNil(x interface{}) bool {
+	if x == nil {
+		return true
+	}
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
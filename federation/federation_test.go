@@ -0,0 +1,293 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqltest"
+)
+
+const testSDL = `
+type Query {
+  me: User
+}
+
+type User @key(fields: "id") {
+  id: ID!
+  name: String!
+  reviews: [Review!]!
+}
+
+type Review @key(fields: "id") @key(fields: "author { id }") {
+  id: ID!
+  body: String!
+  author: User! @provides(fields: "name")
+}
+
+type Product @key(fields: "upc", resolvable: false) @shareable {
+  upc: String!
+  weight: Int @external
+  inStock: Boolean! @requires(fields: "weight")
+}
+`
+
+type testQuery struct{}
+
+func (testQuery) Me() *testUser {
+	return testUsers["1"]
+}
+
+type testUser struct {
+	ID      string
+	Name    string
+	Reviews []*testReview
+}
+
+type testReview struct {
+	ID     string
+	Body   string
+	Author *testUser
+}
+
+var testUsers = map[string]*testUser{
+	"1": {ID: "1", Name: "Alice"},
+}
+
+func init() {
+	testUsers["1"].Reviews = []*testReview{
+		{ID: "10", Body: "Great!", Author: testUsers["1"]},
+	}
+}
+
+func newTestServer(tb testing.TB) *graphql.Server {
+	tb.Helper()
+	sg, err := NewSubgraph(testSDL, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	err = sg.SetEntityResolver("User", func(ctx context.Context, rep map[string]graphql.Value) (interface{}, error) {
+		id := rep["id"].Scalar()
+		if id == "bad" {
+			return nil, xerrors.New("bad user")
+		}
+		u := testUsers[id]
+		if u == nil {
+			return nil, nil
+		}
+		return u, nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	err = sg.SetEntityResolver("Review", func(ctx context.Context, rep map[string]graphql.Value) (interface{}, error) {
+		for _, r := range testUsers["1"].Reviews {
+			if r.ID == rep["id"].Scalar() {
+				return r, nil
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := sg.NewServer(testQuery{}, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+func TestSubgraph(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(t)
+
+	t.Run("Service", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ _service { sdl } }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		var got string
+		if err := resp.Data.ValueFor("_service").ValueFor("sdl").Convert(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != testSDL {
+			t.Errorf("_service.sdl = %q; want %q", got, testSDL)
+		}
+	})
+	t.Run("Delegate", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ me { name reviews { body } } }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{"me": {"name": "Alice", "reviews": [{"body": "Great!"}]}}`)
+	})
+	t.Run("EntitiesVariables", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `query ($representations: [_Any!]!) {
+				_entities(representations: $representations) {
+					__typename
+					... on User { name }
+					... on Review { body author { id } }
+				}
+			}`,
+			Variables: map[string]interface{}{
+				"representations": []interface{}{
+					map[string]interface{}{"__typename": "User", "id": "1"},
+					map[string]interface{}{"__typename": "Review", "id": "10"},
+					map[string]interface{}{"__typename": "User", "id": "404"},
+				},
+			},
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"_entities": [
+				{"__typename": "User", "name": "Alice"},
+				{"__typename": "Review", "body": "Great!", "author": {"id": "1"}},
+				null
+			]
+		}`)
+	})
+	t.Run("EntitiesLiteral", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `query ($id: ID!) {
+				_entities(representations: [{__typename: "User", id: $id}]) {
+					... on User { id name }
+				}
+			}`,
+			Variables: map[string]interface{}{"id": "1"},
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{"_entities": [{"id": "1", "name": "Alice"}]}`)
+	})
+	t.Run("ResolverError", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ _entities(representations: [{__typename: "User", id: "bad"}]) { __typename } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Path:      graphqltest.Path("_entities"),
+				Locations: []graphql.Location{{Line: 1, Column: 3}},
+			},
+		})
+	})
+	t.Run("UnknownType", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ _entities(representations: [{__typename: "Product", upc: "1"}]) { __typename } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Path:      graphqltest.Path("_entities"),
+				Locations: []graphql.Location{{Line: 1, Column: 3}},
+			},
+		})
+	})
+	t.Run("UndefinedVariable", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ _entities(representations: [{__typename: "User", id: $id}]) { __typename } }`,
+		})
+		if len(resp.Errors) == 0 {
+			t.Error("no errors returned")
+		}
+	})
+}
+
+func TestNewSubgraph(t *testing.T) {
+	sg, err := NewSubgraph(testSDL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"User", "Review", "Product"}, sg.EntityTypes()); diff != "" {
+		t.Errorf("EntityTypes() (-want +got):\n%s", diff)
+	}
+	if sg.SDL() != testSDL {
+		t.Errorf("SDL() = %q; want %q", sg.SDL(), testSDL)
+	}
+	for _, name := range []string{"_Any", "_Entity", "_Service"} {
+		if sg.Schema().Type(name) == nil {
+			t.Errorf("Schema().Type(%q) = <nil>", name)
+		}
+	}
+	if err := sg.SetEntityResolver("Query", nil); err == nil {
+		t.Error("SetEntityResolver(\"Query\", ...) did not return an error")
+	}
+	if _, err := sg.NewServer(testQuery{}, nil); err == nil {
+		t.Error("NewServer without entity resolvers did not return an error")
+	}
+}
+
+func TestNewSubgraphErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sdl  string
+	}{
+		{
+			name: "UnknownKeyField",
+			sdl:  `type Query { me: User } type User @key(fields: "uuid") { id: ID! }`,
+		},
+		{
+			name: "KeyMissingSelection",
+			sdl:  `type Query { me: User } type User @key(fields: "self") { id: ID! self: User! }`,
+		},
+		{
+			name: "KeyExtraSelection",
+			sdl:  `type Query { me: User } type User @key(fields: "id { x }") { id: ID! }`,
+		},
+		{
+			name: "KeyArguments",
+			sdl:  `type Query { me: User } type User @key(fields: "name") { name(upper: Boolean): String }`,
+		},
+		{
+			name: "MalformedKey",
+			sdl:  `type Query { me: User } type User @key(fields: "{") { id: ID! }`,
+		},
+		{
+			name: "ReservedField",
+			sdl:  `type Query { _service: String }`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewSubgraph(test.sdl, nil); err == nil {
+				t.Error("NewSubgraph did not return an error")
+			}
+		})
+	}
+}
+
+func TestNoEntities(t *testing.T) {
+	sg, err := NewSubgraph(`type Query { hello: String! }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.Schema().Type("_Entity") != nil {
+		t.Error("schema without entities has _Entity type")
+	}
+	srv, err := sg.NewServer(&struct{ Hello string }{Hello: "world"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := graphqltest.Execute(context.Background(), t, srv, graphqltest.Operation{
+		Query: `{ hello _service { sdl } }`,
+	})
+	graphqltest.AssertErrors(t, resp, nil)
+	graphqltest.AssertData(t, resp, `{"hello": "world", "_service": {"sdl": "type Query { hello: String! }"}}`)
+}
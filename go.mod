@@ -4,6 +4,5 @@ go 1.12
 
 require (
 	github.com/google/go-cmp v0.3.1
-	go.opencensus.io v0.22.2
 	golang.org/x/xerrors v0.0.0-20190717185122-a985d3407aa7
 )
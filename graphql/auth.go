@@ -0,0 +1,110 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// AuthDirectiveName is the name of the directive that marks object types and
+// fields that require authorization. The schema must declare the directive to
+// use it, with whatever arguments the server's Authorizer understands. For
+// example:
+//
+//	enum Role { USER, ADMIN }
+//
+//	directive @auth(requires: Role, scopes: [String!]) on OBJECT | FIELD_DEFINITION
+//
+//	type Query {
+//	  invoices: [Invoice!]! @auth(scopes: ["read:billing"])
+//	}
+const AuthDirectiveName = "auth"
+
+// ForbiddenErrorCode is the "code" entry in the extensions of errors returned
+// for fields that an Authorizer denied.
+const ForbiddenErrorCode = "FORBIDDEN"
+
+// An Authorizer decides whether an operation may resolve fields marked with the
+// @auth directive. Authorize is called before the field's resolver and must be
+// safe to call from multiple goroutines. If Authorize returns an error, then
+// the resolver is not called and the field is treated as null with an error
+// whose extensions have a "code" of "FORBIDDEN". If the field is non-null,
+// then the null propagates to the parent field.
+type Authorizer interface {
+	Authorize(ctx context.Context, info *AuthorizationInfo) error
+}
+
+// AuthorizationInfo describes a field that requires authorization. It must not
+// be modified or retained past the end of the call to Authorize.
+type AuthorizationInfo struct {
+	// ParentType is the name of the object type that the field belongs to.
+	ParentType string
+	// Request holds the parameters that would be passed to the resolver.
+	Request FieldRequest
+	// Directives holds the @auth directives applied to the parent type and to
+	// the field, in that order.
+	Directives []*Directive
+}
+
+type authorizerContextKey struct{}
+
+func withAuthorizer(ctx context.Context, auth Authorizer) context.Context {
+	return context.WithValue(ctx, authorizerContextKey{}, auth)
+}
+
+func authorizerFromContext(ctx context.Context) Authorizer {
+	auth, _ := ctx.Value(authorizerContextKey{}).(Authorizer)
+	return auth
+}
+
+// authorizeField calls the context's Authorizer if the field requires
+// authorization.
+func authorizeField(ctx context.Context, parentType *gqlType, f *SelectedField) error {
+	auth := authorizerFromContext(ctx)
+	if auth == nil {
+		return nil
+	}
+	var directives []*Directive
+	if d := findDirective(parentType.directives, AuthDirectiveName); d != nil {
+		directives = append(directives, d)
+	}
+	if d := findDirective(parentType.obj.field(f.name).directives, AuthDirectiveName); d != nil {
+		directives = append(directives, d)
+	}
+	if len(directives) == 0 {
+		return nil
+	}
+	err := auth.Authorize(ctx, &AuthorizationInfo{
+		ParentType: parentType.toNullable().String(),
+		Request:    f.toRequest(),
+		Directives: directives,
+	})
+	if err != nil {
+		return &authorizationError{err: err}
+	}
+	return nil
+}
+
+// authorizationError is returned for fields denied by an Authorizer.
+type authorizationError struct {
+	err error
+}
+
+func (e *authorizationError) Error() string {
+	return fmt.Sprintf("forbidden: %v", e.err)
+}
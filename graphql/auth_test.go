@@ -0,0 +1,223 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+)
+
+const authSchema = `
+enum Role { USER, ADMIN }
+
+directive @auth(requires: Role, scopes: [String!]) on OBJECT | FIELD_DEFINITION
+
+type Query {
+  me: User
+  billing: Billing
+  users: [User!]! @auth(requires: ADMIN)
+  settings: Settings!
+}
+
+type User {
+  name: String!
+  email: String @auth(scopes: ["read:email"])
+}
+
+type Billing @auth(scopes: ["read:billing"]) {
+  balance: Int!
+}
+
+type Settings {
+  theme: String!
+  secret: String! @auth(requires: ADMIN)
+}
+`
+
+type authQuery struct {
+	called *bool
+}
+
+func (q authQuery) Me() *authUser {
+	return &authUser{Name: "Alice", Email: "alice@example.com"}
+}
+
+func (q authQuery) Billing() *authBilling {
+	return &authBilling{Balance: 42}
+}
+
+func (q authQuery) Users() []*authUser {
+	*q.called = true
+	return []*authUser{{Name: "Alice"}}
+}
+
+func (q authQuery) Settings() *authSettings {
+	return &authSettings{Theme: "dark", Secret: "xyzzy"}
+}
+
+type authUser struct {
+	Name  string
+	Email string
+}
+
+type authBilling struct {
+	Balance int32
+}
+
+type authSettings struct {
+	Theme  string
+	Secret string
+}
+
+// testAuthorizer grants roles and scopes from a fixed set.
+type testAuthorizer struct {
+	role   string
+	scopes map[string]bool
+	infos  []string
+}
+
+func (a *testAuthorizer) Authorize(ctx context.Context, info *AuthorizationInfo) error {
+	for _, d := range info.Directives {
+		a.infos = append(a.infos, info.ParentType+"."+info.Request.Name)
+		if requires := d.Args["requires"]; !requires.IsNull() && requires.Scalar() != a.role {
+			return xerrors.Errorf("requires %s", requires.Scalar())
+		}
+		if scopes := d.Args["scopes"]; !scopes.IsNull() {
+			for i := 0; i < scopes.Len(); i++ {
+				if !a.scopes[scopes.At(i).Scalar()] {
+					return xerrors.Errorf("missing scope %s", scopes.At(i).Scalar())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestAuthorizer(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(authSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		auth       *testAuthorizer
+		query      string
+		want       string
+		wantErrors []*ResponseError
+		wantCalled bool
+	}{
+		{
+			name:       "Allowed",
+			auth:       &testAuthorizer{role: "ADMIN", scopes: map[string]bool{"read:email": true, "read:billing": true}},
+			query:      `{ me { name email } billing { balance } users { name } }`,
+			want:       `{"me":{"name":"Alice","email":"alice@example.com"},"billing":{"balance":42},"users":[{"name":"Alice"}]}`,
+			wantCalled: true,
+		},
+		{
+			name:  "DeniedNullableField",
+			auth:  &testAuthorizer{role: "USER"},
+			query: `{ me { name email } }`,
+			want:  `{"me":{"name":"Alice","email":null}}`,
+			wantErrors: []*ResponseError{{
+				Message:    "field me: field email: forbidden: missing scope read:email",
+				Locations:  []Location{{Line: 1, Column: 13}},
+				Path:       []PathSegment{{Field: "me"}, {Field: "email"}},
+				Extensions: map[string]interface{}{"code": ForbiddenErrorCode},
+			}},
+		},
+		{
+			name:  "DeniedType",
+			auth:  &testAuthorizer{role: "USER"},
+			query: `{ billing { balance } }`,
+			want:  `{"billing":null}`,
+			wantErrors: []*ResponseError{{
+				Message:    "field billing: field balance: forbidden: missing scope read:billing",
+				Locations:  []Location{{Line: 1, Column: 13}},
+				Path:       []PathSegment{{Field: "billing"}, {Field: "balance"}},
+				Extensions: map[string]interface{}{"code": ForbiddenErrorCode},
+			}},
+		},
+		{
+			name:  "DeniedNonNullField",
+			auth:  &testAuthorizer{role: "USER"},
+			query: `{ me { name } settings { theme secret } }`,
+			want:  `null`,
+			wantErrors: []*ResponseError{{
+				Message:    "field settings: field secret: forbidden: requires ADMIN",
+				Locations:  []Location{{Line: 1, Column: 32}},
+				Path:       []PathSegment{{Field: "settings"}, {Field: "secret"}},
+				Extensions: map[string]interface{}{"code": ForbiddenErrorCode},
+			}},
+		},
+		{
+			name:  "DeniedBeforeResolver",
+			auth:  &testAuthorizer{role: "USER"},
+			query: `{ me { name } users { name } }`,
+			want:  `null`,
+			wantErrors: []*ResponseError{{
+				Message:    "field users: forbidden: requires ADMIN",
+				Locations:  []Location{{Line: 1, Column: 15}},
+				Path:       []PathSegment{{Field: "users"}},
+				Extensions: map[string]interface{}{"code": ForbiddenErrorCode},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			server, err := NewServerWithOptions(schema, authQuery{called: &called}, nil, &ServerOptions{
+				Authorizer: test.auth,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Execute(context.Background(), Request{Query: test.query})
+			if diff := cmp.Diff(test.wantErrors, response.Errors); diff != "" {
+				t.Errorf("errors (-want +got):\n%s", diff)
+			}
+			got, err := json.Marshal(response.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("data = %s; want %s", got, test.want)
+			}
+			if called != test.wantCalled {
+				t.Errorf("users resolver called = %t; want %t", called, test.wantCalled)
+			}
+		})
+	}
+
+	t.Run("Undeclared", func(t *testing.T) {
+		schema, err := ParseSchema(`type Query { x: Int }`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewServerWithOptions(schema, struct{ X int32 }{}, nil, &ServerOptions{
+			Authorizer: new(testAuthorizer),
+		})
+		if err == nil {
+			t.Error("NewServerWithOptions did not return an error")
+		}
+	})
+}
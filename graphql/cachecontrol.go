@@ -0,0 +1,263 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// CacheControlDirectiveName is the name of the directive that gives cache
+// hints for object types, union types, and fields. The schema must declare the
+// directive to use it:
+//
+//	enum CacheControlScope { PUBLIC, PRIVATE }
+//
+//	directive @cacheControl(
+//	  maxAge: Int
+//	  scope: CacheControlScope
+//	) on FIELD_DEFINITION | OBJECT | UNION
+//
+// maxAge is the number of seconds that the field's value may be cached.
+// A scope of PRIVATE marks values that are specific to the user that made the
+// request.
+//
+// When the schema declares the directive, the server computes a CachePolicy
+// for each query it executes. A field's hint comes from a call to
+// SetCacheHint in its resolver, or else from the directive on the field, or
+// else from the directive on the field's named return type. Root fields and
+// fields of object or union type without a maxAge use the server's
+// DefaultCacheMaxAge. Other fields without a maxAge inherit the policy of
+// their parent. The response's policy has the smallest maxAge of all the
+// resolved fields and is private if any of them is private.
+const CacheControlDirectiveName = "cacheControl"
+
+// CacheScope specifies who may cache a response.
+type CacheScope int
+
+// Cache scopes.
+const (
+	// CachePublic permits shared caches like CDNs to store a response.
+	CachePublic CacheScope = iota
+	// CachePrivate permits only the requesting user's cache to store a
+	// response.
+	CachePrivate
+)
+
+// String returns the scope's name in the CacheControlScope enum.
+func (scope CacheScope) String() string {
+	switch scope {
+	case CachePublic:
+		return "PUBLIC"
+	case CachePrivate:
+		return "PRIVATE"
+	default:
+		return "CacheScope(" + strconv.Itoa(int(scope)) + ")"
+	}
+}
+
+// CachePolicy describes how long and by whom a response may be cached.
+type CachePolicy struct {
+	MaxAge time.Duration
+	Scope  CacheScope
+}
+
+// Cacheable reports whether the policy permits caching at all.
+func (policy CachePolicy) Cacheable() bool {
+	return policy.MaxAge > 0
+}
+
+// SetCacheHint overrides the cache hint of the field being resolved with
+// policy. It must be called with the context passed to the field's resolver.
+// SetCacheHint does nothing if the server is not computing a cache policy
+// for the operation.
+func SetCacheHint(ctx context.Context, policy CachePolicy) {
+	hint, _ := ctx.Value(fieldCacheHintContextKey{}).(*fieldCacheHint)
+	if hint == nil {
+		return
+	}
+	hint.mu.Lock()
+	hint.policy = policy
+	hint.set = true
+	hint.mu.Unlock()
+}
+
+// cacheHint is the parsed form of a @cacheControl directive.
+type cacheHint struct {
+	maxAge    time.Duration
+	hasMaxAge bool
+	private   bool
+}
+
+// newCacheHint parses the @cacheControl directive in a list of directives.
+// It returns nil if there is no such directive.
+func newCacheHint(directives []*Directive) (*cacheHint, error) {
+	d := findDirective(directives, CacheControlDirectiveName)
+	if d == nil {
+		return nil, nil
+	}
+	hint := new(cacheHint)
+	for name, arg := range d.Args {
+		if arg.IsNull() {
+			continue
+		}
+		switch name {
+		case "maxAge":
+			n, err := strconv.Atoi(arg.Scalar())
+			if err != nil {
+				return nil, xerrors.Errorf("@%s(maxAge:): %w", CacheControlDirectiveName, err)
+			}
+			if n < 0 {
+				return nil, xerrors.Errorf("@%s(maxAge:): negative", CacheControlDirectiveName)
+			}
+			hint.maxAge = time.Duration(n) * time.Second
+			hint.hasMaxAge = true
+		case "scope":
+			switch scope := arg.Scalar(); scope {
+			case "PUBLIC":
+			case "PRIVATE":
+				hint.private = true
+			default:
+				return nil, xerrors.Errorf("@%s(scope:): unknown scope %q", CacheControlDirectiveName, scope)
+			}
+		default:
+			return nil, xerrors.Errorf("@%s(%s:): unsupported argument", CacheControlDirectiveName, name)
+		}
+	}
+	return hint, nil
+}
+
+// cachePolicyState accumulates the cache policy of an operation. It is stored
+// in the context of queries executed by a server whose schema declares
+// @cacheControl.
+type cachePolicyState struct {
+	defaultMaxAge time.Duration
+
+	mu        sync.Mutex
+	maxAge    time.Duration
+	hasMaxAge bool
+	private   bool
+}
+
+type cachePolicyContextKey struct{}
+
+func withCachePolicy(ctx context.Context, defaultMaxAge time.Duration) (context.Context, *cachePolicyState) {
+	if defaultMaxAge < 0 {
+		defaultMaxAge = 0
+	}
+	state := &cachePolicyState{defaultMaxAge: defaultMaxAge}
+	return context.WithValue(ctx, cachePolicyContextKey{}, state), state
+}
+
+func cachePolicyFromContext(ctx context.Context) *cachePolicyState {
+	state, _ := ctx.Value(cachePolicyContextKey{}).(*cachePolicyState)
+	return state
+}
+
+// restrict combines a field's hint into the operation's policy.
+func (state *cachePolicyState) restrict(hint cacheHint) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if hint.hasMaxAge && (!state.hasMaxAge || hint.maxAge < state.maxAge) {
+		state.maxAge = hint.maxAge
+		state.hasMaxAge = true
+	}
+	state.private = state.private || hint.private
+}
+
+// policy returns the operation's policy. Operations that did not resolve any
+// fields with a maxAge are not cacheable.
+func (state *cachePolicyState) policy() CachePolicy {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	var policy CachePolicy
+	if state.hasMaxAge {
+		policy.MaxAge = state.maxAge
+	}
+	if state.private {
+		policy.Scope = CachePrivate
+	}
+	return policy
+}
+
+// fieldHint returns the static hint for a field of parentType that returns
+// typ. root is true for fields of the operation's root type.
+func (state *cachePolicyState) fieldHint(parentType, typ *gqlType, name string, root bool) cacheHint {
+	var hint cacheHint
+	if fh := parentType.obj.field(name).cacheHint; fh != nil {
+		hint = *fh
+	}
+	named := typ
+	for named.isList() {
+		named = named.listElem
+	}
+	if th := named.cacheHint; th != nil {
+		if !hint.hasMaxAge {
+			hint.maxAge, hint.hasMaxAge = th.maxAge, th.hasMaxAge
+		}
+		hint.private = hint.private || th.private
+	}
+	if !hint.hasMaxAge && (root || named.isObject() || named.isUnion()) {
+		hint.maxAge = state.defaultMaxAge
+		hint.hasMaxAge = true
+	}
+	return hint
+}
+
+// fieldCacheHint holds the hint set by SetCacheHint for a single field.
+type fieldCacheHint struct {
+	mu     sync.Mutex
+	policy CachePolicy
+	set    bool
+}
+
+type fieldCacheHintContextKey struct{}
+
+func withFieldCacheHint(ctx context.Context) (context.Context, *fieldCacheHint) {
+	hint := new(fieldCacheHint)
+	return context.WithValue(ctx, fieldCacheHintContextKey{}, hint), hint
+}
+
+// override returns the hint set by SetCacheHint, if any.
+func (hint *fieldCacheHint) override() (_ cacheHint, ok bool) {
+	hint.mu.Lock()
+	defer hint.mu.Unlock()
+	if !hint.set {
+		return cacheHint{}, false
+	}
+	maxAge := hint.policy.MaxAge
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	return cacheHint{
+		maxAge:    maxAge,
+		hasMaxAge: true,
+		private:   hint.policy.Scope == CachePrivate,
+	}, true
+}
+
+// restrictIntrospectionCache applies the default maxAge for the __schema and
+// __type root fields, which cannot have hints.
+func restrictIntrospectionCache(ctx context.Context) {
+	if state := cachePolicyFromContext(ctx); state != nil {
+		state.restrict(cacheHint{maxAge: state.defaultMaxAge, hasMaxAge: true})
+	}
+}
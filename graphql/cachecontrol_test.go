@@ -0,0 +1,249 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const cacheControlSchema = `
+enum CacheControlScope { PUBLIC, PRIVATE }
+
+directive @cacheControl(maxAge: Int, scope: CacheControlScope) on FIELD_DEFINITION | OBJECT | UNION
+
+type Query {
+  version: String! @cacheControl(maxAge: 3600)
+  time: String!
+  news: [Article!]!
+  headline: Article @cacheControl(maxAge: 30)
+  me: User
+  dynamic: String! @cacheControl(maxAge: 3600)
+  broken: String @cacheControl(maxAge: 3600)
+}
+
+type Mutation {
+  touch: String! @cacheControl(maxAge: 3600)
+}
+
+type Article @cacheControl(maxAge: 60) {
+  title: String!
+  views: Int! @cacheControl(maxAge: 5)
+  author: User
+}
+
+type User @cacheControl(maxAge: 120, scope: PRIVATE) {
+  name: String!
+}
+`
+
+type cacheQuery struct{}
+
+func (cacheQuery) Version() string { return "1.0" }
+
+func (cacheQuery) Time() string { return "noon" }
+
+func (cacheQuery) News() []*cacheArticle {
+	return []*cacheArticle{{Title: "Hello", Views: 7, Author: &cacheUser{Name: "Alice"}}}
+}
+
+func (cacheQuery) Headline() *cacheArticle {
+	return &cacheArticle{Title: "Hello", Views: 7}
+}
+
+func (cacheQuery) Me() *cacheUser { return &cacheUser{Name: "Alice"} }
+
+func (cacheQuery) Dynamic(ctx context.Context) string {
+	SetCacheHint(ctx, CachePolicy{MaxAge: 5 * time.Second})
+	return "xyzzy"
+}
+
+func (cacheQuery) Broken() (string, error) { return "", xerrors.New("bork") }
+
+type cacheMutation struct{}
+
+func (cacheMutation) Touch() string { return "ok" }
+
+type cacheArticle struct {
+	Title  string
+	Views  int32
+	Author *cacheUser
+}
+
+type cacheUser struct {
+	Name string
+}
+
+func TestCachePolicy(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(cacheControlSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		defaultMaxAge time.Duration
+		query         string
+		want          CachePolicy
+	}{
+		{
+			name:  "FieldHint",
+			query: `{ version }`,
+			want:  CachePolicy{MaxAge: time.Hour},
+		},
+		{
+			name:  "RootFieldDefault",
+			query: `{ version time }`,
+			want:  CachePolicy{},
+		},
+		{
+			name:          "RootFieldDefaultMaxAge",
+			defaultMaxAge: 5 * time.Minute,
+			query:         `{ version time }`,
+			want:          CachePolicy{MaxAge: 5 * time.Minute},
+		},
+		{
+			name:  "TypeHint",
+			query: `{ news { title } }`,
+			want:  CachePolicy{MaxAge: time.Minute},
+		},
+		{
+			name:  "Minimum",
+			query: `{ version news { title views } }`,
+			want:  CachePolicy{MaxAge: 5 * time.Second},
+		},
+		{
+			name:  "FieldHintOverridesType",
+			query: `{ headline { title } }`,
+			want:  CachePolicy{MaxAge: 30 * time.Second},
+		},
+		{
+			name:  "PrivateType",
+			query: `{ version me { name } }`,
+			want:  CachePolicy{MaxAge: 2 * time.Minute, Scope: CachePrivate},
+		},
+		{
+			name:  "NestedPrivateType",
+			query: `{ news { author { name } } }`,
+			want:  CachePolicy{MaxAge: time.Minute, Scope: CachePrivate},
+		},
+		{
+			name:  "SetCacheHint",
+			query: `{ version dynamic }`,
+			want:  CachePolicy{MaxAge: 5 * time.Second},
+		},
+		{
+			name:  "Errors",
+			query: `{ version broken }`,
+			want:  CachePolicy{},
+		},
+		{
+			name:  "Mutation",
+			query: `mutation { touch }`,
+			want:  CachePolicy{},
+		},
+		{
+			name:  "OnlyTypeName",
+			query: `{ __typename }`,
+			want:  CachePolicy{},
+		},
+		{
+			name:          "Introspection",
+			defaultMaxAge: 5 * time.Minute,
+			query:         `{ version __schema { queryType { name } } }`,
+			want:          CachePolicy{MaxAge: 5 * time.Minute},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, err := NewServerWithOptions(schema, cacheQuery{}, cacheMutation{}, &ServerOptions{
+				DefaultCacheMaxAge: test.defaultMaxAge,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := srv.Execute(context.Background(), Request{Query: test.query})
+			if test.name != "Errors" && len(resp.Errors) > 0 {
+				t.Fatalf("errors: %v", resp.Errors)
+			}
+			if resp.CachePolicy != test.want {
+				t.Errorf("CachePolicy = %+v; want %+v", resp.CachePolicy, test.want)
+			}
+		})
+	}
+}
+
+func TestCachePolicyUndeclared(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			version: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServerWithOptions(schema, cacheQuery{}, nil, &ServerOptions{
+		DefaultCacheMaxAge: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := srv.Execute(context.Background(), Request{Query: `{ version }`})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %v", resp.Errors)
+	}
+	if resp.CachePolicy != (CachePolicy{}) {
+		t.Errorf("CachePolicy = %+v; want zero", resp.CachePolicy)
+	}
+}
+
+func TestCacheControlSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name: "NegativeMaxAge",
+			schema: `
+				directive @cacheControl(maxAge: Int) on FIELD_DEFINITION
+				type Query { version: String! @cacheControl(maxAge: -1) }
+			`,
+		},
+		{
+			name: "UnknownScope",
+			schema: `
+				directive @cacheControl(scope: String) on OBJECT
+				type Query @cacheControl(scope: "SHARED") { version: String! }
+			`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseSchema(test.schema, nil); err == nil {
+				t.Error("ParseSchema did not return an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/xerrors"
+)
+
+// ConstraintDirectiveName is the name of the directive that restricts the
+// values of arguments and input fields. The schema must declare the directive
+// to use it, with any subset of these arguments:
+//
+//	directive @constraint(
+//	  min: Float
+//	  max: Float
+//	  minLength: Int
+//	  maxLength: Int
+//	  pattern: String
+//	  format: String
+//	) on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+//
+// min and max bound the values of Int and Float inputs. minLength and
+// maxLength bound the number of characters in other scalar inputs. pattern is
+// an RE2 regular expression that other scalar inputs must match; it is not
+// anchored unless it starts with ^ and ends with $. format is one of
+// "date", "date-time", "email", "ipv4", "ipv6", "uri", or "uuid". Constraints
+// on list inputs apply to each element.
+//
+// Constraints are checked when arguments and variables are coerced, before any
+// resolvers run. Default values are not checked.
+const ConstraintDirectiveName = "constraint"
+
+// inputConstraint is the parsed form of a @constraint directive.
+type inputConstraint struct {
+	min, max             float64
+	hasMin, hasMax       bool
+	minLength, maxLength int // -1 if not set
+	pattern              *regexp.Regexp
+	format               string
+}
+
+var constraintFormats = map[string]func(string) bool{
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil
+	},
+	"ipv6": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() == nil
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+	"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+// newInputConstraint parses the @constraint directive in a list of directives.
+// It returns nil if there is no such directive.
+func newInputConstraint(directives []*Directive) (*inputConstraint, error) {
+	d := findDirective(directives, ConstraintDirectiveName)
+	if d == nil {
+		return nil, nil
+	}
+	c := &inputConstraint{minLength: -1, maxLength: -1}
+	for name, arg := range d.Args {
+		if arg.IsNull() {
+			continue
+		}
+		var err error
+		switch name {
+		case "min":
+			c.hasMin = true
+			c.min, err = strconv.ParseFloat(arg.Scalar(), 64)
+		case "max":
+			c.hasMax = true
+			c.max, err = strconv.ParseFloat(arg.Scalar(), 64)
+		case "minLength":
+			c.minLength, err = strconv.Atoi(arg.Scalar())
+		case "maxLength":
+			c.maxLength, err = strconv.Atoi(arg.Scalar())
+		case "pattern":
+			c.pattern, err = regexp.Compile(arg.Scalar())
+		case "format":
+			c.format = arg.Scalar()
+			if constraintFormats[c.format] == nil {
+				err = xerrors.Errorf("unknown format %q", c.format)
+			}
+		default:
+			err = xerrors.New("unsupported argument")
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("@%s(%s:): %w", ConstraintDirectiveName, name, err)
+		}
+	}
+	return c, nil
+}
+
+// check returns an error if v does not satisfy the constraint.
+func (c *inputConstraint) check(v Value) error {
+	if c == nil {
+		return nil
+	}
+	switch val := v.val.(type) {
+	case string:
+		if t := v.typ.toNullable(); t == intType || t == floatType {
+			return c.checkNumber(val)
+		}
+		return c.checkString(val)
+	case []Value:
+		for i, elem := range val {
+			if err := c.check(elem); err != nil {
+				return xerrors.Errorf("list[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *inputConstraint) checkNumber(s string) error {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if c.hasMin && n < c.min {
+		return xerrors.Errorf("%s is less than minimum %s", s, formatConstraintNumber(c.min))
+	}
+	if c.hasMax && n > c.max {
+		return xerrors.Errorf("%s is greater than maximum %s", s, formatConstraintNumber(c.max))
+	}
+	return nil
+}
+
+func (c *inputConstraint) checkString(s string) error {
+	n := utf8.RuneCountInString(s)
+	if c.minLength >= 0 && n < c.minLength {
+		return xerrors.Errorf("length %d is less than minimum length %d", n, c.minLength)
+	}
+	if c.maxLength >= 0 && n > c.maxLength {
+		return xerrors.Errorf("length %d is greater than maximum length %d", n, c.maxLength)
+	}
+	if c.pattern != nil && !c.pattern.MatchString(s) {
+		return xerrors.Errorf("%q does not match pattern %q", s, c.pattern)
+	}
+	if c.format != "" && !constraintFormats[c.format](s) {
+		return xerrors.Errorf("%q is not a valid %s", s, c.format)
+	}
+	return nil
+}
+
+func formatConstraintNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
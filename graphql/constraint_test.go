@@ -0,0 +1,219 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const constraintSchema = `
+directive @constraint(
+  min: Float
+  max: Float
+  minLength: Int
+  maxLength: Int
+  pattern: String
+  format: String
+) on ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION
+
+type Query {
+  page(size: Int! @constraint(min: 1, max: 100)): Boolean!
+  search(term: String! @constraint(minLength: 3, maxLength: 10)): Boolean!
+  user(id: ID! @constraint(format: "uuid")): Boolean!
+  tags(tags: [String!]! @constraint(pattern: "^[a-z]+$")): Boolean!
+}
+
+type Mutation {
+  signUp(input: SignUpInput!): Boolean!
+}
+
+input SignUpInput {
+  email: String! @constraint(format: "email")
+  age: Int @constraint(min: 13)
+}
+`
+
+type constraintQuery struct {
+	calls *int
+}
+
+func (q constraintQuery) Page() bool   { *q.calls++; return true }
+func (q constraintQuery) Search() bool { *q.calls++; return true }
+func (q constraintQuery) User() bool   { *q.calls++; return true }
+func (q constraintQuery) Tags() bool   { *q.calls++; return true }
+
+type constraintMutation struct {
+	calls *int
+}
+
+func (m constraintMutation) SignUp() bool { *m.calls++; return true }
+
+func TestConstraint(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(constraintSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]Input
+		want      []*ResponseError
+	}{
+		{
+			name:  "Valid",
+			query: `{ page(size: 10) search(term: "gopher") user(id: "123e4567-e89b-12d3-a456-426614174000") tags(tags: ["a", "b"]) }`,
+		},
+		{
+			name:  "ValidInputObject",
+			query: `mutation { signUp(input: {email: "alice@example.com", age: 30}) }`,
+		},
+		{
+			name:  "Min",
+			query: `{ page(size: 0) }`,
+			want: []*ResponseError{{
+				Message:   "field page: argument size: 0 is less than minimum 1",
+				Locations: []Location{{Line: 1, Column: 8}},
+				Path:      []PathSegment{{Field: "page"}},
+			}},
+		},
+		{
+			name:  "Max",
+			query: `{ page(size: 101) }`,
+			want: []*ResponseError{{
+				Message:   "field page: argument size: 101 is greater than maximum 100",
+				Locations: []Location{{Line: 1, Column: 8}},
+				Path:      []PathSegment{{Field: "page"}},
+			}},
+		},
+		{
+			name:  "MaxLength",
+			query: `{ search(term: "abcdefghijk") }`,
+			want: []*ResponseError{{
+				Message:   "field search: argument term: length 11 is greater than maximum length 10",
+				Locations: []Location{{Line: 1, Column: 10}},
+				Path:      []PathSegment{{Field: "search"}},
+			}},
+		},
+		{
+			name:  "Format",
+			query: `{ user(id: "42") }`,
+			want: []*ResponseError{{
+				Message:   `field user: argument id: "42" is not a valid uuid`,
+				Locations: []Location{{Line: 1, Column: 8}},
+				Path:      []PathSegment{{Field: "user"}},
+			}},
+		},
+		{
+			name:  "PatternListElement",
+			query: `{ tags(tags: ["ok", "NOPE"]) }`,
+			want: []*ResponseError{{
+				Message:   `field tags: argument tags: list[1]: "NOPE" does not match pattern "^[a-z]+$"`,
+				Locations: []Location{{Line: 1, Column: 8}},
+				Path:      []PathSegment{{Field: "tags"}},
+			}},
+		},
+		{
+			name:  "Variable",
+			query: `query($n: Int!) { page(size: $n) }`,
+			variables: map[string]Input{
+				"n": ScalarInput("500"),
+			},
+			want: []*ResponseError{{
+				Message:   "field page: argument size: 500 is greater than maximum 100",
+				Locations: []Location{{Line: 1, Column: 24}},
+				Path:      []PathSegment{{Field: "page"}},
+			}},
+		},
+		{
+			name:  "InputObjectLiteral",
+			query: `mutation { signUp(input: {email: "alice@example.com", age: 12}) }`,
+			want: []*ResponseError{{
+				Message:   "field signUp: argument input: input field age: 12 is less than minimum 13",
+				Locations: []Location{{Line: 1, Column: 55}},
+				Path:      []PathSegment{{Field: "signUp"}},
+			}},
+		},
+		{
+			name:  "InputObjectVariable",
+			query: `mutation($in: SignUpInput!) { signUp(input: $in) }`,
+			variables: map[string]Input{
+				"in": InputObject(map[string]Input{
+					"email": ScalarInput("not an email"),
+				}),
+			},
+			want: []*ResponseError{{
+				Message: `variable $in: input field email: "not an email" is not a valid email`,
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls := 0
+			server, err := NewServer(schema, constraintQuery{&calls}, constraintMutation{&calls})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Execute(context.Background(), Request{
+				Query:     test.query,
+				Variables: test.variables,
+			})
+			if diff := cmp.Diff(test.want, response.Errors); diff != "" {
+				t.Errorf("errors (-want +got):\n%s", diff)
+			}
+			if len(test.want) > 0 && calls > 0 {
+				t.Errorf("%d resolvers called; want 0", calls)
+			}
+		})
+	}
+}
+
+func TestConstraintSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	const directive = `directive @constraint(min: Float, pattern: String, format: String, exclusiveMin: Float) on ARGUMENT_DEFINITION` + "\n"
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name:   "BadPattern",
+			schema: directive + `type Query { x(s: String @constraint(pattern: "(")): Int }`,
+		},
+		{
+			name:   "UnknownFormat",
+			schema: directive + `type Query { x(s: String @constraint(format: "phone")): Int }`,
+		},
+		{
+			name:   "UnsupportedArgument",
+			schema: directive + `type Query { x(n: Int @constraint(exclusiveMin: 0)): Int }`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseSchema(test.schema, nil)
+			if err == nil {
+				t.Fatal("ParseSchema did not return an error")
+			}
+			t.Log(err)
+		})
+	}
+}
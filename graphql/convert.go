@@ -47,6 +47,8 @@ import (
 // Lists will be converted into Go slices. Elements are converted using the same
 // rules as Convert.
 //
+// Uploads may only be converted into a graphql.Upload or a pointer to one.
+//
 // If the Go value is of type graphql.Value, then the Value is copied verbatim.
 //
 // Null will be converted to the zero value of that type.
@@ -162,6 +164,11 @@ func (v Value) convert(dst reflect.Value) error {
 		if err := convertValueMap(dst, val, v.typ); err != nil {
 			return err
 		}
+	case *Upload:
+		if goType != uploadGoType {
+			return newConversionError(goType, v.typ)
+		}
+		dst.Set(reflect.ValueOf(*val))
 	default:
 		panic("unknown type in Value")
 	}
@@ -0,0 +1,237 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"strconv"
+
+	"zombiezen.com/go/graphql-server/ast"
+)
+
+// CostOptions specifies how ValidatedQuery.Cost estimates the cost of an
+// operation. nil is treated the same as the zero value.
+type CostOptions struct {
+	// DefaultListSize is the number of elements assumed for list fields that
+	// do not have a size argument. If zero, 10 is used.
+	DefaultListSize int
+
+	// ListSizeArguments names the integer arguments that bound the number of
+	// elements in a list field. If nil, "first", "last", and "limit" are used.
+	ListSizeArguments []string
+}
+
+// maxCost is the upper bound for costs to avoid overflow.
+const maxCost = 1 << 30
+
+// Cost estimates the cost of executing the named operation without running
+// any resolvers. Each field costs 1, and the cost of the fields selected
+// below a list field is multiplied by the expected size of the list. Fields
+// in fragments are counted for every type they may apply to, so the result
+// is an upper bound. Cost returns 0 if there is no such operation.
+func (query *ValidatedQuery) Cost(operationName string, variables map[string]Input, opts *CostOptions) int {
+	op := query.doc.FindOperation(operationName)
+	if op == nil {
+		return 0
+	}
+	// Variables that fail to coerce make the operation fail before it runs, so
+	// their errors don't matter here.
+	varValues, _ := coerceVariableValues(query.source, query.schema.types, variables, op.VariableDefinitions)
+	c := newCostScope(query.schema.types, opts)
+	c.doc = query.doc
+	c.scope = &selectionSetScope{
+		source:    query.source,
+		doc:       query.doc,
+		types:     query.schema.types,
+		variables: varValues,
+	}
+	typ := query.schema.operationType(op.Type)
+	if typ == nil {
+		return 0
+	}
+	return c.selectionSet(typ, op.SelectionSet)
+}
+
+// Cost estimates the cost of resolving the field and the fields selected
+// below it in the same way as ValidatedQuery.Cost. Cost returns 1 if info
+// was not created by a server.
+func (info *FieldInfo) Cost(opts *CostOptions) int {
+	if info.typ == nil {
+		return 1
+	}
+	c := newCostScope(info.types, opts)
+	return c.selectedField(info.typ, info.Request.Args, info.Request.Selection)
+}
+
+type costScope struct {
+	types       map[string]*gqlType
+	doc         *ast.Document
+	scope       *selectionSetScope
+	defaultSize int
+	sizeArgs    []string
+}
+
+func newCostScope(types map[string]*gqlType, opts *CostOptions) *costScope {
+	c := &costScope{
+		types:       types,
+		defaultSize: 10,
+		sizeArgs:    []string{"first", "last", "limit"},
+	}
+	if opts != nil {
+		if opts.DefaultListSize > 0 {
+			c.defaultSize = opts.DefaultListSize
+		}
+		if opts.ListSizeArguments != nil {
+			c.sizeArgs = opts.ListSizeArguments
+		}
+	}
+	return c
+}
+
+func (c *costScope) selectionSet(typ *gqlType, set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	total := 0
+	for _, sel := range set.Sel {
+		switch {
+		case sel.Field != nil:
+			total = addCost(total, c.field(typ, sel.Field))
+		case sel.FragmentSpread != nil:
+			frag := c.doc.FindFragment(sel.FragmentSpread.Name.Value)
+			if frag == nil {
+				continue
+			}
+			fragType := c.types[frag.Type.Name.Value]
+			total = addCost(total, c.selectionSet(fragType, frag.SelectionSet))
+		case sel.InlineFragment != nil:
+			fragType := typ
+			if cond := sel.InlineFragment.Type; cond != nil {
+				fragType = c.types[cond.Name.Value]
+			}
+			total = addCost(total, c.selectionSet(fragType, sel.InlineFragment.SelectionSet))
+		}
+	}
+	return total
+}
+
+func (c *costScope) field(parent *gqlType, field *ast.Field) int {
+	var info *objectTypeField
+	switch field.Name.Value {
+	case typeNameFieldName:
+		return 1
+	case schemaFieldName:
+		info = schemaField()
+	case typeByNameFieldName:
+		info = typeByNameField()
+	default:
+		if parent == nil || parent.obj == nil {
+			return 1
+		}
+		info = parent.obj.field(field.Name.Value)
+	}
+	if info == nil || field.SelectionSet == nil {
+		return 1
+	}
+	sub := c.selectionSet(info.typ.selectionSetType(), field.SelectionSet)
+	if info.typ.toNullable().isList() {
+		// Size arguments are coerced as they would be for execution, so
+		// omitted arguments and variables take their default values.
+		args, _ := coerceArgumentValues(c.scope, info.args, field.Arguments)
+		sub = mulCost(sub, c.listSize(args))
+	}
+	return addCost(1, sub)
+}
+
+// selectedField estimates the cost of a field of the given type that is
+// being executed with the given arguments and selection set.
+func (c *costScope) selectedField(typ *gqlType, args map[string]Value, set *SelectionSet) int {
+	if set == nil {
+		return 1
+	}
+	parent := typ.selectionSetType()
+	sub := 0
+	for _, f := range set.fields {
+		cost := 1
+		if f.sub != nil {
+			for _, defn := range c.selectedFieldDefinitions(parent, f) {
+				if fcost := c.selectedField(defn.typ, f.args, f.sub); fcost > cost {
+					cost = fcost
+				}
+			}
+		}
+		sub = addCost(sub, cost)
+	}
+	if typ.toNullable().isList() {
+		sub = mulCost(sub, c.listSize(args))
+	}
+	return addCost(1, sub)
+}
+
+// selectedFieldDefinitions returns the definitions of a field selected on the
+// given object or union type. A field selected on a union has a definition
+// for each object type in its type condition.
+func (c *costScope) selectedFieldDefinitions(parent *gqlType, f *SelectedField) []*objectTypeField {
+	switch f.name {
+	case schemaFieldName:
+		return []*objectTypeField{schemaField()}
+	case typeByNameFieldName:
+		return []*objectTypeField{typeByNameField()}
+	}
+	if parent == nil {
+		return nil
+	}
+	if parent.obj != nil {
+		if defn := parent.obj.field(f.name); defn != nil {
+			return []*objectTypeField{defn}
+		}
+		return nil
+	}
+	var defns []*objectTypeField
+	for name := range f.typeCondition {
+		if typ := c.types[name]; typ != nil && typ.obj != nil {
+			if defn := typ.obj.field(f.name); defn != nil {
+				defns = append(defns, defn)
+			}
+		}
+	}
+	return defns
+}
+
+// listSize returns the expected number of elements of a list field with the
+// given argument values.
+func (c *costScope) listSize(args map[string]Value) int {
+	for _, name := range c.sizeArgs {
+		if n, err := strconv.Atoi(args[name].Scalar()); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return c.defaultSize
+}
+
+func addCost(a, b int) int {
+	if a+b > maxCost {
+		return maxCost
+	}
+	return a + b
+}
+
+func mulCost(a, b int) int {
+	if a != 0 && b > maxCost/a {
+		return maxCost
+	}
+	return a * b
+}
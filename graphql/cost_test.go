@@ -0,0 +1,221 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCost(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			me: User
+			users(first: Int): [User!]!
+			search(term: String!): [Result!]!
+			posts(first: Int = 1000): [Post!]!
+		}
+
+		type User {
+			name: String!
+			friends(limit: Int): [User!]!
+		}
+
+		type Post {
+			title: String!
+		}
+
+		union Result = User | Post
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]Input
+		opts          *CostOptions
+		want          int
+	}{
+		{
+			name:  "Scalars",
+			query: `{ me { name } }`,
+			want:  2,
+		},
+		{
+			name:  "DefaultListSize",
+			query: `{ users { name } }`,
+			want:  1 + 10*1,
+		},
+		{
+			name:  "ListSizeArgument",
+			query: `{ users(first: 3) { name friends(limit: 2) { name } } }`,
+			want:  1 + 3*(1+1+2*1),
+		},
+		{
+			name:      "ListSizeVariable",
+			query:     `query($n: Int) { users(first: $n) { name } }`,
+			variables: map[string]Input{"n": ScalarInput("5")},
+			want:      1 + 5*1,
+		},
+		{
+			name:  "VariableDefault",
+			query: `query($n: Int = 100) { users(first: $n) { friends(limit: $n) { name } } }`,
+			want:  1 + 100*(1+100*1),
+		},
+		{
+			name:  "ArgumentDefault",
+			query: `{ posts { title } }`,
+			want:  1 + 1000*1,
+		},
+		{
+			name:  "UnsetVariableUsesArgumentDefault",
+			query: `query($n: Int) { posts(first: $n) { title } }`,
+			want:  1 + 1000*1,
+		},
+		{
+			name:  "Options",
+			query: `{ users(first: 3) { name } }`,
+			opts:  &CostOptions{DefaultListSize: 2, ListSizeArguments: []string{}},
+			want:  1 + 2*1,
+		},
+		{
+			name:  "Fragments",
+			query: `{ search(term: "x") { __typename ...U ... on Post { title } } } fragment U on User { name }`,
+			want:  1 + 10*(1+1+1),
+		},
+		{
+			name:          "NamedOperation",
+			query:         `query A { me { name } } query B { users { name } }`,
+			operationName: "B",
+			want:          11,
+		},
+		{
+			name:          "NoSuchOperation",
+			query:         `query A { me { name } }`,
+			operationName: "B",
+			want:          0,
+		},
+		{
+			name:  "Saturates",
+			query: `{ users(first: 100000) { friends(limit: 100000) { friends(limit: 100000) { name } } } }`,
+			want:  maxCost,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, errs := schema.Validate(test.query)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if got := query.Cost(test.operationName, test.variables, test.opts); got != test.want {
+				t.Errorf("Cost(%q, ...) = %d; want %d", test.operationName, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFieldInfoCost(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			users(first: Int = 2): [User!]!
+		}
+
+		type User {
+			name: String!
+			friends(limit: Int): [User!]!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &costTracer{costs: make(map[string]int)}
+	srv, err := NewServerWithOptions(schema, costQuery{}, nil, &ServerOptions{
+		Tracer: tracer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const query = `query($n: Int = 3) { users { name friends(limit: $n) { name } } }`
+	resp := srv.Execute(context.Background(), Request{Query: query})
+	if len(resp.Errors) > 0 {
+		t.Fatal(resp.Errors)
+	}
+	want := map[string]int{
+		"users":                  1 + 2*(1+1+3*1),
+		"users.0.name":           1,
+		"users.0.friends":        1 + 3*1,
+		"users.0.friends.0.name": 1,
+		"users.1.name":           1,
+		"users.1.friends":        1 + 3*1,
+		"users.1.friends.0.name": 1,
+	}
+	if diff := cmp.Diff(want, tracer.costs); diff != "" {
+		t.Errorf("costs (-want +got):\n%s", diff)
+	}
+	validated, errs := schema.Validate(query)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got := validated.Cost("", nil, nil); got != want["users"] {
+		t.Errorf("ValidatedQuery.Cost(...) = %d; want %d (same as users field)", got, want["users"])
+	}
+}
+
+type costQuery struct{}
+
+func (costQuery) Users(args map[string]Value) []costUser {
+	return make([]costUser, 2)
+}
+
+type costUser struct {
+	Name string
+}
+
+func (u costUser) Friends() []costUser {
+	return make([]costUser, 1)
+}
+
+// costTracer records the estimated cost of each field by path. It is not safe
+// to use concurrently.
+type costTracer struct {
+	costs map[string]int
+}
+
+func (tr *costTracer) TraceExecution(ctx context.Context, req Request) (context.Context, func(*Response)) {
+	return ctx, nil
+}
+
+func (tr *costTracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*ResponseError)) {
+	return ctx, nil
+}
+
+func (tr *costTracer) TraceValidation(ctx context.Context) (context.Context, func(*ValidatedQuery, []*ResponseError)) {
+	return ctx, nil
+}
+
+func (tr *costTracer) TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(interface{}, error)) {
+	tr.costs[formatPath(info.Path)] = info.Cost(nil)
+	return ctx, nil
+}
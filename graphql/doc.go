@@ -48,6 +48,11 @@ Lastly, if the object is a Go struct and the field takes no arguments, then the
 server will read the value from an exported struct field with the same name
 ignoring case.
 
+If a resolver returns an error, the field's value will be null and the error
+will be included in the response. If the error is a *ResponseError, its Path
+is appended to the field's path, so a resolver may report an error for a
+location below the field.
+
 Type Resolution
 
 For abstract types, the server will first attempt to call a GraphQLType method
@@ -23,6 +23,7 @@ import (
 	"log"
 
 	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/graphql-server/ast"
 	"zombiezen.com/go/graphql-server/graphql"
 )
 
@@ -79,6 +80,25 @@ func ExampleValidatedQuery() {
 	// Hiya!
 }
 
+func ExampleValidatedQuery_Document() {
+	server := newServer()
+	query, errs := server.Schema().Validate(`{ genericGreeting, greet(subject: "Alice") }`)
+	if len(errs) > 0 {
+		log.Fatal(errs)
+	}
+
+	// The syntax tree can be inspected with the ast package.
+	ast.Apply(query.Document(), func(c *ast.Cursor) bool {
+		if f, ok := c.Node().(*ast.Field); ok {
+			fmt.Println(f.Name.Value, f.Name.Start.ToPosition(query.Source()))
+		}
+		return true
+	}, nil)
+	// Output:
+	// genericGreeting 1:3
+	// greet 1:20
+}
+
 // GoValue is often useful for testing with go-cmp.
 func ExampleValue_GoValue_gocmp() {
 	ctx := context.Background()
@@ -0,0 +1,94 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"sync"
+)
+
+// An ExtensionCollector gathers entries for the extensions of the response
+// to an operation. Server.Execute creates a collector for each operation and
+// stores it in the context passed to resolvers and tracers. After the
+// operation is executed, the collected entries are added to the response's
+// Extensions, and then the response is passed to the function returned by
+// Tracer.TraceExecution. Tracers thus observe the collected entries, and
+// entries that a tracer sets replace collected entries with the same keys.
+//
+// ExtensionCollector methods are safe to call from multiple goroutines.
+// Methods on a nil collector do nothing, so callers can use the result of
+// ExtensionsFromContext without checking it.
+type ExtensionCollector struct {
+	mu      sync.Mutex
+	entries map[string]interface{}
+}
+
+type extensionCollectorContextKey struct{}
+
+// ExtensionsFromContext returns the extension collector for the operation
+// executing with ctx or nil if ctx is not from an operation.
+func ExtensionsFromContext(ctx context.Context) *ExtensionCollector {
+	c, _ := ctx.Value(extensionCollectorContextKey{}).(*ExtensionCollector)
+	return c
+}
+
+// Set sets the extension entry for key to value. value must be encodable with
+// encoding/json.
+func (c *ExtensionCollector) Set(key string, value interface{}) {
+	c.Update(key, func(interface{}) interface{} { return value })
+}
+
+// Update sets the extension entry for key to the result of calling f with the
+// entry's current value, or nil if the entry is not set. Update holds a lock
+// while calling f, so f must not call methods on c. This is useful for
+// accumulating entries from multiple resolvers, like a list of warnings.
+func (c *ExtensionCollector) Update(key string, f func(old interface{}) interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]interface{})
+	}
+	c.entries[key] = f(c.entries[key])
+}
+
+// Get returns the extension entry for key or nil if the entry is not set.
+func (c *ExtensionCollector) Get(key string) interface{} {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries[key]
+}
+
+// addTo adds the collected entries to the response's extensions.
+func (c *ExtensionCollector) addTo(resp *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) == 0 {
+		return
+	}
+	if resp.Extensions == nil {
+		resp.Extensions = make(map[string]interface{}, len(c.entries))
+	}
+	for k, v := range c.entries {
+		resp.Extensions[k] = v
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"golang.org/x/xerrors"
+)
+
+// SchemaFilter selects the definitions to keep in a schema returned by
+// Schema.Filter. Each function reports whether to keep the given definition;
+// a nil function keeps every definition of its kind. Types passed to the
+// functions belong to the original schema.
+type SchemaFilter struct {
+	// Type is called for each named type defined in the schema source.
+	// Built-in types are always kept.
+	Type func(t *Type) bool
+	// Field is called for each field of a kept object type.
+	Field func(parent *Type, field *FieldDefinition) bool
+	// Argument is called for each argument of a kept field.
+	Argument func(parent *Type, field *FieldDefinition, arg *InputValueDefinition) bool
+	// InputField is called for each field of a kept input object type.
+	InputField func(parent *Type, field *InputValueDefinition) bool
+	// EnumValue is called for each value of a kept enum type.
+	EnumValue func(parent *Type, value *EnumValueDefinition) bool
+}
+
+// ExcludeDirective returns a filter that hides every type, field, argument,
+// input field, and enum value that has the named directive applied to it.
+// For example, given the schema:
+//
+//	directive @internal on OBJECT | FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM_VALUE
+//
+//	type Query {
+//	  user(id: ID!): User
+//	  auditLog: [String!]! @internal
+//	}
+//
+// then schema.Filter(ExcludeDirective("internal")) returns a schema without
+// the auditLog field.
+func ExcludeDirective(name string) *SchemaFilter {
+	keep := func(directives []*Directive) bool {
+		return findDirective(directives, name) == nil
+	}
+	return &SchemaFilter{
+		Type: func(t *Type) bool {
+			return keep(t.Directives())
+		},
+		Field: func(parent *Type, field *FieldDefinition) bool {
+			return keep(field.Directives)
+		},
+		Argument: func(parent *Type, field *FieldDefinition, arg *InputValueDefinition) bool {
+			return keep(arg.Directives)
+		},
+		InputField: func(parent *Type, field *InputValueDefinition) bool {
+			return keep(field.Directives)
+		},
+		EnumValue: func(parent *Type, value *EnumValueDefinition) bool {
+			return keep(value.Directives)
+		},
+	}
+}
+
+// Filter returns a new schema that contains only the definitions selected by
+// filter. The returned schema can be passed to NewServer with the same query,
+// mutation, and subscription objects as the original schema. Operations
+// executed against it can only observe the kept definitions, including through
+// introspection and validation errors.
+//
+// Filter returns an error if the filtered schema would be inconsistent: if a
+// kept definition refers to a hidden type, if a root operation type is
+// hidden, if a required argument or input field is hidden, if a default value
+// uses a hidden enum value, or if an object, union, enum, or input object is
+// left without any members.
+func (schema *Schema) Filter(filter *SchemaFilter) (*Schema, error) {
+	if filter == nil {
+		filter = new(SchemaFilter)
+	}
+	f := &schemaFilter{
+		filter: filter,
+		types:  make(map[string]*gqlType, len(schema.types)),
+	}
+	defined := make(map[string]struct{}, len(schema.typeOrder))
+	for _, name := range schema.typeOrder {
+		defined[name] = struct{}{}
+	}
+	for name, typ := range schema.types {
+		if _, ok := defined[name]; !ok {
+			// Built-in types are shared between schemas.
+			f.types[name] = typ
+		}
+	}
+	var typeOrder []string
+	for _, name := range schema.typeOrder {
+		typ := schema.types[name]
+		if filter.Type != nil && !filter.Type((*Type)(typ)) {
+			if typ == schema.query || typ == schema.mutation || typ == schema.subscription {
+				return nil, xerrors.Errorf("filter schema: cannot hide root type %s", name)
+			}
+			continue
+		}
+		f.types[name] = cloneNamedType(typ)
+		typeOrder = append(typeOrder, name)
+	}
+	for _, name := range typeOrder {
+		if err := f.fill(schema.types[name], f.types[name]); err != nil {
+			return nil, xerrors.Errorf("filter schema: type %s: %w", name, err)
+		}
+	}
+	for _, name := range typeOrder {
+		if err := f.fillDefaults(f.types[name]); err != nil {
+			return nil, xerrors.Errorf("filter schema: type %s: %w", name, err)
+		}
+	}
+	directives := make(map[string]*directiveDefinition, len(schema.directives))
+	for _, name := range schema.directiveOrder {
+		defn := *schema.directives[name]
+		var err error
+		defn.Args, err = f.inputValues(defn.Args, nil)
+		if err == nil {
+			err = f.defaults(defn.Args)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("filter schema: directive @%s: %w", name, err)
+		}
+		directives[name] = &defn
+	}
+	filtered := &Schema{
+		query:          f.types[schema.query.String()],
+		types:          f.types,
+		typeOrder:      typeOrder,
+		directives:     directives,
+		directiveOrder: schema.directiveOrder,
+		goTypes:        make(map[typeKey]*typeDescriptor),
+	}
+	if schema.mutation != nil {
+		filtered.mutation = f.types[schema.mutation.String()]
+	}
+	if schema.subscription != nil {
+		filtered.subscription = f.types[schema.subscription.String()]
+	}
+	return filtered, nil
+}
+
+// schemaFilter holds the state of a call to Schema.Filter.
+type schemaFilter struct {
+	filter *SchemaFilter
+	types  map[string]*gqlType
+}
+
+// cloneNamedType returns a new nullable type with the same name, description,
+// and directives as typ, but with no members.
+func cloneNamedType(typ *gqlType) *gqlType {
+	var clone *gqlType
+	switch {
+	case typ.isScalar():
+		clone = newScalarType(typ.scalar, typ.description)
+		clone.structured = typ.structured
+		clone.nullVariant.structured = typ.structured
+	case typ.isEnum():
+		clone = newEnumType(&enumType{name: typ.enum.name}, typ.description)
+	case typ.isObject():
+		clone = newObjectType(&objectType{name: typ.obj.name}, typ.description)
+	case typ.isUnion():
+		clone = newUnionType(&unionType{name: typ.union.name}, typ.description)
+	case typ.isInputObject():
+		clone = newInputObjectType(&inputObjectType{name: typ.input.name}, typ.description)
+	default:
+		panic("unknown type kind")
+	}
+	clone.directives = typ.directives
+	clone.nullVariant.directives = typ.directives
+	clone.cacheHint = typ.cacheHint
+	clone.nullVariant.cacheHint = typ.cacheHint
+	return clone
+}
+
+// fill copies the kept members of the original named type into its clone.
+func (f *schemaFilter) fill(orig, clone *gqlType) error {
+	parent := (*Type)(orig)
+	switch {
+	case orig.isEnum():
+		for _, v := range orig.enum.values {
+			defn := &EnumValueDefinition{
+				Name:        v.name,
+				Description: v.description,
+				Directives:  v.directives,
+			}
+			if f.filter.EnumValue == nil || f.filter.EnumValue(parent, defn) {
+				clone.enum.values = append(clone.enum.values, v)
+			}
+		}
+		if len(clone.enum.values) == 0 {
+			return xerrors.New("all values hidden")
+		}
+	case orig.isObject():
+		for i := range orig.obj.fields {
+			field := &orig.obj.fields[i]
+			defn := newFieldDefinition(field)
+			if f.filter.Field != nil && !f.filter.Field(parent, defn) {
+				continue
+			}
+			newField := *field
+			var err error
+			newField.typ, err = f.typ(field.typ)
+			if err != nil {
+				return xerrors.Errorf("field %s: %w", field.name, err)
+			}
+			var keepArg func(*InputValueDefinition) bool
+			if f.filter.Argument != nil {
+				keepArg = func(arg *InputValueDefinition) bool {
+					return f.filter.Argument(parent, defn, arg)
+				}
+			}
+			newField.args, err = f.inputValues(field.args, keepArg)
+			if err != nil {
+				return xerrors.Errorf("field %s: %w", field.name, err)
+			}
+			clone.obj.fields = append(clone.obj.fields, newField)
+		}
+		if len(clone.obj.fields) == 0 {
+			return xerrors.New("all fields hidden")
+		}
+	case orig.isUnion():
+		for _, member := range orig.union.possibleTypes {
+			if newMember := f.types[member.String()]; newMember != nil {
+				clone.union.possibleTypes = append(clone.union.possibleTypes, newMember)
+			}
+		}
+		if len(clone.union.possibleTypes) == 0 {
+			return xerrors.New("all possible types hidden")
+		}
+	case orig.isInputObject():
+		var keepField func(*InputValueDefinition) bool
+		if f.filter.InputField != nil {
+			keepField = func(field *InputValueDefinition) bool {
+				return f.filter.InputField(parent, field)
+			}
+		}
+		var err error
+		clone.input.fields, err = f.inputValues(orig.input.fields, keepField)
+		if err != nil {
+			return err
+		}
+		if len(clone.input.fields) == 0 {
+			return xerrors.New("all fields hidden")
+		}
+	}
+	return nil
+}
+
+// fillDefaults replaces the default values of the arguments or input fields
+// of a cloned type.
+func (f *schemaFilter) fillDefaults(clone *gqlType) error {
+	switch {
+	case clone.isObject():
+		for _, field := range clone.obj.fields {
+			if err := f.defaults(field.args); err != nil {
+				return xerrors.Errorf("field %s: %w", field.name, err)
+			}
+		}
+	case clone.isInputObject():
+		return f.defaults(clone.input.fields)
+	}
+	return nil
+}
+
+// inputValues returns the arguments or input fields in list that keep
+// selects. A nil keep selects all. The default values still refer to the
+// original schema until defaults is called.
+func (f *schemaFilter) inputValues(list inputValueDefinitionList, keep func(*InputValueDefinition) bool) (inputValueDefinitionList, error) {
+	defns := newInputValueDefinitions(list)
+	var result inputValueDefinitionList
+	for i, ivd := range list {
+		required := ivd.defaultValue.typ.nonNull && ivd.defaultValue.IsNull()
+		if keep != nil && !keep(defns[i]) {
+			if required {
+				return nil, xerrors.Errorf("cannot hide required %s", ivd.name)
+			}
+			continue
+		}
+		result = append(result, ivd)
+	}
+	return result, nil
+}
+
+// defaults replaces the default values in list with values from the filtered
+// schema. It must be called after all types have been filled.
+func (f *schemaFilter) defaults(list inputValueDefinitionList) error {
+	for i := range list {
+		var err error
+		list[i].defaultValue, err = f.value(list[i].defaultValue)
+		if err != nil {
+			return xerrors.Errorf("%s: %w", list[i].name, err)
+		}
+	}
+	return nil
+}
+
+// typ returns the type in the filtered schema equivalent to typ.
+func (f *schemaFilter) typ(typ *gqlType) (*gqlType, error) {
+	if typ.isList() {
+		elem, err := f.typ(typ.listElem)
+		if err != nil {
+			return nil, err
+		}
+		list := listOf(elem)
+		if typ.nonNull {
+			list = list.toNonNullable()
+		}
+		return list, nil
+	}
+	name := typ.toNullable().String()
+	newType := f.types[name]
+	if newType == nil {
+		return nil, xerrors.Errorf("type %s is hidden", name)
+	}
+	if typ.nonNull {
+		newType = newType.toNonNullable()
+	}
+	return newType, nil
+}
+
+// value returns a copy of the input value v with types from the filtered
+// schema. Hidden input object fields are removed from the copy.
+func (f *schemaFilter) value(v Value) (Value, error) {
+	typ, err := f.typ(v.typ)
+	if err != nil {
+		return Value{}, err
+	}
+	switch val := v.val.(type) {
+	case string:
+		if typ.isEnum() && !typ.enum.has(val) {
+			return Value{}, xerrors.Errorf("default uses hidden enum value %s", val)
+		}
+		return Value{typ: typ, val: val}, nil
+	case []Value:
+		list := make([]Value, 0, len(val))
+		for _, elem := range val {
+			newElem, err := f.value(elem)
+			if err != nil {
+				return Value{}, err
+			}
+			list = append(list, newElem)
+		}
+		return Value{typ: typ, val: list}, nil
+	case map[string]Value:
+		m := make(map[string]Value, len(val))
+		for k, fieldValue := range val {
+			if typ.isInputObject() && typ.input.fields.byName(k) == nil {
+				continue
+			}
+			newFieldValue, err := f.value(fieldValue)
+			if err != nil {
+				return Value{}, err
+			}
+			m[k] = newFieldValue
+		}
+		return Value{typ: typ, val: m}, nil
+	default:
+		return Value{typ: typ, val: v.val}, nil
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const filterSchema = `
+directive @internal on OBJECT | FIELD_DEFINITION | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM_VALUE
+
+type Query {
+  user(id: ID!, includeDeleted: Boolean @internal): User
+  users(role: Role = USER, filter: UserFilter): [User!]!
+  audit: Audit @internal
+}
+
+type Mutation {
+  rename(id: ID!, name: String!): User
+}
+
+type User {
+  id: ID!
+  name: String!
+  passwordHash: String! @internal
+}
+
+type Audit @internal {
+  entries: [String!]!
+}
+
+enum Role {
+  USER
+  ADMIN @internal
+}
+
+input UserFilter {
+  name: String
+  deleted: Boolean @internal
+}
+`
+
+type filterQuery struct{}
+
+func (filterQuery) User(args map[string]Value) *filterUser {
+	return &filterUser{ID: args["id"].Scalar(), Name: "Alice", PasswordHash: "xyzzy"}
+}
+
+func (filterQuery) Users() []*filterUser {
+	return []*filterUser{{ID: "1", Name: "Alice", PasswordHash: "xyzzy"}}
+}
+
+func (filterQuery) Audit() *filterAudit {
+	return &filterAudit{Entries: []string{"secret"}}
+}
+
+type filterMutation struct{}
+
+func (filterMutation) Rename(args map[string]Value) *filterUser {
+	return &filterUser{ID: args["id"].Scalar(), Name: args["name"].Scalar()}
+}
+
+type filterUser struct {
+	ID           string
+	Name         string
+	PasswordHash string
+}
+
+type filterAudit struct {
+	Entries []string
+}
+
+func TestSchemaFilter(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(filterSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := schema.Filter(ExcludeDirective("internal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Types", func(t *testing.T) {
+		var typeNames []string
+		for _, typ := range public.Types() {
+			typeNames = append(typeNames, typ.Name())
+		}
+		want := []string{"Query", "Mutation", "User", "Role", "UserFilter"}
+		if diff := cmp.Diff(want, typeNames); diff != "" {
+			t.Errorf("type names (-want +got):\n%s", diff)
+		}
+		if typ := public.Type("Audit"); typ != nil {
+			t.Errorf("public.Type(\"Audit\") = %v; want nil", typ)
+		}
+		if public.Type("User") == schema.Type("User") {
+			t.Error("filtered schema shares User type with original")
+		}
+		if public.Type("User").Field("passwordHash") != nil {
+			t.Error("User.passwordHash not hidden")
+		}
+		if schema.Type("User").Field("passwordHash") == nil {
+			t.Error("User.passwordHash hidden in original schema")
+		}
+		if args := public.QueryType().Field("user").Args; len(args) != 1 || args[0].Name != "id" {
+			t.Errorf("Query.user args = %v; want [id]", args)
+		}
+		var roles []string
+		for _, v := range public.Type("Role").EnumValues() {
+			roles = append(roles, v.Name)
+		}
+		if diff := cmp.Diff([]string{"USER"}, roles); diff != "" {
+			t.Errorf("Role values (-want +got):\n%s", diff)
+		}
+		if fields := public.Type("UserFilter").InputFields(); len(fields) != 1 || fields[0].Name != "name" {
+			t.Errorf("UserFilter fields = %v; want [name]", fields)
+		}
+		if got := public.QueryType().Field("users").Args[0].Type; got != public.Type("Role") {
+			t.Errorf("Query.users role argument type = %p; want %p (from filtered schema)", got, public.Type("Role"))
+		}
+	})
+
+	server, err := NewServer(public, filterQuery{}, filterMutation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		query     string
+		want      string
+		wantError string
+	}{
+		{
+			name:  "Visible",
+			query: `{ user(id: "1") { id name } users(role: USER) { id } }`,
+			want:  `{"user":{"id":"1","name":"Alice"},"users":[{"id":"1"}]}`,
+		},
+		{
+			name:  "Mutation",
+			query: `mutation { rename(id: "1", name: "Bob") { name } }`,
+			want:  `{"rename":{"name":"Bob"}}`,
+		},
+		{
+			name:      "HiddenField",
+			query:     `{ user(id: "1") { passwordHash } }`,
+			wantError: `field "passwordHash" not found on type User`,
+		},
+		{
+			name:      "HiddenRootField",
+			query:     `{ audit { entries } }`,
+			wantError: `field "audit" not found on type Query`,
+		},
+		{
+			name:      "HiddenArgument",
+			query:     `{ user(id: "1", includeDeleted: true) { id } }`,
+			wantError: "includeDeleted",
+		},
+		{
+			name:      "HiddenEnumValue",
+			query:     `{ users(role: ADMIN) { id } }`,
+			wantError: "ADMIN",
+		},
+		{
+			name:      "HiddenInputField",
+			query:     `{ users(filter: {deleted: true}) { id } }`,
+			wantError: "deleted",
+		},
+		{
+			name:  "Introspection",
+			query: `{ audit: __type(name: "Audit") { name } user: __type(name: "User") { fields { name } } }`,
+			want:  `{"audit":null,"user":{"fields":[{"name":"id"},{"name":"name"}]}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := server.Execute(context.Background(), Request{Query: test.query})
+			if test.wantError != "" {
+				if len(response.Errors) == 0 {
+					t.Fatalf("no errors; want error containing %q", test.wantError)
+				}
+				if msg := response.Errors[0].Message; !strings.Contains(msg, test.wantError) {
+					t.Errorf("error = %q; want to contain %q", msg, test.wantError)
+				}
+				return
+			}
+			for _, err := range response.Errors {
+				t.Errorf("Response error: %v", err)
+			}
+			got, err := response.Data.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("data = %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSchemaFilterErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name: "HiddenQuery",
+			schema: `
+				directive @internal on OBJECT
+				type Query @internal { x: Int }
+			`,
+		},
+		{
+			name: "HiddenMutation",
+			schema: `
+				directive @internal on OBJECT
+				type Query { x: Int }
+				type Mutation @internal { y: Int }
+			`,
+		},
+		{
+			name: "ReferencesHiddenType",
+			schema: `
+				directive @internal on OBJECT
+				type Query { secret: Secret }
+				type Secret @internal { x: Int }
+			`,
+		},
+		{
+			name: "ArgumentReferencesHiddenType",
+			schema: `
+				directive @internal on INPUT_OBJECT
+				type Query { x(in: Secret): Int }
+				input Secret @internal { x: Int }
+			`,
+		},
+		{
+			name: "RequiredArgument",
+			schema: `
+				directive @internal on ARGUMENT_DEFINITION
+				type Query { x(token: String! @internal): Int }
+			`,
+		},
+		{
+			name: "RequiredInputField",
+			schema: `
+				directive @internal on INPUT_FIELD_DEFINITION
+				type Query { x(in: In): Int }
+				input In { a: Int, token: String! @internal }
+			`,
+		},
+		{
+			name: "AllFields",
+			schema: `
+				directive @internal on FIELD_DEFINITION
+				type Query { x: Int @internal }
+			`,
+		},
+		{
+			name: "AllEnumValues",
+			schema: `
+				directive @internal on ENUM_VALUE
+				type Query { x(e: E): Int }
+				enum E { A @internal }
+			`,
+		},
+		{
+			name: "DefaultUsesHiddenEnumValue",
+			schema: `
+				directive @internal on ENUM_VALUE
+				type Query { x(e: E = B): Int }
+				enum E { A, B @internal }
+			`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := ParseSchema(test.schema, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = schema.Filter(ExcludeDirective("internal"))
+			if err == nil {
+				t.Fatal("Filter did not return an error")
+			}
+			t.Log(err)
+		})
+	}
+}
@@ -23,17 +23,66 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
-	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // Server manages execution of GraphQL operations.
 type Server struct {
-	schema   *Schema
-	query    operation
-	mutation operation
+	schema       *Schema
+	query        operation
+	mutation     operation
+	subscription operation
+	tracer       Tracer
+
+	trusted                *trustedDocuments
+	authorizer             Authorizer
+	authorizeIntrospection func(context.Context) bool
+	defaultCacheMaxAge     time.Duration
+}
+
+// ServerOptions specifies optional behavior for a server.
+type ServerOptions struct {
+	// If Tracer is not nil, then it will be notified of each phase of every
+	// operation the server executes.
+	Tracer Tracer
+
+	// If OperationLog is not nil, then the server will log a record of
+	// operations it executes.
+	OperationLog *OperationLogOptions
+
+	// If AuthorizeIntrospection is not nil, then it is called for each query
+	// that selects the __schema or __type fields. If it returns false, then
+	// the query is rejected with a validation error. Use DenyIntrospection to
+	// disable introspection entirely. Queries that only use introspection are
+	// always permitted from contexts returned by WithTrustedIntrospection.
+	AuthorizeIntrospection func(ctx context.Context) bool
+
+	// If Authorizer is not nil, then it is consulted before resolving any
+	// field marked with the @auth directive. The schema must declare the
+	// directive. See AuthDirectiveName for details. If Authorizer is nil, then
+	// @auth directives have no effect.
+	Authorizer Authorizer
+
+	// If TrustedDocuments is not nil, then the server only executes documents
+	// registered in it. Requests for other documents fail with an error.
+	TrustedDocuments *TrustedDocumentOptions
+
+	// DefaultCacheMaxAge is the maximum age of root fields and fields of object
+	// or union type that have no cache hint. It has no effect unless the
+	// schema declares @cacheControl. See CacheControlDirectiveName for
+	// details.
+	DefaultCacheMaxAge time.Duration
+
+	// Subscription is the object that provides the schema's Subscription type.
+	// It follows the same rules as the query and mutation objects passed to
+	// NewServer, except that each of its fields must be a receive-only or
+	// bidirectional channel of the field's type. See Server.Subscribe for
+	// details. If the schema has a Subscription type and Subscription is nil,
+	// then subscription operations fail.
+	Subscription interface{}
 }
 
 // NewServer returns a new server that is backed by the given query object and
@@ -46,6 +95,16 @@ type Server struct {
 // Top-level objects may also implement the OperationFinisher interface. See the
 // interface documentation for details.
 func NewServer(schema *Schema, query, mutation interface{}) (*Server, error) {
+	return NewServerWithOptions(schema, query, mutation, nil)
+}
+
+// NewServerWithOptions returns a new server like NewServer with the given
+// options. opts may be nil, which is equivalent to passing a pointer to the
+// zero value.
+func NewServerWithOptions(schema *Schema, query, mutation interface{}, opts *ServerOptions) (*Server, error) {
+	if opts == nil {
+		opts = new(ServerOptions)
+	}
 	// Check for missing or extra arguments first.
 	if query == nil {
 		return nil, xerrors.New("new server: query is required")
@@ -56,12 +115,41 @@ func NewServer(schema *Schema, query, mutation interface{}) (*Server, error) {
 	if mutation != nil && schema.mutation == nil {
 		return nil, xerrors.New("new server: mutation object given, but no mutation type")
 	}
+	if opts.Subscription != nil && schema.subscription == nil {
+		return nil, xerrors.New("new server: subscription object given, but no subscription type")
+	}
+
+	if opts.OperationLog != nil && opts.OperationLog.Logger == nil {
+		return nil, xerrors.New("new server: operation log has no logger")
+	}
+	if opts.Authorizer != nil && schema.directives[AuthDirectiveName] == nil {
+		return nil, xerrors.Errorf("new server: authorizer given, but schema does not declare @%s", AuthDirectiveName)
+	}
 
 	// Next check for type errors with the arguments provided.
 	srv := &Server{
 		schema: schema,
+		tracer: opts.Tracer,
+
+		authorizer:             opts.Authorizer,
+		authorizeIntrospection: opts.AuthorizeIntrospection,
+		defaultCacheMaxAge:     opts.DefaultCacheMaxAge,
+	}
+	if opts.OperationLog != nil {
+		logTracer := &operationLogTracer{opts: *opts.OperationLog}
+		if srv.tracer == nil {
+			srv.tracer = logTracer
+		} else {
+			srv.tracer = MultiTracer(logTracer, srv.tracer)
+		}
 	}
 	var err error
+	if opts.TrustedDocuments != nil {
+		srv.trusted, err = newTrustedDocuments(schema, opts.TrustedDocuments)
+		if err != nil {
+			return nil, xerrors.Errorf("new server: %w", err)
+		}
+	}
 	srv.query, err = newOperation(schema, schema.query, query)
 	if err != nil {
 		return nil, xerrors.Errorf("new server: %w", err)
@@ -70,6 +158,10 @@ func NewServer(schema *Schema, query, mutation interface{}) (*Server, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("new server: %w", err)
 	}
+	srv.subscription, err = newOperation(schema, schema.subscription, opts.Subscription)
+	if err != nil {
+		return nil, xerrors.Errorf("new server: %w", err)
+	}
 	return srv, nil
 }
 
@@ -82,40 +174,25 @@ func (srv *Server) Schema() *Schema {
 // Execute runs a single GraphQL operation. It is safe to call Execute from
 // multiple goroutines.
 func (srv *Server) Execute(ctx context.Context, req Request) (response Response) {
-	ctx, span := trace.StartSpan(ctx, "graphql:execute", trace.WithSpanKind(trace.SpanKindServer))
-	defer func() {
-		if span.IsRecordingEvents() {
-			span.AddAttributes(trace.BoolAttribute("graphql.has_data", !response.Data.IsNull()))
-			if len(response.Errors) > 0 {
-				if errors, err := json.Marshal(response.Errors); err != nil {
-					span.AddAttributes(trace.StringAttribute("graphql.errors", string(errors)))
-				}
-			}
+	extensions := new(ExtensionCollector)
+	ctx = context.WithValue(ctx, extensionCollectorContextKey{}, extensions)
+	if srv.authorizer != nil {
+		ctx = withAuthorizer(ctx, srv.authorizer)
+	}
+	if tracer := srv.tracerFor(ctx); tracer != nil {
+		var finish func(*Response)
+		ctx, finish = tracer.TraceExecution(withRootTraceFrame(ctx, tracer), req)
+		if finish != nil {
+			defer func() { finish(&response) }()
 		}
-		span.End()
-	}()
-
-	const queryAttribute = "graphql.query"
-	query := req.ValidatedQuery
-	if query == nil {
-		var errs []*ResponseError
+	}
+	// Deferred after the tracer so that the tracer observes the collected
+	// extensions.
+	defer extensions.addTo(&response)
 
-		span.AddAttributes(trace.StringAttribute(queryAttribute, req.Query))
-		_, validateSpan := trace.StartSpan(ctx, "graphql:validate", trace.WithSpanKind(trace.SpanKindServer))
-		query, errs = srv.schema.Validate(req.Query)
-		validateSpan.End()
-		if len(errs) > 0 {
-			return Response{Errors: errs}
-		}
-	} else {
-		span.AddAttributes(trace.StringAttribute(queryAttribute, query.source))
-		if query.schema != srv.schema {
-			return Response{
-				Errors: []*ResponseError{
-					{Message: "query validated with a schema different from the server"},
-				},
-			}
-		}
+	query, errs := srv.lookup(ctx, req)
+	if len(errs) > 0 {
+		return Response{Errors: errs}
 	}
 	return srv.executeValidated(ctx, Request{
 		ValidatedQuery: query,
@@ -124,24 +201,116 @@ func (srv *Server) Execute(ctx context.Context, req Request) (response Response)
 	})
 }
 
-func (srv *Server) executeValidated(ctx context.Context, req Request) Response {
-	span := trace.FromContext(ctx)
-	op := req.ValidatedQuery.doc.FindOperation(req.OperationName)
-	if op == nil {
-		if req.OperationName == "" {
-			return Response{
-				Errors: []*ResponseError{
-					{Message: "multiple operations; must specify operation name"},
-				},
-			}
+// lookup returns the validated document for a request.
+func (srv *Server) lookup(ctx context.Context, req Request) (*ValidatedQuery, []*ResponseError) {
+	query := req.ValidatedQuery
+	if srv.trusted != nil {
+		var err *ResponseError
+		query, err = srv.trusted.lookup(req)
+		var errs []*ResponseError
+		if err != nil {
+			errs = []*ResponseError{err}
+		}
+		srv.traceLookup(ctx, query, errs)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+	} else if query == nil && req.Query == "" && req.DocumentID != "" {
+		return nil, []*ResponseError{
+			{Message: "server does not accept document IDs"},
+		}
+	} else if query == nil {
+		var errs []*ResponseError
+		query, errs = srv.validate(ctx, req.Query)
+		if len(errs) > 0 {
+			return nil, errs
 		}
+	} else if query.schema != srv.schema {
+		return nil, []*ResponseError{
+			{Message: "query validated with a schema different from the server"},
+		}
+	}
+	if errs := srv.checkIntrospection(ctx, query, req.OperationName); len(errs) > 0 {
+		return nil, errs
+	}
+	return query, nil
+}
+
+// validate is like Schema.Validate, but notifies the operation's tracer.
+func (srv *Server) validate(ctx context.Context, source string) (*ValidatedQuery, []*ResponseError) {
+	frame := traceFrameFromContext(ctx)
+	if frame == nil {
+		return srv.schema.Validate(source)
+	}
+	_, finishParse := frame.tracer.TraceParse(ctx, source)
+	doc, errs := srv.schema.parse(source)
+	if finishParse != nil {
+		finishParse(errs)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	_, finishValidation := frame.tracer.TraceValidation(ctx)
+	query, errs := srv.schema.validateDocument(source, doc)
+	if finishValidation != nil {
+		finishValidation(query, errs)
+	}
+	return query, errs
+}
+
+// traceLookup reports a document that was resolved without parsing, like a
+// trusted document, to the operation's tracer as the result of validation.
+// This lets tracers see the document's source even if the request only named
+// it by ID.
+func (srv *Server) traceLookup(ctx context.Context, query *ValidatedQuery, errs []*ResponseError) {
+	frame := traceFrameFromContext(ctx)
+	if frame == nil {
+		return
+	}
+	_, finishValidation := frame.tracer.TraceValidation(ctx)
+	if finishValidation != nil {
+		finishValidation(query, errs)
+	}
+}
+
+// checkIntrospection returns errors for each introspection field in the
+// operation if the server does not permit introspection in ctx.
+func (srv *Server) checkIntrospection(ctx context.Context, query *ValidatedQuery, operationName string) []*ResponseError {
+	if srv.authorizeIntrospection == nil {
+		return nil
+	}
+	op := query.doc.FindOperation(operationName)
+	if op == nil || op.Type != ast.Query {
+		return nil
+	}
+	fields, other := introspectionFields(query.doc, op.SelectionSet)
+	if len(fields) == 0 || (!other && isTrustedIntrospection(ctx)) || srv.authorizeIntrospection(ctx) {
+		return nil
+	}
+	errs := make([]*ResponseError, 0, len(fields))
+	for _, f := range fields {
+		errs = append(errs, &ResponseError{
+			Message: fmt.Sprintf("introspection field %q not allowed", f.Name.Value),
+			Locations: []Location{
+				astPositionToLocation(f.Name.Start.ToPosition(query.source)),
+			},
+		})
+	}
+	return errs
+}
+
+func (srv *Server) executeValidated(ctx context.Context, req Request) Response {
+	op, err := findOperation(req.ValidatedQuery, req.OperationName)
+	if err != nil {
+		return Response{Errors: []*ResponseError{err}}
+	}
+	if op.Type == ast.Subscription {
 		return Response{
 			Errors: []*ResponseError{
-				{Message: fmt.Sprintf("no such operation %q", req.OperationName)},
+				{Message: "subscriptions must be run with Server.Subscribe"},
 			},
 		}
 	}
-	span.AddAttributes(trace.StringAttribute("graphql.operation_name", op.Name.String()))
 	varValues, errs := coerceVariableValues(req.ValidatedQuery.source, srv.schema.types, req.Variables, op.VariableDefinitions)
 	if len(errs) > 0 {
 		resp := Response{}
@@ -156,6 +325,10 @@ func (srv *Server) executeValidated(ctx context.Context, req Request) Response {
 		types:     srv.schema.types,
 		variables: varValues,
 	}
+	var cache *cachePolicyState
+	if op.Type == ast.Query && srv.schema.directives[CacheControlDirectiveName] != nil {
+		ctx, cache = withCachePolicy(ctx, srv.defaultCacheMaxAge)
+	}
 	data, errs := srv.resolve(ctx, scope, op)
 	resp := Response{
 		Data: data,
@@ -163,10 +336,25 @@ func (srv *Server) executeValidated(ctx context.Context, req Request) Response {
 	for _, err := range errs {
 		resp.Errors = append(resp.Errors, toResponseError(err))
 	}
+	if cache != nil && len(resp.Errors) == 0 {
+		resp.CachePolicy = cache.policy()
+	}
 	return resp
 }
 
-func (srv *Server) resolve(ctx context.Context, scope *selectionSetScope, op *gqlang.Operation) (Value, []error) {
+// findOperation returns the operation to execute in a document.
+func findOperation(query *ValidatedQuery, operationName string) (*ast.Operation, *ResponseError) {
+	op := query.doc.FindOperation(operationName)
+	if op == nil {
+		if operationName == "" {
+			return nil, &ResponseError{Message: "multiple operations; must specify operation name"}
+		}
+		return nil, &ResponseError{Message: fmt.Sprintf("no such operation %q", operationName)}
+	}
+	return op, nil
+}
+
+func (srv *Server) resolve(ctx context.Context, scope *selectionSetScope, op *ast.Operation) (Value, []error) {
 	gt, obj, err := srv.operationFor(op.Type)
 	if err != nil {
 		pos := op.Start.ToPosition(scope.source)
@@ -182,26 +370,9 @@ func (srv *Server) resolve(ctx context.Context, scope *selectionSetScope, op *gq
 	if len(errs) > 0 {
 		return Value{}, errs
 	}
-	value := obj.value
-	if obj.flags&operationFunc != 0 {
-		ctx, span := trace.StartSpan(ctx, "graphql:new_operation", trace.WithSpanKind(trace.SpanKindServer))
-		var args []reflect.Value
-		if obj.flags&operationContextParam != 0 {
-			args = append(args, reflect.ValueOf(ctx))
-		}
-		if obj.flags&operationSelectionSetParam != 0 {
-			args = append(args, reflect.ValueOf(sel))
-		}
-		ret := value.Call(args)
-		span.End()
-		if len(ret) == 2 {
-			if err, _ := ret[1].Interface().(error); err != nil {
-				// Intentionally making the returned error opaque to avoid interference in
-				// toResponseError.
-				return Value{}, []error{xerrors.Errorf("server error: %v", err)}
-			}
-		}
-		value = ret[0]
+	value, err := obj.root(ctx, sel)
+	if err != nil {
+		return Value{}, []error{err}
 	}
 	result, resultErrs := srv.schema.valueFromGo(ctx, scope.variables, value, gt, sel)
 	if finisher, ok := interfaceValueForAssertions(value).(OperationFinisher); ok {
@@ -218,15 +389,20 @@ func (srv *Server) resolve(ctx context.Context, scope *selectionSetScope, op *gq
 	return result, resultErrs
 }
 
-func (srv *Server) operationFor(opType gqlang.OperationType) (*gqlType, operation, error) {
+func (srv *Server) operationFor(opType ast.OperationType) (*gqlType, operation, error) {
 	switch opType {
-	case gqlang.Query:
+	case ast.Query:
 		return srv.schema.query, srv.query, nil
-	case gqlang.Mutation:
+	case ast.Mutation:
 		if !srv.mutation.value.IsValid() {
 			return nil, operation{}, xerrors.New("unsupported operation type")
 		}
 		return srv.schema.mutation, srv.mutation, nil
+	case ast.Subscription:
+		if !srv.subscription.value.IsValid() {
+			return nil, operation{}, xerrors.New("unsupported operation type")
+		}
+		return srv.schema.subscription, srv.subscription, nil
 	default:
 		return nil, operation{}, xerrors.New("unsupported operation type")
 	}
@@ -236,7 +412,7 @@ func (srv *Server) operationFor(opType gqlang.OperationType) (*gqlType, operatio
 type ValidatedQuery struct {
 	schema *Schema
 	source string
-	doc    *gqlang.Document
+	doc    *ast.Document
 }
 
 // TypeOf returns the type of the operation with the given name or zero if no
@@ -250,6 +426,18 @@ func (query *ValidatedQuery) TypeOf(operationName string) OperationType {
 	return operationTypeFromAST(op.Type)
 }
 
+// Source returns the GraphQL document text that was validated.
+func (query *ValidatedQuery) Source() string {
+	return query.source
+}
+
+// Document returns the query's parsed syntax tree. Positions in the tree are
+// relative to query.Source(). The returned document is shared with the server
+// and must not be modified.
+func (query *ValidatedQuery) Document() *ast.Document {
+	return query.doc
+}
+
 // If a top-level object (like query or mutation) implements OperationFinisher,
 // then its FinishOperation method will be called after all its fields are
 // resolved. The details struct must not be modified or retained past the end
@@ -285,6 +473,30 @@ const (
 	operationSelectionSetParam
 )
 
+// root returns the operation's top-level object, calling the operation's
+// function if necessary.
+func (obj operation) root(ctx context.Context, sel *SelectionSet) (reflect.Value, error) {
+	if obj.flags&operationFunc == 0 {
+		return obj.value, nil
+	}
+	var args []reflect.Value
+	if obj.flags&operationContextParam != 0 {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	if obj.flags&operationSelectionSetParam != 0 {
+		args = append(args, reflect.ValueOf(sel))
+	}
+	ret := obj.value.Call(args)
+	if len(ret) == 2 {
+		if err, _ := ret[1].Interface().(error); err != nil {
+			// Intentionally making the returned error opaque to avoid interference in
+			// toResponseError.
+			return reflect.Value{}, xerrors.Errorf("server error: %v", err)
+		}
+	}
+	return ret[0], nil
+}
+
 func newOperation(schema *Schema, gt *gqlType, v interface{}) (operation, error) {
 	if v == nil {
 		return operation{}, nil
@@ -361,13 +573,13 @@ const (
 	SubscriptionOperation
 )
 
-func operationTypeFromAST(typ gqlang.OperationType) OperationType {
+func operationTypeFromAST(typ ast.OperationType) OperationType {
 	switch typ {
-	case gqlang.Query:
+	case ast.Query:
 		return QueryOperation
-	case gqlang.Mutation:
+	case ast.Mutation:
 		return MutationOperation
-	case gqlang.Subscription:
+	case ast.Subscription:
 		return SubscriptionOperation
 	default:
 		panic("unknown operation type")
@@ -388,10 +600,21 @@ func (typ OperationType) String() string {
 	}
 }
 
+// MarshalText returns the keyword corresponding to the operation type.
+func (typ OperationType) MarshalText() ([]byte, error) {
+	if typ < QueryOperation || typ > SubscriptionOperation {
+		return nil, xerrors.Errorf("marshal operation type: unknown type %d", int(typ))
+	}
+	return []byte(typ.String()), nil
+}
+
 // Request holds the inputs for a GraphQL execution.
 type Request struct {
 	// Query is the GraphQL document text.
 	Query string `json:"query"`
+	// DocumentID identifies a document registered with the server's
+	// ServerOptions.TrustedDocuments. It may be used instead of Query.
+	DocumentID string `json:"documentId,omitempty"`
 	// If ValidatedQuery is not nil, then it will be used instead of the Query field.
 	ValidatedQuery *ValidatedQuery `json:"-"`
 	// If OperationName is not empty, then the operation with the given name will
@@ -399,12 +622,33 @@ type Request struct {
 	OperationName string `json:"operationName,omitempty"`
 	// Variables specifies the values of the operation's variables.
 	Variables map[string]Input `json:"variables,omitempty"`
+	// Extensions holds additional entries sent by the client, like protocol
+	// metadata. The server does not interpret them. Each value must be
+	// encodable with encoding/json.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // Response holds the output of a GraphQL operation.
 type Response struct {
 	Data   Value            `json:"data"`
 	Errors []*ResponseError `json:"errors,omitempty"`
+	// Extensions holds additional entries for the response's "extensions"
+	// object, like tracing data. Each value must be encodable with
+	// encoding/json. Entries are written in key order.
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	// CachePolicy is the policy computed from cache hints for a query that
+	// finished without errors. It is the zero value, which is not cacheable,
+	// for other responses and for servers whose schema does not declare
+	// @cacheControl. It is not part of the JSON encoding.
+	CachePolicy CachePolicy `json:"-"`
+}
+
+// HasData reports whether the response has a data entry. Responses to
+// requests that fail before execution starts, like those with syntax or
+// validation errors, have no data entry. An execution error can still leave
+// the data entry null.
+func (resp Response) HasData() bool {
+	return resp.Data.typ != nil || !resp.Data.IsNull()
 }
 
 // MarshalJSON converts the response to JSON format.
@@ -418,11 +662,11 @@ func (resp Response) MarshalJSON() ([]byte, error) {
 			return buf, xerrors.Errorf("marshal response: %w", err)
 		}
 		buf = append(buf, errorsData...)
-		if !resp.Data.IsNull() {
+		if resp.HasData() {
 			buf = append(buf, ',')
 		}
 	}
-	if !resp.Data.IsNull() {
+	if resp.HasData() {
 		buf = append(buf, `"data":`...)
 		data, err := json.Marshal(resp.Data)
 		if err != nil {
@@ -430,6 +674,17 @@ func (resp Response) MarshalJSON() ([]byte, error) {
 		}
 		buf = append(buf, data...)
 	}
+	if len(resp.Extensions) > 0 {
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `"extensions":`...)
+		extensions, err := json.Marshal(resp.Extensions)
+		if err != nil {
+			return buf, xerrors.Errorf("marshal response: %w", err)
+		}
+		buf = append(buf, extensions...)
+	}
 	buf = append(buf, '}')
 	return buf, nil
 }
@@ -440,6 +695,10 @@ type ResponseError struct {
 	Message   string        `json:"message"`
 	Locations []Location    `json:"locations,omitempty"`
 	Path      []PathSegment `json:"path,omitempty"`
+
+	// Extensions holds additional information about the error, like a
+	// machine-readable "code".
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // Error returns e.Message.
@@ -464,6 +723,10 @@ func toResponseError(e error) *ResponseError {
 		case *ResponseError:
 			re.Locations = append(re.Locations, e.Locations...)
 			re.Path = append(re.Path, e.Path...)
+			re.Extensions = e.Extensions
+			unknownChain = nil // leaf
+		case *authorizationError:
+			re.Extensions = map[string]interface{}{"code": ForbiddenErrorCode}
 			unknownChain = nil // leaf
 		case *fieldError:
 			re.Path = append(re.Path, PathSegment{Field: e.key})
@@ -474,7 +737,7 @@ func toResponseError(e error) *ResponseError {
 			unknownChain = e.Unwrap()
 		}
 	}
-	if pos, ok := gqlang.ErrorPosition(unknownChain); ok {
+	if pos, ok := ast.ErrorPosition(unknownChain); ok {
 		re.Locations = []Location{astPositionToLocation(pos)}
 	}
 	return re
@@ -489,7 +752,7 @@ func hasLocation(e error) bool {
 	if xerrors.As(e, &fe) {
 		return true
 	}
-	_, ok := gqlang.ErrorPos(e)
+	_, ok := ast.ErrorPos(e)
 	return ok
 }
 
@@ -545,7 +808,7 @@ type Location struct {
 	Column int `json:"column"`
 }
 
-func astPositionToLocation(pos gqlang.Position) Location {
+func astPositionToLocation(pos ast.Position) Location {
 	return Location{
 		Line:   pos.Line,
 		Column: pos.Column,
@@ -19,12 +19,15 @@ package graphql
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -141,9 +144,8 @@ func TestExecuteQuery(t *testing.T) {
 				return &testQueryStruct{MyNonNullString: NullString{}}
 			},
 			request: Request{Query: `{ myNonNullString }`},
-			want: []fieldExpectations{
-				{key: "myNonNullString", value: valueExpectations{null: true}},
-			},
+			// The null propagates to the root, so data is null.
+			want: nil,
 			wantErrors: []*ResponseError{
 				{
 					Locations: []Location{{1, 3}},
@@ -1462,9 +1464,8 @@ func TestExecuteMutate(t *testing.T) {
 			name:           "Method/Error",
 			mutationObject: new(testMutationStruct),
 			request:        Request{Query: `mutation { errorMethod }`},
-			want: []fieldExpectations{
-				{key: "errorMethod", value: valueExpectations{null: true}},
-			},
+			// errorMethod is non-null, so data is null.
+			want: nil,
 			wantErrors: []*ResponseError{
 				{
 					Locations: []Location{{1, 12}},
@@ -1627,6 +1628,200 @@ func (f fieldResolverFunc) Foo() (string, error) {
 	return "WRONG", xerrors.New("this method should never be called")
 }
 
+func TestStructuredScalars(t *testing.T) {
+	t.Parallel()
+
+	const source = `
+		type Query {
+			echo(value: JSON!): String!
+		}
+
+		scalar JSON
+	`
+	schema, err := ParseSchema(source, &SchemaOptions{
+		StructuredScalars: []string{"JSON"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(schema, fieldResolverFunc(func(ctx context.Context, req FieldRequest) (interface{}, error) {
+		return req.Args["value"].String(), nil
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]Input
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:  "Scalar",
+			query: `{ echo(value: "foo") }`,
+			want:  `"foo"`,
+		},
+		{
+			name:  "Object",
+			query: `{ echo(value: {a: 1, b: [true, null]}) }`,
+			want:  "{\n\ta: \"1\",\n\tb: [\"true\", null]\n}",
+		},
+		{
+			name:      "NestedVariable",
+			query:     `query($x: Int) { echo(value: {a: $x}) }`,
+			variables: map[string]Input{"x": ScalarInput("42")},
+			want:      "{\n\ta: \"42\"\n}",
+		},
+		{
+			name:  "ObjectVariable",
+			query: `query($v: JSON!) { echo(value: $v) }`,
+			variables: map[string]Input{
+				"v": InputObject(map[string]Input{
+					"a": ListInput([]Input{ScalarInput("x")}),
+				}),
+			},
+			want: "{\n\ta: [\"x\"]\n}",
+		},
+		{
+			name:    "UndefinedVariable",
+			query:   `{ echo(value: {a: $x}) }`,
+			wantErr: true,
+		},
+		{
+			name:    "DuplicateField",
+			query:   `{ echo(value: {a: 1, a: 2}) }`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := srv.Execute(ctx, Request{
+				Query:     test.query,
+				Variables: test.variables,
+			})
+			if test.wantErr {
+				if len(resp.Errors) == 0 {
+					t.Error("no errors returned")
+				}
+				return
+			}
+			for _, e := range resp.Errors {
+				t.Errorf("Error: %s", e.Message)
+			}
+			if got := resp.Data.ValueFor("echo").Scalar(); got != test.want {
+				t.Errorf("data.echo = %q; want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("NotCustomScalar", func(t *testing.T) {
+		for _, name := range []string{"String", "Query", "Missing"} {
+			_, err := ParseSchema(source, &SchemaOptions{
+				StructuredScalars: []string{name},
+			})
+			if err == nil {
+				t.Errorf("ParseSchema with StructuredScalars: [%q] did not return error", name)
+			}
+		}
+	})
+}
+
+func TestSchemaResolveField(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			foo: String!
+			bar(x: Int!): Int!
+			fail: String
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	q := new(resolveFieldQuery)
+	got, err := schema.ResolveField(ctx, q, "Query", FieldRequest{Name: "foo"})
+	if err != nil || got != "foo" {
+		t.Errorf("ResolveField(foo) = %#v, %v; want \"foo\", <nil>", got, err)
+	}
+	got, err = schema.ResolveField(ctx, q, "Query", FieldRequest{
+		Name: "bar",
+		Args: map[string]Value{"x": {typ: intType, val: "2"}},
+	})
+	if err != nil || got != int32(4) {
+		t.Errorf("ResolveField(bar) = %#v, %v; want int32(4), <nil>", got, err)
+	}
+	_, err = schema.ResolveField(ctx, q, "Query", FieldRequest{Name: "fail"})
+	if err != errResolveFieldFail {
+		t.Errorf("ResolveField(fail) error = %v; want %v", err, errResolveFieldFail)
+	}
+	if _, err := schema.ResolveField(ctx, q, "Query", FieldRequest{Name: "nope"}); err == nil {
+		t.Error("ResolveField(nope) did not return an error")
+	}
+	if _, err := schema.ResolveField(ctx, q, "String", FieldRequest{Name: "foo"}); err == nil {
+		t.Error("ResolveField on String did not return an error")
+	}
+	if _, err := schema.ResolveField(ctx, struct{}{}, "Query", FieldRequest{Name: "foo"}); err == nil {
+		t.Error("ResolveField on struct{}{} did not return an error")
+	}
+}
+
+type resolveFieldQuery struct{}
+
+var errResolveFieldFail = xerrors.New("fail")
+
+func (*resolveFieldQuery) Foo() string { return "foo" }
+
+func (*resolveFieldQuery) Bar(args map[string]Value) int32 {
+	var x int32
+	args["x"].Convert(&x)
+	return x * 2
+}
+
+func (*resolveFieldQuery) Fail() (string, error) { return "", errResolveFieldFail }
+
+func TestResolverResponseError(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			foo: Foo
+		}
+
+		type Foo {
+			bar: String
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(schema, fieldResolverFunc(func(ctx context.Context, req FieldRequest) (interface{}, error) {
+		return nil, &ResponseError{
+			Message: "bad bar",
+			Path:    []PathSegment{{Field: "bar"}},
+		}
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := srv.Execute(context.Background(), Request{
+		Query: `{ foo { bar } }`,
+	})
+	want := []*ResponseError{
+		{
+			Message:   "field foo: bad bar",
+			Locations: []Location{{Line: 1, Column: 3}},
+			Path:      []PathSegment{{Field: "foo"}, {Field: "bar"}},
+		},
+	}
+	if diff := cmp.Diff(want, resp.Errors); diff != "" {
+		t.Errorf("errors (-want +got):\n%s", diff)
+	}
+}
+
 func TestOperationFinisher(t *testing.T) {
 	t.Parallel()
 
@@ -1748,157 +1943,973 @@ func (f *testOperationFinisher) FinishOperation(ctx context.Context, details *Op
 	return f.finishError
 }
 
-func TestUnion(t *testing.T) {
+func TestWithTracer(t *testing.T) {
 	t.Parallel()
 
 	schema, err := ParseSchema(`
 		type Query {
-			fooOrBar: FooOrBar
-			nonNullableFooOrBar: FooOrBar!
-
-			fooOrFoo: FooOrFoo
-		}
-
-		union FooOrBar = UnionFoo | Bar
-
-		type UnionFoo {
-			foo: String
-		}
-
-		type Bar {
-			bar: String
-		}
-
-		union FooOrFoo = UnionFoo | MyFoo
-
-		type MyFoo {
-			foo(arg: String): String
+			greet(name: String!): String!
 		}
 	`, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	ctx := context.Background()
-	const fooOrBarQuery = `{ fooOrBar { __typename, ... on UnionFoo { foo }, ... on Bar { bar } } }`
-	const nonNullableFooOrBarQuery = `{ nonNullableFooOrBar { __typename, ... on UnionFoo { foo }, ... on Bar { bar } } }`
+	const query = `{ greet(name: "Alice") }`
 
-	t.Run("StaticTypeName", func(t *testing.T) {
-		q := &unionQuery{fooOrBar: &UnionFoo{Foo: "xyzzy"}}
-		srv, err := NewServer(schema, q, nil)
+	t.Run("ServerTracer", func(t *testing.T) {
+		var events []string
+		server, err := NewServerWithOptions(schema, new(observerQuery), nil, &ServerOptions{
+			Tracer: &testTracer{prefix: "1 ", events: &events},
+		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
-		if len(resp.Errors) > 0 {
-			t.Fatal(resp.Errors)
+		ctx := WithTracer(context.Background(), &testTracer{prefix: "2 ", events: &events})
+		response := server.Execute(ctx, Request{Query: query})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
 		}
-
-		q.mu.Lock()
-		set := q.set
-		q.mu.Unlock()
-		if !set.Has("foo") {
-			t.Error(`set.Has("foo") = false; want true`)
+		want := []string{
+			"1 start execution",
+			"2 start execution",
+			"1 start parse",
+			"2 start parse",
+			"2 end parse (0 errors)",
+			"1 end parse (0 errors)",
+			"1 start validation",
+			"2 start validation",
+			"2 end validation (0 errors)",
+			"1 end validation (0 errors)",
+			"1 start field greet: Query.greet: String!",
+			"2 start field greet: Query.greet: String!",
+			"2 end field greet",
+			"1 end field greet",
+			"2 end execution (0 errors)",
+			"1 end execution (0 errors)",
 		}
-		if !set.Has("bar") {
-			t.Error(`set.Has("bar") = false; want true`)
+		if diff := cmp.Diff(want, events); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
 		}
-
-		(&valueExpectations{
-			object: []fieldExpectations{
-				{key: "__typename", value: valueExpectations{scalar: "UnionFoo"}},
-				{key: "foo", value: valueExpectations{scalar: "xyzzy"}},
-			},
-		}).check(t, resp.Data.ValueFor("fooOrBar"))
 	})
-
-	t.Run("Null", func(t *testing.T) {
-		q := &unionQuery{fooOrBar: nil}
-		srv, err := NewServer(schema, q, nil)
+	t.Run("NoServerTracer", func(t *testing.T) {
+		var events []string
+		server, err := NewServer(schema, new(observerQuery), nil)
 		if err != nil {
 			t.Fatal(err)
 		}
-		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
-		if len(resp.Errors) > 0 {
-			t.Fatal(resp.Errors)
+		ctx := WithTracer(context.Background(), &testTracer{events: &events})
+		response := server.Execute(ctx, Request{Query: query})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
 		}
-
-		q.mu.Lock()
-		set := q.set
-		q.mu.Unlock()
-		if !set.Has("foo") {
-			t.Error(`set.Has("foo") = false; want true`)
+		want := []string{
+			"start execution",
+			"start parse",
+			"end parse (0 errors)",
+			"start validation",
+			"end validation (0 errors)",
+			"start field greet: Query.greet: String!",
+			"end field greet",
+			"end execution (0 errors)",
 		}
-		if !set.Has("bar") {
-			t.Error(`set.Has("bar") = false; want true`)
+		if diff := cmp.Diff(want, events); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
 		}
-
-		if got := resp.Data.ValueFor("fooOrBar"); !got.IsNull() {
-			t.Errorf("fooOrBar = %v; want null", got)
+		events = nil
+		server.Execute(context.Background(), Request{Query: query})
+		if len(events) > 0 {
+			t.Errorf("events without WithTracer = %q; want none", events)
 		}
 	})
+}
 
-	t.Run("NonNullable", func(t *testing.T) {
-		q := &unionQuery{fooOrBar: &UnionFoo{Foo: "xyzzy"}}
-		srv, err := NewServer(schema, q, nil)
-		if err != nil {
-			t.Fatal(err)
+type observerQuery struct {
+	dog *testDogStruct
+}
+
+func (q *observerQuery) MyDog() *testDogStruct {
+	return q.dog
+}
+
+func (q *observerQuery) Greet(args map[string]Value) string {
+	return "Hello, " + args["name"].Scalar() + "!"
+}
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			myDog: Dog
+			dogs: [Dog!]!
+			greet(name: String!): String!
 		}
-		resp := srv.Execute(ctx, Request{Query: nonNullableFooOrBarQuery})
-		if len(resp.Errors) > 0 {
-			t.Fatal(resp.Errors)
+
+		type Dog {
+			name: String!
+			barkVolume: Int
 		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := &tracerQuery{dogs: []*testDogStruct{{Name: "Fido"}, {Name: "Rex"}}}
+	var events []string
+	tracer := &testTracer{events: &events}
+	server, err := NewServerWithOptions(schema, query, nil, &ServerOptions{
+		Tracer: tracer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		q.mu.Lock()
-		set := q.set
-		q.mu.Unlock()
-		if !set.Has("foo") {
-			t.Error(`set.Has("foo") = false; want true`)
+	t.Run("Query", func(t *testing.T) {
+		events = nil
+		response := server.Execute(context.Background(), Request{
+			Query: `{ myDog { name } dogs { alias: name } greet(name: "Alice") }`,
+		})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
 		}
-		if !set.Has("bar") {
-			t.Error(`set.Has("bar") = false; want true`)
+		want := []string{
+			"start execution",
+			"start parse",
+			"end parse (0 errors)",
+			"start validation",
+			"end validation (0 errors)",
+			"start field myDog: Query.myDog: Dog",
+			"end field myDog",
+			"start field dogs: Query.dogs: [Dog!]!",
+			"end field dogs",
+			"start field dogs.0.alias: Dog.name: String!",
+			"end field dogs.0.alias",
+			"start field dogs.1.alias: Dog.name: String!",
+			"end field dogs.1.alias",
+			"start field greet: Query.greet: String!",
+			"end field greet",
+			"end execution (0 errors)",
+		}
+		if diff := cmp.Diff(want, events); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
+		}
+		if got := response.Extensions["tracer"]; got != "test" {
+			t.Errorf("response.Extensions[\"tracer\"] = %v; want \"test\"", got)
 		}
-
-		(&valueExpectations{
-			object: []fieldExpectations{
-				{key: "__typename", value: valueExpectations{scalar: "UnionFoo"}},
-				{key: "foo", value: valueExpectations{scalar: "xyzzy"}},
-			},
-		}).check(t, resp.Data.ValueFor("nonNullableFooOrBar"))
 	})
-
-	t.Run("DynamicTypeName", func(t *testing.T) {
-		q := &unionQuery{
-			fooOrBar: &DynamicUnion{
-				typename: "Bar",
-				Foo:      "BORK",
-				Bar:      "xyzzy",
-			},
+	t.Run("ParseError", func(t *testing.T) {
+		events = nil
+		response := server.Execute(context.Background(), Request{
+			Query: `{`,
+		})
+		if len(response.Errors) == 0 {
+			t.Error("No errors returned")
 		}
-		srv, err := NewServer(schema, q, nil)
-		if err != nil {
-			t.Fatal(err)
+		want := []string{
+			"start execution",
+			"start parse",
+			"end parse (1 errors)",
+			"end execution (1 errors)",
 		}
-		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
-		if len(resp.Errors) > 0 {
-			t.Fatal(resp.Errors)
+		if diff := cmp.Diff(want, events); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
 		}
-
-		q.mu.Lock()
-		set := q.set
-		q.mu.Unlock()
-		if !set.Has("foo") {
-			t.Error(`set.Has("foo") = false; want true`)
+	})
+	t.Run("ValidatedQuery", func(t *testing.T) {
+		validated, errs := schema.Validate(`{ greet(name: "Bob") }`)
+		if len(errs) > 0 {
+			t.Fatal(errs)
 		}
-		if !set.Has("bar") {
-			t.Error(`set.Has("bar") = false; want true`)
+		events = nil
+		response := server.Execute(context.Background(), Request{
+			ValidatedQuery: validated,
+		})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
+		}
+		want := []string{
+			"start execution",
+			"start field greet: Query.greet: String!",
+			"end field greet",
+			"end execution (0 errors)",
+		}
+		if diff := cmp.Diff(want, events); diff != "" {
+			t.Errorf("events (-want +got):\n%s", diff)
 		}
-
-		(&valueExpectations{
-			object: []fieldExpectations{
-				{key: "__typename", value: valueExpectations{scalar: "Bar"}},
-				{key: "bar", value: valueExpectations{scalar: "xyzzy"}},
-			},
-		}).check(t, resp.Data.ValueFor("fooOrBar"))
+	})
+}
+
+func TestMultiTracer(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			greet(name: String!): String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	tracer1 := &testTracer{prefix: "1 ", events: &events}
+	tracer2 := &testTracer{prefix: "2 ", events: &events}
+	server, err := NewServerWithOptions(schema, new(observerQuery), nil, &ServerOptions{
+		Tracer: MultiTracer(tracer1, tracer2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := server.Execute(context.Background(), Request{
+		Query: `{ greet(name: "Alice") }`,
+	})
+	for _, err := range response.Errors {
+		t.Errorf("Response error: %v", err)
+	}
+	want := []string{
+		"1 start execution",
+		"2 start execution",
+		"1 start parse",
+		"2 start parse",
+		"2 end parse (0 errors)",
+		"1 end parse (0 errors)",
+		"1 start validation",
+		"2 start validation",
+		"2 end validation (0 errors)",
+		"1 end validation (0 errors)",
+		"1 start field greet: Query.greet: String!",
+		"2 start field greet: Query.greet: String!",
+		"2 end field greet",
+		"1 end field greet",
+		"2 end execution (0 errors)",
+		"1 end execution (0 errors)",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("events (-want +got):\n%s", diff)
+	}
+}
+
+func TestOperationLog(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			fast: String!
+			medium: String!
+			slow: String!
+			login(name: String!, apiKey: String, creds: Credentials): Boolean!
+		}
+
+		input Credentials {
+			user: String!
+			password: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Record", func(t *testing.T) {
+		logger := new(testOperationLogger)
+		server, err := NewServerWithOptions(schema, logQuery{}, nil, &ServerOptions{
+			OperationLog: &OperationLogOptions{
+				Logger:     logger,
+				SlowFields: 2,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		const query = `query Login($name: String!, $apiKey: String, $creds: Credentials) { fast medium slow login(name: $name, apiKey: $apiKey, creds: $creds) }`
+		response := server.Execute(context.Background(), Request{
+			Query: query,
+			Variables: map[string]Input{
+				"name":   ScalarInput("Alice"),
+				"apiKey": ScalarInput("xyzzy"),
+				"creds": InputObject(map[string]Input{
+					"user":     ScalarInput("alice"),
+					"password": ScalarInput("hunter2"),
+				}),
+			},
+		})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
+		}
+		if len(logger.records) != 1 {
+			t.Fatalf("logged %d records; want 1", len(logger.records))
+		}
+		rec := logger.records[0]
+		hash := sha256.Sum256([]byte(query))
+		want := OperationRecord{
+			OperationName: "Login",
+			OperationType: QueryOperation,
+			DocumentHash:  hex.EncodeToString(hash[:]),
+			Variables: map[string]interface{}{
+				"name":   "Alice",
+				"apiKey": RedactedValue,
+				"creds": map[string]interface{}{
+					"user":     "alice",
+					"password": RedactedValue,
+				},
+			},
+			SlowestFields: []FieldTiming{
+				{Path: []PathSegment{{Field: "slow"}}, ParentType: "Query", FieldName: "slow"},
+				{Path: []PathSegment{{Field: "medium"}}, ParentType: "Query", FieldName: "medium"},
+			},
+		}
+		ignoreDuration := cmpopts.IgnoreFields(OperationRecord{}, "Duration")
+		ignoreFieldDuration := cmpopts.IgnoreFields(FieldTiming{}, "Duration")
+		if diff := cmp.Diff(want, rec, ignoreDuration, ignoreFieldDuration); diff != "" {
+			t.Errorf("record (-want +got):\n%s", diff)
+		}
+		if rec.Duration < 30*time.Millisecond {
+			t.Errorf("Duration = %v; want >= 30ms", rec.Duration)
+		}
+	})
+	t.Run("ValidationError", func(t *testing.T) {
+		logger := new(testOperationLogger)
+		server, err := NewServerWithOptions(schema, logQuery{}, nil, &ServerOptions{
+			OperationLog: &OperationLogOptions{Logger: logger},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Execute(context.Background(), Request{Query: `{ nope }`})
+		if len(logger.records) != 1 {
+			t.Fatalf("logged %d records; want 1", len(logger.records))
+		}
+		rec := logger.records[0]
+		if rec.ErrorCount != 1 || rec.OperationType != 0 {
+			t.Errorf("record = %+v; want ErrorCount = 1, OperationType = 0", rec)
+		}
+	})
+	t.Run("MinDuration", func(t *testing.T) {
+		logger := new(testOperationLogger)
+		server, err := NewServerWithOptions(schema, logQuery{}, nil, &ServerOptions{
+			OperationLog: &OperationLogOptions{
+				Logger:      logger,
+				MinDuration: 15 * time.Millisecond,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Execute(context.Background(), Request{Query: `{ fast }`})
+		server.Execute(context.Background(), Request{Query: `{ slow }`})
+		if len(logger.records) != 1 {
+			t.Fatalf("logged %d records; want 1", len(logger.records))
+		}
+		if got, want := logger.records[0].DocumentHash, fmt.Sprintf("%x", sha256.Sum256([]byte(`{ slow }`))); got != want {
+			t.Errorf("DocumentHash = %q; want %q (slow query)", got, want)
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		server, err := NewServerWithOptions(schema, logQuery{}, nil, &ServerOptions{
+			OperationLog: &OperationLogOptions{Logger: NewJSONOperationLogger(buf)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.Execute(context.Background(), Request{Query: `query Fast { fast }`})
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%q: %v", buf, err)
+		}
+		if got["operationName"] != "Fast" || got["operationType"] != "query" {
+			t.Errorf("logged %s; want operationName = Fast, operationType = query", buf)
+		}
+	})
+	t.Run("NoLogger", func(t *testing.T) {
+		_, err := NewServerWithOptions(schema, logQuery{}, nil, &ServerOptions{
+			OperationLog: &OperationLogOptions{},
+		})
+		if err == nil {
+			t.Error("NewServerWithOptions did not return an error")
+		}
+	})
+}
+
+type logQuery struct{}
+
+func (logQuery) Fast() string {
+	return "fast"
+}
+
+func (logQuery) Medium() string {
+	time.Sleep(10 * time.Millisecond)
+	return "medium"
+}
+
+func (logQuery) Slow() string {
+	time.Sleep(20 * time.Millisecond)
+	return "slow"
+}
+
+func (logQuery) Login(args map[string]Value) bool {
+	return true
+}
+
+type testOperationLogger struct {
+	mu      sync.Mutex
+	records []OperationRecord
+}
+
+func (l *testOperationLogger) LogOperation(ctx context.Context, rec *OperationRecord) {
+	l.mu.Lock()
+	l.records = append(l.records, *rec)
+	l.mu.Unlock()
+}
+
+func TestAuthorizeIntrospection(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type adminKey struct{}
+	allowAdmin := func(ctx context.Context) bool {
+		return ctx.Value(adminKey{}) != nil
+	}
+	admin := context.WithValue(context.Background(), adminKey{}, true)
+	tests := []struct {
+		name      string
+		authorize func(context.Context) bool
+		ctx       context.Context
+		query     string
+		wantErrs  []*ResponseError
+	}{
+		{
+			name:  "NoPolicy",
+			ctx:   context.Background(),
+			query: `{ __schema { queryType { name } } }`,
+		},
+		{
+			name:      "Denied",
+			authorize: DenyIntrospection,
+			ctx:       context.Background(),
+			query:     `{ greeting __schema { queryType { name } } t: __type(name: "Query") { name } }`,
+			wantErrs: []*ResponseError{
+				{
+					Message:   `introspection field "__schema" not allowed`,
+					Locations: []Location{{Line: 1, Column: 12}},
+				},
+				{
+					Message:   `introspection field "__type" not allowed`,
+					Locations: []Location{{Line: 1, Column: 47}},
+				},
+			},
+		},
+		{
+			name:      "DeniedInFragment",
+			authorize: DenyIntrospection,
+			ctx:       context.Background(),
+			query:     "{ ...Schema }\nfragment Schema on Query { ... { __schema { queryType { name } } } }",
+			wantErrs: []*ResponseError{
+				{
+					Message:   `introspection field "__schema" not allowed`,
+					Locations: []Location{{Line: 2, Column: 34}},
+				},
+			},
+		},
+		{
+			name:      "TypenameAllowed",
+			authorize: DenyIntrospection,
+			ctx:       context.Background(),
+			query:     `{ __typename greeting }`,
+		},
+		{
+			name:      "Authorized",
+			authorize: allowAdmin,
+			ctx:       admin,
+			query:     `{ greeting __schema { queryType { name } } }`,
+		},
+		{
+			name:      "Unauthorized",
+			authorize: allowAdmin,
+			ctx:       context.Background(),
+			query:     `{ __schema { queryType { name } } }`,
+			wantErrs: []*ResponseError{
+				{
+					Message:   `introspection field "__schema" not allowed`,
+					Locations: []Location{{Line: 1, Column: 3}},
+				},
+			},
+		},
+		{
+			name:      "TrustedIntrospectionOnly",
+			authorize: DenyIntrospection,
+			ctx:       WithTrustedIntrospection(context.Background()),
+			query:     `{ __typename __schema { queryType { name } } }`,
+		},
+		{
+			name:      "TrustedMixed",
+			authorize: DenyIntrospection,
+			ctx:       WithTrustedIntrospection(context.Background()),
+			query:     `{ greeting __schema { queryType { name } } }`,
+			wantErrs: []*ResponseError{
+				{
+					Message:   `introspection field "__schema" not allowed`,
+					Locations: []Location{{Line: 1, Column: 12}},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, err := NewServerWithOptions(schema, greetingQuery{}, nil, &ServerOptions{
+				AuthorizeIntrospection: test.authorize,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Execute(test.ctx, Request{Query: test.query})
+			if diff := cmp.Diff(test.wantErrs, response.Errors, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("errors (-want +got):\n%s", diff)
+			}
+			if len(test.wantErrs) > 0 && response.Data.typ != nil {
+				t.Errorf("data = %v; want no data", response.Data)
+			}
+		})
+	}
+}
+
+type greetingQuery struct{}
+
+func (greetingQuery) Greeting() string {
+	return "hi"
+}
+
+func TestExtensionCollector(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			warn(message: String!): Boolean!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var traced interface{}
+	server, err := NewServerWithOptions(schema, extensionsQuery{}, nil, &ServerOptions{
+		Tracer: &finishTracer{finish: func(resp *Response) {
+			traced = resp.Extensions["warnings"]
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := server.Execute(context.Background(), Request{
+		Query: `{ a: warn(message: "foo") b: warn(message: "bar") }`,
+	})
+	for _, err := range response.Errors {
+		t.Errorf("Response error: %v", err)
+	}
+	want := map[string]interface{}{
+		"warnings": []string{"foo", "bar"},
+		"count":    2,
+	}
+	if diff := cmp.Diff(want, response.Extensions); diff != "" {
+		t.Errorf("response.Extensions (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(want["warnings"], traced); diff != "" {
+		t.Errorf("tracer saw warnings (-want +got):\n%s", diff)
+	}
+
+	// Entries set by a tracer replace collected entries.
+	server, err = NewServerWithOptions(schema, extensionsQuery{}, nil, &ServerOptions{
+		Tracer: &finishTracer{finish: func(resp *Response) {
+			resp.Extensions["count"] = "from tracer"
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = server.Execute(context.Background(), Request{
+		Query: `{ warn(message: "foo") }`,
+	})
+	for _, err := range response.Errors {
+		t.Errorf("Response error: %v", err)
+	}
+	want = map[string]interface{}{
+		"warnings": []string{"foo"},
+		"count":    "from tracer",
+	}
+	if diff := cmp.Diff(want, response.Extensions); diff != "" {
+		t.Errorf("response.Extensions with colliding tracer (-want +got):\n%s", diff)
+	}
+
+	// Methods on a nil collector should not panic.
+	c := ExtensionsFromContext(context.Background())
+	if c != nil {
+		t.Fatalf("ExtensionsFromContext(context.Background()) = %v; want nil", c)
+	}
+	c.Set("foo", "bar")
+	if got := c.Get("foo"); got != nil {
+		t.Errorf("nil collector Get(\"foo\") = %v; want nil", got)
+	}
+}
+
+type extensionsQuery struct{}
+
+func (extensionsQuery) Warn(ctx context.Context, args map[string]Value) bool {
+	c := ExtensionsFromContext(ctx)
+	c.Update("warnings", func(old interface{}) interface{} {
+		warnings, _ := old.([]string)
+		return append(warnings, args["message"].Scalar())
+	})
+	n, _ := c.Get("count").(int)
+	c.Set("count", n+1)
+	return true
+}
+
+// finishTracer calls a function at the end of each execution.
+type finishTracer struct {
+	finish func(*Response)
+}
+
+func (tr *finishTracer) TraceExecution(ctx context.Context, req Request) (context.Context, func(*Response)) {
+	return ctx, tr.finish
+}
+
+func (tr *finishTracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*ResponseError)) {
+	return ctx, nil
+}
+
+func (tr *finishTracer) TraceValidation(ctx context.Context) (context.Context, func(*ValidatedQuery, []*ResponseError)) {
+	return ctx, nil
+}
+
+func (tr *finishTracer) TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(interface{}, error)) {
+	return ctx, nil
+}
+
+type tracerQuery struct {
+	observerQuery
+	dogs []*testDogStruct
+}
+
+func (q *tracerQuery) Dogs() []*testDogStruct {
+	return q.dogs
+}
+
+// testTracer records events as strings. It is not safe to use concurrently.
+type testTracer struct {
+	prefix string
+	events *[]string
+}
+
+func (tr *testTracer) record(format string, args ...interface{}) {
+	*tr.events = append(*tr.events, tr.prefix+fmt.Sprintf(format, args...))
+}
+
+func (tr *testTracer) TraceExecution(ctx context.Context, req Request) (context.Context, func(*Response)) {
+	tr.record("start execution")
+	return ctx, func(resp *Response) {
+		tr.record("end execution (%d errors)", len(resp.Errors))
+		resp.Extensions = map[string]interface{}{"tracer": "test"}
+	}
+}
+
+func (tr *testTracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*ResponseError)) {
+	tr.record("start parse")
+	return ctx, func(errs []*ResponseError) {
+		tr.record("end parse (%d errors)", len(errs))
+	}
+}
+
+func (tr *testTracer) TraceValidation(ctx context.Context) (context.Context, func(*ValidatedQuery, []*ResponseError)) {
+	tr.record("start validation")
+	return ctx, func(query *ValidatedQuery, errs []*ResponseError) {
+		tr.record("end validation (%d errors)", len(errs))
+	}
+}
+
+func (tr *testTracer) TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(interface{}, error)) {
+	path := formatPath(info.Path)
+	tr.record("start field %s: %s.%s: %s", path, info.ParentType, info.Request.Name, info.ReturnType)
+	return ctx, func(interface{}, error) {
+		tr.record("end field %s", path)
+	}
+}
+
+func formatPath(path []PathSegment) string {
+	sb := new(strings.Builder)
+	for i, seg := range path {
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
+
+func TestNullPropagation(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			greeting: String
+			outer: Outer
+		}
+
+		type Outer {
+			inner: Inner!
+		}
+
+		type Inner {
+			ok: String
+			fail: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resolver fieldResolverFunc
+	resolver = func(ctx context.Context, req FieldRequest) (interface{}, error) {
+		switch req.Name {
+		case "greeting", "ok":
+			return "hi", nil
+		case "fail":
+			return nil, xerrors.New("bork")
+		default:
+			return resolver, nil
+		}
+	}
+	server, err := NewServer(schema, resolver, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		query    string
+		want     string
+		wantPath []PathSegment
+	}{
+		{
+			name:     "NullableParent",
+			query:    `{ greeting outer { inner { ok fail } } }`,
+			want:     `{"greeting":"hi","outer":null}`,
+			wantPath: []PathSegment{{Field: "outer"}, {Field: "inner"}, {Field: "fail"}},
+		},
+		{
+			name:     "NoError",
+			query:    `{ greeting outer { inner { ok } } }`,
+			want:     `{"greeting":"hi","outer":{"inner":{"ok":"hi"}}}`,
+			wantPath: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := server.Execute(context.Background(), Request{Query: test.query})
+			var gotPaths [][]PathSegment
+			for _, err := range response.Errors {
+				gotPaths = append(gotPaths, err.Path)
+			}
+			var wantPaths [][]PathSegment
+			if test.wantPath != nil {
+				wantPaths = [][]PathSegment{test.wantPath}
+			}
+			if diff := cmp.Diff(wantPaths, gotPaths); diff != "" {
+				t.Errorf("error paths (-want +got):\n%s", diff)
+			}
+			got, err := json.Marshal(response.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("data = %s; want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnion(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`
+		type Query {
+			fooOrBar: FooOrBar
+			nonNullableFooOrBar: FooOrBar!
+			fooOrBarList: [FooOrBar!]!
+
+			fooOrFoo: FooOrFoo
+		}
+
+		union FooOrBar = UnionFoo | Bar
+
+		type UnionFoo {
+			foo: String
+		}
+
+		type Bar {
+			bar: String
+		}
+
+		union FooOrFoo = UnionFoo | MyFoo
+
+		type MyFoo {
+			foo(arg: String): String
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	const fooOrBarQuery = `{ fooOrBar { __typename, ... on UnionFoo { foo }, ... on Bar { bar } } }`
+	const nonNullableFooOrBarQuery = `{ nonNullableFooOrBar { __typename, ... on UnionFoo { foo }, ... on Bar { bar } } }`
+
+	t.Run("StaticTypeName", func(t *testing.T) {
+		q := &unionQuery{fooOrBar: &UnionFoo{Foo: "xyzzy"}}
+		srv, err := NewServer(schema, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+
+		q.mu.Lock()
+		set := q.set
+		q.mu.Unlock()
+		if !set.Has("foo") {
+			t.Error(`set.Has("foo") = false; want true`)
+		}
+		if !set.Has("bar") {
+			t.Error(`set.Has("bar") = false; want true`)
+		}
+
+		(&valueExpectations{
+			object: []fieldExpectations{
+				{key: "__typename", value: valueExpectations{scalar: "UnionFoo"}},
+				{key: "foo", value: valueExpectations{scalar: "xyzzy"}},
+			},
+		}).check(t, resp.Data.ValueFor("fooOrBar"))
+	})
+
+	t.Run("Null", func(t *testing.T) {
+		q := &unionQuery{fooOrBar: nil}
+		srv, err := NewServer(schema, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+
+		q.mu.Lock()
+		set := q.set
+		q.mu.Unlock()
+		if !set.Has("foo") {
+			t.Error(`set.Has("foo") = false; want true`)
+		}
+		if !set.Has("bar") {
+			t.Error(`set.Has("bar") = false; want true`)
+		}
+
+		if got := resp.Data.ValueFor("fooOrBar"); !got.IsNull() {
+			t.Errorf("fooOrBar = %v; want null", got)
+		}
+	})
+
+	t.Run("NonNullable", func(t *testing.T) {
+		q := &unionQuery{fooOrBar: &UnionFoo{Foo: "xyzzy"}}
+		srv, err := NewServer(schema, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, Request{Query: nonNullableFooOrBarQuery})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+
+		q.mu.Lock()
+		set := q.set
+		q.mu.Unlock()
+		if !set.Has("foo") {
+			t.Error(`set.Has("foo") = false; want true`)
+		}
+		if !set.Has("bar") {
+			t.Error(`set.Has("bar") = false; want true`)
+		}
+
+		(&valueExpectations{
+			object: []fieldExpectations{
+				{key: "__typename", value: valueExpectations{scalar: "UnionFoo"}},
+				{key: "foo", value: valueExpectations{scalar: "xyzzy"}},
+			},
+		}).check(t, resp.Data.ValueFor("nonNullableFooOrBar"))
+	})
+
+	t.Run("DynamicTypeName", func(t *testing.T) {
+		q := &unionQuery{
+			fooOrBar: &DynamicUnion{
+				typename: "Bar",
+				Foo:      "BORK",
+				Bar:      "xyzzy",
+			},
+		}
+		srv, err := NewServer(schema, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, Request{Query: fooOrBarQuery})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+
+		q.mu.Lock()
+		set := q.set
+		q.mu.Unlock()
+		if !set.Has("foo") {
+			t.Error(`set.Has("foo") = false; want true`)
+		}
+		if !set.Has("bar") {
+			t.Error(`set.Has("bar") = false; want true`)
+		}
+
+		(&valueExpectations{
+			object: []fieldExpectations{
+				{key: "__typename", value: valueExpectations{scalar: "Bar"}},
+				{key: "bar", value: valueExpectations{scalar: "xyzzy"}},
+			},
+		}).check(t, resp.Data.ValueFor("fooOrBar"))
+	})
+
+	t.Run("List", func(t *testing.T) {
+		q := &unionQuery{
+			fooOrBarList: []interface{}{
+				&UnionFoo{Foo: "xyzzy"},
+				&DynamicUnion{typename: "Bar", Bar: "plugh"},
+			},
+		}
+		srv, err := NewServer(schema, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, Request{Query: `{ fooOrBarList { __typename, ... on UnionFoo { foo }, ... on Bar { bar } } }`})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+
+		(&valueExpectations{
+			list: []valueExpectations{
+				{object: []fieldExpectations{
+					{key: "__typename", value: valueExpectations{scalar: "UnionFoo"}},
+					{key: "foo", value: valueExpectations{scalar: "xyzzy"}},
+				}},
+				{object: []fieldExpectations{
+					{key: "__typename", value: valueExpectations{scalar: "Bar"}},
+					{key: "bar", value: valueExpectations{scalar: "plugh"}},
+				}},
+			},
+		}).check(t, resp.Data.ValueFor("fooOrBarList"))
 	})
 
 	t.Run("OverlappingFieldNames", func(t *testing.T) {
@@ -2001,8 +3012,9 @@ func TestUnion(t *testing.T) {
 }
 
 type unionQuery struct {
-	fooOrBar interface{}
-	fooOrFoo interface{}
+	fooOrBar     interface{}
+	fooOrBarList []interface{}
+	fooOrFoo     interface{}
 
 	mu  sync.Mutex
 	set *SelectionSet
@@ -2022,6 +3034,10 @@ func (q *unionQuery) NonNullableFooOrBar(set *SelectionSet) interface{} {
 	return q.fooOrBar
 }
 
+func (q *unionQuery) FooOrBarList() []interface{} {
+	return q.fooOrBarList
+}
+
 func (q *unionQuery) FooOrFoo(set *SelectionSet) interface{} {
 	q.mu.Lock()
 	q.set = set
@@ -2261,6 +3277,80 @@ func TestResponseMarshalJSON(t *testing.T) {
 				json.Delim('}'),
 			},
 		},
+		{
+			// A typed null means execution started, so data is present.
+			name: "NullDataAndErrors",
+			v: Response{
+				Data: Value{typ: testObjectValue().typ},
+				Errors: []*ResponseError{
+					{
+						Message: "Failure",
+					},
+				},
+			},
+			want: []json.Token{
+				json.Delim('{'),
+				"errors",
+				json.Delim('['),
+				json.Delim('{'),
+				"message", "Failure",
+				json.Delim('}'),
+				json.Delim(']'),
+				"data", nil,
+				json.Delim('}'),
+			},
+		},
+		{
+			name: "DataAndExtensions",
+			v: Response{
+				Data: testObjectValue(),
+				Extensions: map[string]interface{}{
+					"foo": 1,
+					"bar": "baz",
+				},
+			},
+			want: []json.Token{
+				json.Delim('{'),
+				"data",
+				json.Delim('{'),
+				"myInt", json.Number("42"),
+				"myString", "xyzzy",
+				json.Delim('}'),
+				"extensions",
+				json.Delim('{'),
+				"bar", "baz",
+				"foo", json.Number("1"),
+				json.Delim('}'),
+				json.Delim('}'),
+			},
+		},
+		{
+			name: "ErrorsAndExtensions",
+			v: Response{
+				Errors: []*ResponseError{
+					{
+						Message: "Failure",
+					},
+				},
+				Extensions: map[string]interface{}{
+					"foo": 1,
+				},
+			},
+			want: []json.Token{
+				json.Delim('{'),
+				"errors",
+				json.Delim('['),
+				json.Delim('{'),
+				"message", "Failure",
+				json.Delim('}'),
+				json.Delim(']'),
+				"extensions",
+				json.Delim('{'),
+				"foo", json.Number("1"),
+				json.Delim('}'),
+				json.Delim('}'),
+			},
+		},
 		{
 			name: "IDs",
 			v: Response{
@@ -22,12 +22,12 @@ import (
 	"fmt"
 
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // Input is a typeless GraphQL value. The zero value is null.
 type Input struct {
-	val interface{} // one of nil, string, map[string]Input, or []Input
+	val interface{} // one of nil, string, map[string]Input, []Input, or *Upload
 }
 
 // ScalarInput returns a new input with the given scalar value.
@@ -51,8 +51,11 @@ func (in Input) isNull() bool {
 }
 
 // MarshalJSON converts the input into JSON. All scalars will be represented as
-// strings.
+// strings. Uploads will be represented as null.
 func (in Input) MarshalJSON() ([]byte, error) {
+	if _, isUpload := in.val.(*Upload); isUpload {
+		return []byte("null"), nil
+	}
 	return json.Marshal(in.val)
 }
 
@@ -97,11 +100,12 @@ func (in *Input) UnmarshalJSON(data []byte) error {
 //   - string for scalars
 //   - []interface{} for lists
 //   - map[string]interface{} for objects
+//   - *Upload for uploads
 func (in Input) GoValue() interface{} {
 	switch val := in.val.(type) {
 	case nil:
 		return nil
-	case string:
+	case string, *Upload:
 		return val
 	case []Input:
 		goVal := make([]interface{}, len(val))
@@ -122,7 +126,7 @@ func (in Input) GoValue() interface{} {
 
 // coerceVariableValues converts inputs into values.
 // The procedure is specified in https://graphql.github.io/graphql-spec/June2018/#CoerceVariableValues%28%29
-func coerceVariableValues(source string, typeMap map[string]*gqlType, vars map[string]Input, defns *gqlang.VariableDefinitions) (map[string]Value, []error) {
+func coerceVariableValues(source string, typeMap map[string]*gqlType, vars map[string]Input, defns *ast.VariableDefinitions) (map[string]Value, []error) {
 	if defns == nil {
 		return nil, nil
 	}
@@ -170,7 +174,18 @@ func coerceInput(typ *gqlType, input Input) (Value, []error) {
 		}
 		return Value{typ: typ}, nil
 	}
+	if _, isUpload := input.val.(*Upload); isUpload && !isUploadType(typ) && !typ.isList() {
+		return Value{typ: typ}, []error{xerrors.Errorf("file upload not permitted for %v", typ)}
+	}
 	switch {
+	case isUploadType(typ):
+		upload, ok := input.val.(*Upload)
+		if !ok {
+			return Value{typ: typ}, []error{xerrors.Errorf("%v requires a file upload", typ)}
+		}
+		return Value{typ: typ, val: upload}, nil
+	case typ.isScalar() && typ.structured:
+		return coerceStructuredInput(typ, input), nil
 	case typ.isScalar():
 		scalar, ok := input.val.(string)
 		if !ok {
@@ -242,6 +257,11 @@ func coerceInput(typ *gqlType, input Input) (Value, []error) {
 			}
 			var fieldErrs []error
 			valueMap[defn.name], fieldErrs = coerceInput(defn.Type(), field)
+			if len(fieldErrs) == 0 {
+				if err := defn.constraint.check(valueMap[defn.name]); err != nil {
+					fieldErrs = []error{err}
+				}
+			}
 			for _, err := range fieldErrs {
 				errs = append(errs, xerrors.Errorf("input field %s: %w", defn.name, err))
 			}
@@ -254,3 +274,24 @@ func coerceInput(typ *gqlType, input Input) (Value, []error) {
 		panic("unhandled input type")
 	}
 }
+
+// coerceStructuredInput converts an input of any shape into a value of a
+// structured scalar type.
+func coerceStructuredInput(typ *gqlType, input Input) Value {
+	switch val := input.val.(type) {
+	case []Input:
+		list := make([]Value, 0, len(val))
+		for _, elem := range val {
+			list = append(list, coerceStructuredInput(typ.toNullable(), elem))
+		}
+		return Value{typ: typ, val: list}
+	case map[string]Input:
+		m := make(map[string]Value, len(val))
+		for k, elem := range val {
+			m[k] = coerceStructuredInput(typ.toNullable(), elem)
+		}
+		return Value{typ: typ, val: m}
+	default:
+		return Value{typ: typ, val: val}
+	}
+}
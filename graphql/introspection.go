@@ -20,6 +20,8 @@ import (
 	"context"
 	"reflect"
 	"sync"
+
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // Predefined introspection field names.
@@ -72,21 +74,25 @@ type schemaObject struct {
 	QueryType        *gqlType
 	MutationType     *gqlType
 	SubscriptionType *gqlType
-	Directives       []*directive
+	Directives       []*directiveDefinition
 }
 
 func (schema *Schema) introspectSchema(ctx context.Context, variables map[string]Value, field *SelectedField) (Value, []error) {
 	s := &schemaObject{
-		QueryType:    schema.query,
-		MutationType: schema.mutation,
-		Types:        builtins(true),
-		Directives: []*directive{
+		QueryType:        schema.query,
+		MutationType:     schema.mutation,
+		SubscriptionType: schema.subscription,
+		Types:            builtins(true),
+		Directives: []*directiveDefinition{
 			&deprecatedDirective,
 		},
 	}
 	for _, name := range schema.typeOrder {
 		s.Types = append(s.Types, schema.types[name])
 	}
+	for _, name := range schema.directiveOrder {
+		s.Directives = append(s.Directives, schema.directives[name])
+	}
 	v, errs := schema.valueFromGo(ctx, variables, reflect.ValueOf(s), schemaType().toNonNullable(), field.SelectionSet())
 	for i, err := range errs {
 		errs[i] = wrapFieldError(field.key, field.loc, err)
@@ -109,15 +115,26 @@ func (schema *Schema) introspectType(ctx context.Context, variables map[string]V
 	return v, errs
 }
 
-// directive is a representation of __Directive.
-type directive struct {
+// directiveDefinition is a representation of __Directive.
+type directiveDefinition struct {
 	Name        string
 	Description NullString
 	Locations   []string
 	Args        inputValueDefinitionList
+
+	repeatable bool
+}
+
+func (defn *directiveDefinition) allowedOn(loc string) bool {
+	for _, l := range defn.Locations {
+		if l == loc {
+			return true
+		}
+	}
+	return false
 }
 
-var deprecatedDirective = directive{
+var deprecatedDirective = directiveDefinition{
 	Name: "deprecated",
 	Locations: []string{
 		"FIELD_DEFINITION",
@@ -244,3 +261,60 @@ enum __DirectiveLocation {
 	}
 	return introspect.schema
 }
+
+// DenyIntrospection is a function for ServerOptions.AuthorizeIntrospection
+// that rejects all introspection outside of trusted contexts.
+func DenyIntrospection(ctx context.Context) bool {
+	return false
+}
+
+type trustedIntrospectionContextKey struct{}
+
+// WithTrustedIntrospection returns a new context that permits operations that
+// only use introspection, regardless of the server's
+// ServerOptions.AuthorizeIntrospection. This is intended for tooling, like
+// schema registries or internal explorers. Operations that select other fields
+// are still subject to authorization.
+func WithTrustedIntrospection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedIntrospectionContextKey{}, true)
+}
+
+func isTrustedIntrospection(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustedIntrospectionContextKey{}).(bool)
+	return trusted
+}
+
+// introspectionFields returns the __schema and __type fields selected by
+// set, following fragments. other is true if set selects any other fields
+// besides __typename. set must be the top-level selection set of a query.
+func introspectionFields(doc *ast.Document, set *ast.SelectionSet) (fields []*ast.Field, other bool) {
+	visited := make(map[string]struct{})
+	var visit func(set *ast.SelectionSet)
+	visit = func(set *ast.SelectionSet) {
+		for _, sel := range set.Sel {
+			switch {
+			case sel.Field != nil:
+				switch sel.Field.Name.Value {
+				case schemaFieldName, typeByNameFieldName:
+					fields = append(fields, sel.Field)
+				case typeNameFieldName:
+				default:
+					other = true
+				}
+			case sel.FragmentSpread != nil:
+				name := sel.FragmentSpread.Name.Value
+				if _, done := visited[name]; done {
+					continue
+				}
+				visited[name] = struct{}{}
+				if frag := doc.FindFragment(name); frag != nil {
+					visit(frag.SelectionSet)
+				}
+			case sel.InlineFragment != nil:
+				visit(sel.InlineFragment.SelectionSet)
+			}
+		}
+	}
+	visit(set)
+	return fields, other
+}
@@ -365,6 +365,61 @@ func TestIntrospection(t *testing.T) {
 				}}},
 			},
 		},
+		{
+			name: "Schema/CustomDirective",
+			schema: `
+				"Provides sample values."
+				directive @example(value: [String!]!) on FIELD_DEFINITION | SCALAR
+
+				type Query {
+					foo: String @example(value: "bar")
+				}
+			`,
+			request: Request{
+				Query: `{
+					__schema {
+						directives {
+							name
+							description
+							locations
+							args { name }
+						}
+					}
+				}`,
+			},
+			want: []fieldExpectations{
+				{key: "__schema", value: valueExpectations{object: []fieldExpectations{
+					{key: "directives", value: valueExpectations{list: []valueExpectations{
+						{object: []fieldExpectations{
+							{key: "name", value: valueExpectations{scalar: "deprecated"}},
+							{key: "description", value: valueExpectations{null: true}},
+							{key: "locations", value: valueExpectations{list: []valueExpectations{
+								{scalar: "FIELD_DEFINITION"},
+								{scalar: "ENUM_VALUE"},
+							}}},
+							{key: "args", value: valueExpectations{list: []valueExpectations{
+								{object: []fieldExpectations{
+									{key: "name", value: valueExpectations{scalar: "reason"}},
+								}},
+							}}},
+						}},
+						{object: []fieldExpectations{
+							{key: "name", value: valueExpectations{scalar: "example"}},
+							{key: "description", value: valueExpectations{scalar: "Provides sample values."}},
+							{key: "locations", value: valueExpectations{list: []valueExpectations{
+								{scalar: "FIELD_DEFINITION"},
+								{scalar: "SCALAR"},
+							}}},
+							{key: "args", value: valueExpectations{list: []valueExpectations{
+								{object: []fieldExpectations{
+									{key: "name", value: valueExpectations{scalar: "value"}},
+								}},
+							}}},
+						}},
+					}}},
+				}}},
+			},
+		},
 		{
 			name: "Typename",
 			schema: `
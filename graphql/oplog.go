@@ -0,0 +1,270 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An OperationLogger receives a record of operations executed by a server.
+// LogOperation must be safe to call from multiple goroutines. The record must
+// not be modified or retained past the end of the call to LogOperation.
+type OperationLogger interface {
+	LogOperation(ctx context.Context, rec *OperationRecord)
+}
+
+// OperationLogOptions configures a server's operation log.
+type OperationLogOptions struct {
+	// Logger receives the records. It must not be nil.
+	Logger OperationLogger
+
+	// If MinDuration is positive, then only operations that take at least
+	// MinDuration are logged.
+	MinDuration time.Duration
+
+	// SlowFields is the maximum number of fields to include in each record's
+	// SlowestFields.
+	SlowFields int
+
+	// RedactVariable reports whether the value of a variable or an input
+	// object field with the given name should be omitted from records. If nil,
+	// DefaultRedactVariable is used.
+	RedactVariable func(name string) bool
+}
+
+// OperationRecord describes a completed operation.
+type OperationRecord struct {
+	// OperationName is the name of the executed operation. It is empty for
+	// anonymous operations or if the document could not be validated.
+	OperationName string `json:"operationName,omitempty"`
+	// OperationType is the type of the executed operation. It is zero if the
+	// document could not be validated.
+	OperationType OperationType `json:"operationType,omitempty"`
+	// DocumentHash is the hex-encoded SHA-256 hash of the document.
+	DocumentHash string `json:"documentHash"`
+	// Variables holds the request's variables as returned by Input.GoValue,
+	// with redacted values replaced by RedactedValue. Uploads are replaced by
+	// objects with their filename, contentType, and size.
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Duration is the time taken to execute the operation.
+	Duration time.Duration `json:"duration"`
+	// ErrorCount is the number of errors in the response.
+	ErrorCount int `json:"errorCount"`
+	// SlowestFields holds the timings of the slowest resolvers, slowest first.
+	SlowestFields []FieldTiming `json:"slowestFields,omitempty"`
+}
+
+// FieldTiming is the time taken by a single resolver.
+type FieldTiming struct {
+	Path       []PathSegment `json:"path"`
+	ParentType string        `json:"parentType"`
+	FieldName  string        `json:"fieldName"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// RedactedValue replaces redacted variable values in an OperationRecord.
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactVariable reports whether name contains (ignoring case) one of
+// "password", "secret", "token", or "key".
+func DefaultRedactVariable(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token", "key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewJSONOperationLogger returns an OperationLogger that writes each record to
+// w as a single line of JSON.
+func NewJSONOperationLogger(w io.Writer) OperationLogger {
+	return &jsonOperationLogger{w: w}
+}
+
+type jsonOperationLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *jsonOperationLogger) LogOperation(ctx context.Context, rec *OperationRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	l.mu.Lock()
+	l.w.Write(data)
+	l.mu.Unlock()
+}
+
+// operationLogTracer is the Tracer that builds records for the operation log.
+type operationLogTracer struct {
+	opts OperationLogOptions
+}
+
+// operationLogState is the state of a single logged operation.
+type operationLogState struct {
+	start time.Time
+	req   Request
+
+	mu      sync.Mutex
+	query   *ValidatedQuery
+	slowest []FieldTiming
+}
+
+type operationLogStateContextKey struct{}
+
+func (t *operationLogTracer) TraceExecution(ctx context.Context, req Request) (context.Context, func(*Response)) {
+	state := &operationLogState{
+		start: time.Now(),
+		req:   req,
+		query: req.ValidatedQuery,
+	}
+	ctx = context.WithValue(ctx, operationLogStateContextKey{}, state)
+	return ctx, func(resp *Response) {
+		d := time.Since(state.start)
+		if d < t.opts.MinDuration {
+			return
+		}
+		t.opts.Logger.LogOperation(ctx, t.record(state, d, resp))
+	}
+}
+
+func (t *operationLogTracer) record(state *operationLogState, d time.Duration, resp *Response) *OperationRecord {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	source := state.req.Query
+	if state.query != nil {
+		source = state.query.Source()
+	}
+	hash := sha256.Sum256([]byte(source))
+	rec := &OperationRecord{
+		OperationName: state.req.OperationName,
+		DocumentHash:  hex.EncodeToString(hash[:]),
+		Duration:      d,
+		ErrorCount:    len(resp.Errors),
+		SlowestFields: state.slowest,
+	}
+	if state.query != nil {
+		if op := state.query.doc.FindOperation(state.req.OperationName); op != nil {
+			rec.OperationType = operationTypeFromAST(op.Type)
+			if op.Name != nil {
+				rec.OperationName = op.Name.Value
+			}
+		}
+	}
+	if len(state.req.Variables) > 0 {
+		redact := t.opts.RedactVariable
+		if redact == nil {
+			redact = DefaultRedactVariable
+		}
+		rec.Variables = make(map[string]interface{}, len(state.req.Variables))
+		for k, v := range state.req.Variables {
+			if redact(k) {
+				rec.Variables[k] = RedactedValue
+			} else {
+				rec.Variables[k] = redactInput(v.GoValue(), redact)
+			}
+		}
+	}
+	return rec
+}
+
+// redactInput replaces the values of input object fields whose names match
+// redact.
+func redactInput(v interface{}, redact func(string) bool) interface{} {
+	switch v := v.(type) {
+	case *Upload:
+		return map[string]interface{}{
+			"filename":    v.Filename,
+			"contentType": v.ContentType,
+			"size":        v.Size,
+		}
+	case map[string]interface{}:
+		for k, elem := range v {
+			if redact(k) {
+				v[k] = RedactedValue
+			} else {
+				v[k] = redactInput(elem, redact)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactInput(v[i], redact)
+		}
+	}
+	return v
+}
+
+func (t *operationLogTracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*ResponseError)) {
+	return ctx, nil
+}
+
+func (t *operationLogTracer) TraceValidation(ctx context.Context) (context.Context, func(*ValidatedQuery, []*ResponseError)) {
+	state, _ := ctx.Value(operationLogStateContextKey{}).(*operationLogState)
+	if state == nil {
+		return ctx, nil
+	}
+	return ctx, func(query *ValidatedQuery, _ []*ResponseError) {
+		state.mu.Lock()
+		state.query = query
+		state.mu.Unlock()
+	}
+}
+
+func (t *operationLogTracer) TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(interface{}, error)) {
+	state, _ := ctx.Value(operationLogStateContextKey{}).(*operationLogState)
+	if state == nil || t.opts.SlowFields <= 0 {
+		return ctx, nil
+	}
+	start := time.Now()
+	path := append([]PathSegment(nil), info.Path...)
+	parentType := info.ParentType
+	fieldName := info.Request.Name
+	return ctx, func(interface{}, error) {
+		d := time.Since(start)
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		n := len(state.slowest)
+		if n == t.opts.SlowFields && state.slowest[n-1].Duration >= d {
+			return
+		}
+		i := sort.Search(n, func(i int) bool {
+			return state.slowest[i].Duration < d
+		})
+		if n < t.opts.SlowFields {
+			state.slowest = append(state.slowest, FieldTiming{})
+		}
+		copy(state.slowest[i+1:], state.slowest[i:])
+		state.slowest[i] = FieldTiming{
+			Path:       path,
+			ParentType: parentType,
+			FieldName:  fieldName,
+			Duration:   d,
+		}
+	}
+}
@@ -24,17 +24,19 @@ import (
 	"strings"
 	"sync"
 
-	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // Schema is a parsed set of type definitions.
 type Schema struct {
-	query     *gqlType
-	mutation  *gqlType
-	types     map[string]*gqlType
-	typeOrder []string
+	query          *gqlType
+	mutation       *gqlType
+	subscription   *gqlType
+	types          map[string]*gqlType
+	typeOrder      []string
+	directives     map[string]*directiveDefinition
+	directiveOrder []string
 
 	mu      sync.RWMutex
 	goTypes map[typeKey]*typeDescriptor
@@ -45,6 +47,13 @@ type Schema struct {
 type SchemaOptions struct {
 	// IgnoreDescriptions will strip descriptions from the schema as it is parsed.
 	IgnoreDescriptions bool
+
+	// StructuredScalars is a list of custom scalar types that accept input
+	// objects and lists in addition to scalar values. Field resolvers receive
+	// such inputs as values of the scalar type that can be examined with
+	// Value methods or Convert. Output values of these types must still be
+	// scalars.
+	StructuredScalars []string
 }
 
 type schemaOptions struct {
@@ -52,7 +61,7 @@ type schemaOptions struct {
 	internal bool
 }
 
-func (opts schemaOptions) description(d *gqlang.Description) string {
+func (opts schemaOptions) description(d *ast.Description) string {
 	if opts.SchemaOptions != nil && opts.IgnoreDescriptions {
 		return ""
 	}
@@ -70,13 +79,13 @@ func ParseSchema(source string, opts *SchemaOptions) (*Schema, error) {
 }
 
 func parseSchema(source string, opts schemaOptions) (*Schema, error) {
-	doc, errs := gqlang.Parse(source)
+	doc, errs := ast.Parse(source)
 	if len(errs) > 0 {
 		msgBuilder := new(strings.Builder)
 		msgBuilder.WriteString("syntax errors:")
 		for _, err := range errs {
 			msgBuilder.WriteByte('\n')
-			if p, ok := gqlang.ErrorPosition(err); ok {
+			if p, ok := ast.ErrorPosition(err); ok {
 				msgBuilder.WriteString(p.String())
 				msgBuilder.WriteString(": ")
 			}
@@ -97,12 +106,22 @@ func parseSchema(source string, opts schemaOptions) (*Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	directiveMap, directiveOrder, err := buildDirectiveMap(source, opts, typeMap, doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyDirectives(source, typeMap, directiveMap, doc); err != nil {
+		return nil, err
+	}
 	schema := &Schema{
-		query:     typeMap["Query"],
-		mutation:  typeMap["Mutation"],
-		types:     typeMap,
-		typeOrder: typeOrder,
-		goTypes:   make(map[typeKey]*typeDescriptor),
+		query:          typeMap["Query"],
+		mutation:       typeMap["Mutation"],
+		subscription:   typeMap["Subscription"],
+		types:          typeMap,
+		typeOrder:      typeOrder,
+		directives:     directiveMap,
+		directiveOrder: directiveOrder,
+		goTypes:        make(map[typeKey]*typeDescriptor),
 	}
 	if !opts.internal {
 		if schema.query == nil {
@@ -114,6 +133,9 @@ func parseSchema(source string, opts schemaOptions) (*Schema, error) {
 		if schema.mutation != nil && !schema.mutation.isObject() {
 			return nil, xerrors.Errorf("mutation type %v must be an object", schema.mutation)
 		}
+		if schema.subscription != nil && !schema.subscription.isObject() {
+			return nil, xerrors.Errorf("subscription type %v must be an object", schema.subscription)
+		}
 	}
 	return schema, nil
 }
@@ -165,7 +187,12 @@ func builtins(includeIntrospection bool) []*gqlType {
 	)
 }
 
-func buildTypeMap(source string, opts schemaOptions, doc *gqlang.Document) (map[string]*gqlType, error) {
+func isBuiltinScalar(typ *gqlType) bool {
+	typ = typ.toNullable()
+	return typ == booleanType || typ == floatType || typ == intType || typ == stringType || typ == idType
+}
+
+func buildTypeMap(source string, opts schemaOptions, doc *ast.Document) (map[string]*gqlType, error) {
 	typeMap := make(map[string]*gqlType)
 	for _, b := range builtins(!opts.internal) {
 		typeMap[b.String()] = b
@@ -199,16 +226,10 @@ func buildTypeMap(source string, opts schemaOptions, doc *gqlang.Document) (map[
 				if info.has(sym) {
 					return nil, xerrors.Errorf("%v: multiple enum values with name %q", v.Value.Start.ToPosition(source), sym)
 				}
-				ev := enumValue{
+				info.values = append(info.values, enumValue{
 					name:        sym,
 					description: opts.description(v.Description),
-				}
-				var err error
-				ev.deprecated, ev.deprecationReason, err = processTypeDirectives(source, typeMap, v.Directives)
-				if err != nil {
-					return nil, err
-				}
-				info.values = append(info.values, ev)
+				})
 			}
 			typeMap[name.Value] = newEnumType(info, opts.description(t.Enum.Description))
 		case t.Object != nil:
@@ -225,6 +246,16 @@ func buildTypeMap(source string, opts schemaOptions, doc *gqlang.Document) (map[
 			}, opts.description(t.InputObject.Description))
 		}
 	}
+	if opts.SchemaOptions != nil {
+		for _, name := range opts.StructuredScalars {
+			typ := typeMap[name]
+			if typ == nil || !typ.isScalar() || isBuiltinScalar(typ) {
+				return nil, xerrors.Errorf("structured scalar %s is not a custom scalar type", name)
+			}
+			typ.structured = true
+			typ.nullVariant.structured = true
+		}
+	}
 	// Second pass: fill in object definitions.
 	for _, defn := range doc.Definitions {
 		if defn.Type == nil {
@@ -248,7 +279,7 @@ func buildTypeMap(source string, opts schemaOptions, doc *gqlang.Document) (map[
 	return typeMap, nil
 }
 
-func fillObjectTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, obj *gqlang.ObjectTypeDefinition) error {
+func fillObjectTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, obj *ast.ObjectTypeDefinition) error {
 	info := typeMap[obj.Name.Value].obj
 	for _, fieldDefn := range obj.Fields.Defs {
 		fieldName := fieldDefn.Name.Value
@@ -270,38 +301,8 @@ func fillObjectTypeFields(source string, opts schemaOptions, typeMap map[string]
 			description: opts.description(fieldDefn.Description),
 			typ:         typ,
 		}
-		if fieldDefn.Args != nil {
-			for _, arg := range fieldDefn.Args.Args {
-				argName := arg.Name.Value
-				if !opts.internal && strings.HasPrefix(argName, reservedPrefix) {
-					return xerrors.Errorf("%v: use of reserved name %q", arg.Name.Start.ToPosition(source), argName)
-				}
-				if f.args.byName(argName) != nil {
-					return xerrors.Errorf("%v: multiple arguments named %q for field %s.%s", arg.Name.Start.ToPosition(source), argName, obj.Name, fieldName)
-				}
-				typ := resolveTypeRef(typeMap, arg.Type)
-				if typ == nil {
-					return xerrors.Errorf("%v: undefined type %v", arg.Type.Start().ToPosition(source), arg.Type)
-				}
-				if !typ.isInputType() {
-					return xerrors.Errorf("%v: %v is not an input type", arg.Type.Start().ToPosition(source), arg.Type)
-				}
-				argDef := inputValueDefinition{
-					name:         argName,
-					description:  opts.description(arg.Description),
-					defaultValue: Value{typ: typ},
-				}
-				if arg.Default != nil {
-					if errs := validateConstantValue(source, typ, arg.Default.Value); len(errs) > 0 {
-						return errs[0]
-					}
-					argDef.defaultValue = coerceConstantInputValue(typ, arg.Default.Value)
-				}
-				f.args = append(f.args, argDef)
-			}
-		}
 		var err error
-		f.deprecated, f.deprecationReason, err = processTypeDirectives(source, typeMap, fieldDefn.Directives)
+		f.args, err = buildArgumentsDefinition(source, opts, typeMap, "field "+obj.Name.Value+"."+fieldName, fieldDefn.Args)
 		if err != nil {
 			return err
 		}
@@ -310,11 +311,49 @@ func fillObjectTypeFields(source string, opts schemaOptions, typeMap map[string]
 	return nil
 }
 
-func fillUnionTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, u *gqlang.UnionTypeDefinition) error {
+// buildArgumentsDefinition converts the arguments of a field or directive
+// definition. owner is used in error messages.
+func buildArgumentsDefinition(source string, opts schemaOptions, typeMap map[string]*gqlType, owner string, argsDefn *ast.ArgumentsDefinition) (inputValueDefinitionList, error) {
+	if argsDefn == nil {
+		return nil, nil
+	}
+	var list inputValueDefinitionList
+	for _, arg := range argsDefn.Args {
+		argName := arg.Name.Value
+		if !opts.internal && strings.HasPrefix(argName, reservedPrefix) {
+			return nil, xerrors.Errorf("%v: use of reserved name %q", arg.Name.Start.ToPosition(source), argName)
+		}
+		if list.byName(argName) != nil {
+			return nil, xerrors.Errorf("%v: multiple arguments named %q for %s", arg.Name.Start.ToPosition(source), argName, owner)
+		}
+		typ := resolveTypeRef(typeMap, arg.Type)
+		if typ == nil {
+			return nil, xerrors.Errorf("%v: undefined type %v", arg.Type.Start().ToPosition(source), arg.Type)
+		}
+		if !typ.isInputType() {
+			return nil, xerrors.Errorf("%v: %v is not an input type", arg.Type.Start().ToPosition(source), arg.Type)
+		}
+		argDef := inputValueDefinition{
+			name:         argName,
+			description:  opts.description(arg.Description),
+			defaultValue: Value{typ: typ},
+		}
+		if arg.Default != nil {
+			if errs := validateConstantValue(source, typ, arg.Default.Value); len(errs) > 0 {
+				return nil, errs[0]
+			}
+			argDef.defaultValue = coerceConstantInputValue(typ, arg.Default.Value)
+		}
+		list = append(list, argDef)
+	}
+	return list, nil
+}
+
+func fillUnionTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, u *ast.UnionTypeDefinition) error {
 	info := typeMap[u.Name.Value].union
 	usedTypes := make(map[*gqlType]struct{}, len(u.MemberTypes))
 	for _, memberType := range u.MemberTypes {
-		typ := resolveTypeRef(typeMap, &gqlang.TypeRef{Named: memberType})
+		typ := resolveTypeRef(typeMap, &ast.TypeRef{Named: memberType})
 		if typ == nil {
 			return xerrors.Errorf("%v: undefined type %v", memberType.Start.ToPosition(source), memberType)
 		}
@@ -330,7 +369,7 @@ func fillUnionTypeFields(source string, opts schemaOptions, typeMap map[string]*
 	return nil
 }
 
-func fillInputObjectTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, obj *gqlang.InputObjectTypeDefinition) error {
+func fillInputObjectTypeFields(source string, opts schemaOptions, typeMap map[string]*gqlType, obj *ast.InputObjectTypeDefinition) error {
 	info := typeMap[obj.Name.Value].input
 	for _, fieldDefn := range obj.Fields.Defs {
 		fieldName := fieldDefn.Name.Value
@@ -360,7 +399,146 @@ func fillInputObjectTypeFields(source string, opts schemaOptions, typeMap map[st
 	return nil
 }
 
-func processTypeDirectives(source string, typeMap map[string]*gqlType, directives gqlang.Directives) (deprecated bool, deprecationReason NullString, _ error) {
+// buildDirectiveMap converts the directive definitions in a schema document.
+// It must be called after all types have been filled in.
+func buildDirectiveMap(source string, opts schemaOptions, typeMap map[string]*gqlType, doc *ast.Document) (map[string]*directiveDefinition, []string, error) {
+	directiveMap := make(map[string]*directiveDefinition)
+	var order []string
+	for _, defn := range doc.Definitions {
+		d := defn.Directive
+		if d == nil {
+			continue
+		}
+		name := d.Name.Value
+		if !opts.internal && strings.HasPrefix(name, reservedPrefix) {
+			return nil, nil, xerrors.Errorf("%v: use of reserved name %q", d.Name.Start.ToPosition(source), name)
+		}
+		if name == deprecatedDirective.Name || directiveMap[name] != nil {
+			return nil, nil, xerrors.Errorf("%v: multiple directives with name %q", d.Name.Start.ToPosition(source), name)
+		}
+		info := &directiveDefinition{
+			Name:       name,
+			repeatable: d.Repeatable,
+		}
+		if desc := opts.description(d.Description); desc != "" {
+			info.Description = NullString{S: desc, Valid: true}
+		}
+		var err error
+		info.Args, err = buildArgumentsDefinition(source, opts, typeMap, "directive @"+name, d.Args)
+		if err != nil {
+			return nil, nil, err
+		}
+		locationType := introspectionSchema().types["__DirectiveLocation"]
+		for _, loc := range d.Locations {
+			if !locationType.enum.has(loc.Value) {
+				return nil, nil, xerrors.Errorf("%v: unknown directive location %s", loc.Start.ToPosition(source), loc.Value)
+			}
+			if info.allowedOn(loc.Value) {
+				return nil, nil, xerrors.Errorf("%v: location %s listed multiple times", loc.Start.ToPosition(source), loc.Value)
+			}
+			info.Locations = append(info.Locations, loc.Value)
+		}
+		directiveMap[name] = info
+		order = append(order, name)
+	}
+	return directiveMap, order, nil
+}
+
+// applyDirectives validates the directives used in the type definitions and
+// directive definitions of a schema document and records their arguments.
+func applyDirectives(source string, typeMap map[string]*gqlType, directiveMap map[string]*directiveDefinition, doc *ast.Document) error {
+	process := func(loc string, directives ast.Directives) ([]*Directive, error) {
+		return processDirectives(source, typeMap, directiveMap, loc, directives)
+	}
+	processArgs := func(list inputValueDefinitionList, loc string, argsDefn []*ast.InputValueDefinition) error {
+		for _, argDefn := range argsDefn {
+			ivd := list.byName(argDefn.Name.Value)
+			var err error
+			ivd.directives, err = process(loc, argDefn.Directives)
+			if err != nil {
+				return err
+			}
+			ivd.constraint, err = newInputConstraint(ivd.directives)
+			if err != nil {
+				return xerrors.Errorf("%v: %w", argDefn.Name.Start.ToPosition(source), err)
+			}
+		}
+		return nil
+	}
+	for _, defn := range doc.Definitions {
+		if d := defn.Directive; d != nil && d.Args != nil {
+			if err := processArgs(directiveMap[d.Name.Value].Args, "ARGUMENT_DEFINITION", d.Args.Args); err != nil {
+				return err
+			}
+		}
+		t := defn.Type
+		if t == nil {
+			continue
+		}
+		typ := typeMap[t.Name().Value]
+		var err error
+		switch {
+		case t.Scalar != nil:
+			typ.directives, err = process("SCALAR", t.Scalar.Directives)
+		case t.Object != nil:
+			typ.directives, err = process("OBJECT", t.Object.Directives)
+		case t.Union != nil:
+			typ.directives, err = process("UNION", t.Union.Directives)
+		case t.Enum != nil:
+			typ.directives, err = process("ENUM", t.Enum.Directives)
+		case t.InputObject != nil:
+			typ.directives, err = process("INPUT_OBJECT", t.InputObject.Directives)
+		}
+		if err != nil {
+			return err
+		}
+		typ.nullVariant.directives = typ.directives
+		typ.cacheHint, err = newCacheHint(typ.directives)
+		if err != nil {
+			return xerrors.Errorf("%v: %w", t.Name().Start.ToPosition(source), err)
+		}
+		typ.nullVariant.cacheHint = typ.cacheHint
+
+		switch {
+		case t.Object != nil:
+			for _, fieldDefn := range t.Object.Fields.Defs {
+				f := typ.obj.field(fieldDefn.Name.Value)
+				f.directives, err = process("FIELD_DEFINITION", fieldDefn.Directives)
+				if err != nil {
+					return err
+				}
+				f.deprecated, f.deprecationReason = deprecation(f.directives)
+				f.cacheHint, err = newCacheHint(f.directives)
+				if err != nil {
+					return xerrors.Errorf("%v: %w", fieldDefn.Name.Start.ToPosition(source), err)
+				}
+				if fieldDefn.Args != nil {
+					if err := processArgs(f.args, "ARGUMENT_DEFINITION", fieldDefn.Args.Args); err != nil {
+						return err
+					}
+				}
+			}
+		case t.Enum != nil:
+			for _, valueDefn := range t.Enum.Values.Values {
+				ev := typ.enum.value(valueDefn.Value.Value)
+				ev.directives, err = process("ENUM_VALUE", valueDefn.Directives)
+				if err != nil {
+					return err
+				}
+				ev.deprecated, ev.deprecationReason = deprecation(ev.directives)
+			}
+		case t.InputObject != nil:
+			if err := processArgs(typ.input.fields, "INPUT_FIELD_DEFINITION", t.InputObject.Fields.Defs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// processDirectives validates the directives applied at a location in the
+// schema and coerces their arguments.
+func processDirectives(source string, typeMap map[string]*gqlType, directiveMap map[string]*directiveDefinition, loc string, directives ast.Directives) ([]*Directive, error) {
 	v := &validationScope{
 		source: source,
 		types:  typeMap,
@@ -369,32 +547,60 @@ func processTypeDirectives(source string, typeMap map[string]*gqlType, directive
 		source: source,
 		types:  typeMap,
 	}
+	var result []*Directive
 	for _, d := range directives {
-		if d.Name.Value != deprecatedDirective.Name {
-			return false, NullString{}, xerrors.Errorf("%v: unknown directive @%s", d.At.ToPosition(source), d.Name.Value)
+		name := d.Name.Value
+		defn := directiveMap[name]
+		if name == deprecatedDirective.Name {
+			defn = &deprecatedDirective
 		}
-		if deprecated {
-			return false, NullString{}, xerrors.Errorf("%v: multiple @%s directives", d.At.ToPosition(source), d.Name.Value)
+		if defn == nil {
+			return nil, xerrors.Errorf("%v: unknown directive @%s", d.At.ToPosition(source), name)
 		}
-
-		deprecated = true
-		argErrs := validateArguments(v, deprecatedDirective.Args, d.Arguments)
+		if !defn.allowedOn(loc) {
+			return nil, xerrors.Errorf("%v: @%s directive not allowed on %s", d.At.ToPosition(source), name, loc)
+		}
+		if !defn.repeatable && findDirective(result, name) != nil {
+			return nil, xerrors.Errorf("%v: multiple @%s directives", d.At.ToPosition(source), name)
+		}
+		argErrs := validateArguments(v, defn.Args, d.Arguments)
 		if len(argErrs) > 0 {
-			return false, NullString{}, xerrors.Errorf("%v: @%s directive: %w", d.Arguments.LParen.ToPosition(source), d.Name.Value, argErrs[0])
+			return nil, xerrors.Errorf("%v: @%s directive: %w", d.At.ToPosition(source), name, argErrs[0])
 		}
-		var args map[string]Value
-		args, argErrs = coerceArgumentValues(s, deprecatedDirective.Args, d.Arguments)
+		args, argErrs := coerceArgumentValues(s, defn.Args, d.Arguments)
 		if len(argErrs) > 0 {
-			return false, NullString{}, xerrors.Errorf("%v: @%s directive: %w", d.Arguments.LParen.ToPosition(source), d.Name.Value, argErrs[0])
+			return nil, xerrors.Errorf("%v: @%s directive: %w", d.At.ToPosition(source), name, argErrs[0])
 		}
-		if r := args["reason"]; !r.IsNull() {
-			deprecationReason = NullString{S: r.Scalar(), Valid: true}
+		result = append(result, &Directive{
+			Name: name,
+			Args: args,
+		})
+	}
+	return result, nil
+}
+
+// deprecation returns the deprecation status described by a list of directives.
+func deprecation(directives []*Directive) (deprecated bool, reason NullString) {
+	d := findDirective(directives, deprecatedDirective.Name)
+	if d == nil {
+		return false, NullString{}
+	}
+	if r := d.Args["reason"]; !r.IsNull() {
+		reason = NullString{S: r.Scalar(), Valid: true}
+	}
+	return true, reason
+}
+
+func findDirective(directives []*Directive, name string) *Directive {
+	for _, d := range directives {
+		if d.Name == name {
+			return d
 		}
 	}
-	return deprecated, deprecationReason, nil
+	return nil
 }
 
-func resolveTypeRef(typeMap map[string]*gqlType, ref *gqlang.TypeRef) *gqlType {
+func resolveTypeRef(typeMap map[string]*gqlType, ref *ast.TypeRef) *gqlType {
 	switch {
 	case ref.Named != nil:
 		return typeMap[ref.Named.Value]
@@ -421,14 +627,14 @@ func resolveTypeRef(typeMap map[string]*gqlType, ref *gqlang.TypeRef) *gqlType {
 	}
 }
 
-func (schema *Schema) operationType(opType gqlang.OperationType) *gqlType {
+func (schema *Schema) operationType(opType ast.OperationType) *gqlType {
 	switch opType {
-	case gqlang.Query:
+	case ast.Query:
 		return schema.query
-	case gqlang.Mutation:
+	case ast.Mutation:
 		return schema.mutation
-	case gqlang.Subscription:
-		return nil
+	case ast.Subscription:
+		return schema.subscription
 	default:
 		panic("unknown operation type")
 	}
@@ -436,7 +642,16 @@ func (schema *Schema) operationType(opType gqlang.OperationType) *gqlType {
 
 // Validate parses and type-checks an executable GraphQL document.
 func (schema *Schema) Validate(query string) (*ValidatedQuery, []*ResponseError) {
-	doc, errs := gqlang.Parse(query)
+	doc, errs := schema.parse(query)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return schema.validateDocument(query, doc)
+}
+
+// parse parses an executable GraphQL document.
+func (schema *Schema) parse(query string) (*ast.Document, []*ResponseError) {
+	doc, errs := ast.Parse(query)
 	if len(errs) > 0 {
 		respErrs := make([]*ResponseError, 0, len(errs))
 		for _, err := range errs {
@@ -444,7 +659,12 @@ func (schema *Schema) Validate(query string) (*ValidatedQuery, []*ResponseError)
 		}
 		return nil, respErrs
 	}
-	errs = schema.validateRequest(query, doc)
+	return doc, nil
+}
+
+// validateDocument type-checks a parsed executable GraphQL document.
+func (schema *Schema) validateDocument(query string, doc *ast.Document) (*ValidatedQuery, []*ResponseError) {
+	errs := schema.validateRequest(query, doc)
 	if len(errs) > 0 {
 		respErrs := make([]*ResponseError, 0, len(errs))
 		for _, err := range errs {
@@ -572,6 +792,20 @@ func (schema *Schema) typeDescriptorLocked(key typeKey) *typeDescriptor {
 			}
 			return desc
 		}
+		if schema.subscription != nil && key.gqlType == schema.subscription.obj {
+			// Subscription fields produce a stream of values for the field's type.
+			if fdesc.fieldIndex != -1 && fieldGoType.Kind() == reflect.Ptr {
+				fieldGoType = fieldGoType.Elem()
+			}
+			if fieldGoType.Kind() != reflect.Chan || fieldGoType.ChanDir()&reflect.RecvDir == 0 {
+				*desc = typeDescriptor{
+					err: xerrors.Errorf("subscription field %s.%s must be a receive channel (found %v)",
+						key.gqlType.name, field.name, fieldGoType),
+				}
+				return desc
+			}
+			fieldGoType = fieldGoType.Elem()
+		}
 		// TODO(someday): Check field type for scalars.
 		if field.typ.isObject() {
 			err := schema.typeDescriptorLocked(typeKey{
@@ -662,14 +896,9 @@ func (desc *typeDescriptor) read(ctx context.Context, recv reflect.Value, req Fi
 		return reflect.Value{}, desc.err
 	}
 	if desc.hasResolveField {
-		ctx, span := trace.StartSpan(ctx, "graphql:resolve "+req.Name, trace.WithSpanKind(trace.SpanKindServer))
-		defer span.End()
-		span.AddAttributes(trace.StringAttribute("graphql.field", req.Name))
 		val, err := interfaceValueForAssertions(recv).(FieldResolver).ResolveField(ctx, req)
 		if err != nil {
-			// Intentionally making the returned error opaque to avoid interference in
-			// toResponseError.
-			return reflect.Value{}, xerrors.Errorf("server error: %v", err)
+			return reflect.Value{}, &resolverError{err}
 		}
 		return reflect.ValueOf(val), nil
 	}
@@ -680,6 +909,52 @@ func (desc *typeDescriptor) read(ctx context.Context, recv reflect.Value, req Fi
 	return fdesc.read(ctx, recv, req)
 }
 
+// resolverError is an error returned from a field method or ResolveField.
+type resolverError struct {
+	err error
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+// ResolveField resolves a field of the named object type on a Go value,
+// following the rules in the Field Resolution section of the package
+// documentation. It returns an error if the Go value cannot provide the object
+// type or the resolver returns an error. ResolveField is useful for
+// implementing a FieldResolver that wraps another Go value.
+func (schema *Schema) ResolveField(ctx context.Context, obj interface{}, typeName string, req FieldRequest) (interface{}, error) {
+	typ := schema.types[typeName]
+	if typ == nil || !typ.isObject() {
+		return nil, xerrors.Errorf("resolve field %s.%s: no such object type", typeName, req.Name)
+	}
+	if typ.obj.field(req.Name) == nil {
+		return nil, xerrors.Errorf("resolve field %s.%s: no such field", typeName, req.Name)
+	}
+	goValue := valueForAssertions(reflect.ValueOf(obj))
+	if !goValue.IsValid() {
+		return nil, xerrors.Errorf("resolve field %s.%s: nil object", typeName, req.Name)
+	}
+	desc := schema.typeDescriptor(typeKey{
+		goType:  goValue.Type(),
+		gqlType: typ.obj,
+	})
+	if desc == nil {
+		return nil, xerrors.Errorf("resolve field %s.%s: cannot use %v", typeName, req.Name, goValue.Type())
+	}
+	result, err := desc.read(ctx, goValue, req)
+	if re, ok := err.(*resolverError); ok {
+		return nil, re.err
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("resolve field %s.%s: %w", typeName, req.Name, err)
+	}
+	if !result.IsValid() || !result.CanInterface() {
+		return nil, nil
+	}
+	return result.Interface(), nil
+}
+
 type fieldDescriptor struct {
 	fieldIndex int
 
@@ -726,9 +1001,6 @@ func (fdesc fieldDescriptor) read(ctx context.Context, recv reflect.Value, req F
 		panic("unknown field method arguments parameter type")
 	}
 
-	ctx, span := trace.StartSpan(ctx, "graphql:resolve "+req.Name, trace.WithSpanKind(trace.SpanKindServer))
-	defer span.End()
-	span.AddAttributes(trace.StringAttribute("graphql.field", req.Name))
 	var callArgs []reflect.Value
 	if fdesc.methodFlags&contextFieldMethodArg != 0 {
 		callArgs = append(callArgs, reflect.ValueOf(ctx))
@@ -745,10 +1017,7 @@ func (fdesc fieldDescriptor) read(ctx context.Context, recv reflect.Value, req F
 	}
 	out := method.Call(callArgs)
 	if !out[1].IsNil() {
-		// Intentionally making the returned error opaque to avoid interference in
-		// toResponseError.
-		err := out[1].Interface().(error)
-		return reflect.Value{}, xerrors.Errorf("server error: %v", err)
+		return reflect.Value{}, &resolverError{out[1].Interface().(error)}
 	}
 	return out[0], nil
 }
@@ -20,6 +20,8 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestParseSchema(t *testing.T) {
@@ -313,6 +315,101 @@ func TestParseSchema(t *testing.T) {
 			`,
 			wantErr: true,
 		},
+		{
+			name:    "Deprecated",
+			source:  `type Query { foo: String @deprecated(reason: "use bar"), bar: String }`,
+			wantErr: false,
+		},
+		{
+			name:    "Deprecated/WrongLocation",
+			source:  `type Query @deprecated { foo: String }`,
+			wantErr: true,
+		},
+		{
+			name:    "Directive/Unknown",
+			source:  `type Query { foo: String @example(value: "x") }`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/Custom",
+			source: `
+				directive @example(value: [String!]!) on FIELD_DEFINITION | SCALAR
+				directive @tag(name: String!) repeatable on OBJECT | ARGUMENT_DEFINITION | INPUT_FIELD_DEFINITION | ENUM | ENUM_VALUE | UNION | INPUT_OBJECT
+				scalar Date @example(value: "2019-12-25")
+				type Query @tag(name: "a") @tag(name: "b") {
+					foo(arg: Int @tag(name: "c")): String @example(value: ["x", "y"])
+				}
+				enum Color @tag(name: "d") { RED @tag(name: "e") }
+				union U @tag(name: "f") = Query
+				input In @tag(name: "g") { x: Int @tag(name: "h") }
+			`,
+			wantErr: false,
+		},
+		{
+			name: "Directive/WrongLocation",
+			source: `
+				directive @example(value: String!) on FIELD_DEFINITION
+				type Query @example(value: "x") { foo: String }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/NotRepeatable",
+			source: `
+				directive @example(value: String!) on FIELD_DEFINITION
+				type Query { foo: String @example(value: "x") @example(value: "y") }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/MissingArgument",
+			source: `
+				directive @example(value: String!) on FIELD_DEFINITION
+				type Query { foo: String @example }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/WrongArgumentType",
+			source: `
+				directive @example(value: String!) on FIELD_DEFINITION
+				type Query { foo: String @example(value: 42) }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/Duplicate",
+			source: `
+				directive @example on FIELD_DEFINITION
+				directive @example on FIELD_DEFINITION
+				type Query { foo: String }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/RedefineBuiltin",
+			source: `
+				directive @deprecated on FIELD_DEFINITION
+				type Query { foo: String }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/UnknownLocation",
+			source: `
+				directive @example on FOO
+				type Query { foo: String }
+			`,
+			wantErr: true,
+		},
+		{
+			name: "Directive/ReservedName",
+			source: `
+				directive @__example on FIELD_DEFINITION
+				type Query { foo: String }
+			`,
+			wantErr: true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -353,3 +450,107 @@ func TestParseSchemaFile(t *testing.T) {
 		t.Errorf("ParseSchemaFile(%q, nil): %v", f.Name(), err)
 	}
 }
+
+func TestSchemaTypes(t *testing.T) {
+	t.Parallel()
+	schema, err := ParseSchema(`
+		directive @example(value: [String!]!) on FIELD_DEFINITION | SCALAR
+
+		"A calendar date"
+		scalar Date @example(value: "2019-12-25")
+
+		type Query {
+			today: Date!
+			events(since: Date, limit: Int = 10): [Event!]! @example(value: "x")
+			search: SearchResult
+		}
+
+		type Event {
+			name: String!
+			color: Color @deprecated
+		}
+
+		union SearchResult = Event
+
+		enum Color { RED, GREEN }
+
+		input Filter { color: Color! }
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, typ := range schema.Types() {
+		names = append(names, typ.Name())
+	}
+	wantNames := []string{"Date", "Query", "Event", "SearchResult", "Color", "Filter"}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Errorf("Types() names (-want +got):\n%s", diff)
+	}
+	if got, want := schema.QueryType(), schema.Type("Query"); got != want {
+		t.Errorf("QueryType() = %v; want %v", got, want)
+	}
+	if got := schema.MutationType(); got != nil {
+		t.Errorf("MutationType() = %v; want <nil>", got)
+	}
+	if got := schema.Type("Int"); got == nil || got.Kind() != ScalarKind {
+		t.Errorf("Type(\"Int\") = %v; want Int scalar", got)
+	}
+
+	date := schema.Type("Date")
+	if got, want := date.Description(), "A calendar date"; got != want {
+		t.Errorf("Date.Description() = %q; want %q", got, want)
+	}
+	if d := FindDirective(date.Directives(), "example"); d == nil {
+		t.Error("Date does not have @example")
+	} else if got, want := d.Args["value"].String(), `["2019-12-25"]`; got != want {
+		t.Errorf("Date @example(value:) = %s; want %s", got, want)
+	}
+
+	events := schema.QueryType().Field("events")
+	if events == nil {
+		t.Fatal("Query.events not found")
+	}
+	if got, want := events.Type.String(), "[Event!]!"; got != want {
+		t.Errorf("Query.events type = %s; want %s", got, want)
+	}
+	if got, want := events.Type.Kind(), NonNullKind; got != want {
+		t.Errorf("Query.events kind = %v; want %v", got, want)
+	}
+	if got, want := events.Type.OfType().OfType().OfType(), schema.Type("Event"); got != want {
+		t.Errorf("Query.events innermost type = %v; want %v", got, want)
+	}
+	if len(events.Args) != 2 {
+		t.Fatalf("len(Query.events.Args) = %d; want 2", len(events.Args))
+	}
+	if !events.Args[0].DefaultValue.IsNull() {
+		t.Errorf("since default = %v; want null", events.Args[0].DefaultValue)
+	}
+	if got, want := events.Args[1].DefaultValue.String(), "10"; got != want {
+		t.Errorf("limit default = %s; want %s", got, want)
+	}
+	if FindDirective(events.Directives, "example") == nil {
+		t.Error("Query.events does not have @example")
+	}
+	if FindDirective(schema.Type("Event").Field("color").Directives, "deprecated") == nil {
+		t.Error("Event.color does not have @deprecated")
+	}
+
+	if got := schema.Type("SearchResult").PossibleTypes(); len(got) != 1 || got[0] != schema.Type("Event") {
+		t.Errorf("SearchResult.PossibleTypes() = %v; want [Event]", got)
+	}
+	var colors []string
+	for _, v := range schema.Type("Color").EnumValues() {
+		colors = append(colors, v.Name)
+	}
+	if diff := cmp.Diff([]string{"RED", "GREEN"}, colors); diff != "" {
+		t.Errorf("Color.EnumValues() (-want +got):\n%s", diff)
+	}
+	if fields := schema.Type("Filter").InputFields(); len(fields) != 1 || fields[0].Type.String() != "Color!" {
+		t.Errorf("Filter.InputFields() = %v; want [color: Color!]", fields)
+	}
+	if got := schema.Type("Color").Fields(); got != nil {
+		t.Errorf("Color.Fields() = %v; want <nil>", got)
+	}
+}
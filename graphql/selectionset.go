@@ -20,7 +20,7 @@ import (
 	"strings"
 
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // A SelectionSet is a collection of object fields that a client is requesting
@@ -33,7 +33,7 @@ type SelectionSet struct {
 // during validation.
 type selectionSetScope struct {
 	source    string
-	doc       *gqlang.Document // for the fragments
+	doc       *ast.Document // for the fragments
 	types     map[string]*gqlType
 	variables map[string]Value
 
@@ -49,12 +49,12 @@ func (s *selectionSetScope) withTypeCondition(c map[string]struct{}) *selectionS
 
 // newSelectionSet returns a new selection set from the request's AST.
 // It assumes that the AST has been validated.
-func newSelectionSet(s *selectionSetScope, typ *gqlType, ast *gqlang.SelectionSet) (*SelectionSet, []error) {
-	if ast == nil {
+func newSelectionSet(s *selectionSetScope, typ *gqlType, node *ast.SelectionSet) (*SelectionSet, []error) {
+	if node == nil {
 		return nil, nil
 	}
 	sel := new(SelectionSet)
-	errs := sel.merge(s, typ, ast)
+	errs := sel.merge(s, typ, node)
 	return sel, errs
 }
 
@@ -64,12 +64,12 @@ func newSelectionSet(s *selectionSetScope, typ *gqlType, ast *gqlang.SelectionSe
 //
 // See https://graphql.github.io/graphql-spec/June2018/#sec-Field-Collection and
 // https://graphql.github.io/graphql-spec/June2018/#MergeSelectionSets%28%29
-func (set *SelectionSet) merge(s *selectionSetScope, typ *gqlType, ast *gqlang.SelectionSet) []error {
-	if ast == nil {
+func (set *SelectionSet) merge(s *selectionSetScope, typ *gqlType, node *ast.SelectionSet) []error {
+	if node == nil {
 		return nil
 	}
 	var errs []error
-	for _, sel := range ast.Sel {
+	for _, sel := range node.Sel {
 		switch {
 		case sel.Field != nil:
 			errs = append(errs, set.addField(s, typ, sel.Field)...)
@@ -113,7 +113,7 @@ func fragmentTypeCondition(parentType, fragType *gqlType) map[string]struct{} {
 	return possible
 }
 
-func (set *SelectionSet) addField(s *selectionSetScope, typ *gqlType, f *gqlang.Field) []error {
+func (set *SelectionSet) addField(s *selectionSetScope, typ *gqlType, f *ast.Field) []error {
 	name := f.Name.Value
 	key := name
 	if f.Alias != nil {
@@ -412,6 +412,12 @@ func (f *SelectedField) Name() string {
 	return f.name
 }
 
+// Key returns the key used for the field in the response: its alias if it has
+// one or its name otherwise.
+func (f *SelectedField) Key() string {
+	return f.key
+}
+
 // Arg returns the argument with the given name or a null Value if the argument
 // doesn't exist.
 func (f *SelectedField) Arg(name string) Value {
@@ -0,0 +1,214 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"reflect"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/ast"
+)
+
+// Subscribe runs a single GraphQL operation and sends its results on the
+// returned channel. The channel is closed once the operation has no more
+// results. It is safe to call Subscribe from multiple goroutines.
+//
+// For subscription operations, the subscription object's field for the
+// operation's top-level field is read once to obtain a Go channel. Each value
+// received from that channel is an event: Subscribe executes the rest of the
+// operation's selection set against the event and sends the result. The
+// stream ends when the field's channel is closed or ctx is done. Resolvers
+// should stop sending events and close their channels once ctx is done.
+//
+// For queries and mutations, Subscribe calls Execute and sends its response.
+//
+// If the request fails before execution starts, such as when the document is
+// invalid or the subscription field returns an error, then Subscribe returns
+// the errors and a nil channel.
+func (srv *Server) Subscribe(ctx context.Context, req Request) (<-chan Response, []*ResponseError) {
+	if srv.authorizer != nil {
+		ctx = withAuthorizer(ctx, srv.authorizer)
+	}
+	query, errs := srv.lookup(ctx, req)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	op, err := findOperation(query, req.OperationName)
+	if err != nil {
+		return nil, []*ResponseError{err}
+	}
+	if op.Type != ast.Subscription {
+		resp := srv.Execute(ctx, Request{
+			ValidatedQuery: query,
+			DocumentID:     req.DocumentID,
+			OperationName:  req.OperationName,
+			Variables:      req.Variables,
+		})
+		if resp.Data.typ == nil && len(resp.Errors) > 0 {
+			// No data means the request failed before execution.
+			return nil, resp.Errors
+		}
+		c := make(chan Response, 1)
+		c <- resp
+		close(c)
+		return c, nil
+	}
+
+	sub, subErrs := srv.subscribe(ctx, query, op, req.Variables)
+	if len(subErrs) > 0 {
+		errs = make([]*ResponseError, 0, len(subErrs))
+		for _, err := range subErrs {
+			errs = append(errs, toResponseError(err))
+		}
+		return nil, errs
+	}
+	c := make(chan Response)
+	go func() {
+		defer close(c)
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: sub.stream},
+		}
+		for {
+			chosen, event, ok := reflect.Select(cases)
+			if chosen == 0 || !ok {
+				return
+			}
+			resp := srv.executeEvent(ctx, query, req, sub, event)
+			select {
+			case c <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+// subscription is the state of a started subscription operation.
+type subscription struct {
+	variables map[string]Value
+	field     *SelectedField
+	stream    reflect.Value
+}
+
+// subscribe reads the operation's top-level field to obtain its event stream.
+func (srv *Server) subscribe(ctx context.Context, query *ValidatedQuery, op *ast.Operation, variables map[string]Input) (*subscription, []error) {
+	varValues, errs := coerceVariableValues(query.source, srv.schema.types, variables, op.VariableDefinitions)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	scope := &selectionSetScope{
+		source:    query.source,
+		doc:       query.doc,
+		types:     srv.schema.types,
+		variables: varValues,
+	}
+	gt, obj, err := srv.operationFor(op.Type)
+	if err != nil {
+		return nil, []error{&ResponseError{
+			Message: err.Error(),
+			Locations: []Location{
+				astPositionToLocation(op.Start.ToPosition(query.source)),
+			},
+		}}
+	}
+	sel, errs := newSelectionSet(scope, gt, op.SelectionSet)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if len(sel.fields) != 1 {
+		return nil, []error{xerrors.New("subscription must select exactly one top-level field")}
+	}
+	f := sel.fields[0]
+	root, err := obj.root(ctx, sel)
+	if err != nil {
+		return nil, []error{err}
+	}
+	root = valueForAssertions(root)
+	if !root.IsValid() {
+		return nil, []error{xerrors.New("server error: nil subscription object")}
+	}
+	if err := authorizeField(ctx, gt, f); err != nil {
+		return nil, []error{wrapFieldError(f.key, f.loc, err)}
+	}
+	desc := srv.schema.typeDescriptor(typeKey{
+		goType:  root.Type(),
+		gqlType: gt.obj,
+	})
+	stream, err := desc.read(ctx, root, f.toRequest())
+	if re, ok := err.(*resolverError); ok {
+		if rerr, ok := re.err.(*ResponseError); ok {
+			err = rerr
+		} else {
+			// Intentionally making the returned error opaque to avoid interference
+			// in toResponseError.
+			err = xerrors.Errorf("server error: %v", re.err)
+		}
+	}
+	if err == nil {
+		stream = unwrapPointer(stream)
+		if !stream.IsValid() || stream.Kind() != reflect.Chan || stream.Type().ChanDir()&reflect.RecvDir == 0 {
+			err = xerrors.New("server error: subscription field did not return a channel")
+		} else if stream.IsNil() {
+			err = xerrors.New("server error: subscription field returned a nil channel")
+		}
+	}
+	if err != nil {
+		return nil, []error{wrapFieldError(f.key, f.loc, err)}
+	}
+	return &subscription{
+		variables: varValues,
+		field:     f,
+		stream:    stream,
+	}, nil
+}
+
+// executeEvent runs the subscription's selection set on an event received
+// from its stream.
+func (srv *Server) executeEvent(ctx context.Context, query *ValidatedQuery, req Request, sub *subscription, event reflect.Value) (response Response) {
+	extensions := new(ExtensionCollector)
+	ctx = context.WithValue(ctx, extensionCollectorContextKey{}, extensions)
+	if tracer := srv.tracerFor(ctx); tracer != nil {
+		var finish func(*Response)
+		ctx, finish = tracer.TraceExecution(withRootTraceFrame(ctx, tracer), Request{
+			ValidatedQuery: query,
+			DocumentID:     req.DocumentID,
+			OperationName:  req.OperationName,
+			Variables:      req.Variables,
+		})
+		if finish != nil {
+			defer func() { finish(&response) }()
+		}
+	}
+	defer extensions.addTo(&response)
+
+	f := sub.field
+	typ := srv.schema.subscription
+	fieldType := typ.obj.field(f.name).typ
+	ctx = withPathSegment(ctx, PathSegment{Field: f.key})
+	v, errs := srv.schema.valueFromGo(ctx, sub.variables, event, fieldType, f.sub)
+	response.Data = Value{typ: typ}
+	if !v.IsNull() || fieldType.isNullable() || len(errs) == 0 {
+		response.Data.val = []Field{{Key: f.key, Value: v}}
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, toResponseError(wrapFieldError(f.key, f.loc, err)))
+	}
+	return response
+}
@@ -0,0 +1,334 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+)
+
+const subscriptionSchema = `
+type Query {
+  greeting: String!
+}
+
+type Subscription {
+  counter(to: Int!): Int!
+  messages: Message!
+}
+
+type Message {
+  text: String!
+}
+`
+
+type subscriptionQuery struct{}
+
+func (subscriptionQuery) Greeting() string { return "hi" }
+
+type subscriptionRoot struct{}
+
+func (subscriptionRoot) Counter(ctx context.Context, args map[string]Value) (<-chan int32, error) {
+	to, err := json.Number(args["to"].Scalar()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if to <= 0 {
+		return nil, xerrors.New("to must be positive")
+	}
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		for i := int32(1); i <= int32(to); i++ {
+			select {
+			case c <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c, nil
+}
+
+func (subscriptionRoot) Messages(ctx context.Context) <-chan *subscriptionMessage {
+	c := make(chan *subscriptionMessage)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case c <- &subscriptionMessage{Text: "ping"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+type subscriptionMessage struct {
+	Text string
+}
+
+type badSubscriptionRoot struct {
+	Messages *subscriptionMessage
+}
+
+func (badSubscriptionRoot) Counter() <-chan int32 { return nil }
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(subscriptionSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+		Subscription: subscriptionRoot{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		request    Request
+		want       []string
+		wantErrors []*ResponseError
+	}{
+		{
+			name:    "Subscription",
+			request: Request{Query: `subscription { counter(to: 3) }`},
+			want: []string{
+				`{"counter":1}`,
+				`{"counter":2}`,
+				`{"counter":3}`,
+			},
+		},
+		{
+			name: "Variables",
+			request: Request{
+				Query:     `subscription($n: Int!) { n: counter(to: $n) }`,
+				Variables: map[string]Input{"n": ScalarInput("2")},
+			},
+			want: []string{
+				`{"n":1}`,
+				`{"n":2}`,
+			},
+		},
+		{
+			name:    "Query",
+			request: Request{Query: `{ greeting }`},
+			want:    []string{`{"greeting":"hi"}`},
+		},
+		{
+			name:    "MultipleFields",
+			request: Request{Query: `subscription { counter(to: 1) messages { text } }`},
+			wantErrors: []*ResponseError{{
+				Message:   "subscription must select exactly one top-level field",
+				Locations: []Location{{Line: 1, Column: 1}},
+			}},
+		},
+		{
+			name:    "Introspection",
+			request: Request{Query: `subscription { __typename }`},
+			wantErrors: []*ResponseError{{
+				Message:   "subscription cannot select introspection field __typename",
+				Locations: []Location{{Line: 1, Column: 16}},
+			}},
+		},
+		{
+			name:    "ResolverError",
+			request: Request{Query: `subscription { counter(to: 0) }`},
+			wantErrors: []*ResponseError{{
+				Message:   "field counter: server error: to must be positive",
+				Locations: []Location{{Line: 1, Column: 16}},
+				Path:      []PathSegment{{Field: "counter"}},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, errs := server.Subscribe(context.Background(), test.request)
+			if diff := cmp.Diff(test.wantErrors, errs); diff != "" {
+				t.Errorf("errors (-want +got):\n%s", diff)
+			}
+			if len(errs) > 0 {
+				if c != nil {
+					t.Error("channel is not nil")
+				}
+				return
+			}
+			var got []string
+			for resp := range c {
+				if len(resp.Errors) > 0 {
+					t.Errorf("response errors: %v", resp.Errors)
+				}
+				data, err := json.Marshal(resp.Data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, string(data))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("responses (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(subscriptionSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+		Subscription: subscriptionRoot{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c, errs := server.Subscribe(ctx, Request{Query: `subscription { messages { text } }`})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for i := 0; i < 3; i++ {
+		resp := <-c
+		data, err := json.Marshal(resp.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(data), `{"messages":{"text":"ping"}}`; got != want {
+			t.Errorf("event %d = %s; want %s", i, got, want)
+		}
+	}
+	cancel()
+	for range c {
+	}
+}
+
+type nilMessageSubscriptionRoot struct{}
+
+func (nilMessageSubscriptionRoot) Counter() <-chan int32 { return nil }
+
+func (nilMessageSubscriptionRoot) Messages() <-chan *subscriptionMessage {
+	c := make(chan *subscriptionMessage, 1)
+	c <- nil
+	close(c)
+	return c
+}
+
+func TestSubscribeNullPropagation(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(subscriptionSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+		Subscription: nilMessageSubscriptionRoot{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, errs := server.Subscribe(context.Background(), Request{Query: `subscription { messages { text } }`})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	resp, ok := <-c
+	if !ok {
+		t.Fatal("subscription closed before sending an event")
+	}
+	if len(resp.Errors) == 0 {
+		t.Error("no errors for null event")
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "null"; got != want {
+		t.Errorf("data = %s; want %s", got, want)
+	}
+	for range c {
+	}
+}
+
+func TestSubscriptionServer(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(subscriptionSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Execute", func(t *testing.T) {
+		server, err := NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+			Subscription: subscriptionRoot{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := server.Execute(context.Background(), Request{Query: `subscription { counter(to: 1) }`})
+		want := []*ResponseError{{Message: "subscriptions must be run with Server.Subscribe"}}
+		if diff := cmp.Diff(want, response.Errors); diff != "" {
+			t.Errorf("errors (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("NoSubscriptionObject", func(t *testing.T) {
+		server, err := NewServer(schema, subscriptionQuery{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, errs := server.Subscribe(context.Background(), Request{Query: `subscription { counter(to: 1) }`})
+		want := []*ResponseError{{
+			Message:   "unsupported operation type",
+			Locations: []Location{{Line: 1, Column: 1}},
+		}}
+		if diff := cmp.Diff(want, errs); diff != "" {
+			t.Errorf("errors (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("NotChannel", func(t *testing.T) {
+		_, err := NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+			Subscription: badSubscriptionRoot{},
+		})
+		if err == nil {
+			t.Fatal("NewServerWithOptions did not return an error")
+		}
+		const want = "subscription field Subscription.messages must be a receive channel"
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("NewServerWithOptions error = %v; want to contain %q", err, want)
+		}
+	})
+	t.Run("NoSubscriptionType", func(t *testing.T) {
+		schema, err := ParseSchema(`type Query { greeting: String! }`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewServerWithOptions(schema, subscriptionQuery{}, nil, &ServerOptions{
+			Subscription: subscriptionRoot{},
+		})
+		if err == nil {
+			t.Error("NewServerWithOptions did not return an error")
+		}
+	})
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import "context"
+
+// A Tracer is notified of the phases of each operation executed by a server.
+// Each method is called at the start of a phase and returns a context to use
+// for the phase along with a function to call at the end of the phase. The
+// returned function may be nil. Methods must be safe to call from multiple
+// goroutines.
+//
+// TraceExecution is called once per call to Server.Execute and encloses the
+// other phases. TraceParse and TraceValidation are not called if the request
+// has a ValidatedQuery. If the server has trusted documents, then TraceParse
+// is not called and the function returned by TraceValidation receives the
+// trusted document that the request resolved to. TraceField is called for
+// each field that invokes a resolver, but not for __typename or
+// introspection fields. The function returned by TraceExecution may modify
+// the response, for example to add entries to its Extensions.
+type Tracer interface {
+	TraceExecution(ctx context.Context, req Request) (context.Context, func(resp *Response))
+	TraceParse(ctx context.Context, source string) (context.Context, func(errs []*ResponseError))
+	TraceValidation(ctx context.Context) (context.Context, func(query *ValidatedQuery, errs []*ResponseError))
+	TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(result interface{}, err error))
+}
+
+// FieldInfo describes a field about to be resolved. It must not be modified
+// or retained past the end of the field's phase.
+type FieldInfo struct {
+	// Path is the field's path in the response.
+	Path []PathSegment
+	// ParentType is the name of the object type that the field belongs to.
+	ParentType string
+	// ReturnType is the field's type as written in the schema, like "[String!]!".
+	ReturnType string
+	// Request holds the parameters passed to the resolver.
+	Request FieldRequest
+
+	// types and typ are used to estimate the field's cost.
+	types map[string]*gqlType
+	typ   *gqlType
+}
+
+// traceFrame is stored in the context of operations executed by a server with
+// a tracer. Each traced field adds a frame so that resolvers below it can
+// reconstruct their path.
+type traceFrame struct {
+	tracer Tracer
+	parent *traceFrame
+	seg    PathSegment
+}
+
+type traceFrameContextKey struct{}
+
+func withRootTraceFrame(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, traceFrameContextKey{}, &traceFrame{tracer: tracer})
+}
+
+type contextTracerKey struct{}
+
+// WithTracer returns a new context that causes Server.Execute and
+// Server.Subscribe to notify tracer of operations run with the context, after
+// the server's own tracer. It is useful for observing individual operations,
+// like in tests.
+func WithTracer(ctx context.Context, tracer Tracer) context.Context {
+	return context.WithValue(ctx, contextTracerKey{}, tracer)
+}
+
+// tracerFor returns the tracer to notify for an operation run with ctx or nil
+// if there is none.
+func (srv *Server) tracerFor(ctx context.Context) Tracer {
+	ct, _ := ctx.Value(contextTracerKey{}).(Tracer)
+	switch {
+	case ct == nil:
+		return srv.tracer
+	case srv.tracer == nil:
+		return ct
+	default:
+		return MultiTracer(srv.tracer, ct)
+	}
+}
+
+func traceFrameFromContext(ctx context.Context) *traceFrame {
+	frame, _ := ctx.Value(traceFrameContextKey{}).(*traceFrame)
+	return frame
+}
+
+// withPathSegment returns a new context whose trace frame is a child of the
+// context's trace frame. It returns ctx unmodified if ctx has no trace frame.
+func withPathSegment(ctx context.Context, seg PathSegment) context.Context {
+	parent := traceFrameFromContext(ctx)
+	if parent == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, traceFrameContextKey{}, &traceFrame{
+		tracer: parent.tracer,
+		parent: parent,
+		seg:    seg,
+	})
+}
+
+// path returns the path segments from the root frame to frame.
+func (frame *traceFrame) path() []PathSegment {
+	n := 0
+	for f := frame; f.parent != nil; f = f.parent {
+		n++
+	}
+	path := make([]PathSegment, n)
+	for f := frame; f.parent != nil; f = f.parent {
+		n--
+		path[n] = f.seg
+	}
+	return path
+}
+
+// MultiTracer returns a tracer that notifies each of the given tracers in
+// order. The functions returned by the tracers are called in reverse order.
+func MultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(append([]Tracer(nil), tracers...))
+}
+
+type multiTracer []Tracer
+
+func (mt multiTracer) TraceExecution(ctx context.Context, req Request) (context.Context, func(*Response)) {
+	finishers := make([]func(*Response), 0, len(mt))
+	for _, t := range mt {
+		var f func(*Response)
+		ctx, f = t.TraceExecution(ctx, req)
+		if f != nil {
+			finishers = append(finishers, f)
+		}
+	}
+	return ctx, func(resp *Response) {
+		for i := len(finishers) - 1; i >= 0; i-- {
+			finishers[i](resp)
+		}
+	}
+}
+
+func (mt multiTracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*ResponseError)) {
+	finishers := make([]func([]*ResponseError), 0, len(mt))
+	for _, t := range mt {
+		var f func([]*ResponseError)
+		ctx, f = t.TraceParse(ctx, source)
+		if f != nil {
+			finishers = append(finishers, f)
+		}
+	}
+	return ctx, func(errs []*ResponseError) {
+		for i := len(finishers) - 1; i >= 0; i-- {
+			finishers[i](errs)
+		}
+	}
+}
+
+func (mt multiTracer) TraceValidation(ctx context.Context) (context.Context, func(*ValidatedQuery, []*ResponseError)) {
+	finishers := make([]func(*ValidatedQuery, []*ResponseError), 0, len(mt))
+	for _, t := range mt {
+		var f func(*ValidatedQuery, []*ResponseError)
+		ctx, f = t.TraceValidation(ctx)
+		if f != nil {
+			finishers = append(finishers, f)
+		}
+	}
+	return ctx, func(query *ValidatedQuery, errs []*ResponseError) {
+		for i := len(finishers) - 1; i >= 0; i-- {
+			finishers[i](query, errs)
+		}
+	}
+}
+
+func (mt multiTracer) TraceField(ctx context.Context, info *FieldInfo) (context.Context, func(interface{}, error)) {
+	finishers := make([]func(interface{}, error), 0, len(mt))
+	for _, t := range mt {
+		var f func(interface{}, error)
+		ctx, f = t.TraceField(ctx, info)
+		if f != nil {
+			finishers = append(finishers, f)
+		}
+	}
+	return ctx, func(result interface{}, err error) {
+		for i := len(finishers) - 1; i >= 0; i-- {
+			finishers[i](result, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// TrustedDocumentOptions configures a server to only execute documents that
+// were registered ahead of time, typically when building first-party clients.
+type TrustedDocumentOptions struct {
+	// Documents maps document IDs to GraphQL document text. Every document is
+	// validated when the server is created. Requests refer to a document by
+	// setting Request.DocumentID.
+	Documents map[string]string
+
+	// If AllowText is true, then requests may also send the exact text of a
+	// trusted document in Request.Query instead of its ID.
+	AllowText bool
+}
+
+// ReadTrustedDocumentManifest reads a JSON object that maps document IDs to
+// document text, like:
+//
+//	{
+//	  "5f3a1c": "query Viewer { viewer { name } }",
+//	  "9b2e7d": "mutation Logout { logout }"
+//	}
+func ReadTrustedDocumentManifest(r io.Reader) (map[string]string, error) {
+	var manifest map[string]string
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, xerrors.Errorf("read trusted document manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// trustedDocuments is the validated form of TrustedDocumentOptions.
+type trustedDocuments struct {
+	byID      map[string]*ValidatedQuery
+	byText    map[string]*ValidatedQuery
+	allowText bool
+}
+
+func newTrustedDocuments(schema *Schema, opts *TrustedDocumentOptions) (*trustedDocuments, error) {
+	td := &trustedDocuments{
+		byID:      make(map[string]*ValidatedQuery, len(opts.Documents)),
+		allowText: opts.AllowText,
+	}
+	if opts.AllowText {
+		td.byText = make(map[string]*ValidatedQuery, len(opts.Documents))
+	}
+	ids := make([]string, 0, len(opts.Documents))
+	for id := range opts.Documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var invalid []string
+	for _, id := range ids {
+		text := opts.Documents[id]
+		query, errs := schema.Validate(text)
+		if len(errs) > 0 {
+			msgs := make([]string, 0, len(errs))
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			invalid = append(invalid, fmt.Sprintf("document %q: %s", id, strings.Join(msgs, "; ")))
+			continue
+		}
+		td.byID[id] = query
+		if td.byText != nil {
+			td.byText[text] = query
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, xerrors.Errorf("trusted documents:\n%s", strings.Join(invalid, "\n"))
+	}
+	return td, nil
+}
+
+// lookup returns the trusted document for a request or an error if the
+// request does not name a trusted document.
+func (td *trustedDocuments) lookup(req Request) (*ValidatedQuery, *ResponseError) {
+	if req.DocumentID != "" {
+		query := td.byID[req.DocumentID]
+		if query == nil {
+			return nil, &ResponseError{Message: fmt.Sprintf("unknown document ID %q", req.DocumentID)}
+		}
+		if req.Query != "" && req.Query != query.Source() {
+			return nil, &ResponseError{Message: fmt.Sprintf("query does not match document %q", req.DocumentID)}
+		}
+		return query, nil
+	}
+	if !td.allowText {
+		return nil, &ResponseError{Message: "server only accepts trusted documents by ID"}
+	}
+	source := req.Query
+	if req.ValidatedQuery != nil {
+		source = req.ValidatedQuery.Source()
+	}
+	query := td.byText[source]
+	if query == nil {
+		return nil, &ResponseError{Message: "document is not trusted"}
+	}
+	return query, nil
+}
+
+// TrustedDocument returns the trusted document with the given ID or nil if the
+// server does not have such a document.
+func (srv *Server) TrustedDocument(id string) *ValidatedQuery {
+	if srv.trusted == nil {
+		return nil
+	}
+	return srv.trusted.byID[id]
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrustedDocuments(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(`type Query { greeting: String! }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := ReadTrustedDocumentManifest(strings.NewReader(`{
+		"abc": "query Greeting { greeting }",
+		"def": "{ __typename }"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		allowText bool
+		req       Request
+		want      string
+		wantError string
+	}{
+		{
+			name: "ByID",
+			req:  Request{DocumentID: "abc"},
+			want: `{"greeting":"hi"}`,
+		},
+		{
+			name: "ByIDWithMatchingText",
+			req:  Request{DocumentID: "abc", Query: "query Greeting { greeting }"},
+			want: `{"greeting":"hi"}`,
+		},
+		{
+			name:      "ByIDWithDifferentText",
+			req:       Request{DocumentID: "abc", Query: "{ greeting }"},
+			wantError: `query does not match document "abc"`,
+		},
+		{
+			name:      "UnknownID",
+			req:       Request{DocumentID: "xyz"},
+			wantError: `unknown document ID "xyz"`,
+		},
+		{
+			name:      "TextNotAllowed",
+			req:       Request{Query: "query Greeting { greeting }"},
+			wantError: "server only accepts trusted documents by ID",
+		},
+		{
+			name:      "TrustedText",
+			allowText: true,
+			req:       Request{Query: "query Greeting { greeting }"},
+			want:      `{"greeting":"hi"}`,
+		},
+		{
+			name:      "UntrustedText",
+			allowText: true,
+			req:       Request{Query: "{ greeting }"},
+			wantError: "document is not trusted",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, err := NewServerWithOptions(schema, greetingQuery{}, nil, &ServerOptions{
+				TrustedDocuments: &TrustedDocumentOptions{
+					Documents: manifest,
+					AllowText: test.allowText,
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Execute(context.Background(), test.req)
+			if test.wantError != "" {
+				want := []*ResponseError{{Message: test.wantError}}
+				if diff := cmp.Diff(want, response.Errors); diff != "" {
+					t.Errorf("errors (-want +got):\n%s", diff)
+				}
+				return
+			}
+			for _, err := range response.Errors {
+				t.Errorf("Response error: %v", err)
+			}
+			got, err := json.Marshal(response.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("data = %s; want %s", got, test.want)
+			}
+		})
+	}
+
+	t.Run("InvalidDocument", func(t *testing.T) {
+		_, err := NewServerWithOptions(schema, greetingQuery{}, nil, &ServerOptions{
+			TrustedDocuments: &TrustedDocumentOptions{
+				Documents: map[string]string{
+					"abc": "{ greeting }",
+					"bad": "{ farewell }",
+				},
+			},
+		})
+		if err == nil {
+			t.Fatal("NewServerWithOptions did not return an error")
+		}
+		if !strings.Contains(err.Error(), `"bad"`) {
+			t.Errorf("error = %q; want to mention document \"bad\"", err)
+		}
+	})
+
+	t.Run("OperationLog", func(t *testing.T) {
+		logger := new(testOperationLogger)
+		server, err := NewServerWithOptions(schema, greetingQuery{}, nil, &ServerOptions{
+			TrustedDocuments: &TrustedDocumentOptions{Documents: manifest},
+			OperationLog:     &OperationLogOptions{Logger: logger},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := server.Execute(context.Background(), Request{DocumentID: "abc"})
+		for _, err := range response.Errors {
+			t.Errorf("Response error: %v", err)
+		}
+		if len(logger.records) != 1 {
+			t.Fatalf("logged %d records; want 1", len(logger.records))
+		}
+		rec := logger.records[0]
+		hash := sha256.Sum256([]byte("query Greeting { greeting }"))
+		if want := hex.EncodeToString(hash[:]); rec.DocumentHash != want {
+			t.Errorf("DocumentHash = %q; want %q (hash of the trusted document)", rec.DocumentHash, want)
+		}
+		if rec.OperationName != "Greeting" || rec.OperationType != QueryOperation {
+			t.Errorf("operation = %v %q; want %v \"Greeting\"", rec.OperationType, rec.OperationName, QueryOperation)
+		}
+	})
+
+	t.Run("NotEnabled", func(t *testing.T) {
+		server, err := NewServer(schema, greetingQuery{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := server.Execute(context.Background(), Request{DocumentID: "abc"})
+		want := []*ResponseError{{Message: "server does not accept document IDs"}}
+		if diff := cmp.Diff(want, response.Errors); diff != "" {
+			t.Errorf("errors (-want +got):\n%s", diff)
+		}
+		if q := server.TrustedDocument("abc"); q != nil {
+			t.Errorf("server.TrustedDocument(\"abc\") = %v; want nil", q)
+		}
+	})
+}
@@ -26,6 +26,9 @@ import (
 // different schemas are never equal.
 type gqlType struct {
 	description string
+	directives  []*Directive
+	// cacheHint is parsed from the @cacheControl directive, if present.
+	cacheHint *cacheHint
 
 	scalar   string
 	enum     *enumType
@@ -35,6 +38,9 @@ type gqlType struct {
 	input    *inputObjectType
 	nonNull  bool
 
+	// structured is true for scalars that accept input objects and lists.
+	structured bool
+
 	// nullVariant is the same type with the nonNull flag flipped.
 	// This is to ensure that either version of the type has a consistent address.
 	nullVariant *gqlType
@@ -49,17 +55,22 @@ type enumType struct {
 }
 
 func (enum *enumType) has(sym string) bool {
-	for _, v := range enum.values {
-		if v.name == sym {
-			return true
+	return enum.value(sym) != nil
+}
+
+func (enum *enumType) value(sym string) *enumValue {
+	for i := range enum.values {
+		if enum.values[i].name == sym {
+			return &enum.values[i]
 		}
 	}
-	return false
+	return nil
 }
 
 type enumValue struct {
 	name        string
 	description string
+	directives  []*Directive
 
 	deprecated        bool
 	deprecationReason NullString
@@ -472,6 +483,9 @@ type objectTypeField struct {
 	description string
 	typ         *gqlType
 	args        inputValueDefinitionList
+	directives  []*Directive
+	// cacheHint is parsed from the @cacheControl directive, if present.
+	cacheHint *cacheHint
 
 	deprecated        bool
 	deprecationReason NullString
@@ -505,12 +519,16 @@ func (f objectTypeField) DeprecationReason() NullString {
 type inputValueDefinition struct {
 	name        string
 	description string
+	directives  []*Directive
 
 	// defaultValue.typ will always be set. Most of the time, defaultValue
 	// is valid value of the type. However, if the type is non-nullable and
 	// does not have a default, the value will be typed null: this indicates a
 	// required argument or input field.
 	defaultValue Value
+
+	// constraint is parsed from the @constraint directive, if present.
+	constraint *inputConstraint
 }
 
 func (ivd inputValueDefinition) Name() string {
@@ -0,0 +1,290 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import "fmt"
+
+// Type is a read-only view of a type in a schema. Types can be compared for
+// equality using ==. Types with the same name from different schemas are never
+// equal.
+type Type gqlType
+
+// Type returns the named type with the given name or nil if the schema does
+// not have such a type. Built-in scalars and introspection types are included.
+func (schema *Schema) Type(name string) *Type {
+	return (*Type)(schema.types[name])
+}
+
+// Types returns the named types defined in the schema source in the order
+// they were defined. Built-in types are not included.
+func (schema *Schema) Types() []*Type {
+	types := make([]*Type, 0, len(schema.typeOrder))
+	for _, name := range schema.typeOrder {
+		types = append(types, (*Type)(schema.types[name]))
+	}
+	return types
+}
+
+// QueryType returns the schema's query type.
+func (schema *Schema) QueryType() *Type {
+	return (*Type)(schema.query)
+}
+
+// MutationType returns the schema's mutation type or nil if the schema does not
+// permit mutations.
+func (schema *Schema) MutationType() *Type {
+	return (*Type)(schema.mutation)
+}
+
+// SubscriptionType returns the schema's subscription type or nil if the schema
+// does not permit subscriptions.
+func (schema *Schema) SubscriptionType() *Type {
+	return (*Type)(schema.subscription)
+}
+
+func (t *Type) gqlType() *gqlType {
+	return (*gqlType)(t)
+}
+
+// String returns the type reference string, like "[Int!]".
+func (t *Type) String() string {
+	return t.gqlType().String()
+}
+
+// Kind returns the kind of type.
+func (t *Type) Kind() TypeKind {
+	typ := t.gqlType()
+	switch {
+	case !typ.isNullable():
+		return NonNullKind
+	case typ.isScalar():
+		return ScalarKind
+	case typ.isObject():
+		return ObjectKind
+	case typ.isUnion():
+		return UnionKind
+	case typ.isEnum():
+		return EnumKind
+	case typ.isInputObject():
+		return InputObjectKind
+	case typ.isList():
+		return ListKind
+	default:
+		panic("invalid type")
+	}
+}
+
+// Name returns the name of a named type or the empty string for list and
+// non-null types.
+func (t *Type) Name() string {
+	return t.gqlType().Name().S
+}
+
+// Description returns the type's documentation.
+func (t *Type) Description() string {
+	return t.gqlType().description
+}
+
+// OfType returns the wrapped type of a list or non-null type or nil for named
+// types.
+func (t *Type) OfType() *Type {
+	return (*Type)(t.gqlType().OfType())
+}
+
+// Directives returns the directives applied to the type's definition.
+func (t *Type) Directives() []*Directive {
+	return append([]*Directive(nil), t.gqlType().directives...)
+}
+
+// Fields returns the fields of an object type in the order they were defined.
+// It returns nil for other kinds of types.
+func (t *Type) Fields() []*FieldDefinition {
+	typ := t.gqlType()
+	if t.Kind() != ObjectKind {
+		return nil
+	}
+	fields := make([]*FieldDefinition, 0, len(typ.obj.fields))
+	for i := range typ.obj.fields {
+		fields = append(fields, newFieldDefinition(&typ.obj.fields[i]))
+	}
+	return fields
+}
+
+// Field returns the object type's field with the given name or nil if the
+// type does not have such a field.
+func (t *Type) Field(name string) *FieldDefinition {
+	typ := t.gqlType()
+	if t.Kind() != ObjectKind {
+		return nil
+	}
+	f := typ.obj.field(name)
+	if f == nil {
+		return nil
+	}
+	return newFieldDefinition(f)
+}
+
+// PossibleTypes returns the object types that a union type can represent.
+// It returns nil for other kinds of types.
+func (t *Type) PossibleTypes() []*Type {
+	typ := t.gqlType()
+	if t.Kind() != UnionKind {
+		return nil
+	}
+	types := make([]*Type, 0, len(typ.union.possibleTypes))
+	for _, pt := range typ.union.possibleTypes {
+		types = append(types, (*Type)(pt))
+	}
+	return types
+}
+
+// EnumValues returns the values of an enum type in the order they were
+// defined. It returns nil for other kinds of types.
+func (t *Type) EnumValues() []*EnumValueDefinition {
+	typ := t.gqlType()
+	if t.Kind() != EnumKind {
+		return nil
+	}
+	values := make([]*EnumValueDefinition, 0, len(typ.enum.values))
+	for _, v := range typ.enum.values {
+		values = append(values, &EnumValueDefinition{
+			Name:        v.name,
+			Description: v.description,
+			Directives:  append([]*Directive(nil), v.directives...),
+		})
+	}
+	return values
+}
+
+// InputFields returns the fields of an input object type in the order they
+// were defined. It returns nil for other kinds of types.
+func (t *Type) InputFields() []*InputValueDefinition {
+	typ := t.gqlType()
+	if t.Kind() != InputObjectKind {
+		return nil
+	}
+	return newInputValueDefinitions(typ.input.fields)
+}
+
+// TypeKind is an enumeration of the kinds of types.
+type TypeKind int
+
+// Kinds of types. Interfaces are not supported.
+const (
+	ScalarKind TypeKind = 1 + iota
+	ObjectKind
+	UnionKind
+	EnumKind
+	InputObjectKind
+	ListKind
+	NonNullKind
+)
+
+// String returns the name of the kind as used in introspection, like "OBJECT".
+func (kind TypeKind) String() string {
+	switch kind {
+	case ScalarKind:
+		return "SCALAR"
+	case ObjectKind:
+		return "OBJECT"
+	case UnionKind:
+		return "UNION"
+	case EnumKind:
+		return "ENUM"
+	case InputObjectKind:
+		return "INPUT_OBJECT"
+	case ListKind:
+		return "LIST"
+	case NonNullKind:
+		return "NON_NULL"
+	default:
+		return fmt.Sprintf("TypeKind(%d)", int(kind))
+	}
+}
+
+// FieldDefinition describes a field of an object type.
+type FieldDefinition struct {
+	Name        string
+	Description string
+	Type        *Type
+	Args        []*InputValueDefinition
+	Directives  []*Directive
+}
+
+func newFieldDefinition(f *objectTypeField) *FieldDefinition {
+	return &FieldDefinition{
+		Name:        f.name,
+		Description: f.description,
+		Type:        (*Type)(f.typ),
+		Args:        newInputValueDefinitions(f.args),
+		Directives:  append([]*Directive(nil), f.directives...),
+	}
+}
+
+// InputValueDefinition describes an argument or a field of an input object
+// type.
+type InputValueDefinition struct {
+	Name        string
+	Description string
+	Type        *Type
+	// DefaultValue is null if the input value does not have a default.
+	DefaultValue Value
+	Directives   []*Directive
+}
+
+func newInputValueDefinitions(list inputValueDefinitionList) []*InputValueDefinition {
+	if len(list) == 0 {
+		return nil
+	}
+	defns := make([]*InputValueDefinition, 0, len(list))
+	for _, ivd := range list {
+		defns = append(defns, &InputValueDefinition{
+			Name:         ivd.name,
+			Description:  ivd.description,
+			Type:         (*Type)(ivd.defaultValue.typ),
+			DefaultValue: ivd.defaultValue,
+			Directives:   append([]*Directive(nil), ivd.directives...),
+		})
+	}
+	return defns
+}
+
+// EnumValueDefinition describes a value of an enum type.
+type EnumValueDefinition struct {
+	Name        string
+	Description string
+	Directives  []*Directive
+}
+
+// Directive is a directive applied to a type system definition. Custom
+// directives must be declared in the schema source with a directive
+// definition, like:
+//
+//	directive @example(value: String!) on FIELD_DEFINITION
+//
+// Callers must not modify the arguments of a directive returned by a Type.
+type Directive struct {
+	Name string
+	// Args is the set of coerced arguments, including any defaults.
+	Args map[string]Value
+}
+
+// FindDirective returns the first directive in the list with the given name or
+// nil if not found.
+func FindDirective(directives []*Directive, name string) *Directive {
+	return findDirective(directives, name)
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"io"
+	"reflect"
+)
+
+// UploadScalar is the name of the custom scalar type that accepts file
+// uploads. A schema must declare it (as "scalar Upload") to use uploads.
+const UploadScalar = "Upload"
+
+// Upload is a file sent alongside an operation, as described by the GraphQL
+// multipart request specification at
+// https://github.com/jaydenseric/graphql-multipart-request-spec.
+// Uploads can only be passed to arguments of the Upload scalar type through
+// variables. Field resolvers can obtain the upload by converting the argument
+// value into an Upload or *Upload with Value.Convert or ConvertValueMap.
+type Upload struct {
+	// Filename is the name of the file given by the client.
+	Filename string
+	// ContentType is the MIME type of the file given by the client.
+	ContentType string
+	// Size is the length of the file in bytes.
+	Size int64
+	// Content reads the file's bytes. It may only be read once.
+	Content io.Reader
+}
+
+// UploadInput returns a new input for the given file.
+func UploadInput(u *Upload) Input {
+	return Input{val: u}
+}
+
+var uploadGoType = reflect.TypeOf(Upload{})
+
+// isUploadType reports whether typ is the Upload scalar.
+func isUploadType(typ *gqlType) bool {
+	return typ.isScalar() && !isBuiltinScalar(typ) && typ.toNullable().scalar == UploadScalar
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const uploadSchema = `
+scalar Upload
+
+type Query {
+  ok: Boolean!
+}
+
+type Mutation {
+  upload(file: Upload!): String!
+  uploadMany(files: [Upload!]!): String!
+  attach(input: AttachInput!): String!
+  echo(s: String!): String!
+}
+
+input AttachInput {
+  name: String!
+  file: Upload!
+}
+`
+
+type uploadQuery struct{}
+
+func (uploadQuery) Ok() bool { return true }
+
+type uploadMutation struct{}
+
+func (uploadMutation) Upload(args map[string]Value) (string, error) {
+	var file Upload
+	if err := args["file"].Convert(&file); err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(file.Content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%s, %d bytes): %s", file.Filename, file.ContentType, file.Size, data), nil
+}
+
+func (uploadMutation) UploadMany(args map[string]Value) (string, error) {
+	var files []*Upload
+	if err := args["files"].Convert(&files); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Filename)
+	}
+	return strings.Join(names, ","), nil
+}
+
+func (uploadMutation) Attach(args map[string]Value) (string, error) {
+	var parsed struct {
+		Input struct {
+			Name string
+			File *Upload
+		}
+	}
+	if err := ConvertValueMap(&parsed, args); err != nil {
+		return "", err
+	}
+	return parsed.Input.Name + "=" + parsed.Input.File.Filename, nil
+}
+
+func (uploadMutation) Echo(args map[string]Value) string {
+	return args["s"].Scalar()
+}
+
+func TestUpload(t *testing.T) {
+	t.Parallel()
+
+	schema, err := ParseSchema(uploadSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newUpload := func(name, content string) *Upload {
+		return &Upload{
+			Filename:    name,
+			ContentType: "text/plain",
+			Size:        int64(len(content)),
+			Content:     strings.NewReader(content),
+		}
+	}
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]Input
+		want      string
+	}{
+		{
+			name:  "Single",
+			query: `mutation($file: Upload!) { upload(file: $file) }`,
+			variables: map[string]Input{
+				"file": UploadInput(newUpload("a.txt", "Hello")),
+			},
+			want: `{"data":{"upload":"a.txt (text/plain, 5 bytes): Hello"}}`,
+		},
+		{
+			name:  "List",
+			query: `mutation($files: [Upload!]!) { uploadMany(files: $files) }`,
+			variables: map[string]Input{
+				"files": ListInput([]Input{
+					UploadInput(newUpload("a.txt", "A")),
+					UploadInput(newUpload("b.txt", "B")),
+				}),
+			},
+			want: `{"data":{"uploadMany":"a.txt,b.txt"}}`,
+		},
+		{
+			name:  "InputObject",
+			query: `mutation($in: AttachInput!) { attach(input: $in) }`,
+			variables: map[string]Input{
+				"in": InputObject(map[string]Input{
+					"name": ScalarInput("avatar"),
+					"file": UploadInput(newUpload("me.png", "")),
+				}),
+			},
+			want: `{"data":{"attach":"avatar=me.png"}}`,
+		},
+		{
+			name:  "NotUploadType",
+			query: `mutation($s: String!) { echo(s: $s) }`,
+			variables: map[string]Input{
+				"s": UploadInput(newUpload("a.txt", "Hello")),
+			},
+			want: `{"errors":[{"message":"variable $s: file upload not permitted for String!"}]}`,
+		},
+		{
+			name:  "MissingFile",
+			query: `mutation($file: Upload!) { upload(file: $file) }`,
+			variables: map[string]Input{
+				"file": ScalarInput("a.txt"),
+			},
+			want: `{"errors":[{"message":"variable $file: Upload! requires a file upload"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, err := NewServer(schema, uploadQuery{}, uploadMutation{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			response := server.Execute(context.Background(), Request{
+				Query:     test.query,
+				Variables: test.variables,
+			})
+			got, err := json.Marshal(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUploadInputJSON(t *testing.T) {
+	t.Parallel()
+
+	in := InputObject(map[string]Input{
+		"file": UploadInput(&Upload{Filename: "a.txt"}),
+	})
+	got, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"file":null}`; string(got) != want {
+		t.Errorf("json.Marshal(...) = %s; want %s", got, want)
+	}
+}
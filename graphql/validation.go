@@ -19,14 +19,15 @@ package graphql
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // validateRequest validates a parsed GraphQL request according to the procedure
 // defined in https://graphql.github.io/graphql-spec/June2018/#sec-Validation.
-func (schema *Schema) validateRequest(source string, doc *gqlang.Document) []error {
+func (schema *Schema) validateRequest(source string, doc *ast.Document) []error {
 	fragments, errs := schema.validateStructure(source, doc)
 	if len(errs) > 0 {
 		return errs
@@ -58,11 +59,11 @@ func (schema *Schema) validateRequest(source string, doc *gqlang.Document) []err
 }
 
 // validateStructure validates the well-formedness of the top-level definitions.
-func (schema *Schema) validateStructure(source string, doc *gqlang.Document) (map[string]*fragmentValidationState, []error) {
+func (schema *Schema) validateStructure(source string, doc *ast.Document) (map[string]*fragmentValidationState, []error) {
 	var errs []error
-	var anonPosList []gqlang.Pos
-	operationsByName := make(map[string][]gqlang.Pos)
-	fragmentsByName := make(map[string][]gqlang.Pos)
+	var anonPosList []ast.Pos
+	operationsByName := make(map[string][]ast.Pos)
+	fragmentsByName := make(map[string][]ast.Pos)
 	fragments := make(map[string]*fragmentValidationState)
 	for _, defn := range doc.Definitions {
 		switch {
@@ -141,7 +142,7 @@ func (schema *Schema) validateStructure(source string, doc *gqlang.Document) (ma
 	return fragments, errs
 }
 
-func detectFragmentCycles(v *validationScope, depth int, visited map[string]struct{}, set *gqlang.SelectionSet) error {
+func detectFragmentCycles(v *validationScope, depth int, visited map[string]struct{}, set *ast.SelectionSet) error {
 	if set == nil {
 		return nil
 	}
@@ -182,7 +183,7 @@ func detectFragmentCycles(v *validationScope, depth int, visited map[string]stru
 	return nil
 }
 
-func (schema *Schema) validateOperation(source string, fragments map[string]*fragmentValidationState, op *gqlang.Operation) []error {
+func (schema *Schema) validateOperation(source string, fragments map[string]*fragmentValidationState, op *ast.Operation) []error {
 	opType := schema.operationType(op.Type)
 	if opType == nil {
 		return []error{&ResponseError{
@@ -208,7 +209,26 @@ func (schema *Schema) validateOperation(source string, fragments map[string]*fra
 		fragments: fragments,
 	}
 	var errs []error
-	selErrs := validateSelectionSet(v, op.Type == gqlang.Query, opType, op.SelectionSet)
+	if op.Type == ast.Subscription {
+		// https://graphql.github.io/graphql-spec/June2018/#sec-Single-root-field
+		fields := rootFields(v, op.SelectionSet)
+		if len(fields) != 1 {
+			errs = append(errs, &ResponseError{
+				Message: "subscription must select exactly one top-level field",
+				Locations: []Location{
+					astPositionToLocation(op.Start.ToPosition(source)),
+				},
+			})
+		} else if name := fields[0].Name.Value; strings.HasPrefix(name, "__") {
+			errs = append(errs, &ResponseError{
+				Message: fmt.Sprintf("subscription cannot select introspection field %s", name),
+				Locations: []Location{
+					astPositionToLocation(fields[0].Name.Start.ToPosition(source)),
+				},
+			})
+		}
+	}
+	selErrs := validateSelectionSet(v, op.Type == ast.Query, opType, op.SelectionSet)
 	if op.Name != nil {
 		for _, err := range selErrs {
 			errs = append(errs, xerrors.Errorf("operation %s: %w", op.Name, err))
@@ -227,6 +247,43 @@ func (schema *Schema) validateOperation(source string, fragments map[string]*fra
 	return errs
 }
 
+// rootFields returns the first field selected for each distinct response key
+// in a selection set, including those selected in fragments.
+func rootFields(v *validationScope, set *ast.SelectionSet) []*ast.Field {
+	var fields []*ast.Field
+	keys := make(map[string]struct{})
+	visited := make(map[string]struct{})
+	var visit func(set *ast.SelectionSet)
+	visit = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, sel := range set.Sel {
+			switch {
+			case sel.Field != nil:
+				key := sel.Field.Key().Value
+				if _, seen := keys[key]; !seen {
+					keys[key] = struct{}{}
+					fields = append(fields, sel.Field)
+				}
+			case sel.InlineFragment != nil:
+				visit(sel.InlineFragment.SelectionSet)
+			case sel.FragmentSpread != nil:
+				name := sel.FragmentSpread.Name.Value
+				if _, seen := visited[name]; seen {
+					continue
+				}
+				visited[name] = struct{}{}
+				if frag := v.fragments[name]; frag != nil {
+					visit(frag.SelectionSet)
+				}
+			}
+		}
+	}
+	visit(set)
+	return fields
+}
+
 // validationScope defines the symbols and position information used
 // during validation.
 type validationScope struct {
@@ -248,16 +305,16 @@ func (vv *validatedVariable) typ() *gqlType {
 
 // fragmentValidationState records whether a fragment is used.
 type fragmentValidationState struct {
-	*gqlang.FragmentDefinition
+	*ast.FragmentDefinition
 	used bool
 }
 
 // validateVariables validates an operation's variables and resolves their types.
-func validateVariables(source string, typeMap map[string]*gqlType, defns *gqlang.VariableDefinitions) (map[string]*validatedVariable, []error) {
+func validateVariables(source string, typeMap map[string]*gqlType, defns *ast.VariableDefinitions) (map[string]*validatedVariable, []error) {
 	if defns == nil {
 		return nil, nil
 	}
-	variablesByName := make(map[string][]gqlang.Pos)
+	variablesByName := make(map[string][]ast.Pos)
 	result := make(map[string]*validatedVariable)
 	var errs []error
 	for _, defn := range defns.Defs {
@@ -323,7 +380,7 @@ func validateVariables(source string, typeMap map[string]*gqlType, defns *gqlang
 
 // validateVariableUsage verifies that all variables defined in the operation are used.
 // See https://graphql.github.io/graphql-spec/June2018/#sec-All-Variables-Used
-func validateVariableUsage(v *validationScope, defns *gqlang.VariableDefinitions) []error {
+func validateVariableUsage(v *validationScope, defns *ast.VariableDefinitions) []error {
 	if defns == nil {
 		return nil
 	}
@@ -344,7 +401,7 @@ func validateVariableUsage(v *validationScope, defns *gqlang.VariableDefinitions
 
 // validateFragmentUsage verifies that all fragments defined in the document are used.
 // See https://graphql.github.io/graphql-spec/draft/#sec-Fragments-Must-Be-Used
-func validateFragmentUsage(v *validationScope, defns []*gqlang.Definition) []error {
+func validateFragmentUsage(v *validationScope, defns []*ast.Definition) []error {
 	var errs []error
 	for _, defn := range defns {
 		if defn.Fragment == nil {
@@ -363,7 +420,7 @@ func validateFragmentUsage(v *validationScope, defns []*gqlang.Definition) []err
 	return errs
 }
 
-func validateSelectionSet(v *validationScope, isRootQuery bool, typ *gqlType, set *gqlang.SelectionSet) []error {
+func validateSelectionSet(v *validationScope, isRootQuery bool, typ *gqlType, set *ast.SelectionSet) []error {
 	var errs []error
 	for _, selection := range set.Sel {
 		switch {
@@ -418,7 +475,7 @@ func validateSelectionSet(v *validationScope, isRootQuery bool, typ *gqlType, se
 	return errs
 }
 
-func validateFragmentTypeCondition(v *validationScope, parent *gqlType, parentPos gqlang.Pos, cond *gqlang.TypeCondition) (*gqlType, error) {
+func validateFragmentTypeCondition(v *validationScope, parent *gqlType, parentPos ast.Pos, cond *ast.TypeCondition) (*gqlType, error) {
 	if cond == nil {
 		return parent, nil
 	}
@@ -459,7 +516,7 @@ func validateFragmentTypeCondition(v *validationScope, parent *gqlType, parentPo
 	return typ, nil
 }
 
-func validateField(v *validationScope, isRootQuery bool, typ *gqlType, field *gqlang.Field) []error {
+func validateField(v *validationScope, isRootQuery bool, typ *gqlType, field *ast.Field) []error {
 	var fieldInfo *objectTypeField
 	switch field.Name.Value {
 	case typeNameFieldName:
@@ -641,11 +698,11 @@ func sameResponseShape(v *validationScope, fieldA, fieldB groupedField) bool {
 type fieldGroups [][]groupedField
 
 type groupedField struct {
-	*gqlang.Field
+	*ast.Field
 	parentType *gqlType
 }
 
-func (groups *fieldGroups) addSet(v *validationScope, typ *gqlType, set *gqlang.SelectionSet) {
+func (groups *fieldGroups) addSet(v *validationScope, typ *gqlType, set *ast.SelectionSet) {
 	if set == nil {
 		return
 	}
@@ -687,7 +744,7 @@ func (groups *fieldGroups) addSet(v *validationScope, typ *gqlType, set *gqlang.
 	}
 }
 
-func (groups *fieldGroups) add(parentType *gqlType, field *gqlang.Field) {
+func (groups *fieldGroups) add(parentType *gqlType, field *ast.Field) {
 	k := field.Key().Value
 	gf := groupedField{field, parentType}
 	for i, group := range *groups {
@@ -704,9 +761,9 @@ func (gf groupedField) typ() *gqlType {
 	return gf.parentType.obj.field(gf.Name.Value).typ
 }
 
-func validateArguments(v *validationScope, defns inputValueDefinitionList, args *gqlang.Arguments) []error {
+func validateArguments(v *validationScope, defns inputValueDefinitionList, args *ast.Arguments) []error {
 	var argumentNames []string
-	argumentsByName := make(map[string][]*gqlang.Argument)
+	argumentsByName := make(map[string][]*ast.Argument)
 	var endLocation []Location
 	var errs []error
 	if args != nil {
@@ -786,11 +843,11 @@ func validateArguments(v *validationScope, defns inputValueDefinitionList, args
 	return errs
 }
 
-func validateConstantValue(source string, typ *gqlType, val *gqlang.InputValue) []error {
+func validateConstantValue(source string, typ *gqlType, val *ast.InputValue) []error {
 	return validateValue(&validationScope{source: source}, typ, false, val)
 }
 
-func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *gqlang.InputValue) []error {
+func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *ast.InputValue) []error {
 	if val.Null != nil {
 		if !typ.isNullable() {
 			return []error{&ResponseError{
@@ -830,8 +887,10 @@ func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *gqlan
 		},
 	}
 	switch {
+	case typ.isScalar() && typ.structured:
+		return validateStructuredValue(v, val)
 	case typ.isScalar():
-		if val.Scalar == nil || val.Scalar.Type == gqlang.EnumScalar {
+		if val.Scalar == nil || val.Scalar.Type == ast.EnumScalar {
 			return []error{genericErr}
 		}
 		err := validateScalar(typ, val.Scalar.Value(), scalarTypeAffinity(val.Scalar.Type))
@@ -842,7 +901,7 @@ func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *gqlan
 			}}
 		}
 	case typ.isEnum():
-		if val.Scalar == nil || val.Scalar.Type != gqlang.EnumScalar {
+		if val.Scalar == nil || val.Scalar.Type != ast.EnumScalar {
 			return []error{genericErr}
 		}
 		if got := val.Scalar.Value(); !typ.enum.has(got) {
@@ -870,7 +929,7 @@ func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *gqlan
 		if val.InputObject == nil {
 			return []error{genericErr}
 		}
-		fieldsByName := make(map[string][]*gqlang.InputObjectField)
+		fieldsByName := make(map[string][]*ast.InputObjectField)
 		var errs []error
 		for _, field := range val.InputObject.Fields {
 			name := field.Name.Value
@@ -933,6 +992,56 @@ func validateValue(v *validationScope, typ *gqlType, hasDefault bool, val *gqlan
 	return nil
 }
 
+// validateStructuredValue validates a literal for a structured scalar type.
+// Any shape of value is permitted, but variables must be defined and input
+// object fields must be unique.
+func validateStructuredValue(v *validationScope, val *ast.InputValue) []error {
+	switch {
+	case val.VariableRef != nil:
+		vv := v.variables[val.VariableRef.Name.Value]
+		if vv == nil {
+			return []error{&ResponseError{
+				Message: fmt.Sprintf("undefined variable $%s", val.VariableRef.Name.Value),
+				Locations: []Location{
+					astPositionToLocation(val.VariableRef.Dollar.ToPosition(v.source)),
+				},
+			}}
+		}
+		vv.used = true
+		return nil
+	case val.List != nil:
+		var errs []error
+		for i, elem := range val.List.Values {
+			for _, err := range validateStructuredValue(v, elem) {
+				errs = append(errs, xerrors.Errorf("list[%d]: %w", i, err))
+			}
+		}
+		return errs
+	case val.InputObject != nil:
+		var errs []error
+		seen := make(map[string]struct{})
+		for _, field := range val.InputObject.Fields {
+			name := field.Name.Value
+			if _, dup := seen[name]; dup {
+				errs = append(errs, &ResponseError{
+					Message: fmt.Sprintf("multiple input fields named %s", name),
+					Locations: []Location{
+						astPositionToLocation(field.Name.Start.ToPosition(v.source)),
+					},
+				})
+				continue
+			}
+			seen[name] = struct{}{}
+			for _, err := range validateStructuredValue(v, field.Value) {
+				errs = append(errs, xerrors.Errorf("input field %s: %w", name, err))
+			}
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
 // validateVariableRef returns an error if the variable usage is not allowed.
 // See https://graphql.github.io/graphql-spec/June2018/#sec-All-Variable-Usages-are-Allowed
 func validateVariableRef(typ *gqlType, usageHasDefault bool, vv *validatedVariable) error {
@@ -991,17 +1100,17 @@ type scalarAffinity int
 
 const (
 	noAffinity      = scalarAffinity(0)
-	stringAffinity  = scalarAffinity(1 + gqlang.StringScalar)
-	booleanAffinity = scalarAffinity(1 + gqlang.BooleanScalar)
-	intAffinity     = scalarAffinity(1 + gqlang.IntScalar)
-	floatAffinity   = scalarAffinity(1 + gqlang.FloatScalar)
+	stringAffinity  = scalarAffinity(1 + ast.StringScalar)
+	booleanAffinity = scalarAffinity(1 + ast.BooleanScalar)
+	intAffinity     = scalarAffinity(1 + ast.IntScalar)
+	floatAffinity   = scalarAffinity(1 + ast.FloatScalar)
 )
 
-func scalarTypeAffinity(styp gqlang.ScalarType) scalarAffinity {
+func scalarTypeAffinity(styp ast.ScalarType) scalarAffinity {
 	return 1 + scalarAffinity(styp)
 }
 
-func posListToLocationList(source string, posList []gqlang.Pos) []Location {
+func posListToLocationList(source string, posList []ast.Pos) []Location {
 	locList := make([]Location, len(posList))
 	for i := range locList {
 		locList[i] = astPositionToLocation(posList[i].ToPosition(source))
@@ -28,7 +28,7 @@ import (
 	"unicode/utf16"
 
 	"golang.org/x/xerrors"
-	"zombiezen.com/go/graphql-server/internal/gqlang"
+	"zombiezen.com/go/graphql-server/ast"
 )
 
 // A Value is a GraphQL value. The zero value is an untyped null.
@@ -36,7 +36,7 @@ import (
 // For more information on GraphQL types, see https://graphql.org/learn/schema/#type-system
 type Value struct {
 	typ *gqlType
-	val interface{} // one of nil, string, []Value, []Field, or map[string]Value.
+	val interface{} // one of nil, string, []Value, []Field, map[string]Value, or *Upload.
 }
 
 // Field is a field in an object or input object.
@@ -86,7 +86,6 @@ func (schema *Schema) valueFromGo(ctx context.Context, variables map[string]Valu
 		return Value{typ: typ}, []error{err}
 	}
 	typ = resolvedType
-	sel = sel.forType(typ.toNullable().Name().String())
 	if isGraphQLNull(interfaceValueForAssertions(goValue)) {
 		if !typ.isNullable() {
 			return Value{typ: typ}, []error{xerrors.Errorf("cannot convert nil to %v", typ)}
@@ -108,7 +107,7 @@ func (schema *Schema) valueFromGo(ctx context.Context, variables map[string]Valu
 		var errs []error
 		for i := range gqlValues {
 			var ierrs []error
-			gqlValues[i], ierrs = schema.valueFromGo(ctx, variables, goValue.Index(i), typ.listElem, sel)
+			gqlValues[i], ierrs = schema.valueFromGo(withPathSegment(ctx, PathSegment{ListIndex: i}), variables, goValue.Index(i), typ.listElem, sel)
 			for _, err := range ierrs {
 				errs = append(errs, &listElementError{idx: i, err: err})
 			}
@@ -118,6 +117,7 @@ func (schema *Schema) valueFromGo(ctx context.Context, variables map[string]Valu
 		}
 		return Value{typ: typ, val: gqlValues}, errs
 	case typ.isObject():
+		sel = sel.forType(typ.obj.name)
 		if sel == nil {
 			return Value{typ: typ, val: []Field(nil)}, nil
 		}
@@ -143,11 +143,20 @@ func (schema *Schema) valueFromGo(ctx context.Context, variables map[string]Valu
 					val: typ.toNullable().String(),
 				}
 			case schemaFieldName:
+				restrictIntrospectionCache(ctx)
 				fval, ferrs = schema.introspectSchema(ctx, variables, f)
 			case typeByNameFieldName:
+				restrictIntrospectionCache(ctx)
 				fval, ferrs = schema.introspectType(ctx, variables, f)
 			default:
-				fval, ferrs = schema.readField(ctx, variables, goValue, desc, typ.obj.field(f.name).typ, f)
+				fieldType := typ.obj.field(f.name).typ
+				fval, ferrs = schema.readField(ctx, variables, goValue, desc, typ, fieldType, f)
+				if fval.IsNull() && !fieldType.isNullable() && len(ferrs) > 0 {
+					// A non-null field that could not be resolved makes the
+					// whole object null.
+					// https://graphql.github.io/graphql-spec/June2018/#sec-Errors-and-Non-Nullability
+					return Value{typ: typ}, append(errs, ferrs...)
+				}
 			}
 			gqlFields = append(gqlFields, Field{Key: f.key, Value: fval})
 			errs = append(errs, ferrs...)
@@ -221,7 +230,7 @@ func findTypeCaseInsensitive(types map[string]*gqlType, name string) *gqlType {
 // coerceArgumentValues uses the algorithm in
 // https://graphql.github.io/graphql-spec/June2018/#sec-Coercing-Field-Arguments
 // but assumes the arguments were validated.
-func coerceArgumentValues(s *selectionSetScope, argDefns inputValueDefinitionList, args *gqlang.Arguments) (map[string]Value, []error) {
+func coerceArgumentValues(s *selectionSetScope, argDefns inputValueDefinitionList, args *ast.Arguments) (map[string]Value, []error) {
 	argValues := make(map[string]Value)
 	var errs []error
 	for _, defn := range argDefns {
@@ -238,6 +247,11 @@ func coerceArgumentValues(s *selectionSetScope, argDefns inputValueDefinitionLis
 		}
 		var argErrs []error
 		argValues[defn.name], argErrs = coerceInputValue(s, defn.Type(), arg.Value)
+		if len(argErrs) == 0 {
+			if err := defn.constraint.check(argValues[defn.name]); err != nil {
+				argErrs = []error{constraintError(s, arg.Name.Start, err)}
+			}
+		}
 		for _, err := range argErrs {
 			errs = append(errs, xerrors.Errorf("argument %s: %w", defn.name, err))
 		}
@@ -247,7 +261,7 @@ func coerceArgumentValues(s *selectionSetScope, argDefns inputValueDefinitionLis
 
 // coerceConstantInputValue converts a input literal without variables into a
 // value. It assumes that the input literal has been validated.
-func coerceConstantInputValue(typ *gqlType, inputValue *gqlang.InputValue) Value {
+func coerceConstantInputValue(typ *gqlType, inputValue *ast.InputValue) Value {
 	v, errs := coerceInputValue(nil, typ, inputValue)
 	if len(errs) > 0 {
 		// This condition should be impossible.
@@ -258,7 +272,7 @@ func coerceConstantInputValue(typ *gqlType, inputValue *gqlang.InputValue) Value
 
 // coerceInputValue converts an input expression possibly containing variables
 // into a value. It assumes that the input expression has been validated.
-func coerceInputValue(s *selectionSetScope, typ *gqlType, inputValue *gqlang.InputValue) (Value, []error) {
+func coerceInputValue(s *selectionSetScope, typ *gqlType, inputValue *ast.InputValue) (Value, []error) {
 	switch {
 	case inputValue.Null != nil:
 		return Value{typ: typ}, nil
@@ -277,6 +291,8 @@ func coerceInputValue(s *selectionSetScope, typ *gqlType, inputValue *gqlang.Inp
 			typ: typ,
 			val: v.val,
 		}, nil
+	case typ.isScalar() && typ.structured:
+		return coerceStructuredInputValue(s, typ, inputValue), nil
 	case typ.isScalar() || typ.isEnum():
 		return Value{
 			typ: typ,
@@ -311,9 +327,15 @@ func coerceInputValue(s *selectionSetScope, typ *gqlType, inputValue *gqlang.Inp
 		var errs []error
 		for _, field := range inputValue.InputObject.Fields {
 			fieldName := field.Name.Value
-			fieldType := typ.input.fields.byName(fieldName).Type()
+			fieldDefn := typ.input.fields.byName(fieldName)
 			var fieldErrs []error
-			val[fieldName], fieldErrs = coerceInputValue(s, fieldType, field.Value)
+			val[fieldName], fieldErrs = coerceInputValue(s, fieldDefn.Type(), field.Value)
+			if len(fieldErrs) == 0 && s != nil {
+				// Constant values (s == nil) are defaults, which are not checked.
+				if err := fieldDefn.constraint.check(val[fieldName]); err != nil {
+					fieldErrs = []error{constraintError(s, field.Name.Start, err)}
+				}
+			}
 			for _, err := range fieldErrs {
 				errs = append(errs, xerrors.Errorf("input field %s: %w", fieldName, err))
 			}
@@ -324,8 +346,95 @@ func coerceInputValue(s *selectionSetScope, typ *gqlType, inputValue *gqlang.Inp
 	}
 }
 
-func (schema *Schema) readField(ctx context.Context, variables map[string]Value, goValue reflect.Value, desc *typeDescriptor, typ *gqlType, f *SelectedField) (Value, []error) {
-	result, err := desc.read(ctx, valueForAssertions(goValue), f.toRequest())
+// constraintError returns an error for a constraint violation of the
+// argument or input field whose name starts at pos.
+func constraintError(s *selectionSetScope, pos ast.Pos, err error) error {
+	return &ResponseError{
+		Message: err.Error(),
+		Locations: []Location{
+			astPositionToLocation(pos.ToPosition(s.source)),
+		},
+	}
+}
+
+// coerceStructuredInputValue converts a literal of any shape into a value of a
+// structured scalar type. Variables inside the literal are substituted as-is.
+func coerceStructuredInputValue(s *selectionSetScope, typ *gqlType, inputValue *ast.InputValue) Value {
+	switch {
+	case inputValue.Null != nil:
+		return Value{typ: typ}
+	case inputValue.VariableRef != nil:
+		v := s.variables[inputValue.VariableRef.Name.Value]
+		return Value{typ: typ, val: v.val}
+	case inputValue.List != nil:
+		list := make([]Value, 0, len(inputValue.List.Values))
+		for _, elem := range inputValue.List.Values {
+			list = append(list, coerceStructuredInputValue(s, typ.toNullable(), elem))
+		}
+		return Value{typ: typ, val: list}
+	case inputValue.InputObject != nil:
+		m := make(map[string]Value, len(inputValue.InputObject.Fields))
+		for _, field := range inputValue.InputObject.Fields {
+			m[field.Name.Value] = coerceStructuredInputValue(s, typ.toNullable(), field.Value)
+		}
+		return Value{typ: typ, val: m}
+	default:
+		return Value{typ: typ, val: inputValue.Scalar.Value()}
+	}
+}
+
+func (schema *Schema) readField(ctx context.Context, variables map[string]Value, goValue reflect.Value, desc *typeDescriptor, parentType, typ *gqlType, f *SelectedField) (Value, []error) {
+	if err := authorizeField(ctx, parentType, f); err != nil {
+		return Value{typ: typ}, []error{wrapFieldError(f.key, f.loc, err)}
+	}
+	req := f.toRequest()
+	var finishTrace func(interface{}, error)
+	if frame := traceFrameFromContext(ctx); frame != nil {
+		ctx = withPathSegment(ctx, PathSegment{Field: f.key})
+		ctx, finishTrace = frame.tracer.TraceField(ctx, &FieldInfo{
+			Path:       traceFrameFromContext(ctx).path(),
+			ParentType: parentType.toNullable().String(),
+			ReturnType: typ.String(),
+			Request:    req,
+			types:      schema.types,
+			typ:        typ,
+		})
+	}
+	var dynamicHint *fieldCacheHint
+	cache := cachePolicyFromContext(ctx)
+	if cache != nil {
+		ctx, dynamicHint = withFieldCacheHint(ctx)
+	}
+	result, err := desc.read(ctx, valueForAssertions(goValue), req)
+	if cache != nil {
+		hint, ok := dynamicHint.override()
+		if !ok {
+			root := parentType.obj == schema.query.obj
+			hint = cache.fieldHint(parentType, typ, f.name, root)
+		}
+		cache.restrict(hint)
+	}
+	if finishTrace != nil {
+		var goResult interface{}
+		if result.IsValid() && result.CanInterface() {
+			goResult = result.Interface()
+		}
+		traceErr := err
+		if re, ok := err.(*resolverError); ok {
+			traceErr = re.err
+		}
+		finishTrace(goResult, traceErr)
+	}
+	if re, ok := err.(*resolverError); ok {
+		if rerr, ok := re.err.(*ResponseError); ok {
+			// Resolvers may use response errors to report errors below the field.
+			err = rerr
+		} else {
+			// Intentionally making the returned error opaque to avoid interference
+			// in toResponseError.
+			err = xerrors.Errorf("server error: %v", re.err)
+		}
+	}
 	if err != nil {
 		return Value{typ: typ}, []error{wrapFieldError(f.key, f.loc, err)}
 	}
@@ -442,11 +551,12 @@ func interfaceValueForAssertions(v reflect.Value) interface{} {
 //   - string for scalars
 //   - []interface{} for lists
 //   - map[string]interface{} for objects
+//   - *Upload for uploads
 func (v Value) GoValue() interface{} {
 	switch val := v.val.(type) {
 	case nil:
 		return nil
-	case string:
+	case string, *Upload:
 		return val
 	case []Value:
 		goVal := make([]interface{}, len(val))
@@ -544,10 +654,10 @@ func (v Value) ValueFor(key string) Value {
 	}
 }
 
-// MarshalJSON converts the value to JSON.
+// MarshalJSON converts the value to JSON. Uploads are represented as null.
 func (v Value) MarshalJSON() ([]byte, error) {
 	switch val := v.val.(type) {
-	case nil:
+	case nil, *Upload:
 		return []byte("null"), nil
 	case string:
 		if typ := v.typ.toNullable(); typ == booleanType || typ == intType || typ == floatType {
@@ -592,7 +702,7 @@ func (v Value) String() string {
 
 func (v Value) encodeGraphQL(sb *strings.Builder, indent int) {
 	switch val := v.val.(type) {
-	case nil:
+	case nil, *Upload:
 		sb.WriteString("null")
 	case string:
 		if typ := v.typ.toNullable(); typ == booleanType || typ == intType || typ == floatType {
@@ -0,0 +1,66 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlclient_test
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqlclient"
+)
+
+type Query struct{}
+
+func (Query) Greeting(args map[string]graphql.Value) string {
+	return "Hello, " + args["name"].Scalar() + "!"
+}
+
+func ExampleClient() {
+	// Create a server. A real program would typically use an HTTPTransport
+	// to talk to a remote service instead.
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting(name: String!): String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	server, err := graphql.NewServer(schema, Query{}, nil)
+	if err != nil {
+		// handle error
+	}
+	client := graphqlclient.New(graphqlclient.ServerTransport{Server: server})
+
+	// Send a request and decode the response data.
+	var data struct {
+		Greeting string
+	}
+	err = client.Do(context.Background(), graphql.Request{
+		Query: `query ($name: String!) { greeting(name: $name) }`,
+		Variables: map[string]graphql.Input{
+			"name": graphql.ScalarInput("World"),
+		},
+	}, &data)
+	if err != nil {
+		// handle error
+	}
+	fmt.Println(data.Greeting)
+	// Output:
+	// Hello, World!
+}
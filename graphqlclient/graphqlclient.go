@@ -0,0 +1,113 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package graphqlclient provides a client for GraphQL services. Requests are
+// sent through a Transport, and response data is decoded into Go values using
+// the same rules as graphql.Value.Convert.
+package graphqlclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// A Transport sends a GraphQL request and returns the service's response.
+// Execute should only return an error if it could not obtain a response.
+// Errors reported by the service should be returned in the response.
+type Transport interface {
+	Execute(ctx context.Context, req graphql.Request) (*Response, error)
+}
+
+// Response is a GraphQL response as received by a client.
+type Response struct {
+	// Data is the JSON-encoded result of the operation. It is empty or null if
+	// the operation could not be executed.
+	Data   json.RawMessage          `json:"data,omitempty"`
+	Errors []*graphql.ResponseError `json:"errors,omitempty"`
+	// Extensions holds the JSON-encoded entries of the response's extensions
+	// object.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+}
+
+// Client sends GraphQL requests. It is safe to use from multiple goroutines if
+// its Transport is.
+type Client struct {
+	transport Transport
+}
+
+// New returns a new client that sends requests through the given transport.
+func New(t Transport) *Client {
+	return &Client{transport: t}
+}
+
+// Do sends a request and decodes the response data into the value pointed to
+// by data, as described in Unmarshal. data may be nil to discard the response
+// data.
+//
+// If the response has errors, Do decodes any data that is present and returns
+// an *Error.
+func (c *Client) Do(ctx context.Context, req graphql.Request, data interface{}) error {
+	resp, err := c.transport.Execute(ctx, req)
+	if err != nil {
+		return xerrors.Errorf("graphql request: %w", err)
+	}
+	if data != nil && len(resp.Data) > 0 {
+		if err := Unmarshal(resp.Data, data); err != nil {
+			return xerrors.Errorf("graphql request: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return &Error{Errors: resp.Errors}
+	}
+	return nil
+}
+
+// Error is returned by Do when a response includes GraphQL errors.
+type Error struct {
+	Errors []*graphql.ResponseError
+}
+
+// Error returns the first error's message.
+func (e *Error) Error() string {
+	sb := new(strings.Builder)
+	sb.WriteString("graphql: ")
+	if len(e.Errors) == 0 {
+		sb.WriteString("unknown error")
+		return sb.String()
+	}
+	first := e.Errors[0]
+	if len(first.Path) > 0 {
+		for i, seg := range first.Path {
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			sb.WriteString(seg.String())
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(first.Message)
+	if n := len(e.Errors) - 1; n == 1 {
+		sb.WriteString(" (and 1 other error)")
+	} else if n > 1 {
+		fmt.Fprintf(sb, " (and %d other errors)", n)
+	}
+	return sb.String()
+}
@@ -0,0 +1,379 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqlhttp"
+)
+
+const testSchema = `
+type Query {
+  user(id: ID!): User
+  fail: String
+}
+
+type User {
+  id: ID!
+  name: String!
+  age: Int
+  tags: [String!]!
+}
+`
+
+type testQuery struct{}
+
+func (testQuery) User(args map[string]graphql.Value) *testUser {
+	if args["id"].Scalar() != "1" {
+		return nil
+	}
+	return &testUser{ID: "1", Name: "Alice", Tags: []string{"admin", "staff"}}
+}
+
+func (testQuery) Fail() (string, error) {
+	return "", xerrors.New("bork")
+}
+
+type testUser struct {
+	ID   string
+	Name string
+	Age  graphql.NullInt
+	Tags []string
+}
+
+func newTestServer(tb testing.TB) *graphql.Server {
+	tb.Helper()
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := graphql.NewServer(schema, testQuery{}, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+type userData struct {
+	User *struct {
+		ID       int64
+		Nickname string
+		Age      graphql.NullInt
+		Tags     []string
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := newTestServer(t)
+	httpSrv := httptest.NewServer(graphqlhttp.NewHandler(srv))
+	defer httpSrv.Close()
+	transports := []struct {
+		name string
+		t    Transport
+	}{
+		{"Server", ServerTransport{srv}},
+		{"HTTP", &HTTPTransport{URL: httpSrv.URL, Client: httpSrv.Client()}},
+	}
+	ctx := context.Background()
+	for _, transport := range transports {
+		t.Run(transport.name, func(t *testing.T) {
+			client := New(transport.t)
+
+			t.Run("Data", func(t *testing.T) {
+				var data userData
+				err := client.Do(ctx, graphql.Request{
+					Query: `query ($id: ID!) { user(id: $id) { id nickname: name age tags } }`,
+					Variables: map[string]graphql.Input{
+						"id": graphql.ScalarInput("1"),
+					},
+				}, &data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data.User == nil {
+					t.Fatal("data.User = nil")
+				}
+				if data.User.ID != 1 || data.User.Nickname != "Alice" || data.User.Age.Valid {
+					t.Errorf("data.User = %+v; want {ID:1 Nickname:Alice Age:null ...}", *data.User)
+				}
+				if diff := cmp.Diff([]string{"admin", "staff"}, data.User.Tags); diff != "" {
+					t.Errorf("data.User.Tags (-want +got):\n%s", diff)
+				}
+			})
+			t.Run("Null", func(t *testing.T) {
+				data := userData{User: &struct {
+					ID       int64
+					Nickname string
+					Age      graphql.NullInt
+					Tags     []string
+				}{}}
+				err := client.Do(ctx, graphql.Request{
+					Query: `{ user(id: "2") { id } }`,
+				}, &data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data.User != nil {
+					t.Errorf("data.User = %+v; want nil", data.User)
+				}
+			})
+			t.Run("Errors", func(t *testing.T) {
+				var data struct {
+					User struct{ Name string }
+					Fail graphql.NullString
+				}
+				err := client.Do(ctx, graphql.Request{
+					Query: `{ user(id: "1") { name } fail }`,
+				}, &data)
+				var gqlErr *Error
+				if !xerrors.As(err, &gqlErr) {
+					t.Fatalf("Do(...) = %v; want *Error", err)
+				}
+				if len(gqlErr.Errors) != 1 || !strings.Contains(gqlErr.Errors[0].Message, "bork") {
+					t.Errorf("errors = %v; want [bork]", gqlErr.Errors)
+				}
+				if got, want := err.Error(), "graphql: fail: "; !strings.HasPrefix(got, want) {
+					t.Errorf("err.Error() = %q; want prefix %q", got, want)
+				}
+				if data.User.Name != "Alice" {
+					t.Errorf("data.User.Name = %q; want %q", data.User.Name, "Alice")
+				}
+			})
+			t.Run("InvalidQuery", func(t *testing.T) {
+				err := client.Do(ctx, graphql.Request{
+					Query: `{ nope }`,
+				}, nil)
+				var gqlErr *Error
+				if !xerrors.As(err, &gqlErr) {
+					t.Fatalf("Do(...) = %v; want *Error", err)
+				}
+			})
+			t.Run("DecodeError", func(t *testing.T) {
+				var data struct {
+					User struct{ Email string }
+				}
+				err := client.Do(ctx, graphql.Request{
+					Query: `{ user(id: "1") { name } }`,
+				}, &data)
+				if err == nil {
+					t.Error("Do(...) did not return an error")
+				}
+			})
+		})
+	}
+}
+
+func TestHTTPTransport(t *testing.T) {
+	ctx := context.Background()
+	t.Run("Header", func(t *testing.T) {
+		var got string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("Authorization")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data":{"x":1}}`))
+		}))
+		defer srv.Close()
+		transport := &HTTPTransport{
+			URL:    srv.URL,
+			Client: srv.Client(),
+			Header: http.Header{"Authorization": {"Bearer xyzzy"}},
+		}
+		var data struct{ X int }
+		if err := New(transport).Do(ctx, graphql.Request{Query: "{ x }"}, &data); err != nil {
+			t.Fatal(err)
+		}
+		if want := "Bearer xyzzy"; got != want {
+			t.Errorf("Authorization = %q; want %q", got, want)
+		}
+		if data.X != 1 {
+			t.Errorf("data.X = %d; want 1", data.X)
+		}
+	})
+	t.Run("ErrorStatusWithJSON", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":[{"message":"syntax error","locations":[{"line":1,"column":2}]}]}`))
+		}))
+		defer srv.Close()
+		err := New(&HTTPTransport{URL: srv.URL, Client: srv.Client()}).Do(ctx, graphql.Request{Query: "{"}, nil)
+		var gqlErr *Error
+		if !xerrors.As(err, &gqlErr) {
+			t.Fatalf("Do(...) = %v; want *Error", err)
+		}
+		want := []*graphql.ResponseError{{
+			Message:   "syntax error",
+			Locations: []graphql.Location{{Line: 1, Column: 2}},
+		}}
+		if diff := cmp.Diff(want, gqlErr.Errors); diff != "" {
+			t.Errorf("errors (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("NotGraphQL", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		defer srv.Close()
+		err := New(&HTTPTransport{URL: srv.URL, Client: srv.Client()}).Do(ctx, graphql.Request{Query: "{ x }"}, nil)
+		if err == nil {
+			t.Fatal("Do(...) did not return an error")
+		}
+		var gqlErr *Error
+		if xerrors.As(err, &gqlErr) {
+			t.Errorf("Do(...) = %v; want non-GraphQL error", err)
+		}
+	})
+}
+
+func TestUnmarshal(t *testing.T) {
+	type point struct {
+		X, Y float64
+	}
+	tests := []struct {
+		name    string
+		data    string
+		into    func() interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "String",
+			data: `"foo"`,
+			into: func() interface{} { return new(string) },
+			want: "foo",
+		},
+		{
+			name: "NumberAsString",
+			data: `42`,
+			into: func() interface{} { return new(string) },
+			want: "42",
+		},
+		{
+			name: "Int",
+			data: `42`,
+			into: func() interface{} { return new(int32) },
+			want: int32(42),
+		},
+		{
+			name: "IntFromString",
+			data: `"42"`,
+			into: func() interface{} { return new(int) },
+			want: 42,
+		},
+		{
+			name:    "IntOverflow",
+			data:    `300`,
+			into:    func() interface{} { return new(int8) },
+			wantErr: true,
+		},
+		{
+			name: "Float",
+			data: `1.5`,
+			into: func() interface{} { return new(float64) },
+			want: 1.5,
+		},
+		{
+			name: "Bool",
+			data: `true`,
+			into: func() interface{} { return new(bool) },
+			want: true,
+		},
+		{
+			name: "TextUnmarshaler",
+			data: `7`,
+			into: func() interface{} { return new(graphql.NullInt) },
+			want: graphql.NullInt{Int: 7, Valid: true},
+		},
+		{
+			name: "Null",
+			data: `null`,
+			into: func() interface{} { s := "foo"; return &s },
+			want: "",
+		},
+		{
+			name: "List",
+			data: `[1, 2, null]`,
+			into: func() interface{} { return new([]*int) },
+			want: []*int{intPtr(1), intPtr(2), nil},
+		},
+		{
+			name: "Struct",
+			data: `{"x": 1, "Y": 2}`,
+			into: func() interface{} { return new(point) },
+			want: point{X: 1, Y: 2},
+		},
+		{
+			name:    "StructUnknownField",
+			data:    `{"z": 1}`,
+			into:    func() interface{} { return new(point) },
+			wantErr: true,
+		},
+		{
+			name: "Map",
+			data: `{"a": 1, "b": 2}`,
+			into: func() interface{} { return new(map[string]int) },
+			want: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name: "Interface",
+			data: `{"a": [1, "x", true, null]}`,
+			into: func() interface{} { return new(interface{}) },
+			want: map[string]interface{}{"a": []interface{}{1.0, "x", true, nil}},
+		},
+		{
+			name:    "ListIntoScalar",
+			data:    `[1]`,
+			into:    func() interface{} { return new(int) },
+			wantErr: true,
+		},
+		{
+			name:    "ObjectIntoSlice",
+			data:    `{}`,
+			into:    func() interface{} { return new([]int) },
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			into := test.into()
+			err := Unmarshal([]byte(test.data), into)
+			if err != nil {
+				t.Logf("Unmarshal: %v", err)
+				if !test.wantErr {
+					t.Fail()
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatal("Unmarshal did not return an error")
+			}
+			got := reflect.ValueOf(into).Elem().Interface()
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("value (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
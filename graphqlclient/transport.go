@@ -0,0 +1,117 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// HTTPTransport sends requests to a GraphQL service over HTTP, using POST
+// requests with JSON bodies as described in
+// https://graphql.org/learn/serving-over-http/.
+type HTTPTransport struct {
+	// URL is the GraphQL endpoint of the service.
+	URL string
+	// Client is the HTTP client used to make requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+	// Header specifies additional headers to send with each request.
+	Header http.Header
+}
+
+// Execute sends a request to the service. If the request has a ValidatedQuery,
+// then its source is sent.
+func (t *HTTPTransport) Execute(ctx context.Context, req graphql.Request) (*Response, error) {
+	if req.ValidatedQuery != nil {
+		req.Query = req.ValidatedQuery.Source()
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, xerrors.Errorf("http transport: %w", err)
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, xerrors.Errorf("http transport: %w", err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	for k, v := range t.Header {
+		httpReq.Header[k] = append([]string(nil), v...)
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	httpReq.Header.Set("Accept", "application/json")
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, xerrors.Errorf("http transport: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("http transport: %s: %w", t.URL, err)
+	}
+	// Services may use error status codes for GraphQL responses, so only
+	// fail if the body isn't a GraphQL response.
+	contentType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if contentType != "application/json" {
+		return nil, xerrors.Errorf("http transport: %s: %s (Content-Type %q)", t.URL, httpResp.Status, httpResp.Header.Get("Content-Type"))
+	}
+	resp := new(Response)
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return nil, xerrors.Errorf("http transport: %s: %w", t.URL, err)
+	}
+	return resp, nil
+}
+
+// ServerTransport sends requests to a server in the same process. It is useful
+// for testing clients without running a service.
+type ServerTransport struct {
+	Server *graphql.Server
+}
+
+// Execute executes the request on the server.
+func (t ServerTransport) Execute(ctx context.Context, req graphql.Request) (*Response, error) {
+	gqlResp := t.Server.Execute(ctx, req)
+	data, err := json.Marshal(gqlResp.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("server transport: %w", err)
+	}
+	resp := &Response{
+		Data:   data,
+		Errors: gqlResp.Errors,
+	}
+	if len(gqlResp.Extensions) > 0 {
+		resp.Extensions = make(map[string]json.RawMessage, len(gqlResp.Extensions))
+		for k, v := range gqlResp.Extensions {
+			resp.Extensions[k], err = json.Marshal(v)
+			if err != nil {
+				return nil, xerrors.Errorf("server transport: extension %s: %w", k, err)
+			}
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlclient
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Unmarshal decodes JSON response data into the value pointed to by v. It
+// follows the same rules as graphql.Value.Convert:
+//
+// For scalars, Unmarshal will first attempt to use the
+// encoding.TextUnmarshaler interface, if present. Next, Unmarshal will try to
+// convert the scalar to the Go type. A Go string will use the scalar's text
+// verbatim. Numeric types will be converted by parsing the scalar as a number.
+// Boolean types will be converted from the scalars true and false.
+//
+// Objects will be converted into Go structs. Each of the object's fields will
+// be converted into the struct field with the same name, ignoring case. An
+// error will be returned if a field in the object does not match exactly one
+// field in the Go struct. Objects may also be converted into Go maps with
+// string keys.
+//
+// Lists will be converted into Go slices. Elements are converted using the same
+// rules as Unmarshal.
+//
+// If the Go value is an empty interface, then it is set to the value that
+// encoding/json would produce.
+//
+// Null will be converted to the zero value of that type.
+func Unmarshal(data []byte, v interface{}) error {
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr {
+		return xerrors.Errorf("unmarshal GraphQL data: argument not a pointer (got %T)", v)
+	}
+	if dst.IsNil() {
+		return xerrors.New("unmarshal GraphQL data: argument is nil")
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return xerrors.Errorf("unmarshal GraphQL data: %w", err)
+	}
+	if err := convert(dst.Elem(), val); err != nil {
+		return xerrors.Errorf("unmarshal GraphQL data: %w", err)
+	}
+	return nil
+}
+
+var (
+	emptyInterfaceGoType  = reflect.TypeOf((*interface{})(nil)).Elem()
+	textUnmarshalerGoType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// convert converts a value decoded by encoding/json (with UseNumber) into a Go
+// value.
+func convert(dst reflect.Value, val interface{}) error {
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Type() == emptyInterfaceGoType {
+		dst.Set(reflect.ValueOf(plainValue(val)))
+		return nil
+	}
+	for dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			// Create new values if pointers are nil.
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		dst = dst.Elem()
+	}
+	goType := dst.Type()
+	kind := goType.Kind()
+	switch val := val.(type) {
+	case string, json.Number, bool:
+		text := scalarText(val)
+		if reflect.PtrTo(goType).Implements(textUnmarshalerGoType) {
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		}
+		valueErr := func() error {
+			return xerrors.Errorf("cannot convert %q to %v", text, goType)
+		}
+		switch kind {
+		case reflect.String:
+			dst.SetString(text)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(text, 10, goType.Bits())
+			if err != nil {
+				return valueErr()
+			}
+			dst.SetInt(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			i, err := strconv.ParseUint(text, 10, goType.Bits())
+			if err != nil {
+				return valueErr()
+			}
+			dst.SetUint(i)
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(text, goType.Bits())
+			if err != nil {
+				return valueErr()
+			}
+			dst.SetFloat(f)
+		case reflect.Bool:
+			switch text {
+			case "false":
+				dst.SetBool(false)
+			case "true":
+				dst.SetBool(true)
+			default:
+				return valueErr()
+			}
+		default:
+			return xerrors.Errorf("cannot convert scalar to Go type %v", goType)
+		}
+	case []interface{}:
+		if kind != reflect.Slice {
+			return xerrors.Errorf("cannot convert list to Go type %v", goType)
+		}
+		slice := reflect.MakeSlice(goType, len(val), len(val))
+		for i, elem := range val {
+			if err := convert(slice.Index(i), elem); err != nil {
+				return xerrors.Errorf("element[%d]: %w", i, err)
+			}
+		}
+		dst.Set(slice)
+	case map[string]interface{}:
+		switch {
+		case kind == reflect.Map && goType.Key().Kind() == reflect.String:
+			m := reflect.MakeMapWithSize(goType, len(val))
+			for k, v := range val {
+				elem := reflect.New(goType.Elem()).Elem()
+				if err := convert(elem, v); err != nil {
+					return xerrors.Errorf("field %s: %w", k, err)
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(goType.Key()), elem)
+			}
+			dst.Set(m)
+		case kind == reflect.Struct:
+			for k, v := range val {
+				fieldIndex, err := findField(goType, k)
+				if err != nil {
+					return err
+				}
+				if err := convert(dst.Field(fieldIndex), v); err != nil {
+					return xerrors.Errorf("field %s: %w", k, err)
+				}
+			}
+		default:
+			return xerrors.Errorf("cannot convert object to Go type %v", goType)
+		}
+	default:
+		panic("unknown JSON value")
+	}
+	return nil
+}
+
+// scalarText returns the GraphQL scalar text for a JSON scalar.
+func scalarText(val interface{}) string {
+	switch val := val.(type) {
+	case string:
+		return val
+	case json.Number:
+		return string(val)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		panic("not a scalar")
+	}
+}
+
+// plainValue replaces json.Number values with float64, as encoding/json does
+// without UseNumber.
+func plainValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case json.Number:
+		f, _ := val.Float64()
+		return f
+	case []interface{}:
+		for i := range val {
+			val[i] = plainValue(val[i])
+		}
+		return val
+	case map[string]interface{}:
+		for k, v := range val {
+			val[k] = plainValue(v)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// findField returns the field index of a Go struct that's suitable for the
+// given GraphQL field name.
+func findField(goType reflect.Type, name string) (int, error) {
+	var index int
+	numMatches := 0
+	for i, n := 0, goType.NumField(); i < n; i++ {
+		goField := goType.Field(i)
+		if goField.PkgPath != "" {
+			// Don't consider unexported fields.
+			continue
+		}
+		if strings.EqualFold(goField.Name, name) {
+			index = i
+			numMatches++
+		}
+	}
+	if numMatches == 0 {
+		return -1, xerrors.Errorf("field %s: %v has no matching field", name, goType)
+	}
+	if numMatches > 1 {
+		return -1, xerrors.Errorf("field %s: %v has multiple matching fields", name, goType)
+	}
+	return index, nil
+}
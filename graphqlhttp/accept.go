@@ -0,0 +1,125 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// Response media types from the GraphQL over HTTP specification.
+const (
+	graphqlResponseMediaType = "application/graphql-response+json"
+	jsonMediaType            = "application/json"
+)
+
+// negotiateResponseType returns the response media type to use for the given
+// Accept header. If the header is missing or accepts neither GraphQL response
+// media type, then negotiateResponseType returns application/json, as the
+// specification recommends for legacy clients.
+func negotiateResponseType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return jsonMediaType
+	}
+	best := jsonMediaType
+	bestQ := 0.0
+	bestExact := false
+	// Candidates are in order of preference for ties.
+	for _, candidate := range []string{graphqlResponseMediaType, jsonMediaType} {
+		q, exact := acceptQuality(accept, candidate)
+		if q <= 0 {
+			continue
+		}
+		switch {
+		case q > bestQ || (q == bestQ && exact && !bestExact):
+			best, bestQ, bestExact = candidate, q, exact
+		case q == bestQ && !exact && !bestExact:
+			// Clients that only send wildcards are likely to predate
+			// application/graphql-response+json.
+			best = candidate
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality value the Accept header gives to the
+// media type and whether it was named explicitly rather than by a wildcard.
+func acceptQuality(accept, mediaType string) (q float64, exact bool) {
+	typeWildcard := mediaType[:strings.IndexByte(mediaType, '/')] + "/*"
+	specificity := 0
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		var s int
+		switch rangeType {
+		case mediaType:
+			s = 3
+		case typeWildcard:
+			s = 2
+		case "*/*":
+			s = 1
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+		rangeQ := 1.0
+		if v, ok := params["q"]; ok {
+			rangeQ, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if s > specificity || rangeQ > q {
+			q = rangeQ
+		}
+		specificity = s
+	}
+	return q, specificity == 3
+}
+
+// responseStatus returns the HTTP status code for a GraphQL response.
+// Responses without data are request errors, which the
+// application/graphql-response+json media type reports with a 400 status.
+func responseStatus(mediaType string, response graphql.Response) int {
+	if mediaType == graphqlResponseMediaType && !response.HasData() {
+		return http.StatusBadRequest
+	}
+	return http.StatusOK
+}
+
+// writeError writes an error from parsing a request as a GraphQL response.
+func writeError(w http.ResponseWriter, mediaType string, err error) {
+	code := StatusCode(err)
+	response := graphql.Response{
+		Errors: []*graphql.ResponseError{{Message: err.Error()}},
+	}
+	var e *httpError
+	if xerrors.As(err, &e) && len(e.errs) > 0 {
+		response.Errors = e.errs
+		code = responseStatus(mediaType, response)
+	}
+	writeMediaResponse(w, mediaType, code, response)
+}
@@ -0,0 +1,266 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+func TestNegotiateResponseType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", jsonMediaType},
+		{"application/json", jsonMediaType},
+		{"application/graphql-response+json", graphqlResponseMediaType},
+		{"application/graphql-response+json, application/json", graphqlResponseMediaType},
+		{"application/json, application/graphql-response+json", graphqlResponseMediaType},
+		{"application/graphql-response+json;q=0.9, application/json", jsonMediaType},
+		{"application/graphql-response+json, application/json;q=0.9", graphqlResponseMediaType},
+		{"application/json; charset=utf-8", jsonMediaType},
+		{"*/*", jsonMediaType},
+		{"application/*", jsonMediaType},
+		{"application/graphql-response+json, */*;q=0.8", graphqlResponseMediaType},
+		{"text/html, */*;q=0.1", jsonMediaType},
+		{"application/json;q=0, */*", graphqlResponseMediaType},
+		{"text/html", jsonMediaType},
+		{"text/plain", jsonMediaType},
+		{"application/json;q=0", jsonMediaType},
+	}
+	for _, test := range tests {
+		if got := negotiateResponseType(test.accept); got != test.want {
+			t.Errorf("negotiateResponseType(%q) = %q; want %q", test.accept, got, test.want)
+		}
+	}
+}
+
+type complianceQuery struct{}
+
+func (complianceQuery) Greeting() string { return "hi" }
+
+func (complianceQuery) Broken() (string, error) { return "", errors.New("bork") }
+
+func TestHandlerMediaTypes(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+			broken: String!
+		}
+
+		type Mutation {
+			greet: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, complianceQuery{}, greeter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(server)
+	tests := []struct {
+		name        string
+		method      string
+		query       url.Values
+		contentType string
+		body        string
+		accept      string
+
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+		wantAllow       bool
+	}{
+		{
+			name:            "JSON/Success",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ greeting }"}`,
+			accept:          "application/json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"data":{"greeting":"hi"}}`,
+		},
+		{
+			name:            "GraphQLResponse/Success",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ greeting }"}`,
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"data":{"greeting":"hi"}}`,
+		},
+		{
+			name:            "JSON/ValidationError",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ nope }"}`,
+			accept:          "application/json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}`,
+		},
+		{
+			name:            "GraphQLResponse/ValidationError",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ nope }"}`,
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}`,
+		},
+		{
+			name:            "GraphQLResponse/SyntaxError",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{"}`,
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+		},
+		{
+			name:            "JSON/GETValidationError",
+			method:          http.MethodGet,
+			query:           url.Values{"query": {"{ nope }"}},
+			accept:          "application/json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}`,
+		},
+		{
+			name:            "GraphQLResponse/GETValidationError",
+			method:          http.MethodGet,
+			query:           url.Values{"query": {"{ nope }"}},
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}`,
+		},
+		{
+			name:            "GraphQLResponse/FieldError",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ broken }"}`,
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"field broken: server error: bork","locations":[{"line":1,"column":3}],"path":["broken"]}],"data":null}`,
+		},
+		{
+			name:            "GraphQLResponse/MalformedBody",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query":`,
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"parse graphql request: unexpected EOF"}]}`,
+		},
+		{
+			name:            "JSON/MalformedBody",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query":`,
+			accept:          "application/json",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"parse graphql request: unexpected EOF"}]}`,
+		},
+		{
+			name:            "UnsupportedContentType",
+			method:          http.MethodPost,
+			contentType:     "text/plain",
+			body:            `{ greeting }`,
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"parse graphql request: unrecognized content type: text/plain"}]}`,
+		},
+		{
+			name:            "GETMutation",
+			method:          http.MethodGet,
+			query:           url.Values{"query": {"mutation { greet }"}},
+			accept:          "application/graphql-response+json",
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantContentType: "application/graphql-response+json; charset=utf-8",
+			wantBody:        `{"errors":[{"message":"parse graphql request: GET requests must be queries"}]}`,
+			wantAllow:       true,
+		},
+		{
+			name:            "UnacceptableFallsBackToJSON",
+			method:          http.MethodPost,
+			contentType:     "application/json",
+			body:            `{"query": "{ greeting }"}`,
+			accept:          "text/plain",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"data":{"greeting":"hi"}}`,
+		},
+		{
+			name:        "FormPost",
+			method:      http.MethodPost,
+			contentType: "application/x-www-form-urlencoded",
+			body: url.Values{
+				"query":         {"query A { greeting } query B { broken }"},
+				"operationName": {"A"},
+				"variables":     {`{}`},
+			}.Encode(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json; charset=utf-8",
+			wantBody:        `{"data":{"greeting":"hi"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := "/graphql"
+			if test.query != nil {
+				target += "?" + test.query.Encode()
+			}
+			r := httptest.NewRequest(test.method, target, strings.NewReader(test.body))
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Errorf("status = %d; want %d (body: %s)", w.Code, test.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != test.wantContentType {
+				t.Errorf("Content-Type = %q; want %q", got, test.wantContentType)
+			}
+			if test.wantBody != "" && w.Body.String() != test.wantBody {
+				t.Errorf("body = %s; want %s", w.Body, test.wantBody)
+			}
+			if got := w.Header().Get("Allow"); test.wantAllow && got == "" {
+				t.Error("Allow header not set")
+			}
+		})
+	}
+}
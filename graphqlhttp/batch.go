@@ -0,0 +1,146 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sync"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// ParseBatch parses a GraphQL HTTP request that may contain a batch of
+// operations. A batch is a POST request with a JSON array body, where each
+// element is a request object. ParseBatch reports whether the request was a
+// batch, in which case the response should be a JSON array of responses in the
+// same order. Other requests are parsed with Parse and returned as a single
+// element. If an error is returned, StatusCode will return the proper HTTP
+// status code to use.
+//
+// Multipart requests may also contain a batch, as described in the GraphQL
+// multipart request specification.
+func ParseBatch(schema *graphql.Schema, r *http.Request) (requests []graphql.Request, batch bool, err error) {
+	return ParseBatchWithOptions(schema, r, nil)
+}
+
+// ParseBatchWithOptions parses a GraphQL HTTP request like ParseBatch with the
+// given limits. opts may be nil, which is equivalent to passing a pointer to
+// the zero value.
+func ParseBatchWithOptions(schema *graphql.Schema, r *http.Request, opts *ParseOptions) (requests []graphql.Request, batch bool, err error) {
+	contentType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method == http.MethodPost && contentType == "multipart/form-data" {
+		return parseMultipart(r, params["boundary"], opts)
+	}
+	if r.Method != http.MethodPost || contentType != "application/json" || r.Body == nil {
+		req, err := ParseWithOptions(schema, r, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		return []graphql.Request{req}, false, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, false, &httpError{
+			msg:   "parse graphql request: ",
+			code:  http.StatusBadRequest,
+			cause: err,
+		}
+	}
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+		r2 := r.WithContext(r.Context())
+		r2.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req, err := ParseWithOptions(schema, r2, opts)
+		if err != nil {
+			return nil, false, err
+		}
+		return []graphql.Request{req}, false, nil
+	}
+	if err := json.Unmarshal(body, &requests); err != nil {
+		return nil, false, &httpError{
+			msg:   "parse graphql request: ",
+			code:  http.StatusBadRequest,
+			cause: err,
+		}
+	}
+	if len(requests) == 0 {
+		return nil, false, &httpError{
+			msg:  "parse graphql request: empty batch",
+			code: http.StatusBadRequest,
+		}
+	}
+	return requests, true, nil
+}
+
+// serveBatch executes the operations of a batched request and writes their
+// responses as a JSON array.
+func (h *Handler) serveBatch(w http.ResponseWriter, r *http.Request, mediaType string, requests []graphql.Request) {
+	if h.opts.MaxBatchSize <= 0 {
+		writeError(w, mediaType, &httpError{
+			msg:  "parse graphql request: batched requests not supported",
+			code: http.StatusBadRequest,
+		})
+		return
+	}
+	if len(requests) > h.opts.MaxBatchSize {
+		writeError(w, mediaType, &httpError{
+			msg:  fmt.Sprintf("parse graphql request: batch of %d operations exceeds limit of %d", len(requests), h.opts.MaxBatchSize),
+			code: http.StatusBadRequest,
+		})
+		return
+	}
+	ctx := r.Context()
+	if h.opts.ShareBatchContext {
+		ctx = h.newContext(ctx)
+	}
+	responses := make([]graphql.Response, len(requests))
+	execute := func(i int) {
+		opCtx := ctx
+		if !h.opts.ShareBatchContext {
+			opCtx = h.newContext(ctx)
+		}
+		responses[i] = h.server.Execute(opCtx, requests[i])
+	}
+	if h.opts.ConcurrentBatches {
+		var wg sync.WaitGroup
+		wg.Add(len(requests))
+		for i := range requests {
+			go func(i int) {
+				defer wg.Done()
+				execute(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range requests {
+			execute(i)
+		}
+	}
+	payload, err := json.Marshal(responses)
+	if err != nil {
+		http.Error(w, "GraphQL marshal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprint(len(payload)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+func TestParseBatch(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+
+		want          []graphql.Request
+		wantBatch     bool
+		wantErrStatus int
+	}{
+		{
+			name:        "Single",
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        `{"query": "{ greeting }"}`,
+			want:        []graphql.Request{{Query: "{ greeting }"}},
+		},
+		{
+			name:        "Batch",
+			method:      http.MethodPost,
+			contentType: "application/json; charset=utf-8",
+			body:        ` [{"query": "{ greeting }"}, {"query": "query Foo { greeting }", "operationName": "Foo"}]`,
+			want: []graphql.Request{
+				{Query: "{ greeting }"},
+				{Query: "query Foo { greeting }", OperationName: "Foo"},
+			},
+			wantBatch: true,
+		},
+		{
+			name:          "EmptyBatch",
+			method:        http.MethodPost,
+			contentType:   "application/json",
+			body:          `[]`,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:          "MalformedBatch",
+			method:        http.MethodPost,
+			contentType:   "application/json",
+			body:          `[{"query": 42}]`,
+			wantErrStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "GraphQLBody",
+			method:      http.MethodPost,
+			contentType: "application/graphql",
+			body:        `{ greeting }`,
+			want:        []graphql.Request{{Query: "{ greeting }"}},
+		},
+		{
+			name:          "BadMethod",
+			method:        http.MethodPut,
+			contentType:   "application/json",
+			body:          `[{"query": "{ greeting }"}]`,
+			wantErrStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/graphql", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			got, batch, err := ParseBatch(schema, req)
+			if err != nil {
+				if test.wantErrStatus == 0 {
+					t.Fatalf("ParseBatch(...) = _, _, %v; want <nil>", err)
+				}
+				if code := StatusCode(err); code != test.wantErrStatus {
+					t.Errorf("StatusCode(ParseBatch(...)) = %d; want %d (error: %v)", code, test.wantErrStatus, err)
+				}
+				return
+			}
+			if test.wantErrStatus != 0 {
+				t.Fatalf("ParseBatch(...) = %+v, %t, <nil>; want error with status %d", got, batch, test.wantErrStatus)
+			}
+			if batch != test.wantBatch {
+				t.Errorf("batch = %t; want %t", batch, test.wantBatch)
+			}
+			diff := cmp.Diff(test.want, got,
+				cmpopts.IgnoreFields(graphql.Request{}, "ValidatedQuery"),
+				cmpopts.EquateEmpty())
+			if diff != "" {
+				t.Errorf("ParseBatch(...) (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandlerBatch(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+
+		type Mutation {
+			greet: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, greeter{}, greeter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const batch = `[
+		{"query": "{ greeting }"},
+		{"query": "mutation { greet }"},
+		{"query": "{ nope }"}
+	]`
+	const wantBatch = `[` +
+		`{"data":{"greeting":"hi"}},` +
+		`{"data":{"greet":"hello"}},` +
+		`{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}` +
+		`]`
+	type ctxKey struct{}
+	tests := []struct {
+		name         string
+		opts         HandlerOptions
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantContexts int32
+	}{
+		{
+			name:       "Disabled",
+			body:       batch,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "TooLarge",
+			opts:       HandlerOptions{MaxBatchSize: 2},
+			body:       batch,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "Sequential",
+			opts:         HandlerOptions{MaxBatchSize: 3},
+			body:         batch,
+			wantStatus:   http.StatusOK,
+			wantBody:     wantBatch,
+			wantContexts: 3,
+		},
+		{
+			name:         "Concurrent",
+			opts:         HandlerOptions{MaxBatchSize: 3, ConcurrentBatches: true},
+			body:         batch,
+			wantStatus:   http.StatusOK,
+			wantBody:     wantBatch,
+			wantContexts: 3,
+		},
+		{
+			name:         "SharedContext",
+			opts:         HandlerOptions{MaxBatchSize: 3, ConcurrentBatches: true, ShareBatchContext: true},
+			body:         batch,
+			wantStatus:   http.StatusOK,
+			wantBody:     wantBatch,
+			wantContexts: 1,
+		},
+		{
+			name:         "Single",
+			opts:         HandlerOptions{MaxBatchSize: 3, ShareBatchContext: true},
+			body:         `{"query": "{ greeting }"}`,
+			wantStatus:   http.StatusOK,
+			wantBody:     `{"data":{"greeting":"hi"}}`,
+			wantContexts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var contexts int32
+			opts := test.opts
+			opts.NewContext = func(ctx context.Context) context.Context {
+				atomic.AddInt32(&contexts, 1)
+				return context.WithValue(ctx, ctxKey{}, true)
+			}
+			h := NewHandlerWithOptions(server, &opts)
+			r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(test.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.wantStatus {
+				t.Errorf("status = %d; want %d (body: %s)", w.Code, test.wantStatus, w.Body)
+			}
+			if test.wantBody != "" && w.Body.String() != test.wantBody {
+				t.Errorf("body = %s; want %s", w.Body, test.wantBody)
+			}
+			if contexts != test.wantContexts {
+				t.Errorf("NewContext called %d times; want %d", contexts, test.wantContexts)
+			}
+		})
+	}
+}
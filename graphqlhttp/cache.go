@@ -0,0 +1,116 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// ServeResponse writes a GraphQL result as the response to r. It is like
+// WriteResponse, but successful responses to GET and HEAD requests also
+// receive caching headers: Cache-Control from the response's CachePolicy (if
+// it is cacheable), an ETag computed from the body, and Vary: Accept. If the
+// request's If-None-Match header matches the ETag, then ServeResponse sends a
+// 304 Not Modified status with no body.
+func ServeResponse(w http.ResponseWriter, r *http.Request, response graphql.Response) {
+	serveMediaResponse(w, r, jsonMediaType, http.StatusOK, response)
+}
+
+func serveMediaResponse(w http.ResponseWriter, r *http.Request, mediaType string, statusCode int, response graphql.Response) {
+	if statusCode != http.StatusOK || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		writeMediaResponse(w, mediaType, statusCode, response)
+		return
+	}
+	payload, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "GraphQL marshal error", http.StatusInternalServerError)
+		return
+	}
+	etag := responseETag(mediaType, payload)
+	h := w.Header()
+	if cc := cacheControl(response.CachePolicy); cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	h.Set("ETag", etag)
+	addVary(h, "Accept")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writePayload(w, mediaType, statusCode, payload)
+}
+
+// cacheControl returns the Cache-Control header value for a policy or the
+// empty string if the policy is not cacheable.
+func cacheControl(policy graphql.CachePolicy) string {
+	if !policy.Cacheable() {
+		return ""
+	}
+	// Cache-Control counts whole seconds. Round down so that caches never
+	// keep a response longer than the policy permits.
+	seconds := int64(policy.MaxAge.Seconds())
+	if seconds <= 0 {
+		return ""
+	}
+	scope := "public"
+	if policy.Scope == graphql.CachePrivate {
+		scope = "private"
+	}
+	return "max-age=" + strconv.FormatInt(seconds, 10) + ", " + scope
+}
+
+// responseETag returns a strong entity tag for a response body. The media
+// type is included because the same body is sent with either GraphQL
+// response media type.
+func responseETag(mediaType string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(mediaType))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+// using the weak comparison from RFC 7232.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// addVary adds a field name to the Vary header unless it is already listed.
+func addVary(h http.Header, field string) {
+	for _, v := range h["Vary"] {
+		for _, name := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(name), field) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", field)
+}
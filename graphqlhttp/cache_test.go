@@ -0,0 +1,190 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type cacheQuery struct{}
+
+func (cacheQuery) Greeting() string { return "hi" }
+
+func (cacheQuery) Secret() string { return "xyzzy" }
+
+func (cacheQuery) Now() string { return "noon" }
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		policy graphql.CachePolicy
+		want   string
+	}{
+		{graphql.CachePolicy{}, ""},
+		{graphql.CachePolicy{MaxAge: 500 * time.Millisecond}, ""},
+		{graphql.CachePolicy{MaxAge: time.Minute}, "max-age=60, public"},
+		{graphql.CachePolicy{MaxAge: 90500 * time.Millisecond}, "max-age=90, public"},
+		{graphql.CachePolicy{MaxAge: time.Minute, Scope: graphql.CachePrivate}, "max-age=60, private"},
+	}
+	for _, test := range tests {
+		if got := cacheControl(test.policy); got != test.want {
+			t.Errorf("cacheControl(%+v) = %q; want %q", test.policy, got, test.want)
+		}
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	const etag = `"abc"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{"", false},
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`"xyz"`, false},
+		{`*`, true},
+		{`abc`, false},
+	}
+	for _, test := range tests {
+		if got := etagMatches(test.ifNoneMatch, etag); got != test.want {
+			t.Errorf("etagMatches(%q, %q) = %t; want %t", test.ifNoneMatch, etag, got, test.want)
+		}
+	}
+}
+
+func TestHandlerCaching(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		enum CacheControlScope { PUBLIC, PRIVATE }
+
+		directive @cacheControl(maxAge: Int, scope: CacheControlScope) on FIELD_DEFINITION | OBJECT | UNION
+
+		type Query {
+			greeting: String! @cacheControl(maxAge: 60)
+			secret: String! @cacheControl(maxAge: 30, scope: PRIVATE)
+			now: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, cacheQuery{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(server)
+	get := func(query, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{"query": {query}}.Encode(), nil)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Public", func(t *testing.T) {
+		w := get("{ greeting }", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d (body: %s)", w.Code, http.StatusOK, w.Body)
+		}
+		if got, want := w.Header().Get("Cache-Control"), "max-age=60, public"; got != want {
+			t.Errorf("Cache-Control = %q; want %q", got, want)
+		}
+		if got := w.Header().Get("ETag"); got == "" {
+			t.Error("ETag not set")
+		}
+		if got := w.Header().Get("Vary"); got != "Accept" {
+			t.Errorf("Vary = %q; want \"Accept\"", got)
+		}
+	})
+	t.Run("Private", func(t *testing.T) {
+		w := get("{ greeting secret }", "")
+		if got, want := w.Header().Get("Cache-Control"), "max-age=30, private"; got != want {
+			t.Errorf("Cache-Control = %q; want %q", got, want)
+		}
+	})
+	t.Run("NotCacheable", func(t *testing.T) {
+		w := get("{ greeting now }", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d (body: %s)", w.Code, http.StatusOK, w.Body)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "" {
+			t.Errorf("Cache-Control = %q; want empty", got)
+		}
+		if got := w.Header().Get("ETag"); got == "" {
+			t.Error("ETag not set")
+		}
+	})
+	t.Run("NotModified", func(t *testing.T) {
+		etag := get("{ greeting }", "").Header().Get("ETag")
+		w := get("{ greeting }", `"stale", `+etag)
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("status = %d; want %d", w.Code, http.StatusNotModified)
+		}
+		if w.Body.Len() > 0 {
+			t.Errorf("body = %q; want empty", w.Body)
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("ETag = %q; want %q", got, etag)
+		}
+		if got, want := w.Header().Get("Cache-Control"), "max-age=60, public"; got != want {
+			t.Errorf("Cache-Control = %q; want %q", got, want)
+		}
+	})
+	t.Run("Modified", func(t *testing.T) {
+		etag := get("{ greeting }", "").Header().Get("ETag")
+		w := get("{ greeting secret }", etag)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusOK)
+		}
+	})
+	t.Run("POST", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ greeting }"}`))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("If-None-Match", "*")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusOK)
+		}
+		for _, name := range []string{"Cache-Control", "ETag"} {
+			if got := w.Header().Get(name); got != "" {
+				t.Errorf("%s = %q; want empty", name, got)
+			}
+		}
+	})
+	t.Run("ValidationError", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/graphql?"+url.Values{"query": {"{ nope }"}}.Encode(), nil)
+		r.Header.Set("Accept", graphqlResponseMediaType)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusBadRequest)
+		}
+		if got := w.Header().Get("ETag"); got != "" {
+			t.Errorf("ETag = %q; want empty", got)
+		}
+	})
+}
@@ -49,3 +49,31 @@ func Example() {
 	http.Handle("/graphql", graphqlhttp.NewHandler(server))
 	http.ListenAndServe(":8080", nil)
 }
+
+func ExampleExplorerHandler() {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+	`, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, &Query{Greeting: "Hello, World!"}, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Browsers that navigate to /graphql see the explorer page, while
+	// GraphQL clients get GraphQL responses. The page's script and styles
+	// are served under /graphql/assets/.
+	h := graphqlhttp.NewHandlerWithOptions(server, &graphqlhttp.HandlerOptions{
+		Explorer: graphqlhttp.NewExplorerHandler(&graphqlhttp.ExplorerOptions{
+			Title:   "Greeting API",
+			Headers: map[string]string{"X-Client-Name": "explorer"},
+		}),
+	})
+	http.Handle("/graphql", h)
+	http.Handle("/graphql/", h)
+	http.ListenAndServe(":8080", nil)
+}
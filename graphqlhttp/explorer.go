@@ -0,0 +1,243 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go run gen_explorer_assets.go
+
+// ExplorerHandler serves an interactive GraphQL explorer page in the style of
+// GraphiQL. The page is self-contained: its script and styles are vendored in
+// this package and served by the handler under the assets/ path below the
+// page, so it does not load anything from a CDN. Register the handler for a
+// subtree (like "/explorer/") or, when it is used as HandlerOptions.Explorer,
+// register the Handler for both the endpoint and its subtree (like "/graphql"
+// and "/graphql/"). The page can run queries and mutations over HTTP, run
+// subscriptions over the graphql-transport-ws WebSocket subprotocol, and
+// browse the schema using introspection.
+type ExplorerHandler struct {
+	title  string
+	config explorerConfig
+}
+
+// ExplorerOptions specifies how the explorer page connects to a server.
+// nil is treated the same as the zero value.
+type ExplorerOptions struct {
+	// Title is the page's title. If empty, "GraphQL Explorer" is used.
+	Title string
+
+	// Endpoint is the URL that queries and mutations are sent to. It may be
+	// relative to the page. If empty, the page's own URL is used, which
+	// works when the explorer is served through HandlerOptions.Explorer.
+	Endpoint string
+
+	// SubscriptionEndpoint is the WebSocket URL (usually ws: or wss:) that
+	// subscriptions are sent to, as served by a graphqlws.Handler. It may
+	// also be a path, in which case it is resolved against the page's host.
+	// If empty, the page does not run subscriptions.
+	SubscriptionEndpoint string
+
+	// Headers holds the HTTP headers that the page sends with each request
+	// by default. Users can edit them on the page. They are also sent as the
+	// connection_init payload for subscriptions.
+	Headers map[string]string
+
+	// DefaultQuery is the query shown when the page is first opened. A query
+	// URL parameter takes precedence.
+	DefaultQuery string
+}
+
+// NewExplorerHandler returns a new explorer handler. opts may be nil, which is
+// equivalent to passing a pointer to the zero value.
+func NewExplorerHandler(opts *ExplorerOptions) *ExplorerHandler {
+	if opts == nil {
+		opts = new(ExplorerOptions)
+	}
+	config := explorerConfig{
+		Endpoint:             opts.Endpoint,
+		SubscriptionEndpoint: opts.SubscriptionEndpoint,
+		Headers:              opts.Headers,
+		DefaultQuery:         opts.DefaultQuery,
+	}
+	if config.Headers == nil {
+		config.Headers = map[string]string{}
+	}
+	title := opts.Title
+	if title == "" {
+		title = "GraphQL Explorer"
+	}
+	return &ExplorerHandler{title: title, config: config}
+}
+
+// explorerConfig is passed to the page's script.
+type explorerConfig struct {
+	Endpoint             string            `json:"endpoint"`
+	SubscriptionEndpoint string            `json:"subscriptionEndpoint"`
+	Headers              map[string]string `json:"headers"`
+	DefaultQuery         string            `json:"defaultQuery"`
+}
+
+// explorerAsset is a file that the explorer page loads. explorer_assets.go
+// holds the contents of the explorer directory, keyed by file name.
+type explorerAsset struct {
+	contentType string
+	etag        string
+	data        string
+}
+
+// explorerAssetDir is the path, relative to the page, that assets are
+// served under.
+const explorerAssetDir = "assets/"
+
+// ServeHTTP serves the explorer page, or one of the page's assets if the
+// request's path ends in assets/ followed by the asset's name.
+func (h *ExplorerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method "+r.Method+" not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if name, asset := findExplorerAsset(r.URL.Path); asset != nil {
+		w.Header().Set("Content-Type", asset.contentType)
+		w.Header().Set("ETag", asset.etag)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, name, time.Time{}, strings.NewReader(asset.data))
+		return
+	}
+	buf := new(bytes.Buffer)
+	err := explorerTemplate.Execute(buf, struct {
+		Title  string
+		Assets string
+		Config explorerConfig
+	}{h.title, explorerAssetBase(r.URL.EscapedPath()), h.config})
+	if err != nil {
+		// The template and its data are fixed, so this is a programming error.
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+// findExplorerAsset returns the asset named by a request path that ends in
+// assets/ followed by the asset's name, or nil if the path does not name an
+// asset.
+func findExplorerAsset(p string) (name string, asset *explorerAsset) {
+	i := strings.LastIndex(p, "/"+explorerAssetDir)
+	if i == -1 {
+		return "", nil
+	}
+	name = p[i+1+len(explorerAssetDir):]
+	asset = explorerAssets[name]
+	if asset == nil {
+		return "", nil
+	}
+	return name, asset
+}
+
+// isExplorerAssetRequest reports whether r requests one of the explorer
+// page's assets from an ExplorerHandler.
+func isExplorerAssetRequest(h http.Handler, r *http.Request) bool {
+	if _, ok := h.(*ExplorerHandler); !ok {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	_, asset := findExplorerAsset(r.URL.Path)
+	return asset != nil
+}
+
+// explorerAssetBase returns the URL, relative to a page served at the given
+// path, that the page's assets are served under.
+func explorerAssetBase(p string) string {
+	if p == "" || strings.HasSuffix(p, "/") {
+		return "./" + explorerAssetDir
+	}
+	return "./" + path.Base(p) + "/" + explorerAssetDir
+}
+
+// wantsHTML reports whether a request is a browser navigation that prefers
+// an HTML page over a GraphQL response.
+func wantsHTML(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	htmlQ, htmlExact := acceptQuality(accept, "text/html")
+	if !htmlExact || htmlQ <= 0 {
+		return false
+	}
+	for _, mediaType := range []string{graphqlResponseMediaType, jsonMediaType} {
+		if q, exact := acceptQuality(accept, mediaType); exact && q >= htmlQ {
+			return false
+		}
+	}
+	return true
+}
+
+// explorerTemplate renders the explorer page. The page's script and styles
+// are in the explorer directory and the script reads its connection settings
+// from the explorer-config element.
+var explorerTemplate = template.Must(template.New("explorer").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Assets}}explorer.css">
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<span id="status"></span>
+<button id="docs-button" type="button">Schema</button>
+<button id="run" class="primary" type="button" title="Run (Ctrl-Enter)">Run</button>
+</header>
+<main>
+<section id="editors">
+<label for="query">Query</label>
+<textarea id="query" spellcheck="false"></textarea>
+<label for="variables">Variables</label>
+<textarea id="variables" spellcheck="false"></textarea>
+<label for="headers">Headers</label>
+<textarea id="headers" spellcheck="false"></textarea>
+</section>
+<section id="result">
+<label>Response</label>
+<pre id="output"></pre>
+</section>
+<section id="docs"></section>
+</main>
+<script id="explorer-config" type="application/json">{{.Config}}</script>
+<script src="{{.Assets}}explorer.js"></script>
+</body>
+</html>
+`))
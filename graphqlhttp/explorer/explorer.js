@@ -0,0 +1,221 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+(function() {
+  "use strict";
+  var config = JSON.parse(document.getElementById("explorer-config").textContent);
+  var $ = function(id) { return document.getElementById(id); };
+  var query = $("query"), variables = $("variables"), headers = $("headers");
+  var output = $("output"), status = $("status"), runButton = $("run");
+  var storageKey = "graphql-explorer:" + location.pathname;
+  var socket = null;
+
+  var params = new URLSearchParams(location.search);
+  var saved = {};
+  try { saved = JSON.parse(localStorage.getItem(storageKey)) || {}; } catch (e) {}
+  query.value = params.get("query") || saved.query || config.defaultQuery || "{\n  __typename\n}\n";
+  variables.value = params.get("variables") || saved.variables || "";
+  headers.value = saved.headers || JSON.stringify(config.headers, null, 2);
+
+  function save() {
+    try {
+      localStorage.setItem(storageKey, JSON.stringify({
+        query: query.value, variables: variables.value, headers: headers.value
+      }));
+    } catch (e) {}
+  }
+
+  function parseJSON(text, what) {
+    if (!text.trim()) { return {}; }
+    try { return JSON.parse(text); } catch (e) { throw new Error(what + ": " + e.message); }
+  }
+
+  function show(value) {
+    output.textContent = typeof value === "string" ? value : JSON.stringify(value, null, 2);
+  }
+
+  function endpoint() {
+    return config.endpoint || location.pathname;
+  }
+
+  function isSubscription(text) {
+    var m = /\b(query|mutation|subscription)\b/.exec(text.replace(/#[^\n]*/g, ""));
+    return m !== null && m[1] === "subscription";
+  }
+
+  function fetchGraphQL(body, hdrs) {
+    var h = { "Content-Type": "application/json", "Accept": "application/graphql-response+json, application/json" };
+    Object.keys(hdrs).forEach(function(k) { h[k] = hdrs[k]; });
+    return fetch(endpoint(), { method: "POST", headers: h, body: JSON.stringify(body), credentials: "same-origin" })
+      .then(function(resp) {
+        return resp.text().then(function(text) {
+          try { return { status: resp.status, body: JSON.parse(text) }; }
+          catch (e) { return { status: resp.status, body: text }; }
+        });
+      });
+  }
+
+  function subscribe(body, hdrs) {
+    var url = new URL(config.subscriptionEndpoint, location.href);
+    if (url.protocol === "http:") { url.protocol = "ws:"; }
+    if (url.protocol === "https:") { url.protocol = "wss:"; }
+    var events = [];
+    socket = new WebSocket(url.href, "graphql-transport-ws");
+    runButton.textContent = "Stop";
+    status.textContent = "Connecting...";
+    socket.onopen = function() {
+      socket.send(JSON.stringify({ type: "connection_init", payload: hdrs }));
+    };
+    socket.onmessage = function(ev) {
+      var msg = JSON.parse(ev.data);
+      switch (msg.type) {
+      case "connection_ack":
+        status.textContent = "Subscribed";
+        socket.send(JSON.stringify({ id: "1", type: "subscribe", payload: body }));
+        break;
+      case "ping":
+        socket.send(JSON.stringify({ type: "pong" }));
+        break;
+      case "next":
+        events.unshift(msg.payload);
+        show(events);
+        break;
+      case "error":
+        show({ errors: msg.payload });
+        break;
+      case "complete":
+        stop("Complete");
+        break;
+      }
+    };
+    socket.onclose = function(ev) {
+      if (socket) { stop(ev.code === 1000 ? "Complete" : "Closed (" + ev.code + (ev.reason ? ": " + ev.reason : "") + ")"); }
+    };
+  }
+
+  function stop(message) {
+    var s = socket;
+    socket = null;
+    if (s && s.readyState <= 1) { s.close(1000); }
+    runButton.textContent = "Run";
+    status.textContent = message || "";
+  }
+
+  function run() {
+    if (socket) { stop("Stopped"); return; }
+    save();
+    var body, hdrs;
+    try {
+      body = { query: query.value };
+      var vars = parseJSON(variables.value, "Variables");
+      if (Object.keys(vars).length) { body.variables = vars; }
+      hdrs = parseJSON(headers.value, "Headers");
+    } catch (e) {
+      show(e.message);
+      return;
+    }
+    if (isSubscription(query.value)) {
+      if (!config.subscriptionEndpoint) {
+        show("Subscriptions are not available: no subscription endpoint is configured.");
+        return;
+      }
+      subscribe(body, hdrs);
+      return;
+    }
+    runButton.disabled = true;
+    status.textContent = "Running...";
+    var start = Date.now();
+    fetchGraphQL(body, hdrs).then(function(result) {
+      show(result.body);
+      status.textContent = result.status + " in " + (Date.now() - start) + " ms";
+    }, function(err) {
+      show(String(err));
+      status.textContent = "";
+    }).then(function() { runButton.disabled = false; });
+  }
+
+  var introspectionQuery = "{ __schema { queryType { name } mutationType { name } subscriptionType { name } " +
+    "types { kind name description fields { name description args { name type { ...T } } type { ...T } } " +
+    "inputFields { name type { ...T } } enumValues { name } possibleTypes { name } } } }\n" +
+    "fragment T on __Type { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }";
+
+  function typeName(t) {
+    if (t.kind === "NON_NULL") { return typeName(t.ofType) + "!"; }
+    if (t.kind === "LIST") { return "[" + typeName(t.ofType) + "]"; }
+    return t.name;
+  }
+
+  function element(tag, text, className) {
+    var e = document.createElement(tag);
+    if (text) { e.textContent = text; }
+    if (className) { e.className = className; }
+    return e;
+  }
+
+  function showDocs() {
+    var docs = $("docs");
+    if (docs.classList.toggle("open") === false) { return; }
+    docs.textContent = "Loading...";
+    var hdrs;
+    try { hdrs = parseJSON(headers.value, "Headers"); } catch (e) { docs.textContent = e.message; return; }
+    fetchGraphQL({ query: introspectionQuery }, hdrs).then(function(result) {
+      docs.textContent = "";
+      if (!result.body || !result.body.data) {
+        docs.textContent = "Could not load schema: " + JSON.stringify(result.body);
+        return;
+      }
+      var schema = result.body.data.__schema;
+      var roots = [schema.queryType, schema.mutationType, schema.subscriptionType]
+        .filter(Boolean).map(function(t) { return t.name; });
+      var types = schema.types.filter(function(t) { return t.name.indexOf("__") !== 0; });
+      types.sort(function(a, b) {
+        var ra = roots.indexOf(a.name), rb = roots.indexOf(b.name);
+        if (ra !== rb) { return (ra < 0 ? roots.length : ra) - (rb < 0 ? roots.length : rb); }
+        return a.name < b.name ? -1 : a.name > b.name ? 1 : 0;
+      });
+      types.forEach(function(t) {
+        docs.appendChild(element("h2", t.kind.toLowerCase().replace("_", " ") + " " + t.name));
+        if (t.description) { docs.appendChild(element("div", t.description, "desc")); }
+        var list = element("ul");
+        (t.fields || []).forEach(function(f) {
+          var args = (f.args || []).map(function(a) { return a.name + ": " + typeName(a.type); });
+          var li = element("li", f.name + (args.length ? "(" + args.join(", ") + ")" : "") + ": " + typeName(f.type));
+          if (f.description) { li.appendChild(element("div", f.description, "desc")); }
+          list.appendChild(li);
+        });
+        (t.inputFields || []).forEach(function(f) { list.appendChild(element("li", f.name + ": " + typeName(f.type))); });
+        (t.enumValues || []).forEach(function(v) { list.appendChild(element("li", v.name)); });
+        (t.possibleTypes || []).forEach(function(p) { list.appendChild(element("li", p.name)); });
+        docs.appendChild(list);
+      });
+    }, function(err) { docs.textContent = String(err); });
+  }
+
+  runButton.addEventListener("click", run);
+  $("docs-button").addEventListener("click", showDocs);
+  [query, variables, headers].forEach(function(t) {
+    t.addEventListener("keydown", function(ev) {
+      if (ev.key === "Enter" && (ev.ctrlKey || ev.metaKey)) { ev.preventDefault(); run(); }
+      if (ev.key === "Tab" && !ev.shiftKey) {
+        ev.preventDefault();
+        var s = t.selectionStart;
+        t.value = t.value.slice(0, s) + "  " + t.value.slice(t.selectionEnd);
+        t.selectionStart = t.selectionEnd = s + 2;
+      }
+    });
+    t.addEventListener("change", save);
+  });
+})();
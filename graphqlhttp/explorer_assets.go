@@ -0,0 +1,278 @@
+// Code generated by gen_explorer_assets.go. DO NOT EDIT.
+
+package graphqlhttp
+
+var explorerAssets = map[string]*explorerAsset{
+	"explorer.css": {
+		contentType: "text/css; charset=utf-8",
+		etag:        "\"f8ed97a2a8870458d6bdf5969b96c051\"",
+		data: "" +
+			"/*\n" +
+			" * Copyright 2019 Ross Light\n" +
+			" *\n" +
+			" * Licensed under the Apache License, Version 2.0 (the \"License\");\n" +
+			" * you may not use this file except in compliance with the License.\n" +
+			" * You may obtain a copy of the License at\n" +
+			" *\n" +
+			" *     https://www.apache.org/licenses/LICENSE-2.0\n" +
+			" *\n" +
+			" * Unless required by applicable law or agreed to in writing, software\n" +
+			" * distributed under the License is distributed on an \"AS IS\" BASIS,\n" +
+			" * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n" +
+			" * See the License for the specific language governing permissions and\n" +
+			" * limitations under the License.\n" +
+			" *\n" +
+			" * SPDX-License-Identifier: Apache-2.0\n" +
+			" */\n" +
+			"\n" +
+			"* { box-sizing: border-box; }\n" +
+			"html, body { height: 100%; margin: 0; }\n" +
+			"body { display: flex; flex-direction: column; font: 14px system-ui, sans-serif; color: #1b1f23; background: #f6f7f9; }\n" +
+			"header { display: flex; align-items: center; gap: 8px; padding: 8px 12px; background: #fff; border-bottom: 1px solid #d8dde3; }\n" +
+			"header h1 { flex: 1; margin: 0; font-size: 16px; font-weight: 600; }\n" +
+			"button { padding: 6px 14px; font: inherit; border: 1px solid #b6bec8; border-radius: 4px; background: #fff; cursor: pointer; }\n" +
+			"button.primary { color: #fff; background: #e10098; border-color: #c10083; }\n" +
+			"button:disabled { opacity: 0.5; cursor: default; }\n" +
+			"main { flex: 1; display: flex; min-height: 0; }\n" +
+			"section { display: flex; flex-direction: column; min-width: 0; min-height: 0; border-right: 1px solid #d8dde3; }\n" +
+			"#editors { flex: 1; }\n" +
+			"#result { flex: 1; }\n" +
+			"#docs { width: 320px; display: none; overflow: auto; padding: 8px 12px; background: #fff; }\n" +
+			"#docs.open { display: block; }\n" +
+			"label { padding: 4px 12px; font-size: 12px; font-weight: 600; text-transform: uppercase; color: #5c6670; background: #eef0f3; border-bottom: 1px solid #d8dde3; }\n" +
+			"textarea, pre { flex: 1; margin: 0; padding: 8px 12px; font: 13px/1.45 ui-monospace, SFMono-Regular, Menlo, monospace; border: 0; outline: 0; resize: none; background: #fff; overflow: auto; white-space: pre; }\n" +
+			"#query { flex: 3; }\n" +
+			"#variables, #headers { flex: 1; border-top: 1px solid #d8dde3; }\n" +
+			"#status { font-size: 12px; color: #5c6670; }\n" +
+			"#docs h2 { margin: 12px 0 4px; font-size: 14px; }\n" +
+			"#docs ul { margin: 0; padding-left: 16px; }\n" +
+			"#docs li { margin: 2px 0; font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 12px; }\n" +
+			"#docs .desc { color: #5c6670; font-family: system-ui, sans-serif; }\n",
+	},
+	"explorer.js": {
+		contentType: "text/javascript; charset=utf-8",
+		etag:        "\"8de8a22bf7a93356282cf58a72de1463\"",
+		data: "" +
+			"// Copyright 2019 Ross Light\n" +
+			"//\n" +
+			"// Licensed under the Apache License, Version 2.0 (the \"License\");\n" +
+			"// you may not use this file except in compliance with the License.\n" +
+			"// You may obtain a copy of the License at\n" +
+			"//\n" +
+			"//     https://www.apache.org/licenses/LICENSE-2.0\n" +
+			"//\n" +
+			"// Unless required by applicable law or agreed to in writing, software\n" +
+			"// distributed under the License is distributed on an \"AS IS\" BASIS,\n" +
+			"// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n" +
+			"// See the License for the specific language governing permissions and\n" +
+			"// limitations under the License.\n" +
+			"//\n" +
+			"// SPDX-License-Identifier: Apache-2.0\n" +
+			"\n" +
+			"(function() {\n" +
+			"  \"use strict\";\n" +
+			"  var config = JSON.parse(document.getElementById(\"explorer-config\").textContent);\n" +
+			"  var $ = function(id) { return document.getElementById(id); };\n" +
+			"  var query = $(\"query\"), variables = $(\"variables\"), headers = $(\"headers\");\n" +
+			"  var output = $(\"output\"), status = $(\"status\"), runButton = $(\"run\");\n" +
+			"  var storageKey = \"graphql-explorer:\" + location.pathname;\n" +
+			"  var socket = null;\n" +
+			"\n" +
+			"  var params = new URLSearchParams(location.search);\n" +
+			"  var saved = {};\n" +
+			"  try { saved = JSON.parse(localStorage.getItem(storageKey)) || {}; } catch (e) {}\n" +
+			"  query.value = params.get(\"query\") || saved.query || config.defaultQuery || \"{\\n  __typename\\n}\\n\";\n" +
+			"  variables.value = params.get(\"variables\") || saved.variables || \"\";\n" +
+			"  headers.value = saved.headers || JSON.stringify(config.headers, null, 2);\n" +
+			"\n" +
+			"  function save() {\n" +
+			"    try {\n" +
+			"      localStorage.setItem(storageKey, JSON.stringify({\n" +
+			"        query: query.value, variables: variables.value, headers: headers.value\n" +
+			"      }));\n" +
+			"    } catch (e) {}\n" +
+			"  }\n" +
+			"\n" +
+			"  function parseJSON(text, what) {\n" +
+			"    if (!text.trim()) { return {}; }\n" +
+			"    try { return JSON.parse(text); } catch (e) { throw new Error(what + \": \" + e.message); }\n" +
+			"  }\n" +
+			"\n" +
+			"  function show(value) {\n" +
+			"    output.textContent = typeof value === \"string\" ? value : JSON.stringify(value, null, 2);\n" +
+			"  }\n" +
+			"\n" +
+			"  function endpoint() {\n" +
+			"    return config.endpoint || location.pathname;\n" +
+			"  }\n" +
+			"\n" +
+			"  function isSubscription(text) {\n" +
+			"    var m = /\\b(query|mutation|subscription)\\b/.exec(text.replace(/#[^\\n]*/g, \"\"));\n" +
+			"    return m !== null && m[1] === \"subscription\";\n" +
+			"  }\n" +
+			"\n" +
+			"  function fetchGraphQL(body, hdrs) {\n" +
+			"    var h = { \"Content-Type\": \"application/json\", \"Accept\": \"application/graphql-response+json, application/json\" };\n" +
+			"    Object.keys(hdrs).forEach(function(k) { h[k] = hdrs[k]; });\n" +
+			"    return fetch(endpoint(), { method: \"POST\", headers: h, body: JSON.stringify(body), credentials: \"same-origin\" })\n" +
+			"      .then(function(resp) {\n" +
+			"        return resp.text().then(function(text) {\n" +
+			"          try { return { status: resp.status, body: JSON.parse(text) }; }\n" +
+			"          catch (e) { return { status: resp.status, body: text }; }\n" +
+			"        });\n" +
+			"      });\n" +
+			"  }\n" +
+			"\n" +
+			"  function subscribe(body, hdrs) {\n" +
+			"    var url = new URL(config.subscriptionEndpoint, location.href);\n" +
+			"    if (url.protocol === \"http:\") { url.protocol = \"ws:\"; }\n" +
+			"    if (url.protocol === \"https:\") { url.protocol = \"wss:\"; }\n" +
+			"    var events = [];\n" +
+			"    socket = new WebSocket(url.href, \"graphql-transport-ws\");\n" +
+			"    runButton.textContent = \"Stop\";\n" +
+			"    status.textContent = \"Connecting...\";\n" +
+			"    socket.onopen = function() {\n" +
+			"      socket.send(JSON.stringify({ type: \"connection_init\", payload: hdrs }));\n" +
+			"    };\n" +
+			"    socket.onmessage = function(ev) {\n" +
+			"      var msg = JSON.parse(ev.data);\n" +
+			"      switch (msg.type) {\n" +
+			"      case \"connection_ack\":\n" +
+			"        status.textContent = \"Subscribed\";\n" +
+			"        socket.send(JSON.stringify({ id: \"1\", type: \"subscribe\", payload: body }));\n" +
+			"        break;\n" +
+			"      case \"ping\":\n" +
+			"        socket.send(JSON.stringify({ type: \"pong\" }));\n" +
+			"        break;\n" +
+			"      case \"next\":\n" +
+			"        events.unshift(msg.payload);\n" +
+			"        show(events);\n" +
+			"        break;\n" +
+			"      case \"error\":\n" +
+			"        show({ errors: msg.payload });\n" +
+			"        break;\n" +
+			"      case \"complete\":\n" +
+			"        stop(\"Complete\");\n" +
+			"        break;\n" +
+			"      }\n" +
+			"    };\n" +
+			"    socket.onclose = function(ev) {\n" +
+			"      if (socket) { stop(ev.code === 1000 ? \"Complete\" : \"Closed (\" + ev.code + (ev.reason ? \": \" + ev.reason : \"\") + \")\"); }\n" +
+			"    };\n" +
+			"  }\n" +
+			"\n" +
+			"  function stop(message) {\n" +
+			"    var s = socket;\n" +
+			"    socket = null;\n" +
+			"    if (s && s.readyState <= 1) { s.close(1000); }\n" +
+			"    runButton.textContent = \"Run\";\n" +
+			"    status.textContent = message || \"\";\n" +
+			"  }\n" +
+			"\n" +
+			"  function run() {\n" +
+			"    if (socket) { stop(\"Stopped\"); return; }\n" +
+			"    save();\n" +
+			"    var body, hdrs;\n" +
+			"    try {\n" +
+			"      body = { query: query.value };\n" +
+			"      var vars = parseJSON(variables.value, \"Variables\");\n" +
+			"      if (Object.keys(vars).length) { body.variables = vars; }\n" +
+			"      hdrs = parseJSON(headers.value, \"Headers\");\n" +
+			"    } catch (e) {\n" +
+			"      show(e.message);\n" +
+			"      return;\n" +
+			"    }\n" +
+			"    if (isSubscription(query.value)) {\n" +
+			"      if (!config.subscriptionEndpoint) {\n" +
+			"        show(\"Subscriptions are not available: no subscription endpoint is configured.\");\n" +
+			"        return;\n" +
+			"      }\n" +
+			"      subscribe(body, hdrs);\n" +
+			"      return;\n" +
+			"    }\n" +
+			"    runButton.disabled = true;\n" +
+			"    status.textContent = \"Running...\";\n" +
+			"    var start = Date.now();\n" +
+			"    fetchGraphQL(body, hdrs).then(function(result) {\n" +
+			"      show(result.body);\n" +
+			"      status.textContent = result.status + \" in \" + (Date.now() - start) + \" ms\";\n" +
+			"    }, function(err) {\n" +
+			"      show(String(err));\n" +
+			"      status.textContent = \"\";\n" +
+			"    }).then(function() { runButton.disabled = false; });\n" +
+			"  }\n" +
+			"\n" +
+			"  var introspectionQuery = \"{ __schema { queryType { name } mutationType { name } subscriptionType { name } \" +\n" +
+			"    \"types { kind name description fields { name description args { name type { ...T } } type { ...T } } \" +\n" +
+			"    \"inputFields { name type { ...T } } enumValues { name } possibleTypes { name } } } }\\n\" +\n" +
+			"    \"fragment T on __Type { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }\";\n" +
+			"\n" +
+			"  function typeName(t) {\n" +
+			"    if (t.kind === \"NON_NULL\") { return typeName(t.ofType) + \"!\"; }\n" +
+			"    if (t.kind === \"LIST\") { return \"[\" + typeName(t.ofType) + \"]\"; }\n" +
+			"    return t.name;\n" +
+			"  }\n" +
+			"\n" +
+			"  function element(tag, text, className) {\n" +
+			"    var e = document.createElement(tag);\n" +
+			"    if (text) { e.textContent = text; }\n" +
+			"    if (className) { e.className = className; }\n" +
+			"    return e;\n" +
+			"  }\n" +
+			"\n" +
+			"  function showDocs() {\n" +
+			"    var docs = $(\"docs\");\n" +
+			"    if (docs.classList.toggle(\"open\") === false) { return; }\n" +
+			"    docs.textContent = \"Loading...\";\n" +
+			"    var hdrs;\n" +
+			"    try { hdrs = parseJSON(headers.value, \"Headers\"); } catch (e) { docs.textContent = e.message; return; }\n" +
+			"    fetchGraphQL({ query: introspectionQuery }, hdrs).then(function(result) {\n" +
+			"      docs.textContent = \"\";\n" +
+			"      if (!result.body || !result.body.data) {\n" +
+			"        docs.textContent = \"Could not load schema: \" + JSON.stringify(result.body);\n" +
+			"        return;\n" +
+			"      }\n" +
+			"      var schema = result.body.data.__schema;\n" +
+			"      var roots = [schema.queryType, schema.mutationType, schema.subscriptionType]\n" +
+			"        .filter(Boolean).map(function(t) { return t.name; });\n" +
+			"      var types = schema.types.filter(function(t) { return t.name.indexOf(\"__\") !== 0; });\n" +
+			"      types.sort(function(a, b) {\n" +
+			"        var ra = roots.indexOf(a.name), rb = roots.indexOf(b.name);\n" +
+			"        if (ra !== rb) { return (ra < 0 ? roots.length : ra) - (rb < 0 ? roots.length : rb); }\n" +
+			"        return a.name < b.name ? -1 : a.name > b.name ? 1 : 0;\n" +
+			"      });\n" +
+			"      types.forEach(function(t) {\n" +
+			"        docs.appendChild(element(\"h2\", t.kind.toLowerCase().replace(\"_\", \" \") + \" \" + t.name));\n" +
+			"        if (t.description) { docs.appendChild(element(\"div\", t.description, \"desc\")); }\n" +
+			"        var list = element(\"ul\");\n" +
+			"        (t.fields || []).forEach(function(f) {\n" +
+			"          var args = (f.args || []).map(function(a) { return a.name + \": \" + typeName(a.type); });\n" +
+			"          var li = element(\"li\", f.name + (args.length ? \"(\" + args.join(\", \") + \")\" : \"\") + \": \" + typeName(f.type));\n" +
+			"          if (f.description) { li.appendChild(element(\"div\", f.description, \"desc\")); }\n" +
+			"          list.appendChild(li);\n" +
+			"        });\n" +
+			"        (t.inputFields || []).forEach(function(f) { list.appendChild(element(\"li\", f.name + \": \" + typeName(f.type))); });\n" +
+			"        (t.enumValues || []).forEach(function(v) { list.appendChild(element(\"li\", v.name)); });\n" +
+			"        (t.possibleTypes || []).forEach(function(p) { list.appendChild(element(\"li\", p.name)); });\n" +
+			"        docs.appendChild(list);\n" +
+			"      });\n" +
+			"    }, function(err) { docs.textContent = String(err); });\n" +
+			"  }\n" +
+			"\n" +
+			"  runButton.addEventListener(\"click\", run);\n" +
+			"  $(\"docs-button\").addEventListener(\"click\", showDocs);\n" +
+			"  [query, variables, headers].forEach(function(t) {\n" +
+			"    t.addEventListener(\"keydown\", function(ev) {\n" +
+			"      if (ev.key === \"Enter\" && (ev.ctrlKey || ev.metaKey)) { ev.preventDefault(); run(); }\n" +
+			"      if (ev.key === \"Tab\" && !ev.shiftKey) {\n" +
+			"        ev.preventDefault();\n" +
+			"        var s = t.selectionStart;\n" +
+			"        t.value = t.value.slice(0, s) + \"  \" + t.value.slice(t.selectionEnd);\n" +
+			"        t.selectionStart = t.selectionEnd = s + 2;\n" +
+			"      }\n" +
+			"    });\n" +
+			"    t.addEventListener(\"change\", save);\n" +
+			"  });\n" +
+			"})();\n",
+	},
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+func TestExplorerHandler(t *testing.T) {
+	h := NewExplorerHandler(&ExplorerOptions{
+		Title:                "<Acme> API",
+		Endpoint:             "/api/graphql",
+		SubscriptionEndpoint: "wss://example.com/subscriptions",
+		Headers:              map[string]string{"Authorization": "Bearer </script>"},
+	})
+
+	t.Run("GET", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/explorer", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusOK)
+		}
+		if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+			t.Errorf("Content-Type = %q; want %q", got, want)
+		}
+		body := w.Body.String()
+		for _, want := range []string{
+			"<title>&lt;Acme&gt; API</title>",
+			`"endpoint":"/api/graphql"`,
+			`"subscriptionEndpoint":"wss://example.com/subscriptions"`,
+			`"Authorization":"Bearer \u003c/script\u003e"`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("page does not contain %s", want)
+			}
+		}
+		if n := strings.Count(body, "</script>"); n != 2 {
+			t.Errorf("page contains %d closing script tags; want 2", n)
+		}
+		for _, want := range []string{
+			`<link rel="stylesheet" href="./explorer/assets/explorer.css">`,
+			`<script src="./explorer/assets/explorer.js"></script>`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("page does not contain %s", want)
+			}
+		}
+		for _, external := range []string{`src="http`, `href="http`, `src="//`, `href="//`} {
+			if strings.Contains(body, external) {
+				t.Errorf("page loads external assets (contains %s)", external)
+			}
+		}
+	})
+	t.Run("AssetBase", func(t *testing.T) {
+		tests := []struct {
+			path string
+			want string
+		}{
+			{"/", `href="./assets/explorer.css"`},
+			{"/explorer/", `href="./assets/explorer.css"`},
+			{"/api/graphql", `href="./graphql/assets/explorer.css"`},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if body := w.Body.String(); !strings.Contains(body, test.want) {
+				t.Errorf("page at %s does not contain %s", test.path, test.want)
+			}
+		}
+	})
+	t.Run("Assets", func(t *testing.T) {
+		tests := []struct {
+			path        string
+			name        string
+			contentType string
+		}{
+			{"/explorer/assets/explorer.css", "explorer.css", "text/css; charset=utf-8"},
+			{"/api/graphql/assets/explorer.js", "explorer.js", "text/javascript; charset=utf-8"},
+		}
+		for _, test := range tests {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("GET %s status = %d; want %d", test.path, w.Code, http.StatusOK)
+				continue
+			}
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("GET %s Content-Type = %q; want %q", test.path, got, test.contentType)
+			}
+			if got, want := w.Body.String(), explorerAssets[test.name].data; got != want {
+				t.Errorf("GET %s body does not match %s", test.path, test.name)
+			}
+
+			// A request with the asset's ETag should not resend it.
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("GET %s with If-None-Match status = %d; want %d", test.path, w.Code, http.StatusNotModified)
+			}
+		}
+
+		// Unknown names get the page.
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/explorer/assets/nope.js", nil))
+		if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("GET /explorer/assets/nope.js Content-Type = %q; want page", got)
+		}
+	})
+	t.Run("HEAD", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/explorer", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusOK)
+		}
+		if w.Body.Len() > 0 {
+			t.Errorf("body has %d bytes; want empty", w.Body.Len())
+		}
+	})
+	t.Run("POST", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/explorer", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// TestExplorerAssetsGenerated checks that explorer_assets.go is up to date
+// with the explorer directory.
+func TestExplorerAssetsGenerated(t *testing.T) {
+	infos, err := ioutil.ReadDir("explorer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, info := range infos {
+		name := info.Name()
+		names[name] = true
+		data, err := ioutil.ReadFile(filepath.Join("explorer", name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		asset := explorerAssets[name]
+		if asset == nil || asset.data != string(data) {
+			t.Errorf("explorer_assets.go does not match explorer/%s; run go generate", name)
+		}
+	}
+	for name := range explorerAssets {
+		if !names[name] {
+			t.Errorf("explorer_assets.go has %s, which is not in explorer; run go generate", name)
+		}
+	}
+}
+
+func TestHandlerExplorer(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, greeter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlerWithOptions(server, &HandlerOptions{
+		Explorer: NewExplorerHandler(nil),
+	})
+	const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	tests := []struct {
+		name     string
+		method   string
+		accept   string
+		wantHTML bool
+	}{
+		{name: "Browser", method: http.MethodGet, accept: browserAccept, wantHTML: true},
+		{name: "BrowserHEAD", method: http.MethodHead, accept: browserAccept, wantHTML: true},
+		{name: "NoAccept", method: http.MethodGet},
+		{name: "JSON", method: http.MethodGet, accept: "application/json"},
+		{name: "Wildcard", method: http.MethodGet, accept: "*/*"},
+		{name: "JSONPreferred", method: http.MethodGet, accept: "application/json, text/html;q=0.5"},
+		{name: "EqualQuality", method: http.MethodGet, accept: "text/html, application/graphql-response+json"},
+		{name: "POST", method: http.MethodPost, accept: browserAccept},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := "/graphql?" + url.Values{"query": {"{ greeting }"}}.Encode()
+			var r *http.Request
+			if test.method == http.MethodPost {
+				r = httptest.NewRequest(test.method, "/graphql", strings.NewReader(`{"query": "{ greeting }"}`))
+				r.Header.Set("Content-Type", "application/json")
+			} else {
+				r = httptest.NewRequest(test.method, target, nil)
+			}
+			if test.accept != "" {
+				r.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d; want %d (body: %s)", w.Code, http.StatusOK, w.Body)
+			}
+			contentType := w.Header().Get("Content-Type")
+			if gotHTML := strings.HasPrefix(contentType, "text/html"); gotHTML != test.wantHTML {
+				t.Errorf("Content-Type = %q; want HTML = %t", contentType, test.wantHTML)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q; want \"Accept\"", got)
+			}
+		})
+	}
+	t.Run("Asset", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/graphql/assets/explorer.js", nil)
+		r.Header.Set("Accept", "*/*")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d; want %d (body: %s)", w.Code, http.StatusOK, w.Body)
+		}
+		if got, want := w.Header().Get("Content-Type"), "text/javascript; charset=utf-8"; got != want {
+			t.Errorf("Content-Type = %q; want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build ignore
+// +build ignore
+
+// gen_explorer_assets.go generates explorer_assets.go from the files in the
+// explorer directory. Run it with go generate after changing them.
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// contentTypes maps file extensions to the Content-Type headers that assets
+// are served with. mime.TypeByExtension is not used because its results
+// depend on the system.
+var contentTypes = map[string]string{
+	".css": "text/css; charset=utf-8",
+	".js":  "text/javascript; charset=utf-8",
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gen_explorer_assets: ")
+	names, err := filepath.Glob(filepath.Join("explorer", "*"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(names)
+	buf := new(bytes.Buffer)
+	buf.WriteString("// Code generated by gen_explorer_assets.go. DO NOT EDIT.\n\n")
+	buf.WriteString("package graphqlhttp\n\n")
+	buf.WriteString("var explorerAssets = map[string]*explorerAsset{\n")
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		contentType := contentTypes[filepath.Ext(name)]
+		if contentType == "" {
+			log.Fatalf("%s: unknown content type", name)
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(buf, "%q: {\n", filepath.Base(name))
+		fmt.Fprintf(buf, "contentType: %q,\n", contentType)
+		fmt.Fprintf(buf, "etag: %q,\n", fmt.Sprintf(`"%x"`, sum[:16]))
+		buf.WriteString("data: \"\"")
+		for _, line := range strings.SplitAfter(string(data), "\n") {
+			if line != "" {
+				buf.WriteString(" +\n" + strconv.Quote(line))
+			}
+		}
+		buf.WriteString(",\n},\n")
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("explorer_assets.go", src, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
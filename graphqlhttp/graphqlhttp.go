@@ -19,11 +19,13 @@
 package graphqlhttp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"golang.org/x/xerrors"
@@ -33,14 +35,72 @@ import (
 // Handler serves GraphQL HTTP requests by executing them on its server.
 type Handler struct {
 	server *graphql.Server
+	opts   HandlerOptions
+}
+
+// HandlerOptions specifies optional behavior for a handler.
+type HandlerOptions struct {
+	// MaxBatchSize is the maximum number of operations in a batched request,
+	// which is a POST request whose JSON body is an array of requests. The
+	// response to a batched request is an array of responses in the same
+	// order. If MaxBatchSize is zero, then batched requests are rejected.
+	MaxBatchSize int
+
+	// If ConcurrentBatches is true, then the operations in a batched request
+	// run concurrently. Otherwise, they run in order.
+	ConcurrentBatches bool
+
+	// If NewContext is not nil, then it is called to derive the context that
+	// operations run with from the HTTP request's context. It can attach
+	// per-request state like data loader caches.
+	NewContext func(ctx context.Context) context.Context
+
+	// If ShareBatchContext is true, then NewContext is called once for a
+	// batched request and all of its operations share the returned context,
+	// so that data loaders can deduplicate work across operations. Otherwise,
+	// NewContext is called for each operation.
+	ShareBatchContext bool
+
+	// ParseOptions specifies limits for parsing requests, including file
+	// uploads. It may be nil to use the defaults.
+	ParseOptions *ParseOptions
+
+	// If Explorer is not nil, then it serves GET requests whose Accept header
+	// prefers text/html over GraphQL responses, like those from a browser
+	// navigating to the endpoint. It is usually an ExplorerHandler, in which
+	// case requests for the explorer page's assets are sent to it too.
+	Explorer http.Handler
 }
 
 // NewHandler returns a new handler that sends requests to the given server.
 func NewHandler(server *graphql.Server) *Handler {
-	return &Handler{server: server}
+	return NewHandlerWithOptions(server, nil)
+}
+
+// NewHandlerWithOptions returns a new handler like NewHandler with the given
+// options. opts may be nil, which is equivalent to passing a pointer to the
+// zero value.
+func NewHandlerWithOptions(server *graphql.Server, opts *HandlerOptions) *Handler {
+	h := &Handler{server: server}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
 }
 
-// ServeHTTP executes a GraphQL request.
+// ServeHTTP executes a GraphQL request. Batched requests are rejected unless
+// the handler's MaxBatchSize option permits them.
+//
+// ServeHTTP follows the GraphQL over HTTP specification at
+// https://graphql.github.io/graphql-over-http/draft/. Responses use the
+// application/graphql-response+json media type if the request's Accept header
+// prefers it and application/json otherwise. With application/json, requests
+// that could be parsed receive a 200 status code even if they fail validation.
+// With application/graphql-response+json, responses without data receive a 400
+// status code. Errors are always reported as JSON GraphQL responses.
+//
+// Successful GET and HEAD requests receive caching headers as described in
+// ServeResponse.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	const allowedMethods = "GET, HEAD, OPTIONS, POST"
 	if r.Method == http.MethodOptions {
@@ -48,17 +108,56 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	gqlRequest, err := Parse(h.server.Schema(), r)
+	if h.opts.Explorer != nil {
+		addVary(w.Header(), "Accept")
+		if wantsHTML(r) || isExplorerAssetRequest(h.opts.Explorer, r) {
+			h.opts.Explorer.ServeHTTP(w, r)
+			return
+		}
+	}
+	mediaType := negotiateResponseType(r.Header.Get("Accept"))
+	gqlRequests, batch, err := h.parseBatch(r)
 	if err != nil {
-		code := StatusCode(err)
-		if code == http.StatusMethodNotAllowed {
+		if StatusCode(err) == http.StatusMethodNotAllowed {
 			w.Header().Set("Allow", allowedMethods)
 		}
-		http.Error(w, err.Error(), code)
+		writeError(w, mediaType, err)
 		return
 	}
-	gqlResponse := h.server.Execute(r.Context(), gqlRequest)
-	WriteResponse(w, gqlResponse)
+	if batch {
+		h.serveBatch(w, r, mediaType, gqlRequests)
+		return
+	}
+	gqlRequest := gqlRequests[0]
+	if r.Method != http.MethodPost && gqlRequest.ValidatedQuery == nil {
+		if query := h.server.TrustedDocument(gqlRequest.DocumentID); query != nil {
+			if query.TypeOf(gqlRequest.OperationName) != graphql.QueryOperation {
+				w.Header().Set("Allow", allowedMethods)
+				writeError(w, mediaType, errGetNotQuery)
+				return
+			}
+			gqlRequest.ValidatedQuery = query
+		}
+	}
+	gqlResponse := h.server.Execute(h.newContext(r.Context()), gqlRequest)
+	serveMediaResponse(w, r, mediaType, responseStatus(mediaType, gqlResponse), gqlResponse)
+}
+
+// parseBatch parses the request's operations, reusing the result of a
+// CostLimiter in front of the handler if there is one.
+func (h *Handler) parseBatch(r *http.Request) ([]graphql.Request, bool, error) {
+	parsed, _ := r.Context().Value(parsedRequestContextKey{}).(*parsedRequest)
+	if parsed != nil && parsed.schema == h.server.Schema() {
+		return parsed.requests, parsed.batch, parsed.err
+	}
+	return ParseBatchWithOptions(h.server.Schema(), r, h.opts.ParseOptions)
+}
+
+func (h *Handler) newContext(ctx context.Context) context.Context {
+	if h.opts.NewContext == nil {
+		return ctx
+	}
+	return h.opts.NewContext(ctx)
 }
 
 // Parse parses a GraphQL HTTP request. If an error is returned, StatusCode
@@ -68,22 +167,44 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // Request methods may be GET, HEAD, or POST. If the method is not one of these,
 // then an error is returned that will make StatusCode return
 // http.StatusMethodNotAllowed.
+//
+// GET requests and POST requests with an application/x-www-form-urlencoded
+// body pass the query, operationName, variables, and extensions as
+// parameters. variables and extensions are JSON-encoded. GET requests must be
+// queries: other operations make StatusCode return
+// http.StatusMethodNotAllowed.
+//
+// The request may name a trusted document with a documentId parameter instead
+// of sending a query. Parse does not check that a GET request for a trusted
+// document is a query; Handler does.
+//
+// POST requests with a multipart/form-data body follow the GraphQL multipart
+// request specification at https://github.com/jaydenseric/graphql-multipart-request-spec.
+// Uploaded files are passed to the operation as graphql.Upload variables.
+// Parse sets r.MultipartForm, whose temporary files the caller should remove
+// after the operation finishes. The net/http server does so automatically for
+// the request given to a handler.
 func Parse(schema *graphql.Schema, r *http.Request) (graphql.Request, error) {
+	return ParseWithOptions(schema, r, nil)
+}
+
+// ParseWithOptions parses a GraphQL HTTP request like Parse with the given
+// limits. opts may be nil, which is equivalent to passing a pointer to the
+// zero value.
+func ParseWithOptions(schema *graphql.Schema, r *http.Request, opts *ParseOptions) (graphql.Request, error) {
 	request := graphql.Request{
-		Query: r.URL.Query().Get("query"),
+		Query:      r.URL.Query().Get("query"),
+		DocumentID: r.URL.Query().Get("documentId"),
 	}
 	switch r.Method {
 	case http.MethodGet, http.MethodHead:
-		if v := r.FormValue("variables"); v != "" {
-			if err := json.Unmarshal([]byte(v), &request.Variables); err != nil {
-				return graphql.Request{}, &httpError{
-					msg:   "parse graphql request: ",
-					code:  http.StatusBadRequest,
-					cause: err,
-				}
-			}
+		if err := parseParams(&request, r.URL.Query()); err != nil {
+			return graphql.Request{}, err
+		}
+		if request.Query == "" && request.DocumentID != "" {
+			// The server resolves the document. Handler checks that it is a query.
+			break
 		}
-		request.OperationName = r.FormValue("operationName")
 		var errs []*graphql.ResponseError
 		request.ValidatedQuery, errs = schema.Validate(request.Query)
 		if len(errs) > 0 {
@@ -91,17 +212,15 @@ func Parse(schema *graphql.Schema, r *http.Request) (graphql.Request, error) {
 				msg:   "parse graphql request: ",
 				code:  http.StatusBadRequest,
 				cause: errs[0],
+				errs:  errs,
 			}
 		}
 		if request.ValidatedQuery.TypeOf(request.OperationName) != graphql.QueryOperation {
-			return graphql.Request{}, &httpError{
-				msg:  "parse graphql request: GET requests must be queries",
-				code: http.StatusBadRequest,
-			}
+			return graphql.Request{}, errGetNotQuery
 		}
 	case http.MethodPost:
 		rawContentType := r.Header.Get("Content-Type")
-		contentType, _, err := mime.ParseMediaType(rawContentType)
+		contentType, params, err := mime.ParseMediaType(rawContentType)
 		if err != nil {
 			return graphql.Request{}, &httpError{
 				msg:  "parse graphql request: invalid content type: " + rawContentType,
@@ -118,7 +237,28 @@ func Parse(schema *graphql.Schema, r *http.Request) (graphql.Request, error) {
 				}
 			}
 		case "application/x-www-form-urlencoded":
-			request.Query = r.FormValue("query")
+			if err := r.ParseForm(); err != nil {
+				return graphql.Request{}, &httpError{
+					msg:   "parse graphql request: ",
+					code:  http.StatusBadRequest,
+					cause: err,
+				}
+			}
+			if err := parseParams(&request, r.Form); err != nil {
+				return graphql.Request{}, err
+			}
+		case "multipart/form-data":
+			requests, batch, err := parseMultipart(r, params["boundary"], opts)
+			if err != nil {
+				return graphql.Request{}, err
+			}
+			if batch {
+				return graphql.Request{}, &httpError{
+					msg:  "parse graphql request: batched requests not supported",
+					code: http.StatusBadRequest,
+				}
+			}
+			request = requests[0]
 		case "application/graphql":
 			data, err := ioutil.ReadAll(r.Body)
 			if err != nil {
@@ -146,10 +286,45 @@ func Parse(schema *graphql.Schema, r *http.Request) (graphql.Request, error) {
 	return request, nil
 }
 
+// parseParams fills in a request from URL query or form parameters.
+func parseParams(request *graphql.Request, params url.Values) error {
+	request.Query = params.Get("query")
+	request.DocumentID = params.Get("documentId")
+	request.OperationName = params.Get("operationName")
+	if v := params.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &request.Variables); err != nil {
+			return &httpError{
+				msg:   "parse graphql request: variables: ",
+				code:  http.StatusBadRequest,
+				cause: err,
+			}
+		}
+	}
+	if v := params.Get("extensions"); v != "" {
+		if err := json.Unmarshal([]byte(v), &request.Extensions); err != nil {
+			return &httpError{
+				msg:   "parse graphql request: extensions: ",
+				code:  http.StatusBadRequest,
+				cause: err,
+			}
+		}
+	}
+	return nil
+}
+
+var errGetNotQuery = &httpError{
+	msg:  "parse graphql request: GET requests must be queries",
+	code: http.StatusMethodNotAllowed,
+}
+
 type httpError struct {
 	msg   string
 	code  int
 	cause error
+
+	// errs is the list of GraphQL errors for a request that was well-formed
+	// but failed validation.
+	errs []*graphql.ResponseError
 }
 
 func (e *httpError) Error() string {
@@ -177,13 +352,26 @@ func StatusCode(err error) int {
 
 // WriteResponse writes a GraphQL result as an HTTP response.
 func WriteResponse(w http.ResponseWriter, response graphql.Response) {
+	writeResponse(w, http.StatusOK, response)
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, response graphql.Response) {
+	writeMediaResponse(w, jsonMediaType, statusCode, response)
+}
+
+func writeMediaResponse(w http.ResponseWriter, mediaType string, statusCode int, response graphql.Response) {
 	payload, err := json.Marshal(response)
 	if err != nil {
 		http.Error(w, "GraphQL marshal error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writePayload(w, mediaType, statusCode, payload)
+}
+
+func writePayload(w http.ResponseWriter, mediaType string, statusCode int, payload []byte) {
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
 	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	w.WriteHeader(statusCode)
 	if _, err := w.Write(payload); err != nil {
 		return
 	}
@@ -19,6 +19,7 @@ package graphqlhttp
 import (
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -95,8 +96,26 @@ func TestParse(t *testing.T) {
 				"query":     {"mutation {me{name}}"},
 				"variables": {`{"foo":"bar"}`},
 			},
+			wantErrStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:          "GET/Invalid",
+			method:        http.MethodGet,
+			query:         url.Values{"query": {"{nope}"}},
 			wantErrStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "GET/Extensions",
+			method: http.MethodGet,
+			query: url.Values{
+				"query":      {"{me{name}}"},
+				"extensions": {`{"trace":true}`},
+			},
+			want: graphql.Request{
+				Query:      "{me{name}}",
+				Extensions: map[string]interface{}{"trace": true},
+			},
+		},
 		{
 			name:        "POST/JustQuery",
 			method:      http.MethodPost,
@@ -147,6 +166,56 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "GET/DocumentID",
+			method: http.MethodGet,
+			query: url.Values{
+				"documentId":    {"abc"},
+				"operationName": {"Baz"},
+			},
+			want: graphql.Request{
+				DocumentID:    "abc",
+				OperationName: "Baz",
+			},
+		},
+		{
+			name:        "POST/DocumentID",
+			method:      http.MethodPost,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"documentId": "abc", "variables": {"foo":"bar"}}`,
+			want: graphql.Request{
+				DocumentID: "abc",
+				Variables: map[string]graphql.Input{
+					"foo": graphql.ScalarInput("bar"),
+				},
+			},
+		},
+		{
+			name:        "POST/Form",
+			method:      http.MethodPost,
+			contentType: "application/x-www-form-urlencoded",
+			body: url.Values{
+				"query":         {"mutation Baz{me{name}}"},
+				"variables":     {`{"foo":"bar"}`},
+				"operationName": {"Baz"},
+				"extensions":    {`{"trace":true}`},
+			}.Encode(),
+			want: graphql.Request{
+				Query:         "mutation Baz{me{name}}",
+				OperationName: "Baz",
+				Variables: map[string]graphql.Input{
+					"foo": graphql.ScalarInput("bar"),
+				},
+				Extensions: map[string]interface{}{"trace": true},
+			},
+		},
+		{
+			name:          "POST/FormBadVariables",
+			method:        http.MethodPost,
+			contentType:   "application/x-www-form-urlencoded",
+			body:          url.Values{"query": {"{me{name}}"}, "variables": {"{"}}.Encode(),
+			wantErrStatus: http.StatusBadRequest,
+		},
 		{
 			name:        "POST/GraphQLContentType",
 			method:      http.MethodPost,
@@ -192,3 +261,96 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerTrustedDocuments(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+
+		type Mutation {
+			greet: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServerWithOptions(schema, greeter{}, greeter{}, &graphql.ServerOptions{
+		TrustedDocuments: &graphql.TrustedDocumentOptions{
+			Documents: map[string]string{
+				"q": "{ greeting }",
+				"m": "mutation { greet }",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewHandler(server))
+	defer srv.Close()
+	tests := []struct {
+		name       string
+		method     string
+		documentID string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "GET/Query",
+			method:     http.MethodGet,
+			documentID: "q",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"greeting":"hi"}}`,
+		},
+		{
+			name:       "GET/Mutation",
+			method:     http.MethodGet,
+			documentID: "m",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "GET/Unknown",
+			method:     http.MethodGet,
+			documentID: "x",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"errors":[{"message":"unknown document ID \"x\""}]}`,
+		},
+		{
+			name:       "POST/Mutation",
+			method:     http.MethodPost,
+			documentID: "m",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"greet":"hello"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var resp *http.Response
+			var err error
+			if test.method == http.MethodGet {
+				resp, err = srv.Client().Get(srv.URL + "?" + url.Values{"documentId": {test.documentID}}.Encode())
+			} else {
+				resp, err = srv.Client().Post(srv.URL, "application/json", strings.NewReader(`{"documentId":"`+test.documentID+`"}`))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("status = %d; want %d (body: %s)", resp.StatusCode, test.wantStatus, body)
+			}
+			if test.wantBody != "" && string(body) != test.wantBody {
+				t.Errorf("body = %s; want %s", body, test.wantBody)
+			}
+		})
+	}
+}
+
+type greeter struct{}
+
+func (greeter) Greeting() string { return "hi" }
+func (greeter) Greet() string    { return "hello" }
@@ -0,0 +1,353 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// Headers set by CostLimiter on every GraphQL request it admits or rejects.
+const (
+	// CostHeader is the estimated cost of the request's operation.
+	CostHeader = "GraphQL-Cost"
+	// LimitHeader is the client's maximum budget.
+	LimitHeader = "RateLimit-Limit"
+	// RemainingHeader is the client's remaining budget after the request.
+	RemainingHeader = "RateLimit-Remaining"
+)
+
+// CostLimiterOptions configures a CostLimiter.
+type CostLimiterOptions struct {
+	// ClientID returns the identity that a request is charged to, like an API
+	// key or an authenticated user. If nil, the request's remote IP address
+	// is used.
+	ClientID func(r *http.Request) string
+
+	// Capacity is the maximum budget of each client. It must be positive.
+	Capacity int
+
+	// Refill is the budget that each client regains per second. It must be
+	// positive.
+	Refill float64
+
+	// Cost configures how query costs are estimated.
+	Cost *graphql.CostOptions
+
+	// MaxBodySize is the maximum size in bytes of a request body that the
+	// limiter reads to estimate its cost. For multipart requests, it limits
+	// the size of the operations field. Larger requests are rejected with
+	// 413 Request Entity Too Large. If MaxBodySize is zero, 1 MiB is used.
+	MaxBodySize int64
+}
+
+const defaultMaxCostBodySize = 1 << 20
+
+// CostLimiter is HTTP middleware that charges the estimated cost of each
+// GraphQL request against a token bucket for the requesting client. See
+// graphql.ValidatedQuery.Cost for how costs are estimated. Requests that
+// exceed the client's remaining budget are rejected with a
+// 429 Too Many Requests response and a Retry-After header. Requests that cost
+// more than the bucket's capacity are rejected with 400 Bad Request. Requests
+// that cannot be parsed or validated are charged a cost of 1 and passed to the
+// next handler to report the error.
+//
+// If the next handler is a Handler for the same server, it executes the
+// requests that the limiter parsed and validated instead of parsing the body
+// again. Multipart requests are estimated from their operations field alone:
+// uploaded files are left for the next handler to read.
+//
+// A CostLimiter is safe to use from multiple goroutines.
+type CostLimiter struct {
+	server *graphql.Server
+	next   http.Handler
+	opts   CostLimiterOptions
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*costBucket
+	lastSweep time.Time
+}
+
+type costBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewCostLimiter returns a new CostLimiter that passes admitted requests to
+// next. The server is used to validate queries and resolve trusted documents.
+func NewCostLimiter(server *graphql.Server, next http.Handler, opts *CostLimiterOptions) *CostLimiter {
+	if opts.Capacity <= 0 {
+		panic("graphqlhttp: CostLimiter capacity must be positive")
+	}
+	if opts.Refill <= 0 {
+		panic("graphqlhttp: CostLimiter refill must be positive")
+	}
+	return &CostLimiter{
+		server:  server,
+		next:    next,
+		opts:    *opts,
+		now:     time.Now,
+		buckets: make(map[string]*costBucket),
+	}
+}
+
+// ServeHTTP charges the request's cost and passes it to the next handler if
+// the client has enough budget.
+func (l *CostLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		l.next.ServeHTTP(w, r)
+		return
+	}
+	cost, r, err := l.estimate(r)
+	if err != nil {
+		writeError(w, jsonMediaType, err)
+		return
+	}
+	w.Header().Set(CostHeader, strconv.Itoa(cost))
+	w.Header().Set(LimitHeader, strconv.Itoa(l.opts.Capacity))
+	if cost > l.opts.Capacity {
+		w.Header().Set(RemainingHeader, strconv.Itoa(l.remaining(l.clientID(r))))
+		writeResponse(w, http.StatusBadRequest, graphql.Response{
+			Errors: []*graphql.ResponseError{{
+				Message:    fmt.Sprintf("query cost %d exceeds limit %d", cost, l.opts.Capacity),
+				Extensions: map[string]interface{}{"code": "QUERY_TOO_COSTLY"},
+			}},
+		})
+		return
+	}
+	remaining, wait := l.take(l.clientID(r), cost)
+	w.Header().Set(RemainingHeader, strconv.Itoa(remaining))
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeResponse(w, http.StatusTooManyRequests, graphql.Response{
+			Errors: []*graphql.ResponseError{{
+				Message:    "rate limit exceeded",
+				Extensions: map[string]interface{}{"code": "RATE_LIMITED"},
+			}},
+		})
+		return
+	}
+	l.next.ServeHTTP(w, r)
+}
+
+// estimate returns the cost of a request and the request to pass to the next
+// handler. The cost of a batched request is the sum of its operations' costs.
+// estimate returns an error only if the request body could not be read.
+func (l *CostLimiter) estimate(r *http.Request) (int, *http.Request, error) {
+	contentType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Method == http.MethodPost && contentType == "multipart/form-data" {
+		cost, err := l.multipartCost(r, params["boundary"])
+		return cost, r, err
+	}
+	var body []byte
+	if r.Body != nil {
+		lr := newUploadLimitReader(r.Body, l.maxBodySize())
+		var err error
+		body, err = ioutil.ReadAll(lr)
+		r.Body.Close()
+		if lr.exceeded {
+			return 0, nil, &httpError{
+				msg:  fmt.Sprintf("read request: body larger than %d bytes", l.maxBodySize()),
+				code: http.StatusRequestEntityTooLarge,
+			}
+		}
+		if err != nil {
+			return 0, nil, &httpError{
+				msg:   "read request: ",
+				code:  http.StatusBadRequest,
+				cause: err,
+			}
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	r2 := r.WithContext(r.Context())
+	r2.Body = ioutil.NopCloser(bytes.NewReader(body))
+	parsed := &parsedRequest{schema: l.server.Schema()}
+	parsed.requests, parsed.batch, parsed.err = ParseBatch(parsed.schema, r2)
+	cost := 1
+	if parsed.err == nil {
+		cost = 0
+		for i := range parsed.requests {
+			cost += l.operationCost(&parsed.requests[i])
+		}
+	}
+	ctx := context.WithValue(r.Context(), parsedRequestContextKey{}, parsed)
+	return cost, r.WithContext(ctx), nil
+}
+
+// multipartCost estimates the cost of a multipart request from its operations
+// field, which the GraphQL multipart request specification requires to be the
+// first part. The bytes that multipartCost reads are replayed for the next
+// handler, so the request's files are only read once.
+func (l *CostLimiter) multipartCost(r *http.Request, boundary string) (int, error) {
+	if r.Body == nil || boundary == "" {
+		return 1, nil
+	}
+	body := r.Body
+	consumed := new(bytes.Buffer)
+	defer func() {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(consumed, body), body}
+	}()
+	part, err := multipart.NewReader(io.TeeReader(body, consumed), boundary).NextPart()
+	if err != nil || part.FormName() != "operations" {
+		return 1, nil
+	}
+	lr := newUploadLimitReader(part, l.maxBodySize())
+	raw, err := ioutil.ReadAll(lr)
+	if lr.exceeded {
+		return 0, &httpError{
+			msg:  fmt.Sprintf("read request: operations larger than %d bytes", l.maxBodySize()),
+			code: http.StatusRequestEntityTooLarge,
+		}
+	}
+	if err != nil {
+		return 1, nil
+	}
+	requests, _, err := parseOperations(raw)
+	if err != nil {
+		return 1, nil
+	}
+	total := 0
+	for i := range requests {
+		total += l.operationCost(&requests[i])
+	}
+	return total, nil
+}
+
+// operationCost estimates the cost of a single operation. If operationCost
+// validates the operation's query, it stores the result in
+// req.ValidatedQuery.
+func (l *CostLimiter) operationCost(req *graphql.Request) int {
+	query := req.ValidatedQuery
+	if query == nil && req.Query == "" && req.DocumentID != "" {
+		query = l.server.TrustedDocument(req.DocumentID)
+	} else if query == nil {
+		query, _ = l.server.Schema().Validate(req.Query)
+		req.ValidatedQuery = query
+	}
+	if query == nil {
+		return 1
+	}
+	if cost := query.Cost(req.OperationName, req.Variables, l.opts.Cost); cost > 0 {
+		return cost
+	}
+	return 1
+}
+
+func (l *CostLimiter) maxBodySize() int64 {
+	if l.opts.MaxBodySize <= 0 {
+		return defaultMaxCostBodySize
+	}
+	return l.opts.MaxBodySize
+}
+
+// parsedRequest is the result of parsing a request with ParseBatch. A
+// CostLimiter stores it in the request context for the next Handler.
+type parsedRequest struct {
+	schema   *graphql.Schema
+	requests []graphql.Request
+	batch    bool
+	err      error
+}
+
+type parsedRequestContextKey struct{}
+
+func (l *CostLimiter) clientID(r *http.Request) string {
+	if l.opts.ClientID != nil {
+		return l.opts.ClientID(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// take removes cost tokens from the client's bucket. If the bucket does not
+// have enough tokens, then take leaves the bucket unchanged and returns how
+// long until it will.
+func (l *CostLimiter) take(client string, cost int) (remaining int, wait time.Duration) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	b := l.buckets[client]
+	if b == nil {
+		b = &costBucket{tokens: float64(l.opts.Capacity), last: now}
+		l.buckets[client] = b
+	}
+	l.refill(b, now)
+	if b.tokens < float64(cost) {
+		need := float64(cost) - b.tokens
+		return int(b.tokens), time.Duration(need / l.opts.Refill * float64(time.Second))
+	}
+	b.tokens -= float64(cost)
+	return int(b.tokens), 0
+}
+
+// remaining returns the client's budget without charging it.
+func (l *CostLimiter) remaining(client string) int {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.buckets[client]
+	if b == nil {
+		return l.opts.Capacity
+	}
+	l.refill(b, now)
+	return int(b.tokens)
+}
+
+func (l *CostLimiter) refill(b *costBucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(l.opts.Capacity), b.tokens+elapsed*l.opts.Refill)
+	}
+	b.last = now
+}
+
+// sweep removes buckets that have refilled to capacity, since they are
+// equivalent to new buckets. It runs at most once per full refill period.
+// The caller must hold l.mu.
+func (l *CostLimiter) sweep(now time.Time) {
+	period := time.Duration(float64(l.opts.Capacity) / l.opts.Refill * float64(time.Second))
+	if now.Sub(l.lastSweep) < period {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= period {
+			delete(l.buckets, client)
+		}
+	}
+}
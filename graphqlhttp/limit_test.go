@@ -0,0 +1,252 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type limitQuery struct{}
+
+func (limitQuery) Greeting() string { return "hi" }
+
+func (limitQuery) Items() []limitItem { return []limitItem{{"a"}, {"b"}} }
+
+type limitItem struct {
+	Name string
+}
+
+func newLimitTestServer(t *testing.T) *graphql.Server {
+	t.Helper()
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+			items(first: Int): [Item!]!
+		}
+
+		type Item {
+			name: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, limitQuery{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestCostLimiter(t *testing.T) {
+	server := newLimitTestServer(t)
+	now := time.Date(2019, time.June, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewCostLimiter(server, NewHandlerWithOptions(server, &HandlerOptions{MaxBatchSize: 5}), &CostLimiterOptions{
+		ClientID: func(r *http.Request) string { return r.Header.Get("X-Client") },
+		Capacity: 10,
+		Refill:   2,
+	})
+	limiter.now = func() time.Time { return now }
+
+	type result struct {
+		status     int
+		cost       string
+		remaining  string
+		retryAfter string
+	}
+	do := func(client, body string) result {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		limiter.ServeHTTP(w, r)
+		return result{
+			status:     w.Code,
+			cost:       w.Header().Get(CostHeader),
+			remaining:  w.Header().Get(RemainingHeader),
+			retryAfter: w.Header().Get("Retry-After"),
+		}
+	}
+	const expensive = `{"query": "{ items(first: 3) { name } }"}` // cost 4
+	steps := []struct {
+		name    string
+		client  string
+		body    string
+		advance time.Duration
+		want    result
+	}{
+		{
+			name:   "First",
+			client: "alice",
+			body:   expensive,
+			want:   result{status: http.StatusOK, cost: "4", remaining: "6"},
+		},
+		{
+			name:   "Second",
+			client: "alice",
+			body:   expensive,
+			want:   result{status: http.StatusOK, cost: "4", remaining: "2"},
+		},
+		{
+			name:   "Exhausted",
+			client: "alice",
+			body:   expensive,
+			want:   result{status: http.StatusTooManyRequests, cost: "4", remaining: "2", retryAfter: "1"},
+		},
+		{
+			name:   "OtherClient",
+			client: "bob",
+			body:   expensive,
+			want:   result{status: http.StatusOK, cost: "4", remaining: "6"},
+		},
+		{
+			name:    "Refilled",
+			client:  "alice",
+			body:    expensive,
+			advance: 1 * time.Second,
+			want:    result{status: http.StatusOK, cost: "4", remaining: "0"},
+		},
+		{
+			// Bob's bucket also refilled during the last second.
+			name:   "Cheap",
+			client: "bob",
+			body:   `{"query": "{ greeting }"}`,
+			want:   result{status: http.StatusOK, cost: "1", remaining: "7"},
+		},
+		{
+			name:   "TooExpensive",
+			client: "bob",
+			body:   `{"query": "{ items(first: 20) { name } }"}`,
+			want:   result{status: http.StatusBadRequest, cost: "21", remaining: "7"},
+		},
+		{
+			name:   "Invalid",
+			client: "bob",
+			body:   `{"query": "{ nope }"}`,
+			want:   result{status: http.StatusOK, cost: "1", remaining: "6"},
+		},
+		{
+			name:   "Batch",
+			client: "carol",
+			body:   `[{"query": "{ greeting }"}, ` + expensive + `]`,
+			want:   result{status: http.StatusOK, cost: "5", remaining: "5"},
+		},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if got := do(step.client, step.body); got != step.want {
+			t.Errorf("%s: got %+v; want %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestCostLimiterBody(t *testing.T) {
+	server := newLimitTestServer(t)
+	opts := &CostLimiterOptions{
+		Capacity:    10,
+		Refill:      1,
+		MaxBodySize: 64,
+	}
+
+	t.Run("TooLarge", func(t *testing.T) {
+		limiter := NewCostLimiter(server, NewHandler(server), opts)
+		body := `{"query": "{ greeting }", "extensions": {"padding": "` + strings.Repeat("x", 64) + `"}}`
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		limiter.ServeHTTP(w, r)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d; want %d", w.Code, http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	t.Run("ReusesParse", func(t *testing.T) {
+		// The handler must not read the body that the limiter already parsed.
+		h := NewHandler(server)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = ioutil.NopCloser(iotest.TimeoutReader(strings.NewReader("")))
+			h.ServeHTTP(w, r)
+		})
+		limiter := NewCostLimiter(server, next, opts)
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "{ greeting }"}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		limiter.ServeHTTP(w, r)
+		if got, want := w.Body.String(), `{"data":{"greeting":"hi"}}`; w.Code != http.StatusOK || got != want {
+			t.Errorf("response = %d %s; want %d %s", w.Code, got, http.StatusOK, want)
+		}
+	})
+}
+
+func TestCostLimiterMultipart(t *testing.T) {
+	server := newLimitTestServer(t)
+	r := newMultipartRequest(t,
+		`{"query": "{ items(first: 3) { name } }", "variables": {"file": null}}`,
+		`{"0": ["variables.file"]}`,
+		multipartFile{field: "0", filename: "big.txt", content: strings.Repeat("x", 1<<20)},
+	)
+	want, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := &countingReader{r: bytes.NewReader(want)}
+	r.Body = ioutil.NopCloser(body)
+	var readBeforeNext int64
+	var got []byte
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readBeforeNext = body.n
+		got, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	limiter := NewCostLimiter(server, next, &CostLimiterOptions{Capacity: 10, Refill: 1})
+	w := httptest.NewRecorder()
+	limiter.ServeHTTP(w, r)
+
+	if got := w.Header().Get(CostHeader); got != "4" {
+		t.Errorf("%s = %q; want \"4\"", CostHeader, got)
+	}
+	if readBeforeNext >= int64(len(want)) {
+		t.Errorf("limiter read %d bytes of the %d byte body before passing it on", readBeforeNext, len(want))
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("next handler read %d bytes; want the original %d byte body", len(got), len(want))
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,536 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// StreamTokenHeader is the HTTP header that identifies a reserved event stream
+// in the single connection mode of SSEHandler.
+const StreamTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+// SSEOptions configures an SSEHandler.
+type SSEOptions struct {
+	// KeepAlive is the interval between comments sent on idle event streams to
+	// keep proxies from closing them. If zero, 12 seconds is used. If
+	// negative, no keep-alive comments are sent.
+	KeepAlive time.Duration
+
+	// ReservationTimeout is how long a reserved stream in the single
+	// connection mode may go without being opened before it is discarded.
+	// If zero, 1 minute is used.
+	ReservationTimeout time.Duration
+
+	// MaxReservations is the maximum number of streams that may be reserved
+	// but not yet opened. Further reservations are rejected with
+	// 503 Service Unavailable. If zero, 1000 is used.
+	MaxReservations int
+}
+
+// SSEHandler serves GraphQL operations over Server-Sent Events as described in
+// https://github.com/enisdenjo/graphql-sse/blob/master/PROTOCOL.md. Each result
+// is sent as a "next" event and the end of an operation as a "complete" event.
+// Subscriptions are run with graphql.Server.Subscribe. Queries and mutations
+// produce a single result.
+//
+// In the distinct connections mode, the client sends a GET or POST request
+// that accepts text/event-stream, and the response streams the events of that
+// single operation. Mutations may not be sent with GET.
+//
+// In the single connection mode, the client reserves a stream with a PUT
+// request, whose response body is a token. The client opens the stream with a
+// GET request that sends the token in the X-GraphQL-Event-Stream-Token header
+// or the token query parameter, then starts operations by sending POST
+// requests with the token whose extensions include a unique "operationId".
+// Events on the stream carry the operation ID. A DELETE request with the token
+// and an operationId query parameter stops an operation. Operations run with
+// the context of the stream's request.
+//
+// Operations stop when the client disconnects.
+type SSEHandler struct {
+	server *graphql.Server
+	opts   SSEOptions
+
+	mu       sync.Mutex
+	streams  map[string]*sseStream
+	reserved int // number of streams that have not been opened
+}
+
+// NewSSEHandler returns a new handler that runs operations on the given
+// server. opts may be nil, which is equivalent to passing a pointer to the
+// zero value.
+func NewSSEHandler(server *graphql.Server, opts *SSEOptions) *SSEHandler {
+	h := &SSEHandler{
+		server:  server,
+		streams: make(map[string]*sseStream),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.KeepAlive == 0 {
+		h.opts.KeepAlive = 12 * time.Second
+	}
+	if h.opts.ReservationTimeout <= 0 {
+		h.opts.ReservationTimeout = time.Minute
+	}
+	if h.opts.MaxReservations <= 0 {
+		h.opts.MaxReservations = 1000
+	}
+	return h
+}
+
+// ServeHTTP serves a request in either the distinct connections mode or the
+// single connection mode.
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(StreamTokenHeader)
+	if token == "" && r.Method == http.MethodGet {
+		token = r.URL.Query().Get("token")
+	}
+	switch {
+	case r.Method == http.MethodPut:
+		h.reserve(w)
+	case token != "" && r.Method == http.MethodGet:
+		h.serveStream(w, r, token)
+	case token != "" && r.Method == http.MethodPost:
+		h.startOperation(w, r, token)
+	case token != "" && r.Method == http.MethodDelete:
+		h.stopOperation(w, r, token)
+	case r.Method == http.MethodGet || r.Method == http.MethodPost:
+		h.serveDistinct(w, r)
+	default:
+		w.Header().Set("Allow", "DELETE, GET, POST, PUT")
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+// serveDistinct runs a single operation and streams its results in the
+// response.
+func (h *SSEHandler) serveDistinct(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "request must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	req, err := h.parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), StatusCode(err))
+		return
+	}
+	results, errs := h.server.Subscribe(r.Context(), req)
+	if len(errs) > 0 {
+		writeResponse(w, http.StatusBadRequest, graphql.Response{Errors: errs})
+		return
+	}
+	sw, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	keepAlive, stopKeepAlive := h.keepAlive()
+	defer stopKeepAlive()
+	for {
+		select {
+		case resp, ok := <-results:
+			if !ok {
+				if r.Context().Err() == nil {
+					sw.event("complete", nil)
+				}
+				return
+			}
+			if err := sw.event("next", marshalSSEResponse(resp)); err != nil {
+				return
+			}
+		case <-keepAlive:
+			if err := sw.comment(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parse reads a GraphQL request in the distinct connections mode. Unlike
+// Parse, GET requests may be subscriptions.
+func (h *SSEHandler) parse(r *http.Request) (graphql.Request, error) {
+	if r.Method != http.MethodGet {
+		return Parse(h.server.Schema(), r)
+	}
+	q := r.URL.Query()
+	req := graphql.Request{
+		Query:         q.Get("query"),
+		DocumentID:    q.Get("documentId"),
+		OperationName: q.Get("operationName"),
+	}
+	if v := q.Get("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+			return graphql.Request{}, &httpError{
+				msg:   "parse graphql request: ",
+				code:  http.StatusBadRequest,
+				cause: err,
+			}
+		}
+	}
+	query := h.server.TrustedDocument(req.DocumentID)
+	if req.Query != "" {
+		// Invalid documents are left for the server to report.
+		query, _ = h.server.Schema().Validate(req.Query)
+		req.ValidatedQuery = query
+	}
+	if query != nil && query.TypeOf(req.OperationName) == graphql.MutationOperation {
+		return graphql.Request{}, &httpError{
+			msg:  "parse graphql request: GET requests must not be mutations",
+			code: http.StatusMethodNotAllowed,
+		}
+	}
+	return req, nil
+}
+
+// keepAlive returns a channel that receives at the keep-alive interval and a
+// function to stop it.
+func (h *SSEHandler) keepAlive() (<-chan time.Time, func()) {
+	if h.opts.KeepAlive < 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(h.opts.KeepAlive)
+	return t.C, t.Stop
+}
+
+// sseStream is a reserved stream in the single connection mode.
+type sseStream struct {
+	events chan sseEvent
+	done   chan struct{}
+
+	reserved bool // guarded by SSEHandler.mu
+
+	mu     sync.Mutex
+	ctx    context.Context // nil until the stream is opened
+	closed bool
+	ops    map[string]context.CancelFunc
+}
+
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+func (h *SSEHandler) reserve(w http.ResponseWriter) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		http.Error(w, "generate token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(buf[:])
+	stream := &sseStream{
+		events:   make(chan sseEvent),
+		done:     make(chan struct{}),
+		reserved: true,
+		ops:      make(map[string]context.CancelFunc),
+	}
+	h.mu.Lock()
+	if h.reserved >= h.opts.MaxReservations {
+		h.mu.Unlock()
+		http.Error(w, "too many reserved streams", http.StatusServiceUnavailable)
+		return
+	}
+	h.streams[token] = stream
+	h.reserved++
+	h.mu.Unlock()
+	time.AfterFunc(h.opts.ReservationTimeout, func() {
+		stream.mu.Lock()
+		opened := stream.ctx != nil
+		stream.mu.Unlock()
+		if !opened {
+			h.removeStream(token, stream)
+		}
+	})
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(token))
+}
+
+func (h *SSEHandler) lookupStream(token string) *sseStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams[token]
+}
+
+// release stops counting a stream against MaxReservations.
+func (h *SSEHandler) release(stream *sseStream) {
+	h.mu.Lock()
+	h.releaseLocked(stream)
+	h.mu.Unlock()
+}
+
+// releaseLocked is like release, but the caller must hold h.mu.
+func (h *SSEHandler) releaseLocked(stream *sseStream) {
+	if stream.reserved {
+		stream.reserved = false
+		h.reserved--
+	}
+}
+
+// removeStream discards a stream and stops its operations.
+func (h *SSEHandler) removeStream(token string, stream *sseStream) {
+	h.mu.Lock()
+	if h.streams[token] == stream {
+		delete(h.streams, token)
+	}
+	h.releaseLocked(stream)
+	h.mu.Unlock()
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.closed {
+		return
+	}
+	stream.closed = true
+	close(stream.done)
+	for _, cancel := range stream.ops {
+		cancel()
+	}
+	stream.ops = nil
+}
+
+// serveStream sends the events of a reserved stream until the client
+// disconnects.
+func (h *SSEHandler) serveStream(w http.ResponseWriter, r *http.Request, token string) {
+	stream := h.lookupStream(token)
+	if stream == nil {
+		http.Error(w, "stream not found", http.StatusNotFound)
+		return
+	}
+	if !acceptsEventStream(r) {
+		http.Error(w, "request must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	stream.mu.Lock()
+	if stream.ctx != nil || stream.closed {
+		stream.mu.Unlock()
+		http.Error(w, "stream already open", http.StatusConflict)
+		return
+	}
+	stream.ctx = r.Context()
+	stream.mu.Unlock()
+	h.release(stream)
+	defer h.removeStream(token, stream)
+
+	sw, ok := newSSEWriter(w)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	keepAlive, stopKeepAlive := h.keepAlive()
+	defer stopKeepAlive()
+	for {
+		select {
+		case e := <-stream.events:
+			if err := sw.event(e.name, e.data); err != nil {
+				return
+			}
+		case <-keepAlive:
+			if err := sw.comment(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// startOperation starts an operation on an open stream.
+func (h *SSEHandler) startOperation(w http.ResponseWriter, r *http.Request, token string) {
+	stream := h.lookupStream(token)
+	if stream == nil {
+		http.Error(w, "stream not found", http.StatusNotFound)
+		return
+	}
+	req, err := Parse(h.server.Schema(), r)
+	if err != nil {
+		http.Error(w, err.Error(), StatusCode(err))
+		return
+	}
+	id, _ := req.Extensions["operationId"].(string)
+	if id == "" {
+		http.Error(w, "parse graphql request: missing operationId extension", http.StatusBadRequest)
+		return
+	}
+
+	stream.mu.Lock()
+	switch {
+	case stream.closed:
+		stream.mu.Unlock()
+		http.Error(w, "stream not found", http.StatusNotFound)
+		return
+	case stream.ctx == nil:
+		stream.mu.Unlock()
+		http.Error(w, "stream is not open", http.StatusConflict)
+		return
+	case stream.ops[id] != nil:
+		stream.mu.Unlock()
+		http.Error(w, fmt.Sprintf("operation %q already exists", id), http.StatusConflict)
+		return
+	}
+	streamCtx := stream.ctx
+	ctx, cancel := context.WithCancel(streamCtx)
+	stream.ops[id] = cancel
+	stream.mu.Unlock()
+
+	results, errs := h.server.Subscribe(ctx, req)
+	if len(errs) > 0 {
+		stream.removeOperation(id)
+		cancel()
+		writeResponse(w, http.StatusBadRequest, graphql.Response{Errors: errs})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	go func() {
+		defer cancel()
+		for resp := range results {
+			data, _ := json.Marshal(struct {
+				ID      string          `json:"id"`
+				Payload json.RawMessage `json:"payload"`
+			}{id, marshalSSEResponse(resp)})
+			if !stream.send(ctx, sseEvent{"next", data}) {
+				return
+			}
+		}
+		if stream.removeOperation(id) {
+			data, _ := json.Marshal(struct {
+				ID string `json:"id"`
+			}{id})
+			stream.send(streamCtx, sseEvent{"complete", data})
+		}
+	}()
+}
+
+// stopOperation cancels an operation on a stream.
+func (h *SSEHandler) stopOperation(w http.ResponseWriter, r *http.Request, token string) {
+	stream := h.lookupStream(token)
+	if stream == nil {
+		http.Error(w, "stream not found", http.StatusNotFound)
+		return
+	}
+	id := r.URL.Query().Get("operationId")
+	if id == "" {
+		http.Error(w, "missing operationId", http.StatusBadRequest)
+		return
+	}
+	stream.mu.Lock()
+	cancel := stream.ops[id]
+	delete(stream.ops, id)
+	stream.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// removeOperation removes an operation from the stream, reporting whether it
+// was still running.
+func (stream *sseStream) removeOperation(id string) bool {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.ops[id] == nil {
+		return false
+	}
+	delete(stream.ops, id)
+	return true
+}
+
+// send delivers an event to the stream, reporting whether it was sent before
+// ctx was done or the stream closed.
+func (stream *sseStream) send(ctx context.Context, e sseEvent) bool {
+	select {
+	case stream.events <- e:
+		return true
+	case <-stream.done:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sseWriter writes Server-Sent Events to an HTTP response.
+type sseWriter struct {
+	w http.ResponseWriter
+	f http.Flusher
+}
+
+// newSSEWriter starts an event stream response. It returns false if the
+// response cannot be streamed.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &sseWriter{w: w, f: f}, true
+}
+
+func (sw *sseWriter) event(name string, data []byte) error {
+	if _, err := fmt.Fprintf(sw.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	sw.f.Flush()
+	return nil
+}
+
+func (sw *sseWriter) comment() error {
+	if _, err := sw.w.Write([]byte(":\n\n")); err != nil {
+		return err
+	}
+	sw.f.Flush()
+	return nil
+}
+
+func marshalSSEResponse(resp graphql.Response) []byte {
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		payload, _ = json.Marshal(graphql.Response{
+			Errors: []*graphql.ResponseError{{Message: err.Error()}},
+		})
+	}
+	return payload
+}
+
+// acceptsEventStream reports whether the request's Accept header includes
+// text/event-stream.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header["Accept"] {
+		for _, part := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == "text/event-stream" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,480 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// testEvent is a Server-Sent Event or, if Name and Data are empty, a comment.
+type testEvent struct {
+	Name string
+	Data string
+}
+
+// readTestEvent reads the next event or comment from an event stream.
+func readTestEvent(t *testing.T, r *bufio.Reader) testEvent {
+	t.Helper()
+	var e testEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal("read event:", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return e
+		case strings.HasPrefix(line, ":"):
+			// Comment.
+		case strings.HasPrefix(line, "event:"):
+			e.Name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			e.Data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func openTestEventStream(t *testing.T, req *http.Request) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		t.Fatalf("status = %s; body:\n%s", resp.Status, body)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "text/event-stream; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q; want %q", got, want)
+	}
+	return resp, bufio.NewReader(resp.Body)
+}
+
+type sseQuery struct{}
+
+func (sseQuery) Greeting() string {
+	return "hi"
+}
+
+type sseSubscription struct{}
+
+func (sseSubscription) Counter(ctx context.Context, args map[string]graphql.Value) <-chan int32 {
+	n, _ := json.Number(args["to"].Scalar()).Int64()
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		for i := int32(1); i <= int32(n); i++ {
+			select {
+			case c <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (sseSubscription) Ticks(ctx context.Context) <-chan string {
+	c := make(chan string)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case c <- "tick":
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// newSubscriptionTestServer returns a GraphQL server with a subscription type
+// provided by sub.
+func newSubscriptionTestServer(t *testing.T, sub interface{}) *graphql.Server {
+	t.Helper()
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+
+		type Subscription {
+			counter(to: Int!): Int!
+			ticks: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServerWithOptions(schema, sseQuery{}, nil, &graphql.ServerOptions{
+		Subscription: sub,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestSSEHandlerDistinct(t *testing.T) {
+	srv := httptest.NewServer(NewSSEHandler(newSubscriptionTestServer(t, sseSubscription{}), nil))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		body   string
+		want   []testEvent
+	}{
+		{
+			name:   "GETSubscription",
+			method: http.MethodGet,
+			query:  "query=" + url.QueryEscape("subscription($n: Int!) { counter(to: $n) }") + "&variables=" + url.QueryEscape(`{"n":2}`),
+			want: []testEvent{
+				{"next", `{"data":{"counter":1}}`},
+				{"next", `{"data":{"counter":2}}`},
+				{"complete", ""},
+			},
+		},
+		{
+			name:   "POSTSubscription",
+			method: http.MethodPost,
+			body:   `{"query":"subscription { counter(to: 1) }"}`,
+			want: []testEvent{
+				{"next", `{"data":{"counter":1}}`},
+				{"complete", ""},
+			},
+		},
+		{
+			name:   "Query",
+			method: http.MethodGet,
+			query:  "query=" + url.QueryEscape("{ greeting }"),
+			want: []testEvent{
+				{"next", `{"data":{"greeting":"hi"}}`},
+				{"complete", ""},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, srv.URL+"?"+test.query, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			resp, events := openTestEventStream(t, req)
+			defer resp.Body.Close()
+			var got []testEvent
+			for len(got) < len(test.want) {
+				got = append(got, readTestEvent(t, events))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("events (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSSEHandlerErrors(t *testing.T) {
+	srv := httptest.NewServer(NewSSEHandler(newSubscriptionTestServer(t, sseSubscription{}), nil))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ValidationError",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape("{ nope }"),
+			accept:     "text/event-stream",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":[{"message":"field \"nope\" not found on type Query","locations":[{"line":1,"column":3}],"path":["nope"]}]}`,
+		},
+		{
+			name:       "NotAcceptable",
+			method:     http.MethodGet,
+			target:     "?query=" + url.QueryEscape("{ greeting }"),
+			accept:     "application/json",
+			wantStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:       "UnknownStream",
+			method:     http.MethodGet,
+			target:     "?token=bogus",
+			accept:     "text/event-stream",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "MethodNotAllowed",
+			method:     http.MethodPatch,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, srv.URL+test.target, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("status = %d; want %d", resp.StatusCode, test.wantStatus)
+			}
+			if test.wantBody != "" && string(body) != test.wantBody {
+				t.Errorf("body = %s; want %s", body, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestSSEHandlerKeepAlive(t *testing.T) {
+	h := NewSSEHandler(newSubscriptionTestServer(t, sseSubscription{}), &SSEOptions{
+		KeepAlive: 10 * time.Millisecond,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// Reserve a stream, but do not start any operations so that the stream is
+	// idle.
+	token := reserveTestStream(t, srv)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(StreamTokenHeader, token)
+	resp, events := openTestEventStream(t, req)
+	defer resp.Body.Close()
+	if got := readTestEvent(t, events); got != (testEvent{}) {
+		t.Errorf("got event %+v; want comment", got)
+	}
+}
+
+// disconnectSubscription reports when a ticks subscription stops.
+type disconnectSubscription struct {
+	sseSubscription
+	stopped chan struct{}
+}
+
+func (sub disconnectSubscription) Ticks(ctx context.Context) <-chan string {
+	c := sub.sseSubscription.Ticks(ctx)
+	go func() {
+		<-ctx.Done()
+		close(sub.stopped)
+	}()
+	return c
+}
+
+func TestSSEHandlerDisconnect(t *testing.T) {
+	sub := disconnectSubscription{stopped: make(chan struct{})}
+	srv := httptest.NewServer(NewSSEHandler(newSubscriptionTestServer(t, sub), nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"?query="+url.QueryEscape("subscription { ticks }"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, events := openTestEventStream(t, req.WithContext(ctx))
+	defer resp.Body.Close()
+	if got, want := readTestEvent(t, events), (testEvent{"next", `{"data":{"ticks":"tick"}}`}); got != want {
+		t.Errorf("got event %+v; want %+v", got, want)
+	}
+	cancel()
+	select {
+	case <-sub.stopped:
+	case <-time.After(10 * time.Second):
+		t.Error("subscription did not stop after client disconnected")
+	}
+}
+
+func reserveTestStream(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPut, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("reserve status = %s; want 201 Created", resp.Status)
+	}
+	return string(token)
+}
+
+func TestSSEHandlerSingleConnection(t *testing.T) {
+	srv := httptest.NewServer(NewSSEHandler(newSubscriptionTestServer(t, sseSubscription{}), nil))
+	defer srv.Close()
+	token := reserveTestStream(t, srv)
+
+	do := func(method, target, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+target, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(StreamTokenHeader, token)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Operations may not start before the stream is open.
+	if got, want := do(http.MethodPost, "", `{"query":"{ greeting }","extensions":{"operationId":"q"}}`), http.StatusConflict; got != want {
+		t.Errorf("POST before stream status = %d; want %d", got, want)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, events := openTestEventStream(t, req)
+	defer resp.Body.Close()
+	if got, want := do(http.MethodGet, "", ""), http.StatusConflict; got != want {
+		t.Errorf("second GET status = %d; want %d", got, want)
+	}
+
+	if got, want := do(http.MethodPost, "", `{"query":"{ greeting }","extensions":{"operationId":"q"}}`), http.StatusAccepted; got != want {
+		t.Fatalf("POST status = %d; want %d", got, want)
+	}
+	want := []testEvent{
+		{"next", `{"id":"q","payload":{"data":{"greeting":"hi"}}}`},
+		{"complete", `{"id":"q"}`},
+	}
+	var got []testEvent
+	for len(got) < len(want) {
+		got = append(got, readTestEvent(t, events))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("events (-want +got):\n%s", diff)
+	}
+
+	if got, want := do(http.MethodPost, "", `{"query":"subscription { ticks }","extensions":{"operationId":"s"}}`), http.StatusAccepted; got != want {
+		t.Fatalf("POST subscription status = %d; want %d", got, want)
+	}
+	if got, want := readTestEvent(t, events), (testEvent{"next", `{"id":"s","payload":{"data":{"ticks":"tick"}}}`}); got != want {
+		t.Errorf("got event %+v; want %+v", got, want)
+	}
+	if got, want := do(http.MethodPost, "", `{"query":"subscription { ticks }","extensions":{"operationId":"s"}}`), http.StatusConflict; got != want {
+		t.Errorf("duplicate POST status = %d; want %d", got, want)
+	}
+	if got, want := do(http.MethodDelete, "?operationId=s", ""), http.StatusOK; got != want {
+		t.Errorf("DELETE status = %d; want %d", got, want)
+	}
+
+	// The ID may be reused once the operation is stopped. Events from the
+	// stopped subscription may still be in flight.
+	if got, want := do(http.MethodPost, "", `{"query":"subscription { counter(to: 1) }","extensions":{"operationId":"s"}}`), http.StatusAccepted; got != want {
+		t.Fatalf("POST after DELETE status = %d; want %d", got, want)
+	}
+	for {
+		e := readTestEvent(t, events)
+		if e.Data == `{"id":"s","payload":{"data":{"ticks":"tick"}}}` {
+			continue
+		}
+		if want := (testEvent{"next", `{"id":"s","payload":{"data":{"counter":1}}}`}); e != want {
+			t.Errorf("got event %+v; want %+v", e, want)
+		}
+		break
+	}
+	if got, want := readTestEvent(t, events), (testEvent{"complete", `{"id":"s"}`}); got != want {
+		t.Errorf("got event %+v; want %+v", got, want)
+	}
+}
+
+func TestSSEHandlerMaxReservations(t *testing.T) {
+	srv := httptest.NewServer(NewSSEHandler(newSubscriptionTestServer(t, sseSubscription{}), &SSEOptions{
+		MaxReservations: 1,
+	}))
+	defer srv.Close()
+	reserve := func() int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	token := reserveTestStream(t, srv)
+	if got, want := reserve(), http.StatusServiceUnavailable; got != want {
+		t.Errorf("second PUT status = %d; want %d", got, want)
+	}
+
+	// Opening the stream frees its reservation.
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, _ := openTestEventStream(t, req)
+	defer resp.Body.Close()
+	if got, want := reserve(), http.StatusCreated; got != want {
+		t.Errorf("PUT after opening stream status = %d; want %d", got, want)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// ParseOptions specifies limits for parsing GraphQL HTTP requests.
+// nil is treated the same as the zero value.
+type ParseOptions struct {
+	// MaxUploadMemory is the number of bytes of a multipart request's files
+	// that are held in memory. The remainder is stored in temporary files.
+	// If MaxUploadMemory is zero, then 32 MiB is used.
+	MaxUploadMemory int64
+
+	// MaxFileSize is the maximum size in bytes of a single uploaded file.
+	// If MaxFileSize is zero, then files may be of any size.
+	MaxFileSize int64
+
+	// MaxUploadSize is the maximum size in bytes of a multipart request body.
+	// If MaxUploadSize is zero, then the body may be of any size.
+	MaxUploadSize int64
+}
+
+const defaultMaxUploadMemory = 32 << 20
+
+// parseMultipart parses a request following the GraphQL multipart request
+// specification at https://github.com/jaydenseric/graphql-multipart-request-spec.
+// The "operations" field may hold either a single request or a batch.
+func parseMultipart(r *http.Request, boundary string, opts *ParseOptions) (requests []graphql.Request, batch bool, err error) {
+	if opts == nil {
+		opts = new(ParseOptions)
+	}
+	if boundary == "" {
+		return nil, false, &httpError{
+			msg:  "parse graphql request: multipart boundary missing",
+			code: http.StatusBadRequest,
+		}
+	}
+	body := newUploadLimitReader(r.Body, opts.MaxUploadSize)
+	maxMemory := opts.MaxUploadMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxUploadMemory
+	}
+	form, err := multipart.NewReader(body, boundary).ReadForm(maxMemory)
+	if body.exceeded {
+		if form != nil {
+			form.RemoveAll()
+		}
+		return nil, false, &httpError{
+			msg:  fmt.Sprintf("parse graphql request: multipart body larger than %d bytes", opts.MaxUploadSize),
+			code: http.StatusRequestEntityTooLarge,
+		}
+	}
+	if err != nil {
+		return nil, false, &httpError{
+			msg:   "parse graphql request: ",
+			code:  http.StatusBadRequest,
+			cause: err,
+		}
+	}
+	// Like http.Request.ParseMultipartForm, leave the form on the request so
+	// that its temporary files can be removed once the request is finished.
+	r.MultipartForm = form
+
+	operations := form.Value["operations"]
+	if len(operations) != 1 {
+		return nil, false, &httpError{
+			msg:  "parse graphql request: multipart request must have exactly one operations field",
+			code: http.StatusBadRequest,
+		}
+	}
+	requests, batch, err = parseOperations([]byte(operations[0]))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var fileMap map[string][]string
+	if m := form.Value["map"]; len(m) == 1 {
+		if err := json.Unmarshal([]byte(m[0]), &fileMap); err != nil {
+			return nil, false, &httpError{
+				msg:   "parse graphql request: map: ",
+				code:  http.StatusBadRequest,
+				cause: err,
+			}
+		}
+	} else if len(m) > 1 {
+		return nil, false, &httpError{
+			msg:  "parse graphql request: multipart request has multiple map fields",
+			code: http.StatusBadRequest,
+		}
+	}
+	// Substitute files in a stable order so that errors are deterministic.
+	keys := make([]string, 0, len(fileMap))
+	for k := range fileMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		files := form.File[k]
+		if len(files) != 1 {
+			return nil, false, &httpError{
+				msg:  fmt.Sprintf("parse graphql request: map references missing file %q", k),
+				code: http.StatusBadRequest,
+			}
+		}
+		fh := files[0]
+		if opts.MaxFileSize > 0 && fh.Size > opts.MaxFileSize {
+			return nil, false, &httpError{
+				msg:  fmt.Sprintf("parse graphql request: file %q larger than %d bytes", k, opts.MaxFileSize),
+				code: http.StatusRequestEntityTooLarge,
+			}
+		}
+		upload := &graphql.Upload{
+			Filename:    fh.Filename,
+			ContentType: fh.Header.Get("Content-Type"),
+			Size:        fh.Size,
+			Content:     &uploadReader{header: fh},
+		}
+		for _, path := range fileMap[k] {
+			if err := setUpload(requests, batch, path, upload); err != nil {
+				return nil, false, &httpError{
+					msg:   fmt.Sprintf("parse graphql request: map file %q: ", k),
+					code:  http.StatusBadRequest,
+					cause: err,
+				}
+			}
+		}
+	}
+	return requests, batch, nil
+}
+
+// parseOperations parses a multipart request's operations field, which may
+// hold either a single request or a batch.
+func parseOperations(raw []byte) (requests []graphql.Request, batch bool, err error) {
+	if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		batch = true
+		err = json.Unmarshal(raw, &requests)
+	} else {
+		requests = make([]graphql.Request, 1)
+		err = json.Unmarshal(raw, &requests[0])
+	}
+	if err != nil {
+		return nil, false, &httpError{
+			msg:   "parse graphql request: operations: ",
+			code:  http.StatusBadRequest,
+			cause: err,
+		}
+	}
+	if len(requests) == 0 {
+		return nil, false, &httpError{
+			msg:  "parse graphql request: empty batch",
+			code: http.StatusBadRequest,
+		}
+	}
+	return requests, batch, nil
+}
+
+// setUpload replaces the variable value at the given object path with the
+// upload. Paths have the form "variables.NAME[.KEY...]", prefixed with the
+// operation's index for batches.
+func setUpload(requests []graphql.Request, batch bool, path string, upload *graphql.Upload) error {
+	parts := strings.Split(path, ".")
+	req := &requests[0]
+	if batch {
+		i, err := strconv.Atoi(parts[0])
+		if err != nil || i < 0 || i >= len(requests) {
+			return xerrors.Errorf("path %q: invalid operation index", path)
+		}
+		req = &requests[i]
+		parts = parts[1:]
+	}
+	if len(parts) < 2 || parts[0] != "variables" {
+		return xerrors.Errorf("path %q does not name a variable", path)
+	}
+	name := parts[1]
+	v, err := setUploadPath(req.Variables[name].GoValue(), parts[2:], upload)
+	if err != nil {
+		return xerrors.Errorf("path %q: %w", path, err)
+	}
+	if req.Variables == nil {
+		req.Variables = make(map[string]graphql.Input)
+	}
+	req.Variables[name] = inputFromGo(v)
+	return nil
+}
+
+func setUploadPath(v interface{}, path []string, upload *graphql.Upload) (interface{}, error) {
+	if len(path) == 0 {
+		return upload, nil
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		elem, err := setUploadPath(v[path[0]], path[1:], upload)
+		if err != nil {
+			return nil, err
+		}
+		v[path[0]] = elem
+		return v, nil
+	case []interface{}:
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, xerrors.Errorf("invalid list index %q", path[0])
+		}
+		v[i], err = setUploadPath(v[i], path[1:], upload)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	default:
+		return nil, xerrors.Errorf("%q is not an object or list", path[0])
+	}
+}
+
+// inputFromGo converts the result of graphql.Input.GoValue back into an input.
+func inputFromGo(v interface{}) graphql.Input {
+	switch v := v.(type) {
+	case string:
+		return graphql.ScalarInput(v)
+	case *graphql.Upload:
+		return graphql.UploadInput(v)
+	case []interface{}:
+		list := make([]graphql.Input, len(v))
+		for i, elem := range v {
+			list[i] = inputFromGo(elem)
+		}
+		return graphql.ListInput(list)
+	case map[string]interface{}:
+		obj := make(map[string]graphql.Input, len(v))
+		for k, elem := range v {
+			obj[k] = inputFromGo(elem)
+		}
+		return graphql.InputObject(obj)
+	default:
+		return graphql.Input{}
+	}
+}
+
+// uploadReader reads an uploaded file, opening it on first read and closing
+// it once the end of the file is reached.
+type uploadReader struct {
+	header *multipart.FileHeader
+	f      multipart.File
+	err    error
+}
+
+func (ur *uploadReader) Read(p []byte) (int, error) {
+	if ur.err != nil {
+		return 0, ur.err
+	}
+	if ur.f == nil {
+		ur.f, ur.err = ur.header.Open()
+		if ur.err != nil {
+			return 0, ur.err
+		}
+	}
+	n, err := ur.f.Read(p)
+	if err != nil {
+		ur.f.Close()
+		ur.err = err
+	}
+	return n, err
+}
+
+// uploadLimitReader reads at most limit bytes from r if limit is positive.
+// It records whether the limit was exceeded.
+type uploadLimitReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+	exceeded  bool
+}
+
+func newUploadLimitReader(r io.Reader, limit int64) *uploadLimitReader {
+	return &uploadLimitReader{r: r, limit: limit, remaining: limit}
+}
+
+func (lr *uploadLimitReader) Read(p []byte) (int, error) {
+	if lr.limit <= 0 {
+		return lr.r.Read(p)
+	}
+	if lr.exceeded {
+		return 0, errUploadTooLarge
+	}
+	// Read one byte past the limit to detect bodies that are too large.
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+	n, err := lr.r.Read(p)
+	if int64(n) > lr.remaining {
+		lr.exceeded = true
+		n = int(lr.remaining)
+		lr.remaining = 0
+		return n, errUploadTooLarge
+	}
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+var errUploadTooLarge = xerrors.New("multipart body too large")
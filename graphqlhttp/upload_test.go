@@ -0,0 +1,218 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlhttp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type uploadMutation struct{}
+
+func (uploadMutation) Upload(args map[string]graphql.Value) (string, error) {
+	var file graphql.Upload
+	if err := args["file"].Convert(&file); err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(file.Content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d bytes): %s", file.Filename, file.Size, data), nil
+}
+
+func (uploadMutation) UploadMany(args map[string]graphql.Value) (string, error) {
+	var files []*graphql.Upload
+	if err := args["files"].Convert(&files); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Filename)
+	}
+	return strings.Join(names, ","), nil
+}
+
+type multipartFile struct {
+	field    string
+	filename string
+	content  string
+}
+
+func newMultipartRequest(t *testing.T, operations, fileMap string, files ...multipartFile) *http.Request {
+	t.Helper()
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	if operations != "" {
+		if err := mw.WriteField("operations", operations); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fileMap != "" {
+		if err := mw.WriteField("map", fileMap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		w, err := mw.CreateFormFile(f.field, f.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/graphql", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestHandlerUpload(t *testing.T) {
+	schema, err := graphql.ParseSchema(`
+		scalar Upload
+
+		type Query {
+			greeting: String!
+		}
+
+		type Mutation {
+			upload(file: Upload!): String!
+			uploadMany(files: [Upload!]!): String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServer(schema, greeter{}, uploadMutation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const single = `{"query": "mutation($file: Upload!) { upload(file: $file) }", "variables": {"file": null}}`
+	hello := multipartFile{field: "0", filename: "a.txt", content: "Hello"}
+	tests := []struct {
+		name       string
+		opts       HandlerOptions
+		operations string
+		fileMap    string
+		files      []multipartFile
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "Single",
+			operations: single,
+			fileMap:    `{"0": ["variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"upload":"a.txt (5 bytes): Hello"}}`,
+		},
+		{
+			name:       "List",
+			operations: `{"query": "mutation($files: [Upload!]!) { uploadMany(files: $files) }", "variables": {"files": [null, null]}}`,
+			fileMap:    `{"0": ["variables.files.0"], "1": ["variables.files.1"]}`,
+			files: []multipartFile{
+				hello,
+				{field: "1", filename: "b.txt", content: "Goodbye"},
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"uploadMany":"a.txt,b.txt"}}`,
+		},
+		{
+			name:       "Batch",
+			opts:       HandlerOptions{MaxBatchSize: 2},
+			operations: `[` + single + `, {"query": "{ greeting }"}]`,
+			fileMap:    `{"0": ["0.variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"data":{"upload":"a.txt (5 bytes): Hello"}},{"data":{"greeting":"hi"}}]`,
+		},
+		{
+			name:       "Spilled",
+			opts:       HandlerOptions{ParseOptions: &ParseOptions{MaxUploadMemory: 1}},
+			operations: single,
+			fileMap:    `{"0": ["variables.file"]}`,
+			files:      []multipartFile{{field: "0", filename: "big.txt", content: strings.Repeat("x", 4096)}},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"upload":"big.txt (4096 bytes): ` + strings.Repeat("x", 4096) + `"}}`,
+		},
+		{
+			name:       "MissingOperations",
+			fileMap:    `{"0": ["variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "MissingFile",
+			operations: single,
+			fileMap:    `{"1": ["variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "BadPath",
+			operations: single,
+			fileMap:    `{"0": ["query"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "FileTooLarge",
+			opts:       HandlerOptions{ParseOptions: &ParseOptions{MaxFileSize: 4}},
+			operations: single,
+			fileMap:    `{"0": ["variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "BodyTooLarge",
+			opts:       HandlerOptions{ParseOptions: &ParseOptions{MaxUploadSize: 64}},
+			operations: single,
+			fileMap:    `{"0": ["variables.file"]}`,
+			files:      []multipartFile{hello},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := test.opts
+			h := NewHandlerWithOptions(server, &opts)
+			r := newMultipartRequest(t, test.operations, test.fileMap, test.files...)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if r.MultipartForm != nil {
+				r.MultipartForm.RemoveAll()
+			}
+			if w.Code != test.wantStatus {
+				t.Errorf("status = %d; want %d (body: %s)", w.Code, test.wantStatus, w.Body)
+			}
+			if test.wantBody != "" && w.Body.String() != test.wantBody {
+				t.Errorf("body = %s; want %s", w.Body, test.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlmock_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqlmock"
+)
+
+func ExampleNewServer() {
+	schema, err := graphql.ParseSchema(graphqlmock.ExampleDirective+`
+		type Query {
+			user(id: ID!): User
+		}
+
+		type User {
+			id: ID!
+			name: String! @example(value: ["Alice", "Bob", "Carol"])
+			friends: [User!]!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	srv, err := graphqlmock.NewServer(schema, &graphqlmock.Options{
+		Fields: map[string]graphqlmock.FieldMock{
+			// Give every user the same ID.
+			"User.id": func(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+				return "1234", nil
+			},
+		},
+	})
+	if err != nil {
+		// handle error
+	}
+	response := srv.Execute(context.Background(), graphql.Request{
+		Query: `{ user(id: "1234") { id name friends { name } } }`,
+	})
+	output, _ := json.Marshal(response)
+	fmt.Println(string(output))
+	// Output:
+	// {"data":{"user":{"id":"1234","name":"Bob","friends":[{"name":"Bob"},{"name":"Alice"}]}}}
+}
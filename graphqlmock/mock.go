@@ -0,0 +1,378 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package graphqlmock provides a GraphQL server that generates data for any
+// schema. This is useful for developing clients before the real server exists
+// or for testing clients without a backend.
+//
+// Values are generated deterministically: the same schema, options, and
+// request always produce the same response. Generated values can be replaced
+// for a whole type or a single field using Options, or hinted in the schema
+// source with the @example directive:
+//
+//	directive @example(value: [String!]!) on FIELD_DEFINITION | SCALAR
+//
+//	scalar DateTime @example(value: "2019-12-25T09:00:00Z")
+//
+//	type User {
+//	  name: String! @example(value: ["Alice", "Bob", "Carol"])
+//	}
+package graphqlmock
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// ExampleDirective is the definition of the @example directive. It can be
+// added to a schema's source to permit giving examples for fields and scalars.
+// When a field or scalar type has examples, the mock server picks one of the
+// examples instead of generating a value.
+const ExampleDirective = "directive @example(value: [String!]!) on FIELD_DEFINITION | SCALAR\n"
+
+// Options controls the values a mock server generates. nil is treated the same
+// as the zero value.
+type Options struct {
+	// Seed changes the generated values. Servers with the same schema,
+	// options, and seed return the same responses.
+	Seed int64
+
+	// ListLength is the number of elements generated for list fields.
+	// If zero, two elements are generated.
+	ListLength int
+
+	// Types is a map of type names to functions that generate their values.
+	// Functions can be given for scalar, enum, and object types.
+	Types map[string]TypeMock
+
+	// Fields is a map of "Type.field" names to functions that resolve the
+	// field, like "Query.user".
+	Fields map[string]FieldMock
+}
+
+// TypeMock generates a value for a type. The value is converted to GraphQL
+// following the same rules as values returned from a field method. r is seeded
+// from the value's position in the response, so a TypeMock that only uses r
+// to vary its output is deterministic.
+type TypeMock func(r *rand.Rand) interface{}
+
+// FieldMock resolves a field. The returned value is converted to GraphQL
+// following the same rules as values returned from a field method.
+type FieldMock func(ctx context.Context, req graphql.FieldRequest) (interface{}, error)
+
+// NewServer returns a server that generates responses for the given schema.
+// It returns an error if the options or @example directives in the schema are
+// not compatible with the schema.
+func NewServer(schema *graphql.Schema, opts *Options) (*graphql.Server, error) {
+	m, err := newMocker(schema, opts)
+	if err != nil {
+		return nil, xerrors.Errorf("new mock server: %w", err)
+	}
+	query := m.newObject(schema.QueryType(), derive(m.seed, "query", 0))
+	var mutation interface{}
+	if typ := schema.MutationType(); typ != nil {
+		mutation = m.newObject(typ, derive(m.seed, "mutation", 0))
+	}
+	srv, err := graphql.NewServer(schema, query, mutation)
+	if err != nil {
+		return nil, xerrors.Errorf("new mock server: %w", err)
+	}
+	return srv, nil
+}
+
+type mocker struct {
+	seed       int64
+	listLength int
+	types      map[string]TypeMock
+	fields     map[string]FieldMock
+
+	// objects maps object type names to their fields.
+	objects map[string]map[string]*mockField
+	// examples maps scalar type names to their examples.
+	examples map[string][]interface{}
+}
+
+type mockField struct {
+	defn     *graphql.FieldDefinition
+	examples []interface{}
+}
+
+func newMocker(schema *graphql.Schema, opts *Options) (*mocker, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	m := &mocker{
+		seed:       opts.Seed,
+		listLength: opts.ListLength,
+		types:      opts.Types,
+		fields:     opts.Fields,
+		objects:    make(map[string]map[string]*mockField),
+		examples:   make(map[string][]interface{}),
+	}
+	if m.listLength == 0 {
+		m.listLength = 2
+	}
+	if m.listLength < 0 {
+		return nil, xerrors.Errorf("negative list length %d", m.listLength)
+	}
+	for name := range opts.Types {
+		typ := schema.Type(name)
+		if typ == nil {
+			return nil, xerrors.Errorf("type mock for unknown type %s", name)
+		}
+		if k := typ.Kind(); k != graphql.ScalarKind && k != graphql.EnumKind && k != graphql.ObjectKind {
+			return nil, xerrors.Errorf("type mock for %s: cannot mock %v types", name, k)
+		}
+	}
+	for name := range opts.Fields {
+		i := strings.IndexByte(name, '.')
+		if i == -1 {
+			return nil, xerrors.Errorf("field mock %q: name must be in the form Type.field", name)
+		}
+		typ := schema.Type(name[:i])
+		if typ == nil || typ.Field(name[i+1:]) == nil {
+			return nil, xerrors.Errorf("field mock for unknown field %s", name)
+		}
+	}
+	for _, typ := range schema.Types() {
+		switch typ.Kind() {
+		case graphql.ScalarKind:
+			examples, err := exampleValues(typ, typ.Directives())
+			if err != nil {
+				return nil, xerrors.Errorf("scalar %s: %w", typ.Name(), err)
+			}
+			if len(examples) > 0 {
+				m.examples[typ.Name()] = examples
+			}
+		case graphql.ObjectKind:
+			fields := make(map[string]*mockField)
+			for _, defn := range typ.Fields() {
+				examples, err := exampleValues(namedType(defn.Type), defn.Directives)
+				if err != nil {
+					return nil, xerrors.Errorf("field %s.%s: %w", typ.Name(), defn.Name, err)
+				}
+				fields[defn.Name] = &mockField{
+					defn:     defn,
+					examples: examples,
+				}
+			}
+			m.objects[typ.Name()] = fields
+		}
+	}
+	return m, nil
+}
+
+// exampleValues converts the arguments of any @example directives in the list
+// to Go values that can be returned for the given named type.
+func exampleValues(typ *graphql.Type, directives []*graphql.Directive) ([]interface{}, error) {
+	d := graphql.FindDirective(directives, "example")
+	if d == nil {
+		return nil, nil
+	}
+	var examples []string
+	switch v := d.Args["value"].GoValue().(type) {
+	case string:
+		examples = []string{v}
+	case []interface{}:
+		for _, elem := range v {
+			if s, ok := elem.(string); ok {
+				examples = append(examples, s)
+			}
+		}
+	}
+	if len(examples) == 0 {
+		return nil, xerrors.New("@example must have a value")
+	}
+	values := make([]interface{}, 0, len(examples))
+	for _, s := range examples {
+		v, err := parseExample(typ, s)
+		if err != nil {
+			return nil, xerrors.Errorf("@example: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseExample(typ *graphql.Type, s string) (interface{}, error) {
+	switch typ.Kind() {
+	case graphql.ScalarKind:
+		switch typ.Name() {
+		case "Int":
+			i, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				return nil, xerrors.Errorf("%q is not an Int", s)
+			}
+			return int32(i), nil
+		case "Float":
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, xerrors.Errorf("%q is not a Float", s)
+			}
+			return f, nil
+		case "Boolean":
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, xerrors.Errorf("%q is not a Boolean", s)
+			}
+			return b, nil
+		default:
+			return s, nil
+		}
+	case graphql.EnumKind:
+		for _, v := range typ.EnumValues() {
+			if v.Name == s {
+				return s, nil
+			}
+		}
+		return nil, xerrors.Errorf("%q is not a value of %v", s, typ)
+	default:
+		return nil, xerrors.Errorf("examples not supported for %v", typ)
+	}
+}
+
+// namedType returns the innermost named type of a list or non-null type.
+func namedType(typ *graphql.Type) *graphql.Type {
+	for typ.OfType() != nil {
+		typ = typ.OfType()
+	}
+	return typ
+}
+
+// value generates a Go value for the given type.
+func (m *mocker) value(typ *graphql.Type, seed int64, examples []interface{}) interface{} {
+	switch typ.Kind() {
+	case graphql.NonNullKind:
+		return m.value(typ.OfType(), seed, examples)
+	case graphql.ListKind:
+		list := make([]interface{}, m.listLength)
+		for i := range list {
+			list[i] = m.value(typ.OfType(), derive(seed, "", i), examples)
+		}
+		return list
+	}
+	r := rand.New(rand.NewSource(seed))
+	if f := m.types[typ.Name()]; f != nil {
+		return f(r)
+	}
+	if len(examples) == 0 {
+		examples = m.examples[typ.Name()]
+	}
+	if len(examples) > 0 {
+		return examples[r.Intn(len(examples))]
+	}
+	switch typ.Kind() {
+	case graphql.ScalarKind:
+		return scalarValue(typ.Name(), r)
+	case graphql.EnumKind:
+		values := typ.EnumValues()
+		return values[r.Intn(len(values))].Name
+	case graphql.ObjectKind:
+		return m.newObject(typ, seed)
+	case graphql.UnionKind:
+		possible := typ.PossibleTypes()
+		return m.value(possible[r.Intn(len(possible))], seed, nil)
+	default:
+		panic("unhandled type " + typ.String())
+	}
+}
+
+var words = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing",
+	"elit", "sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore",
+	"et", "dolore", "magna", "aliqua",
+}
+
+func scalarValue(name string, r *rand.Rand) interface{} {
+	switch name {
+	case "Int":
+		return int32(r.Intn(100))
+	case "Float":
+		return math.Round(r.Float64()*10000) / 100
+	case "Boolean":
+		return r.Intn(2) == 1
+	case "ID":
+		return strconv.FormatUint(uint64(r.Uint32()), 16)
+	default:
+		n := 1 + r.Intn(3)
+		sb := new(strings.Builder)
+		for i := 0; i < n; i++ {
+			w := words[r.Intn(len(words))]
+			if i == 0 {
+				sb.WriteString(strings.ToUpper(w[:1]))
+				sb.WriteString(w[1:])
+			} else {
+				sb.WriteByte(' ')
+				sb.WriteString(w)
+			}
+		}
+		return sb.String()
+	}
+}
+
+// object is a generated object. It resolves its fields from the schema.
+type object struct {
+	m      *mocker
+	typ    *graphql.Type
+	fields map[string]*mockField
+	seed   int64
+}
+
+func (m *mocker) newObject(typ *graphql.Type, seed int64) *object {
+	return &object{
+		m:      m,
+		typ:    typ,
+		fields: m.objects[typ.Name()],
+		seed:   seed,
+	}
+}
+
+// GraphQLType returns the object's type name.
+func (obj *object) GraphQLType() string {
+	return obj.typ.Name()
+}
+
+// ResolveField generates a value for the requested field.
+func (obj *object) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	if f := obj.m.fields[obj.typ.Name()+"."+req.Name]; f != nil {
+		return f(ctx, req)
+	}
+	field := obj.fields[req.Name]
+	if field == nil {
+		return nil, xerrors.Errorf("no field %s on %s", req.Name, obj.typ.Name())
+	}
+	return obj.m.value(field.defn.Type, derive(obj.seed, req.Name, 0), field.examples), nil
+}
+
+// derive returns a new seed for a child of the value with the given seed.
+func derive(seed int64, name string, i int) int64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(seed))
+	h.Write(buf[:])
+	h.Write([]byte(name))
+	binary.LittleEndian.PutUint64(buf[:], uint64(i))
+	h.Write(buf[:])
+	return int64(h.Sum64())
+}
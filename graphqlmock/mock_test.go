@@ -0,0 +1,277 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlmock
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+const testSchema = ExampleDirective + `
+scalar Date @example(value: "2019-12-25")
+
+type Query {
+	user(id: ID!): User
+	users: [User!]!
+	search: [SearchResult!]!
+	today: Date!
+}
+
+type Mutation {
+	deleteUser(id: ID!): Boolean!
+}
+
+type User {
+	id: ID!
+	name: String! @example(value: ["Alice", "Bob"])
+	age: Int
+	score: Float
+	admin: Boolean!
+	role: Role!
+}
+
+type Post {
+	title: String!
+}
+
+enum Role { ADMIN, MEMBER }
+
+union SearchResult = User | Post
+`
+
+func TestNewServer(t *testing.T) {
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	execute := func(t *testing.T, srv *graphql.Server, query string) map[string]interface{} {
+		t.Helper()
+		resp := srv.Execute(ctx, graphql.Request{Query: query})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+		return resp.Data.GoValue().(map[string]interface{})
+	}
+	const userQuery = `{ user(id: "1") { id name age score admin role } today }`
+
+	t.Run("Deterministic", func(t *testing.T) {
+		srv1, err := NewServer(schema, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv2, err := NewServer(schema, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got1 := execute(t, srv1, userQuery)
+		got2 := execute(t, srv2, userQuery)
+		if diff := cmp.Diff(got1, got2); diff != "" {
+			t.Errorf("responses differ (-first +second):\n%s", diff)
+		}
+	})
+	t.Run("Seed", func(t *testing.T) {
+		srv1, err := NewServer(schema, &Options{Seed: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv2, err := NewServer(schema, &Options{Seed: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		const query = `{ users { id } }`
+		if got1, got2 := execute(t, srv1, query), execute(t, srv2, query); cmp.Equal(got1, got2) {
+			t.Errorf("seeds 1 and 2 both returned %v", got1)
+		}
+	})
+	t.Run("Examples", func(t *testing.T) {
+		srv, err := NewServer(schema, &Options{ListLength: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := execute(t, srv, `{ users { name } today }`)
+		for _, u := range got["users"].([]interface{}) {
+			if name := u.(map[string]interface{})["name"]; name != "Alice" && name != "Bob" {
+				t.Errorf("name = %q; want \"Alice\" or \"Bob\"", name)
+			}
+		}
+		if got, want := got["today"], "2019-12-25"; got != want {
+			t.Errorf("today = %q; want %q", got, want)
+		}
+	})
+	t.Run("ListLength", func(t *testing.T) {
+		srv, err := NewServer(schema, &Options{ListLength: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := execute(t, srv, `{ users { id } }`)
+		if n := len(got["users"].([]interface{})); n != 3 {
+			t.Errorf("len(users) = %d; want 3", n)
+		}
+	})
+	t.Run("Union", func(t *testing.T) {
+		srv, err := NewServer(schema, &Options{ListLength: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := execute(t, srv, `{ search { __typename ... on User { name } ... on Post { title } } }`)
+		for i, result := range got["search"].([]interface{}) {
+			obj := result.(map[string]interface{})
+			switch obj["__typename"] {
+			case "User":
+				if _, ok := obj["name"]; !ok {
+					t.Errorf("search[%d] is a User without a name", i)
+				}
+			case "Post":
+				if _, ok := obj["title"]; !ok {
+					t.Errorf("search[%d] is a Post without a title", i)
+				}
+			default:
+				t.Errorf("search[%d].__typename = %q", i, obj["__typename"])
+			}
+		}
+	})
+	t.Run("Overrides", func(t *testing.T) {
+		srv, err := NewServer(schema, &Options{
+			Types: map[string]TypeMock{
+				"Role": func(r *rand.Rand) interface{} { return "ADMIN" },
+			},
+			Fields: map[string]FieldMock{
+				"Query.user": func(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+					return &testUser{ID: req.Args["id"].Scalar()}, nil
+				},
+				"Mutation.deleteUser": func(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+					return req.Args["id"].Scalar() == "1", nil
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := execute(t, srv, `{ user(id: "42") { id name } users { role } }`)
+		want := map[string]interface{}{
+			"user": map[string]interface{}{"id": "42", "name": "Eve"},
+			"users": []interface{}{
+				map[string]interface{}{"role": "ADMIN"},
+				map[string]interface{}{"role": "ADMIN"},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("data (-want +got):\n%s", diff)
+		}
+		got = execute(t, srv, `mutation { deleteUser(id: "1") }`)
+		if diff := cmp.Diff(map[string]interface{}{"deleteUser": "true"}, got); diff != "" {
+			t.Errorf("data (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		srv, err := NewServer(schema, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := srv.Execute(ctx, graphql.Request{Query: userQuery})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+		if _, err := json.Marshal(resp); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+type testUser struct {
+	ID   string
+	Name string
+}
+
+func (u *testUser) GraphQLType() string { return "User" }
+
+func (u *testUser) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	switch req.Name {
+	case "id":
+		return u.ID, nil
+	case "name":
+		return "Eve", nil
+	default:
+		return nil, nil
+	}
+}
+
+func TestNewServerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		opts   *Options
+	}{
+		{
+			name:   "UnknownType",
+			schema: testSchema,
+			opts:   &Options{Types: map[string]TypeMock{"Foo": nil}},
+		},
+		{
+			name:   "UnionTypeMock",
+			schema: testSchema,
+			opts:   &Options{Types: map[string]TypeMock{"SearchResult": nil}},
+		},
+		{
+			name:   "UnknownField",
+			schema: testSchema,
+			opts:   &Options{Fields: map[string]FieldMock{"User.email": nil}},
+		},
+		{
+			name:   "MalformedFieldName",
+			schema: testSchema,
+			opts:   &Options{Fields: map[string]FieldMock{"user": nil}},
+		},
+		{
+			name:   "NegativeListLength",
+			schema: testSchema,
+			opts:   &Options{ListLength: -1},
+		},
+		{
+			name:   "BadIntExample",
+			schema: ExampleDirective + `type Query { n: Int @example(value: "many") }`,
+		},
+		{
+			name:   "BadEnumExample",
+			schema: ExampleDirective + `type Query { c: Color @example(value: "BLUE") } enum Color { RED }`,
+		},
+		{
+			name:   "ObjectExample",
+			schema: ExampleDirective + `type Query { q: Query @example(value: "x") }`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := graphql.ParseSchema(test.schema, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = NewServer(schema, test.opts)
+			if err == nil {
+				t.Error("NewServer did not return an error")
+			} else {
+				t.Log(err)
+			}
+		})
+	}
+}
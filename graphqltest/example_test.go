@@ -0,0 +1,62 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqltest_test
+
+import (
+	"context"
+	"testing"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqltest"
+)
+
+var t *testing.T
+
+func Example() {
+	// This would normally be inside a test function.
+	var srv *graphql.Server // = graphql.NewServer(...)
+
+	rec := new(graphqltest.Recorder)
+	ctx := rec.Context(context.Background())
+	resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+		Query: `query ($id: ID!) { user(id: $id) { name } }`,
+		Variables: map[string]interface{}{
+			"id": 1234,
+		},
+	})
+	graphqltest.AssertErrors(t, resp, nil)
+	graphqltest.AssertData(t, resp, `{"user": {"name": "Alice"}}`)
+	if calls := rec.CallsTo("Query.user"); len(calls) != 1 {
+		t.Errorf("user resolved %d times; want 1", len(calls))
+	}
+}
+
+func ExampleAssertErrors() {
+	// This would normally be inside a test function.
+	var srv *graphql.Server // = graphql.NewServer(...)
+
+	resp := graphqltest.Execute(context.Background(), t, srv, graphqltest.Operation{
+		Query: `{ user(id: "42") { name } }`,
+	})
+	graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+		{
+			Message:   "not found",
+			Path:      graphqltest.Path("user"),
+			Locations: []graphql.Location{{Line: 1, Column: 3}},
+		},
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package graphqltest provides utilities for testing GraphQL servers.
+//
+// Golden files used by AssertGolden can be rewritten by running the tests with
+// the -graphqltest.update flag:
+//
+//	go test -graphqltest.update
+package graphqltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+var update = flag.Bool("graphqltest.update", false, "rewrite golden files compared with graphqltest.AssertGolden")
+
+// Operation describes a GraphQL operation to run in a test.
+type Operation struct {
+	// Query is the GraphQL document text.
+	Query string
+	// OperationName selects the operation to run if the document contains more
+	// than one.
+	OperationName string
+	// Variables specifies the values of the operation's variables. The values
+	// are converted to inputs using the same rules as encoding/json.
+	Variables map[string]interface{}
+}
+
+// Execute runs an operation on a server. If the operation's variables cannot
+// be converted, then Execute stops the test with tb.Fatal.
+func Execute(ctx context.Context, tb testing.TB, srv *graphql.Server, op Operation) graphql.Response {
+	tb.Helper()
+	vars, err := Variables(op.Variables)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv.Execute(ctx, graphql.Request{
+		Query:         op.Query,
+		OperationName: op.OperationName,
+		Variables:     vars,
+	})
+}
+
+// Variables converts Go values to inputs using the same rules as
+// encoding/json.
+func Variables(vars map[string]interface{}) (map[string]graphql.Input, error) {
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return nil, xerrors.Errorf("convert variables: %w", err)
+	}
+	var inputs map[string]graphql.Input
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, xerrors.Errorf("convert variables: %w", err)
+	}
+	return inputs, nil
+}
+
+// AssertData reports a test error if the response's data is not equivalent to
+// the given JSON. Whitespace and the order of object keys are ignored.
+func AssertData(tb testing.TB, resp graphql.Response, wantJSON string) {
+	tb.Helper()
+	want, err := decodeJSON([]byte(wantJSON))
+	if err != nil {
+		tb.Fatalf("want data: %v", err)
+	}
+	gotJSON, err := json.Marshal(resp.Data)
+	if err != nil {
+		tb.Fatalf("marshal data: %v", err)
+	}
+	got, err := decodeJSON(gotJSON)
+	if err != nil {
+		tb.Fatalf("got data: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		tb.Errorf("data (-want +got):\n%s", diff)
+	}
+}
+
+// AssertErrors reports a test error if the response's errors do not match the
+// given errors. The errors must appear in the same order and must have the same
+// paths and locations, although the order of locations is ignored. If a wanted
+// error has a non-empty message, then the received error's message must
+// contain it. A nil or empty want asserts that the response has no errors.
+// Nil entries in want are reported as test errors.
+func AssertErrors(tb testing.TB, resp graphql.Response, want []*graphql.ResponseError) {
+	tb.Helper()
+	for i, e := range want {
+		if e == nil {
+			tb.Errorf("AssertErrors: want[%d] is nil", i)
+			return
+		}
+	}
+	got := make([]*graphql.ResponseError, 0, len(resp.Errors))
+	for i, e := range resp.Errors {
+		e2 := new(graphql.ResponseError)
+		*e2 = *e
+		if i < len(want) && strings.Contains(e.Message, want[i].Message) {
+			// Replace matching messages so that they don't appear in the diff.
+			e2.Message = want[i].Message
+		}
+		got = append(got, e2)
+	}
+	diff := cmp.Diff(want, got,
+		cmpopts.EquateEmpty(),
+		cmpopts.SortSlices(func(l, m graphql.Location) bool {
+			if l.Line == m.Line {
+				return l.Column < m.Column
+			}
+			return l.Line < m.Line
+		}))
+	if diff != "" {
+		tb.Errorf("errors (-want +got):\n%s", diff)
+	}
+}
+
+// Path builds an error path from field names (strings) and list indices
+// (ints). It panics if given any other type.
+func Path(segments ...interface{}) []graphql.PathSegment {
+	path := make([]graphql.PathSegment, 0, len(segments))
+	for _, seg := range segments {
+		switch seg := seg.(type) {
+		case string:
+			path = append(path, graphql.PathSegment{Field: seg})
+		case int:
+			path = append(path, graphql.PathSegment{ListIndex: seg})
+		default:
+			panic(fmt.Sprintf("graphqltest.Path: invalid segment %#v", seg))
+		}
+	}
+	return path
+}
+
+// AssertGolden reports a test error if the JSON encoding of the response is not
+// equivalent to the contents of the file at path. Whitespace and the order of
+// object keys are ignored. If the -graphqltest.update flag is set, then
+// AssertGolden writes the response to the file instead.
+func AssertGolden(tb testing.TB, resp graphql.Response, path string) {
+	tb.Helper()
+	gotJSON, err := json.Marshal(resp)
+	if err != nil {
+		tb.Fatalf("marshal response: %v", err)
+	}
+	if *update {
+		buf := new(bytes.Buffer)
+		if err := json.Indent(buf, gotJSON, "", "  "); err != nil {
+			tb.Fatalf("marshal response: %v", err)
+		}
+		buf.WriteByte('\n')
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			tb.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0666); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+	wantJSON, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		tb.Fatalf("%s does not exist (run with -graphqltest.update to create)", path)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	want, err := decodeJSON(wantJSON)
+	if err != nil {
+		tb.Fatalf("%s: %v", path, err)
+	}
+	got, err := decodeJSON(gotJSON)
+	if err != nil {
+		tb.Fatalf("got response: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		tb.Errorf("response does not match %s (-want +got):\n%s", path, diff)
+	}
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, xerrors.New("extra data after JSON value")
+	}
+	return v, nil
+}
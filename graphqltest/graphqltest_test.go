@@ -0,0 +1,405 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqltest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+const testSchema = `
+type Query {
+	user(id: ID!): User
+	users(ids: [ID!]!): [User]!
+}
+
+type User {
+	id: ID!
+	name: String!
+	friends(first: Int = 10): [User!]!
+}
+`
+
+type testQuery struct{}
+
+func (testQuery) User(args map[string]graphql.Value) (*testUser, error) {
+	return findUser(args["id"].Scalar())
+}
+
+func (testQuery) Users(args map[string]graphql.Value) []*testUser {
+	ids := args["ids"]
+	users := make([]*testUser, 0, ids.Len())
+	for i := 0; i < ids.Len(); i++ {
+		u, err := findUser(ids.At(i).Scalar())
+		if err != nil {
+			users = append(users, nil)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users
+}
+
+type testUser struct {
+	ID        string
+	Name      string
+	friendIDs []string
+}
+
+func (u *testUser) Friends(args map[string]graphql.Value) ([]*testUser, error) {
+	var friends []*testUser
+	for _, id := range u.friendIDs {
+		f, err := findUser(id)
+		if err != nil {
+			return nil, err
+		}
+		friends = append(friends, f)
+	}
+	return friends, nil
+}
+
+func findUser(id string) (*testUser, error) {
+	switch id {
+	case "1":
+		return &testUser{ID: "1", Name: "Alice", friendIDs: []string{"2"}}, nil
+	case "2":
+		return &testUser{ID: "2", Name: "Bob", friendIDs: []string{"1", "3"}}, nil
+	default:
+		return nil, xerrors.Errorf("no user %q", id)
+	}
+}
+
+func newTestServer(tb testing.TB) *graphql.Server {
+	tb.Helper()
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := graphql.NewServer(schema, testQuery{}, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+func TestExecute(t *testing.T) {
+	srv := newTestServer(t)
+	resp := Execute(context.Background(), t, srv, Operation{
+		Query: `query ($id: ID!) { user(id: $id) { name } }`,
+		Variables: map[string]interface{}{
+			"id": 1,
+		},
+	})
+	AssertErrors(t, resp, nil)
+	AssertData(t, resp, `{"user": {"name": "Alice"}}`)
+}
+
+func TestAssertData(t *testing.T) {
+	srv := newTestServer(t)
+	resp := Execute(context.Background(), t, srv, Operation{
+		Query: `{ user(id: "2") { id name } }`,
+	})
+	tests := []struct {
+		name     string
+		wantJSON string
+		fail     bool
+	}{
+		{
+			name:     "Equal",
+			wantJSON: `{"user":{"id":"2","name":"Bob"}}`,
+		},
+		{
+			name:     "KeyOrder",
+			wantJSON: `{"user": {"name": "Bob", "id": "2"}}`,
+		},
+		{
+			name:     "DifferentValue",
+			wantJSON: `{"user":{"id":"2","name":"Alice"}}`,
+			fail:     true,
+		},
+		{
+			name:     "MissingKey",
+			wantJSON: `{"user":{"id":"2"}}`,
+			fail:     true,
+		},
+		{
+			name:     "BadJSON",
+			wantJSON: `{"user":`,
+			fail:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := runFake(t, func(tb testing.TB) {
+				AssertData(tb, resp, test.wantJSON)
+			})
+			if tb.failed != test.fail {
+				t.Errorf("failed = %t; want %t. Log:\n%s", tb.failed, test.fail, tb.log)
+			}
+		})
+	}
+}
+
+func TestAssertErrors(t *testing.T) {
+	srv := newTestServer(t)
+	resp := Execute(context.Background(), t, srv, Operation{
+		Query: "{\n  users(ids: [\"1\", \"42\"]) { name }\n  user(id: \"2\") { friends { id } }\n}",
+	})
+	tests := []struct {
+		name string
+		want []*graphql.ResponseError
+		fail bool
+	}{
+		{
+			name: "Match",
+			want: []*graphql.ResponseError{
+				{
+					Message:   `no user "3"`,
+					Path:      Path("user", "friends"),
+					Locations: []graphql.Location{{Line: 3, Column: 19}},
+				},
+			},
+		},
+		{
+			name: "IgnoreMessage",
+			want: []*graphql.ResponseError{
+				{
+					Path:      Path("user", "friends"),
+					Locations: []graphql.Location{{Line: 3, Column: 19}},
+				},
+			},
+		},
+		{
+			name: "WrongMessage",
+			want: []*graphql.ResponseError{
+				{
+					Message:   "permission denied",
+					Path:      Path("user", "friends"),
+					Locations: []graphql.Location{{Line: 3, Column: 19}},
+				},
+			},
+			fail: true,
+		},
+		{
+			name: "WrongPath",
+			want: []*graphql.ResponseError{
+				{
+					Path:      Path("user"),
+					Locations: []graphql.Location{{Line: 3, Column: 19}},
+				},
+			},
+			fail: true,
+		},
+		{
+			name: "WrongLocation",
+			want: []*graphql.ResponseError{
+				{
+					Path:      Path("user", "friends"),
+					Locations: []graphql.Location{{Line: 3, Column: 3}},
+				},
+			},
+			fail: true,
+		},
+		{
+			name: "None",
+			fail: true,
+		},
+		{
+			name: "NilWant",
+			want: []*graphql.ResponseError{nil},
+			fail: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := runFake(t, func(tb testing.TB) {
+				AssertErrors(tb, resp, test.want)
+			})
+			if tb.failed != test.fail {
+				t.Errorf("failed = %t; want %t. Log:\n%s", tb.failed, test.fail, tb.log)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	got := Path("users", 1, "name")
+	want := []graphql.PathSegment{
+		{Field: "users"},
+		{ListIndex: 1},
+		{Field: "name"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Path(...) (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	srv := newTestServer(t)
+	resp := Execute(context.Background(), t, srv, Operation{
+		Query: `{ user(id: "2") { name friends(first: 1) { name } } }`,
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		AssertGolden(t, resp, filepath.Join("testdata", "golden.json"))
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		other := Execute(context.Background(), t, srv, Operation{
+			Query: `{ user(id: "1") { name } }`,
+		})
+		tb := runFake(t, func(tb testing.TB) {
+			AssertGolden(tb, other, filepath.Join("testdata", "golden.json"))
+		})
+		if !tb.failed {
+			t.Error("AssertGolden did not fail")
+		}
+	})
+	t.Run("Missing", func(t *testing.T) {
+		tb := runFake(t, func(tb testing.TB) {
+			AssertGolden(tb, resp, filepath.Join("testdata", "missing.json"))
+		})
+		if !tb.failed {
+			t.Error("AssertGolden did not fail")
+		}
+	})
+	t.Run("Update", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "graphqltest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "sub", "update.json")
+
+		*update = true
+		tb := runFake(t, func(tb testing.TB) {
+			AssertGolden(tb, resp, path)
+		})
+		*update = false
+		if tb.failed {
+			t.Fatalf("AssertGolden failed while updating. Log:\n%s", tb.log)
+		}
+		AssertGolden(t, resp, path)
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	srv := newTestServer(t)
+	rec := new(Recorder)
+	ctx := rec.Context(context.Background())
+	resp := Execute(ctx, t, srv, Operation{
+		Query: `{ user(id: "1") { name friends { pal: name } } }`,
+	})
+	AssertErrors(t, resp, nil)
+
+	type call struct {
+		Field  string
+		Key    string
+		Args   map[string]interface{}
+		HasSub bool
+	}
+	var got []call
+	for _, c := range rec.Calls() {
+		gc := call{
+			Field:  c.ParentType + "." + c.Name,
+			Key:    c.Key,
+			HasSub: c.Selection != nil,
+		}
+		if len(c.Args) > 0 {
+			gc.Args = make(map[string]interface{})
+			for k, v := range c.Args {
+				gc.Args[k] = v.GoValue()
+			}
+		}
+		got = append(got, gc)
+	}
+	want := []call{
+		{Field: "Query.user", Key: "user", Args: map[string]interface{}{"id": "1"}, HasSub: true},
+		{Field: "User.name", Key: "name"},
+		{Field: "User.friends", Key: "friends", Args: map[string]interface{}{"first": "10"}, HasSub: true},
+		{Field: "User.name", Key: "pal"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("calls (-want +got):\n%s", diff)
+	}
+
+	friendCalls := rec.CallsTo("User.friends")
+	if len(friendCalls) != 1 {
+		t.Fatalf("len(CallsTo(\"User.friends\")) = %d; want 1", len(friendCalls))
+	}
+	if !friendCalls[0].Selection.Has("name") {
+		t.Error("User.friends selection does not include name")
+	}
+	if friends, ok := friendCalls[0].Result.([]*testUser); !ok || len(friends) != 1 || friends[0].Name != "Bob" {
+		t.Errorf("User.friends result = %#v; want [Bob]", friendCalls[0].Result)
+	}
+
+	rec.Reset()
+	if calls := rec.Calls(); len(calls) > 0 {
+		t.Errorf("after Reset, Calls() = %v; want []", calls)
+	}
+}
+
+// fakeTB is a testing.TB that records failures instead of reporting them.
+type fakeTB struct {
+	testing.TB
+	failed bool
+	log    string
+}
+
+// runFake calls f with a fakeTB in a new goroutine and waits for it to return
+// or call Fatal.
+func runFake(t *testing.T, f func(tb testing.TB)) *fakeTB {
+	tb := &fakeTB{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(tb)
+	}()
+	<-done
+	return tb
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Error(args ...interface{}) {
+	tb.failed = true
+	tb.log += fmt.Sprintln(args...)
+}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.failed = true
+	tb.log += fmt.Sprintf(format, args...) + "\n"
+}
+
+func (tb *fakeTB) Fatal(args ...interface{}) {
+	tb.Error(args...)
+	runtime.Goexit()
+}
+
+func (tb *fakeTB) Fatalf(format string, args ...interface{}) {
+	tb.Errorf(format, args...)
+	runtime.Goexit()
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqltest
+
+import (
+	"context"
+	"sync"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// Recorder records every field resolution of the operations executed with its
+// context. It is a graphql.Tracer, so it can also be set as a server's tracer
+// to record every operation. The zero value is an empty recorder. It is safe
+// to use from multiple goroutines.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []*Call
+}
+
+// Call is a recorded field resolution.
+type Call struct {
+	// ParentType is the name of the object type that the field belongs to.
+	ParentType string
+	// Key is the field's response key: its alias if present, otherwise its name.
+	Key string
+	// Name is the field's name.
+	Name string
+	// Args is the set of coerced arguments passed to the resolver.
+	Args map[string]graphql.Value
+	// Selection is the field's selection set or nil if the field is a scalar.
+	Selection *graphql.SelectionSet
+	// Result is the Go value returned by the resolver.
+	Result interface{}
+	// Err is the error returned by the resolver, if any.
+	Err error
+}
+
+// Context returns a new context that causes Server.Execute to record field
+// resolutions to rec.
+func (rec *Recorder) Context(ctx context.Context) context.Context {
+	return graphql.WithTracer(ctx, rec)
+}
+
+// TraceExecution implements graphql.Tracer. It does nothing.
+func (rec *Recorder) TraceExecution(ctx context.Context, req graphql.Request) (context.Context, func(*graphql.Response)) {
+	return ctx, nil
+}
+
+// TraceParse implements graphql.Tracer. It does nothing.
+func (rec *Recorder) TraceParse(ctx context.Context, source string) (context.Context, func([]*graphql.ResponseError)) {
+	return ctx, nil
+}
+
+// TraceValidation implements graphql.Tracer. It does nothing.
+func (rec *Recorder) TraceValidation(ctx context.Context) (context.Context, func(*graphql.ValidatedQuery, []*graphql.ResponseError)) {
+	return ctx, nil
+}
+
+// TraceField implements graphql.Tracer by recording the field's resolution
+// once its resolver returns.
+func (rec *Recorder) TraceField(ctx context.Context, info *graphql.FieldInfo) (context.Context, func(interface{}, error)) {
+	call := &Call{
+		ParentType: info.ParentType,
+		Name:       info.Request.Name,
+		Args:       info.Request.Args,
+		Selection:  info.Request.Selection,
+	}
+	if len(info.Path) > 0 {
+		call.Key = info.Path[len(info.Path)-1].Field
+	}
+	return ctx, func(result interface{}, err error) {
+		call.Result = result
+		call.Err = err
+		rec.mu.Lock()
+		rec.calls = append(rec.calls, call)
+		rec.mu.Unlock()
+	}
+}
+
+// Calls returns the recorded field resolutions in the order they occurred.
+func (rec *Recorder) Calls() []*Call {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]*Call(nil), rec.calls...)
+}
+
+// CallsTo returns the recorded resolutions of a field, given as "Type.field".
+func (rec *Recorder) CallsTo(field string) []*Call {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var calls []*Call
+	for _, call := range rec.calls {
+		if call.ParentType+"."+call.Name == field {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset discards all recorded field resolutions.
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	rec.calls = nil
+	rec.mu.Unlock()
+}
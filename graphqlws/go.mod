@@ -0,0 +1,10 @@
+module zombiezen.com/go/graphql-server/graphqlws
+
+go 1.12
+
+require (
+	github.com/google/go-cmp v0.3.1
+	github.com/gorilla/websocket v1.4.2
+	golang.org/x/xerrors v0.0.0-20190717185122-a985d3407aa7
+	zombiezen.com/go/graphql-server v0.8.0
+)
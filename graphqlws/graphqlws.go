@@ -0,0 +1,517 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package graphqlws serves GraphQL operations over WebSockets using the
+// graphql-transport-ws subprotocol described in
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+//
+// This package is a separate module so that programs that don't serve
+// WebSockets don't depend on a WebSocket implementation.
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// Subprotocol is the WebSocket subprotocol spoken by Handler.
+const Subprotocol = "graphql-transport-ws"
+
+// Close codes defined by the graphql-transport-ws protocol.
+const (
+	closeBadRequest               = 4400
+	closeUnauthorized             = 4401
+	closeForbidden                = 4403
+	closeSubprotocolNotAcceptable = 4406
+	closeInitTimeout              = 4408
+	closeSubscriberExists         = 4409
+	closeTooManyInits             = 4429
+)
+
+// Message types defined by the graphql-transport-ws protocol.
+const (
+	wsConnectionInit = "connection_init"
+	wsConnectionAck  = "connection_ack"
+	wsPing           = "ping"
+	wsPong           = "pong"
+	wsSubscribe      = "subscribe"
+	wsNext           = "next"
+	wsError          = "error"
+	wsComplete       = "complete"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// OnInit is called with the payload of the client's connection_init
+	// message, which is nil if the client did not send one. The returned
+	// context is used for all operations on the connection, so OnInit can
+	// attach the client's credentials to it. The context passed to OnInit is
+	// the HTTP request's context, so OnInit can also inspect values added by
+	// HTTP middleware. If OnInit returns an error, then the connection is
+	// closed with the 4403 Forbidden close code. If OnInit is nil, then every
+	// connection is accepted.
+	OnInit func(ctx context.Context, payload map[string]interface{}) (context.Context, error)
+
+	// ConnectionInitTimeout is how long the client has to send
+	// connection_init after connecting. If zero, 10 seconds is used.
+	ConnectionInitTimeout time.Duration
+
+	// WriteTimeout is how long a write to the client may take before the
+	// connection is closed. If zero, 10 seconds is used.
+	WriteTimeout time.Duration
+
+	// If PingInterval is positive, then the handler sends a ping message to
+	// the client at this interval after acknowledging the connection to keep
+	// it alive.
+	PingInterval time.Duration
+
+	// CheckOrigin returns whether to accept the handshake request. If nil,
+	// requests with an Origin header are only accepted from the same host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Handler serves GraphQL operations over WebSockets using the
+// graphql-transport-ws subprotocol. Subscriptions are run with
+// graphql.Server.Subscribe. Queries and mutations are also accepted and
+// produce a single result.
+//
+// A Handler is safe to use from multiple goroutines.
+type Handler struct {
+	server   *graphql.Server
+	opts     Options
+	upgrader websocket.Upgrader
+
+	mu           sync.Mutex
+	conns        map[*wsConn]struct{}
+	shuttingDown bool
+	active       sync.WaitGroup
+}
+
+// NewHandler returns a new handler that runs operations on the given
+// server. opts may be nil, which is equivalent to passing a pointer to the
+// zero value.
+func NewHandler(server *graphql.Server, opts *Options) *Handler {
+	h := &Handler{
+		server: server,
+		conns:  make(map[*wsConn]struct{}),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.ConnectionInitTimeout <= 0 {
+		h.opts.ConnectionInitTimeout = 10 * time.Second
+	}
+	if h.opts.WriteTimeout <= 0 {
+		h.opts.WriteTimeout = 10 * time.Second
+	}
+	h.upgrader = websocket.Upgrader{
+		Subprotocols: []string{Subprotocol},
+		CheckOrigin:  h.opts.CheckOrigin,
+	}
+	return h
+}
+
+// ServeHTTP upgrades the request to a WebSocket and serves operations on it
+// until the connection is closed.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	shuttingDown := h.shuttingDown
+	h.mu.Unlock()
+	if shuttingDown {
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := &wsConn{
+		h:      h,
+		ws:     ws,
+		ctx:    ctx,
+		cancel: cancel,
+		ops:    make(map[string]*wsOperation),
+	}
+	h.mu.Lock()
+	if h.shuttingDown {
+		h.mu.Unlock()
+		c.close(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	h.conns[c] = struct{}{}
+	h.active.Add(1)
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.conns, c)
+		h.mu.Unlock()
+		h.active.Done()
+	}()
+	c.serve()
+}
+
+// Shutdown gracefully closes all connections. It stops accepting new
+// connections and operations, ends active subscriptions with a complete
+// message, and waits for in-flight queries and mutations to send their
+// results before closing each connection. If ctx is done before all
+// connections are closed, then Shutdown calls Close and returns ctx's error.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	conns := make([]*wsConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+	for _, c := range conns {
+		c.drain()
+	}
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		h.Close()
+		return ctx.Err()
+	}
+}
+
+// Close immediately closes all connections and cancels their operations.
+// New connections are refused.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	h.shuttingDown = true
+	for c := range h.conns {
+		c.cancel()
+		c.ws.Close()
+	}
+	h.mu.Unlock()
+	return nil
+}
+
+// wsMessage is a graphql-transport-ws message.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn is a single WebSocket connection.
+type wsConn struct {
+	h      *Handler
+	ws     *websocket.Conn
+	cancel context.CancelFunc
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	ctx      context.Context
+	inited   bool
+	acked    bool
+	draining bool
+	ops      map[string]*wsOperation
+	wg       sync.WaitGroup
+}
+
+// wsOperation is an operation started by a subscribe message.
+type wsOperation struct {
+	cancel       context.CancelFunc
+	subscription bool
+}
+
+func (c *wsConn) serve() {
+	defer func() {
+		c.cancel()
+		c.ws.Close()
+		c.wg.Wait()
+	}()
+	if c.ws.Subprotocol() != Subprotocol {
+		c.close(closeSubprotocolNotAcceptable, "Subprotocol not acceptable")
+		return
+	}
+	initTimer := time.AfterFunc(c.h.opts.ConnectionInitTimeout, func() {
+		c.mu.Lock()
+		acked := c.acked
+		c.mu.Unlock()
+		if !acked {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+		if code, reason := c.handle(msg); code != 0 {
+			c.close(code, reason)
+			return
+		}
+	}
+}
+
+// handle processes a message from the client. It returns a non-zero close code
+// if the connection should be closed.
+func (c *wsConn) handle(msg wsMessage) (code int, reason string) {
+	switch msg.Type {
+	case wsConnectionInit:
+		return c.init(msg.Payload)
+	case wsPing:
+		c.send(wsMessage{Type: wsPong})
+		return 0, ""
+	case wsPong:
+		return 0, ""
+	case wsSubscribe:
+		return c.subscribe(msg)
+	case wsComplete:
+		c.mu.Lock()
+		op := c.ops[msg.ID]
+		last := c.removeOpLocked(msg.ID)
+		c.mu.Unlock()
+		if op != nil {
+			op.cancel()
+		}
+		if last {
+			c.close(websocket.CloseGoingAway, "server shutting down")
+		}
+		return 0, ""
+	default:
+		return closeBadRequest, fmt.Sprintf("Invalid message type %q", msg.Type)
+	}
+}
+
+func (c *wsConn) init(rawPayload json.RawMessage) (code int, reason string) {
+	c.mu.Lock()
+	inited := c.inited
+	c.inited = true
+	ctx := c.ctx
+	c.mu.Unlock()
+	if inited {
+		return closeTooManyInits, "Too many initialisation requests"
+	}
+	var payload map[string]interface{}
+	if len(rawPayload) > 0 {
+		if err := json.Unmarshal(rawPayload, &payload); err != nil {
+			return closeBadRequest, "Invalid connection_init payload"
+		}
+	}
+	if c.h.opts.OnInit != nil {
+		var err error
+		ctx, err = c.h.opts.OnInit(ctx, payload)
+		if err != nil {
+			return closeForbidden, "Forbidden"
+		}
+	}
+	c.mu.Lock()
+	c.ctx = ctx
+	c.acked = true
+	c.mu.Unlock()
+	c.send(wsMessage{Type: wsConnectionAck})
+	if c.h.opts.PingInterval > 0 {
+		go c.ping(ctx, c.h.opts.PingInterval)
+	}
+	return 0, ""
+}
+
+func (c *wsConn) subscribe(msg wsMessage) (code int, reason string) {
+	if msg.ID == "" {
+		return closeBadRequest, "Invalid subscribe message: missing id"
+	}
+	var req graphql.Request
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		return closeBadRequest, "Invalid subscribe message: " + err.Error()
+	}
+	// Determine the operation type up front so that shutdown can end
+	// subscriptions while letting queries and mutations finish. Invalid
+	// documents are left for the server to report.
+	query := c.h.server.TrustedDocument(req.DocumentID)
+	if req.Query != "" {
+		query, _ = c.h.server.Schema().Validate(req.Query)
+		req.ValidatedQuery = query
+	}
+	isSubscription := query != nil && query.TypeOf(req.OperationName) == graphql.SubscriptionOperation
+
+	c.mu.Lock()
+	if !c.acked {
+		c.mu.Unlock()
+		return closeUnauthorized, "Unauthorized"
+	}
+	if c.ops[msg.ID] != nil {
+		c.mu.Unlock()
+		return closeSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID)
+	}
+	if c.draining {
+		c.mu.Unlock()
+		c.sendErrors(msg.ID, []*graphql.ResponseError{{Message: "server shutting down"}})
+		return 0, ""
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	op := &wsOperation{
+		cancel:       cancel,
+		subscription: isSubscription,
+	}
+	c.ops[msg.ID] = op
+	c.wg.Add(1)
+	c.mu.Unlock()
+	go c.run(ctx, msg.ID, op, req)
+	return 0, ""
+}
+
+// run executes an operation and sends its results to the client. Messages are
+// sent without holding c.mu so that a slow client does not block the
+// connection's other operations or shutdown.
+func (c *wsConn) run(ctx context.Context, id string, op *wsOperation, req graphql.Request) {
+	defer c.wg.Done()
+	defer op.cancel()
+	results, errs := c.h.server.Subscribe(ctx, req)
+	if len(errs) > 0 {
+		if current, last := c.finish(id, op); current {
+			c.sendErrors(id, errs)
+			if last {
+				c.close(websocket.CloseGoingAway, "server shutting down")
+			}
+		}
+		return
+	}
+	for resp := range results {
+		payload, err := json.Marshal(resp)
+		if err != nil {
+			payload, _ = json.Marshal(graphql.Response{
+				Errors: []*graphql.ResponseError{{Message: err.Error()}},
+			})
+		}
+		c.mu.Lock()
+		current := c.ops[id] == op
+		c.mu.Unlock()
+		if !current {
+			// The client completed the operation.
+			return
+		}
+		if err := c.send(wsMessage{ID: id, Type: wsNext, Payload: payload}); err != nil {
+			return
+		}
+	}
+	if current, last := c.finish(id, op); current {
+		c.send(wsMessage{ID: id, Type: wsComplete})
+		if last {
+			c.close(websocket.CloseGoingAway, "server shutting down")
+		}
+	}
+}
+
+// finish removes op from the connection. current reports whether op was
+// still running, and last reports whether the connection should be closed
+// after the operation's final message.
+func (c *wsConn) finish(id string, op *wsOperation) (current, last bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ops[id] != op {
+		return false, false
+	}
+	return true, c.removeOpLocked(id)
+}
+
+// removeOpLocked removes an operation. It reports whether it was the last
+// operation of a draining connection, in which case the caller should close
+// the connection after releasing c.mu. The caller must hold c.mu.
+func (c *wsConn) removeOpLocked(id string) (last bool) {
+	if c.ops[id] == nil {
+		return false
+	}
+	delete(c.ops, id)
+	return c.draining && len(c.ops) == 0
+}
+
+// drain ends the connection's subscriptions and closes the connection once
+// its remaining operations finish.
+func (c *wsConn) drain() {
+	c.mu.Lock()
+	c.draining = true
+	empty := len(c.ops) == 0
+	for _, op := range c.ops {
+		if op.subscription {
+			op.cancel()
+		}
+	}
+	c.mu.Unlock()
+	if empty {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+func (c *wsConn) ping(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := c.send(wsMessage{Type: wsPing}); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) sendErrors(id string, errs []*graphql.ResponseError) {
+	payload, err := json.Marshal(errs)
+	if err != nil {
+		payload, _ = json.Marshal([]*graphql.ResponseError{{Message: err.Error()}})
+	}
+	c.send(wsMessage{ID: id, Type: wsError, Payload: payload})
+}
+
+// send writes a message to the client. If the write fails or does not finish
+// within the handler's WriteTimeout, then the connection is closed.
+func (c *wsConn) send(msg wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(c.h.opts.WriteTimeout))
+	if err := c.ws.WriteJSON(msg); err != nil {
+		// A failed write leaves the connection in an unknown state.
+		c.ws.Close()
+		return err
+	}
+	return nil
+}
+
+// close sends a close message with the given code and closes the connection.
+func (c *wsConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.writeMu.Unlock()
+	c.ws.Close()
+}
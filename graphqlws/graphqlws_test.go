@@ -0,0 +1,469 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package graphqlws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type wsQuery struct{}
+
+func (wsQuery) Greeting(ctx context.Context) string {
+	if user, _ := ctx.Value(wsUserKey{}).(string); user != "" {
+		return "hi " + user
+	}
+	return "hi"
+}
+
+type wsSubscription struct{}
+
+func (wsSubscription) Counter(ctx context.Context, args map[string]graphql.Value) <-chan int32 {
+	n, _ := json.Number(args["to"].Scalar()).Int64()
+	c := make(chan int32)
+	go func() {
+		defer close(c)
+		for i := int32(1); i <= int32(n); i++ {
+			select {
+			case c <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (wsSubscription) Ticks(ctx context.Context) <-chan string {
+	c := make(chan string)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case c <- "tick":
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+type wsUserKey struct{}
+
+// newSubscriptionTestServer returns a GraphQL server with a subscription type
+// provided by sub.
+func newSubscriptionTestServer(t *testing.T, sub interface{}) *graphql.Server {
+	t.Helper()
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			greeting: String!
+		}
+
+		type Subscription {
+			counter(to: Int!): Int!
+			ticks: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := graphql.NewServerWithOptions(schema, wsQuery{}, nil, &graphql.ServerOptions{
+		Subscription: sub,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// newWebSocketTestServer starts a WebSocket server. The caller must close the
+// returned server.
+func newWebSocketTestServer(t *testing.T, opts *Options) (*Handler, *httptest.Server) {
+	t.Helper()
+	h := NewHandler(newSubscriptionTestServer(t, wsSubscription{}), opts)
+	return h, httptest.NewServer(h)
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, subprotocols ...string) *websocket.Conn {
+	t.Helper()
+	if subprotocols == nil {
+		subprotocols = []string{Subprotocol}
+	}
+	dialer := &websocket.Dialer{Subprotocols: subprotocols}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	return conn
+}
+
+func writeWebSocketMessage(t *testing.T, conn *websocket.Conn, msg string) {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readWebSocketMessage reads the next message as a JSON-compatible Go value.
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// readWebSocketClose reads messages until the connection is closed and
+// returns the close code.
+func readWebSocketClose(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !xerrors.As(err, &closeErr) {
+			t.Fatalf("read: %v; want close error", err)
+		}
+		return closeErr.Code
+	}
+}
+
+func initWebSocket(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	writeWebSocketMessage(t, conn, `{"type":"connection_init"}`)
+	if got := readWebSocketMessage(t, conn); got["type"] != "connection_ack" {
+		t.Fatalf("got %v; want connection_ack", got)
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe string
+		want      []map[string]interface{}
+	}{
+		{
+			name:      "Subscription",
+			subscribe: `{"id":"1","type":"subscribe","payload":{"query":"subscription { counter(to: 2) }"}}`,
+			want: []map[string]interface{}{
+				{"id": "1", "type": "next", "payload": map[string]interface{}{"data": map[string]interface{}{"counter": 1.0}}},
+				{"id": "1", "type": "next", "payload": map[string]interface{}{"data": map[string]interface{}{"counter": 2.0}}},
+				{"id": "1", "type": "complete"},
+			},
+		},
+		{
+			name:      "Variables",
+			subscribe: `{"id":"1","type":"subscribe","payload":{"query":"subscription($n: Int!) { counter(to: $n) }","variables":{"n":1}}}`,
+			want: []map[string]interface{}{
+				{"id": "1", "type": "next", "payload": map[string]interface{}{"data": map[string]interface{}{"counter": 1.0}}},
+				{"id": "1", "type": "complete"},
+			},
+		},
+		{
+			name:      "Query",
+			subscribe: `{"id":"q","type":"subscribe","payload":{"query":"{ greeting }"}}`,
+			want: []map[string]interface{}{
+				{"id": "q", "type": "next", "payload": map[string]interface{}{"data": map[string]interface{}{"greeting": "hi"}}},
+				{"id": "q", "type": "complete"},
+			},
+		},
+		{
+			name:      "ValidationError",
+			subscribe: `{"id":"1","type":"subscribe","payload":{"query":"{ nope }"}}`,
+			want: []map[string]interface{}{
+				{"id": "1", "type": "error", "payload": []interface{}{
+					map[string]interface{}{
+						"message":   `field "nope" not found on type Query`,
+						"locations": []interface{}{map[string]interface{}{"line": 1.0, "column": 3.0}},
+						"path":      []interface{}{"nope"},
+					},
+				}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, srv := newWebSocketTestServer(t, nil)
+			defer srv.Close()
+			conn := dialWebSocket(t, srv)
+			defer conn.Close()
+			initWebSocket(t, conn)
+			writeWebSocketMessage(t, conn, test.subscribe)
+			var got []map[string]interface{}
+			for len(got) < len(test.want) {
+				got = append(got, readWebSocketMessage(t, conn))
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("messages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWebSocketHandlerProtocol(t *testing.T) {
+	t.Run("Ping", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		writeWebSocketMessage(t, conn, `{"type":"ping"}`)
+		if got := readWebSocketMessage(t, conn); got["type"] != "pong" {
+			t.Errorf("got %v; want pong", got)
+		}
+	})
+	t.Run("ServerPing", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, &Options{PingInterval: 10 * time.Millisecond})
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		initWebSocket(t, conn)
+		if got := readWebSocketMessage(t, conn); got["type"] != "ping" {
+			t.Errorf("got %v; want ping", got)
+		}
+	})
+	t.Run("ClientComplete", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		initWebSocket(t, conn)
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+		if got := readWebSocketMessage(t, conn); got["type"] != "next" {
+			t.Fatalf("got %v; want next", got)
+		}
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"complete"}`)
+		writeWebSocketMessage(t, conn, `{"type":"ping"}`)
+		for {
+			got := readWebSocketMessage(t, conn)
+			if got["type"] == "pong" {
+				break
+			}
+			if got["type"] != "next" {
+				t.Fatalf("got %v; want next or pong", got)
+			}
+		}
+		// The ID may be reused once the operation is complete.
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"{ greeting }"}}`)
+		if got := readWebSocketMessage(t, conn); got["type"] != "next" {
+			t.Errorf("got %v; want next", got)
+		}
+	})
+	t.Run("SubscribeBeforeInit", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"{ greeting }"}}`)
+		if got, want := readWebSocketClose(t, conn), 4401; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+	t.Run("DuplicateInit", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		initWebSocket(t, conn)
+		writeWebSocketMessage(t, conn, `{"type":"connection_init"}`)
+		if got, want := readWebSocketClose(t, conn), 4429; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+	t.Run("DuplicateID", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		initWebSocket(t, conn)
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+		if got, want := readWebSocketClose(t, conn), 4409; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+	t.Run("InvalidMessage", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		writeWebSocketMessage(t, conn, `{"type":"bogus"}`)
+		if got, want := readWebSocketClose(t, conn), 4400; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+	t.Run("InitTimeout", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, &Options{ConnectionInitTimeout: 10 * time.Millisecond})
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		if got, want := readWebSocketClose(t, conn), 4408; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+	t.Run("WrongSubprotocol", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, nil)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv, "graphql-ws")
+		defer conn.Close()
+		if got, want := readWebSocketClose(t, conn), 4406; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+}
+
+func TestWebSocketHandlerOnInit(t *testing.T) {
+	opts := &Options{
+		OnInit: func(ctx context.Context, payload map[string]interface{}) (context.Context, error) {
+			token, _ := payload["token"].(string)
+			if token != "secret" {
+				return nil, xerrors.New("bad token")
+			}
+			return context.WithValue(ctx, wsUserKey{}, "alice"), nil
+		},
+	}
+	t.Run("Accepted", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, opts)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		writeWebSocketMessage(t, conn, `{"type":"connection_init","payload":{"token":"secret"}}`)
+		if got := readWebSocketMessage(t, conn); got["type"] != "connection_ack" {
+			t.Fatalf("got %v; want connection_ack", got)
+		}
+		writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"{ greeting }"}}`)
+		got := readWebSocketMessage(t, conn)
+		want := map[string]interface{}{
+			"id":      "1",
+			"type":    "next",
+			"payload": map[string]interface{}{"data": map[string]interface{}{"greeting": "hi alice"}},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("message (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Rejected", func(t *testing.T) {
+		_, srv := newWebSocketTestServer(t, opts)
+		defer srv.Close()
+		conn := dialWebSocket(t, srv)
+		defer conn.Close()
+		writeWebSocketMessage(t, conn, `{"type":"connection_init","payload":{"token":"guess"}}`)
+		if got, want := readWebSocketClose(t, conn), 4403; got != want {
+			t.Errorf("close code = %d; want %d", got, want)
+		}
+	})
+}
+
+func TestWebSocketHandlerShutdown(t *testing.T) {
+	h, srv := newWebSocketTestServer(t, nil)
+	defer srv.Close()
+	conn := dialWebSocket(t, srv)
+	defer conn.Close()
+	initWebSocket(t, conn)
+	writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+	if got := readWebSocketMessage(t, conn); got["type"] != "next" {
+		t.Fatalf("got %v; want next", got)
+	}
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		shutdownDone <- h.Shutdown(ctx)
+	}()
+	for {
+		got := readWebSocketMessage(t, conn)
+		if got["type"] == "complete" {
+			break
+		}
+		if got["type"] != "next" {
+			t.Fatalf("got %v; want next or complete", got)
+		}
+	}
+	if got, want := readWebSocketClose(t, conn), websocket.CloseGoingAway; got != want {
+		t.Errorf("close code = %d; want %d", got, want)
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Error("Shutdown:", err)
+	}
+
+	// New connections are refused.
+	dialer := &websocket.Dialer{Subprotocols: []string{Subprotocol}}
+	if conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil); err == nil {
+		conn.Close()
+		t.Error("Dial after Shutdown succeeded")
+	}
+}
+
+func TestWebSocketHandlerSlowClient(t *testing.T) {
+	h := NewHandler(newSubscriptionTestServer(t, bigTicksSubscription{}), &Options{
+		WriteTimeout: 100 * time.Millisecond,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	conn := dialWebSocket(t, srv)
+	defer conn.Close()
+	initWebSocket(t, conn)
+	// Subscribe and stop reading so that the server's writes stall.
+	writeWebSocketMessage(t, conn, `{"id":"1","type":"subscribe","payload":{"query":"subscription { ticks }"}}`)
+	time.Sleep(500 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Error("Shutdown:", err)
+	}
+}
+
+// bigTicksSubscription sends large ticks to fill the connection's buffers
+// quickly.
+type bigTicksSubscription struct {
+	wsSubscription
+}
+
+func (bigTicksSubscription) Ticks(ctx context.Context) <-chan string {
+	tick := strings.Repeat("tick", 64<<10)
+	c := make(chan string)
+	go func() {
+		defer close(c)
+		for {
+			select {
+			case c <- tick:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocgraphql_test
+
+import (
+	"go.opencensus.io/stats/view"
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/ocgraphql"
+)
+
+func ExampleTracer() {
+	// Register the views to export stats for.
+	if err := view.Register(ocgraphql.DefaultViews...); err != nil {
+		// handle error
+	}
+
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			hello: String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	server, err := graphql.NewServerWithOptions(schema, Query{}, nil, &graphql.ServerOptions{
+		Tracer: &ocgraphql.Tracer{
+			// Only tag field measurements with field names for fields that
+			// are estimated to cost at least 100.
+			FieldCostThreshold: 100,
+		},
+	})
+	if err != nil {
+		// handle error
+	}
+	_ = server
+}
+
+type Query struct{}
+
+func (Query) Hello() string {
+	return "Hello, World!"
+}
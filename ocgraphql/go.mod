@@ -0,0 +1,9 @@
+module zombiezen.com/go/graphql-server/ocgraphql
+
+go 1.12
+
+require (
+	github.com/google/go-cmp v0.3.1
+	go.opencensus.io v0.22.2
+	zombiezen.com/go/graphql-server v0.8.0
+)
@@ -0,0 +1,218 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ocgraphql provides a graphql.Tracer that records OpenCensus spans
+// and stats.
+package ocgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// Tracer is a graphql.Tracer that records OpenCensus spans for the phases of
+// each operation and the measures defined in this package. The zero value
+// records spans for executing, parsing, and validating operations.
+type Tracer struct {
+	// If FieldSpans is true, then the tracer records a span for each resolved
+	// field.
+	FieldSpans bool
+
+	// FieldCostThreshold limits the cardinality of field measurements. If it
+	// is positive, then only measurements for fields whose estimated cost is
+	// at least FieldCostThreshold are recorded with the KeyParentType and
+	// KeyFieldName tags. Measurements for cheaper fields are recorded with
+	// only the operation's tags. See graphql.FieldInfo.Cost for how the cost
+	// is estimated.
+	FieldCostThreshold int
+
+	// CostOptions is passed to graphql.FieldInfo.Cost when FieldCostThreshold
+	// is positive.
+	CostOptions *graphql.CostOptions
+}
+
+// operationState is the state of a single traced operation.
+type operationState struct {
+	start         time.Time
+	operationName string
+
+	mu     sync.Mutex
+	opName string
+	opType string
+	source string
+}
+
+type operationStateContextKey struct{}
+
+// setQuery records the operation's source, name, and type from its document.
+func (state *operationState) setQuery(query *graphql.ValidatedQuery) {
+	if query == nil {
+		return
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.source = query.Source()
+	op := query.Document().FindOperation(state.operationName)
+	if op == nil {
+		return
+	}
+	state.opType = query.TypeOf(state.operationName).String()
+	if op.Name != nil {
+		state.opName = op.Name.Value
+	}
+}
+
+// tags returns the tags for the operation's measurements.
+func (state *operationState) tags() []tag.Mutator {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return []tag.Mutator{
+		tag.Upsert(KeyOperationName, state.opName),
+		tag.Upsert(KeyOperationType, state.opType),
+	}
+}
+
+// TraceExecution starts a "graphql:execute" span.
+func (t *Tracer) TraceExecution(ctx context.Context, req graphql.Request) (context.Context, func(*graphql.Response)) {
+	state := &operationState{
+		start:         time.Now(),
+		operationName: req.OperationName,
+	}
+	state.setQuery(req.ValidatedQuery)
+	ctx = context.WithValue(ctx, operationStateContextKey{}, state)
+	ctx, span := trace.StartSpan(ctx, "graphql:execute", trace.WithSpanKind(trace.SpanKindServer))
+	query := req.Query
+	if req.ValidatedQuery != nil {
+		query = req.ValidatedQuery.Source()
+	}
+	if query != "" {
+		span.AddAttributes(trace.StringAttribute("graphql.query", query))
+	}
+	if req.OperationName != "" {
+		span.AddAttributes(trace.StringAttribute("graphql.operation_name", req.OperationName))
+	}
+	return ctx, func(resp *graphql.Response) {
+		if query == "" {
+			// The request named a trusted document, which was resolved later.
+			state.mu.Lock()
+			source := state.source
+			state.mu.Unlock()
+			span.AddAttributes(trace.StringAttribute("graphql.query", source))
+		}
+		stats.RecordWithTags(ctx, state.tags(),
+			OperationLatency.M(sinceMillis(state.start)),
+			OperationErrors.M(int64(len(resp.Errors))))
+		if span.IsRecordingEvents() {
+			span.AddAttributes(trace.BoolAttribute("graphql.has_data", !resp.Data.IsNull()))
+			if len(resp.Errors) > 0 {
+				if errors, err := json.Marshal(resp.Errors); err == nil {
+					span.AddAttributes(trace.StringAttribute("graphql.errors", string(errors)))
+				}
+			}
+		}
+		span.End()
+	}
+}
+
+// TraceParse starts a "graphql:parse" span.
+func (t *Tracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*graphql.ResponseError)) {
+	ctx, span := trace.StartSpan(ctx, "graphql:parse", trace.WithSpanKind(trace.SpanKindServer))
+	return ctx, func(errs []*graphql.ResponseError) {
+		setErrorStatus(span, errs)
+		span.End()
+	}
+}
+
+// TraceValidation starts a "graphql:validate" span.
+func (t *Tracer) TraceValidation(ctx context.Context) (context.Context, func(*graphql.ValidatedQuery, []*graphql.ResponseError)) {
+	state, _ := ctx.Value(operationStateContextKey{}).(*operationState)
+	ctx, span := trace.StartSpan(ctx, "graphql:validate", trace.WithSpanKind(trace.SpanKindServer))
+	return ctx, func(query *graphql.ValidatedQuery, errs []*graphql.ResponseError) {
+		if state != nil {
+			state.setQuery(query)
+		}
+		setErrorStatus(span, errs)
+		span.End()
+	}
+}
+
+// TraceField records the field's measures and starts a "graphql:resolve" span
+// if t.FieldSpans is true.
+func (t *Tracer) TraceField(ctx context.Context, info *graphql.FieldInfo) (context.Context, func(interface{}, error)) {
+	state, _ := ctx.Value(operationStateContextKey{}).(*operationState)
+	parentType, fieldName := info.ParentType, info.Request.Name
+	tagField := t.FieldCostThreshold <= 0 || info.Cost(t.CostOptions) >= t.FieldCostThreshold
+	start := time.Now()
+	var span *trace.Span
+	if t.FieldSpans {
+		ctx, span = trace.StartSpan(ctx, "graphql:resolve "+fieldName, trace.WithSpanKind(trace.SpanKindServer))
+		span.AddAttributes(
+			trace.StringAttribute("graphql.field", fieldName),
+			trace.StringAttribute("graphql.parent_type", parentType),
+		)
+	}
+	return ctx, func(_ interface{}, err error) {
+		if state != nil {
+			elapsed := time.Since(start)
+			tags := state.tags()
+			if tagField {
+				tags = append(tags,
+					tag.Upsert(KeyParentType, parentType),
+					tag.Upsert(KeyFieldName, fieldName))
+			}
+			measurements := []stats.Measurement{FieldLatency.M(durationMillis(elapsed))}
+			if err != nil {
+				measurements = append(measurements, FieldErrors.M(1))
+			}
+			stats.RecordWithTags(ctx, tags, measurements...)
+		}
+		if span == nil {
+			return
+		}
+		if err != nil {
+			span.SetStatus(trace.Status{
+				Code:    trace.StatusCodeUnknown,
+				Message: err.Error(),
+			})
+		}
+		span.End()
+	}
+}
+
+func setErrorStatus(span *trace.Span, errs []*graphql.ResponseError) {
+	if len(errs) == 0 {
+		return
+	}
+	span.SetStatus(trace.Status{
+		Code:    trace.StatusCodeInvalidArgument,
+		Message: errs[0].Message,
+	})
+}
+
+func sinceMillis(start time.Time) float64 {
+	return durationMillis(time.Since(start))
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
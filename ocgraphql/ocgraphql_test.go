@@ -0,0 +1,309 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocgraphql
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/trace"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+type testQuery struct{}
+
+func (testQuery) Greet(args map[string]graphql.Value) string {
+	return "Hello, " + args["name"].Scalar() + "!"
+}
+
+func (testQuery) Slow() string {
+	time.Sleep(50 * time.Millisecond)
+	return "done"
+}
+
+func (testQuery) Fail() (string, error) {
+	return "", errors.New("bork")
+}
+
+func (testQuery) Items() []testItem {
+	return []testItem{{Name: "a"}, {Name: "b"}}
+}
+
+type testItem struct {
+	Name string
+}
+
+const testSchema = `
+	type Query {
+		greet(name: String!): String!
+		slow: String!
+		fail: String
+		items(first: Int): [Item!]!
+	}
+
+	type Item {
+		name: String!
+	}
+`
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s *trace.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func (r *spanRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, s := range r.spans {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestTracer(t *testing.T) {
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := new(spanRecorder)
+	trace.RegisterExporter(rec)
+	defer trace.UnregisterExporter(rec)
+
+	tests := []struct {
+		name   string
+		tracer *Tracer
+		query  string
+		want   []string
+	}{
+		{
+			name:   "Default",
+			tracer: new(Tracer),
+			query:  `{ greet(name: "World") }`,
+			want:   []string{"graphql:parse", "graphql:validate", "graphql:execute"},
+		},
+		{
+			name:   "FieldSpans",
+			tracer: &Tracer{FieldSpans: true},
+			query:  `{ greet(name: "World") }`,
+			want:   []string{"graphql:parse", "graphql:validate", "graphql:resolve greet", "graphql:execute"},
+		},
+		{
+			name:   "ParseError",
+			tracer: new(Tracer),
+			query:  `{`,
+			want:   []string{"graphql:parse", "graphql:execute"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, err := graphql.NewServerWithOptions(schema, testQuery{}, nil, &graphql.ServerOptions{
+				Tracer: test.tracer,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec.mu.Lock()
+			rec.spans = nil
+			rec.mu.Unlock()
+			ctx, parent := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+			srv.Execute(ctx, graphql.Request{Query: test.query})
+			parent.End()
+
+			want := append(test.want, "test")
+			if diff := cmp.Diff(want, rec.names()); diff != "" {
+				t.Errorf("spans (-want +got):\n%s", diff)
+			}
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			for _, s := range rec.spans {
+				if s.Name != "test" && s.TraceID != parent.SpanContext().TraceID {
+					t.Errorf("span %q not in request trace", s.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestTracerTrustedDocument(t *testing.T) {
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := new(spanRecorder)
+	trace.RegisterExporter(rec)
+	defer trace.UnregisterExporter(rec)
+	const doc = `query Greet { greet(name: "World") }`
+	srv, err := graphql.NewServerWithOptions(schema, testQuery{}, nil, &graphql.ServerOptions{
+		Tracer: new(Tracer),
+		TrustedDocuments: &graphql.TrustedDocumentOptions{
+			Documents: map[string]string{"abc": doc},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+	srv.Execute(ctx, graphql.Request{DocumentID: "abc"})
+	parent.End()
+
+	want := []string{"graphql:validate", "graphql:execute", "test"}
+	if diff := cmp.Diff(want, rec.names()); diff != "" {
+		t.Errorf("spans (-want +got):\n%s", diff)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, s := range rec.spans {
+		if s.Name == "graphql:execute" {
+			if got := s.Attributes["graphql.query"]; got != doc {
+				t.Errorf("graphql.query = %q; want %q", got, doc)
+			}
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		tracer *Tracer
+		query  string
+		want   map[string][]string
+	}{
+		{
+			name:   "Fields",
+			tracer: new(Tracer),
+			query:  `query Greeting { greet(name: "World") fail }`,
+			want: map[string][]string{
+				OperationCountView.Name: {
+					"graphql.operation_name=Greeting graphql.operation_type=query: 1",
+				},
+				OperationErrorCountView.Name: {
+					"graphql.operation_name=Greeting graphql.operation_type=query: 1",
+				},
+				FieldCountView.Name: {
+					"graphql.field_name=fail graphql.operation_name=Greeting graphql.operation_type=query graphql.parent_type=Query: 1",
+					"graphql.field_name=greet graphql.operation_name=Greeting graphql.operation_type=query graphql.parent_type=Query: 1",
+				},
+				FieldErrorCountView.Name: {
+					"graphql.field_name=fail graphql.operation_name=Greeting graphql.operation_type=query graphql.parent_type=Query: 1",
+				},
+			},
+		},
+		{
+			name:   "ParseError",
+			tracer: new(Tracer),
+			query:  `{`,
+			want: map[string][]string{
+				OperationCountView.Name: {
+					": 1",
+				},
+				OperationErrorCountView.Name: {
+					": 1",
+				},
+			},
+		},
+		{
+			name:   "FieldCostThreshold",
+			tracer: &Tracer{FieldCostThreshold: 5},
+			query:  `{ greet(name: "World") items(first: 10) { name } }`,
+			want: map[string][]string{
+				OperationCountView.Name: {
+					"graphql.operation_type=query: 1",
+				},
+				OperationErrorCountView.Name: {
+					"graphql.operation_type=query: 0",
+				},
+				FieldCountView.Name: {
+					"graphql.field_name=items graphql.operation_type=query graphql.parent_type=Query: 1",
+					"graphql.operation_type=query: 3",
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := view.Register(DefaultViews...); err != nil {
+				t.Fatal(err)
+			}
+			defer view.Unregister(DefaultViews...)
+			srv, err := graphql.NewServerWithOptions(schema, testQuery{}, nil, &graphql.ServerOptions{
+				Tracer: test.tracer,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv.Execute(context.Background(), graphql.Request{Query: test.query})
+
+			for _, v := range DefaultViews {
+				rows, err := view.RetrieveData(v.Name)
+				if err != nil {
+					t.Errorf("RetrieveData(%q): %v", v.Name, err)
+					continue
+				}
+				if v == OperationLatencyView || v == FieldLatencyView {
+					continue
+				}
+				got := formatRows(rows)
+				if diff := cmp.Diff(test.want[v.Name], got); diff != "" {
+					t.Errorf("%s rows (-want +got):\n%s", v.Name, diff)
+				}
+			}
+		})
+	}
+}
+
+// formatRows formats count and sum rows in a stable order.
+func formatRows(rows []*view.Row) []string {
+	var lines []string
+	for _, row := range rows {
+		var tags []string
+		for _, tg := range row.Tags {
+			tags = append(tags, tg.Key.Name()+"="+tg.Value)
+		}
+		sort.Strings(tags)
+		var value string
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			value = strconv.FormatInt(data.Value, 10)
+		case *view.SumData:
+			value = strconv.FormatFloat(data.Value, 'g', -1, 64)
+		default:
+			value = "?"
+		}
+		lines = append(lines, strings.Join(tags, " ")+": "+value)
+	}
+	sort.Strings(lines)
+	return lines
+}
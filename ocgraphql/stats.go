@@ -0,0 +1,114 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package ocgraphql
+
+import (
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// Measures recorded by Tracer.
+var (
+	OperationLatency = stats.Float64(
+		"zombiezen.com/go/graphql-server/operation_latency",
+		"End-to-end latency of GraphQL operations",
+		stats.UnitMilliseconds)
+	OperationErrors = stats.Int64(
+		"zombiezen.com/go/graphql-server/operation_errors",
+		"Number of errors in GraphQL responses",
+		stats.UnitDimensionless)
+	FieldLatency = stats.Float64(
+		"zombiezen.com/go/graphql-server/field_latency",
+		"Latency of GraphQL field resolvers",
+		stats.UnitMilliseconds)
+	FieldErrors = stats.Int64(
+		"zombiezen.com/go/graphql-server/field_errors",
+		"Number of GraphQL field resolvers that returned an error",
+		stats.UnitDimensionless)
+)
+
+// Tag keys added to measurements recorded by Tracer. KeyOperationName and
+// KeyOperationType are added to all measurements. KeyParentType and
+// KeyFieldName are added to field measurements.
+var (
+	KeyOperationName = tag.MustNewKey("graphql.operation_name")
+	KeyOperationType = tag.MustNewKey("graphql.operation_type")
+	KeyParentType    = tag.MustNewKey("graphql.parent_type")
+	KeyFieldName     = tag.MustNewKey("graphql.field_name")
+)
+
+// DefaultLatencyDistribution is the latency distribution used by the
+// predefined views, in milliseconds.
+var DefaultLatencyDistribution = view.Distribution(1, 2, 3, 4, 5, 6, 8, 10, 13, 16, 20, 25, 30, 40, 50, 65, 80, 100, 130, 160, 200, 250, 300, 400, 500, 650, 800, 1000, 2000, 5000, 10000, 20000, 50000, 100000)
+
+// Predefined views of the measures recorded by Tracer. They must be registered
+// with view.Register to be exported.
+var (
+	OperationLatencyView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/operation_latency",
+		Measure:     OperationLatency,
+		Aggregation: DefaultLatencyDistribution,
+		Description: "Latency distribution of GraphQL operations, by operation name and type",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType},
+	}
+	OperationCountView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/operation_count",
+		Measure:     OperationLatency,
+		Aggregation: view.Count(),
+		Description: "Count of GraphQL operations, by operation name and type",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType},
+	}
+	OperationErrorCountView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/operation_error_count",
+		Measure:     OperationErrors,
+		Aggregation: view.Sum(),
+		Description: "Count of errors in GraphQL responses, by operation name and type",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType},
+	}
+	FieldLatencyView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/field_latency",
+		Measure:     FieldLatency,
+		Aggregation: DefaultLatencyDistribution,
+		Description: "Latency distribution of GraphQL field resolvers, by operation and field",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType, KeyParentType, KeyFieldName},
+	}
+	FieldCountView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/field_count",
+		Measure:     FieldLatency,
+		Aggregation: view.Count(),
+		Description: "Count of GraphQL field resolver calls, by operation and field",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType, KeyParentType, KeyFieldName},
+	}
+	FieldErrorCountView = &view.View{
+		Name:        "zombiezen.com/go/graphql-server/field_error_count",
+		Measure:     FieldErrors,
+		Aggregation: view.Sum(),
+		Description: "Count of GraphQL field resolvers that returned an error, by operation and field",
+		TagKeys:     []tag.Key{KeyOperationName, KeyOperationType, KeyParentType, KeyFieldName},
+	}
+)
+
+// DefaultViews are the default views provided by this package.
+var DefaultViews = []*view.View{
+	OperationLatencyView,
+	OperationCountView,
+	OperationErrorCountView,
+	FieldLatencyView,
+	FieldCountView,
+	FieldErrorCountView,
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otelgraphql_test
+
+import (
+	"time"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/otelgraphql"
+)
+
+func ExampleNewTracer() {
+	schema, err := graphql.ParseSchema(`
+		type Query {
+			hello: String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	// Record spans for the operation and for any resolver that takes longer
+	// than 10 milliseconds, using the global tracer provider.
+	tracer := otelgraphql.NewTracer(&otelgraphql.Options{
+		FieldSpans:         true,
+		FieldSpanThreshold: 10 * time.Millisecond,
+	})
+	server, err := graphql.NewServerWithOptions(schema, Query{}, nil, &graphql.ServerOptions{
+		Tracer: tracer,
+	})
+	if err != nil {
+		// handle error
+	}
+	_ = server
+}
+
+type Query struct{}
+
+func (Query) Hello() string {
+	return "Hello, World!"
+}
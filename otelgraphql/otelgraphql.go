@@ -0,0 +1,266 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package otelgraphql provides a graphql.Tracer that records OpenTelemetry
+// spans following the GraphQL semantic conventions described in
+// https://opentelemetry.io/docs/specs/semconv/graphql/graphql-spans/.
+//
+// Spans are started as children of the span in the context passed to
+// Server.Execute, so wrapping an HTTP handler with OpenTelemetry's HTTP
+// instrumentation propagates incoming trace context to the operation. The
+// operation's span is stored in the context passed to resolvers.
+//
+// This package is a separate module so that programs that don't use
+// OpenTelemetry don't depend on it.
+package otelgraphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+const instrumentationName = "zombiezen.com/go/graphql-server/otelgraphql"
+
+// Attribute keys used in addition to the semantic conventions.
+const (
+	DocumentHashKey    = attribute.Key("graphql.document.hash")
+	ErrorCountKey      = attribute.Key("graphql.error.count")
+	FieldNameKey       = attribute.Key("graphql.field.name")
+	FieldPathKey       = attribute.Key("graphql.field.path")
+	FieldParentTypeKey = attribute.Key("graphql.field.parent_type")
+	FieldReturnTypeKey = attribute.Key("graphql.field.type")
+)
+
+// Options specifies optional parameters to NewTracer.
+type Options struct {
+	// TracerProvider is used to create spans. If nil, the global provider
+	// is used.
+	TracerProvider trace.TracerProvider
+
+	// If IncludeDocument is true, then the operation's span includes the
+	// graphql.document attribute. Documents may include sensitive values in
+	// literals, so this is off by default. The document's SHA-256 hash is
+	// always included as graphql.document.hash.
+	IncludeDocument bool
+
+	// If FieldSpans is true, then a span is recorded for each resolved field
+	// that takes at least FieldSpanThreshold to resolve. If FieldSpanThreshold
+	// is positive, field spans are recorded after the resolver returns, so spans
+	// started by the resolver will not be children of the field's span.
+	FieldSpans         bool
+	FieldSpanThreshold time.Duration
+}
+
+// Tracer is a graphql.Tracer that records OpenTelemetry spans.
+type Tracer struct {
+	tracer             trace.Tracer
+	includeDocument    bool
+	fieldSpans         bool
+	fieldSpanThreshold time.Duration
+}
+
+// NewTracer returns a new tracer. opts may be nil, which is equivalent to
+// passing a pointer to the zero value.
+func NewTracer(opts *Options) *Tracer {
+	if opts == nil {
+		opts = new(Options)
+	}
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{
+		tracer:             tp.Tracer(instrumentationName),
+		includeDocument:    opts.IncludeDocument,
+		fieldSpans:         opts.FieldSpans,
+		fieldSpanThreshold: opts.FieldSpanThreshold,
+	}
+}
+
+// operationState is the state of a single traced operation.
+type operationState struct {
+	mu    sync.Mutex
+	query *graphql.ValidatedQuery
+}
+
+type operationStateContextKey struct{}
+
+// TraceExecution starts the operation's span.
+func (t *Tracer) TraceExecution(ctx context.Context, req graphql.Request) (context.Context, func(*graphql.Response)) {
+	source := req.Query
+	if req.ValidatedQuery != nil {
+		source = req.ValidatedQuery.Source()
+	}
+	var attrs []attribute.KeyValue
+	if source != "" {
+		attrs = t.documentAttributes(source)
+	}
+	ctx, span := t.tracer.Start(ctx, "GraphQL Operation", trace.WithAttributes(attrs...))
+	state := &operationState{query: req.ValidatedQuery}
+	ctx = context.WithValue(ctx, operationStateContextKey{}, state)
+	return ctx, func(resp *graphql.Response) {
+		state.mu.Lock()
+		query := state.query
+		state.mu.Unlock()
+		if source == "" {
+			// The request named a trusted document, which was resolved during
+			// validation.
+			var resolved string
+			if query != nil {
+				resolved = query.Source()
+			}
+			span.SetAttributes(t.documentAttributes(resolved)...)
+		}
+		if query != nil {
+			if op := query.Document().FindOperation(req.OperationName); op != nil {
+				opType := query.TypeOf(req.OperationName).String()
+				name := opType
+				span.SetAttributes(semconv.GraphqlOperationTypeKey.String(opType))
+				if op.Name != nil {
+					span.SetAttributes(semconv.GraphqlOperationName(op.Name.Value))
+					name += " " + op.Name.Value
+				}
+				span.SetName(name)
+			}
+		}
+		if len(resp.Errors) > 0 {
+			span.SetAttributes(ErrorCountKey.Int(len(resp.Errors)))
+			for _, err := range resp.Errors {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, resp.Errors[0].Message)
+		}
+		span.End()
+	}
+}
+
+// documentAttributes returns the operation span's attributes for a document.
+func (t *Tracer) documentAttributes(source string) []attribute.KeyValue {
+	hash := sha256.Sum256([]byte(source))
+	attrs := []attribute.KeyValue{
+		DocumentHashKey.String(hex.EncodeToString(hash[:])),
+	}
+	if t.includeDocument {
+		attrs = append(attrs, semconv.GraphqlDocument(source))
+	}
+	return attrs
+}
+
+// TraceParse starts a span for parsing the document.
+func (t *Tracer) TraceParse(ctx context.Context, source string) (context.Context, func([]*graphql.ResponseError)) {
+	ctx, span := t.tracer.Start(ctx, "GraphQL Parse")
+	return ctx, func(errs []*graphql.ResponseError) {
+		endWithErrors(span, errs)
+	}
+}
+
+// TraceValidation starts a span for validating the document.
+func (t *Tracer) TraceValidation(ctx context.Context) (context.Context, func(*graphql.ValidatedQuery, []*graphql.ResponseError)) {
+	state, _ := ctx.Value(operationStateContextKey{}).(*operationState)
+	ctx, span := t.tracer.Start(ctx, "GraphQL Validate")
+	return ctx, func(query *graphql.ValidatedQuery, errs []*graphql.ResponseError) {
+		if state != nil {
+			state.mu.Lock()
+			state.query = query
+			state.mu.Unlock()
+		}
+		endWithErrors(span, errs)
+	}
+}
+
+// TraceField starts a span for a field if field spans are enabled.
+func (t *Tracer) TraceField(ctx context.Context, info *graphql.FieldInfo) (context.Context, func(interface{}, error)) {
+	if !t.fieldSpans {
+		return ctx, nil
+	}
+	name := info.ParentType + "." + info.Request.Name
+	if t.fieldSpanThreshold <= 0 {
+		ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(fieldAttributes(info)...))
+		return ctx, func(_ interface{}, err error) {
+			endWithError(span, err)
+		}
+	}
+	// Most fields finish under the threshold, so the span's attributes are
+	// only built for fields that are recorded.
+	info = &graphql.FieldInfo{
+		Path:       info.Path,
+		ParentType: info.ParentType,
+		ReturnType: info.ReturnType,
+		Request:    graphql.FieldRequest{Name: info.Request.Name},
+	}
+	start := time.Now()
+	return ctx, func(_ interface{}, err error) {
+		end := time.Now()
+		if end.Sub(start) < t.fieldSpanThreshold {
+			return
+		}
+		_, span := t.tracer.Start(ctx, name, trace.WithAttributes(fieldAttributes(info)...), trace.WithTimestamp(start))
+		if err != nil {
+			span.RecordError(err, trace.WithTimestamp(end))
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+func fieldAttributes(info *graphql.FieldInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		FieldNameKey.String(info.Request.Name),
+		FieldPathKey.String(formatPath(info.Path)),
+		FieldParentTypeKey.String(info.ParentType),
+		FieldReturnTypeKey.String(info.ReturnType),
+	}
+}
+
+func endWithErrors(span trace.Span, errs []*graphql.ResponseError) {
+	if len(errs) > 0 {
+		for _, err := range errs {
+			span.RecordError(err)
+		}
+		span.SetStatus(codes.Error, errs[0].Message)
+	}
+	span.End()
+}
+
+func endWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func formatPath(path []graphql.PathSegment) string {
+	sb := new(strings.Builder)
+	for i, seg := range path {
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(seg.String())
+	}
+	return sb.String()
+}
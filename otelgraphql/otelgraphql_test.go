@@ -0,0 +1,273 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package otelgraphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+const testSchema = `
+	type Query {
+		greet(name: String!): String!
+		slow: String!
+		fail: String
+	}
+
+	type Mutation {
+		touch: Boolean!
+	}
+`
+
+type testQuery struct{}
+
+func (testQuery) Greet(args map[string]graphql.Value) string {
+	return "Hello, " + args["name"].Scalar() + "!"
+}
+
+func (testQuery) Slow() string {
+	time.Sleep(100 * time.Millisecond)
+	return "done"
+}
+
+func (testQuery) Fail() (string, error) {
+	return "", errors.New("bork")
+}
+
+type testMutation struct{}
+
+func (testMutation) Touch() bool {
+	return true
+}
+
+func newTestServer(tb testing.TB, opts *Options) (*graphql.Server, *tracetest.SpanRecorder) {
+	tb.Helper()
+	rec := tracetest.NewSpanRecorder()
+	if opts == nil {
+		opts = new(Options)
+	}
+	opts.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	schema, err := graphql.ParseSchema(testSchema, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := graphql.NewServerWithOptions(schema, testQuery{}, testMutation{}, &graphql.ServerOptions{
+		Tracer: NewTracer(opts),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv, rec
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func attrMap(kvs []attribute.KeyValue) map[attribute.Key]string {
+	m := make(map[attribute.Key]string)
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value.Emit()
+	}
+	return m
+}
+
+func TestOperationSpan(t *testing.T) {
+	ctx := context.Background()
+	t.Run("NamedQuery", func(t *testing.T) {
+		srv, rec := newTestServer(t, nil)
+		resp := srv.Execute(ctx, graphql.Request{
+			Query: `query Hello { greet(name: "World") }`,
+		})
+		if len(resp.Errors) > 0 {
+			t.Fatal(resp.Errors)
+		}
+		spans := rec.Ended()
+		if diff := cmp.Diff([]string{"GraphQL Parse", "GraphQL Validate", "query Hello"}, spanNames(spans)); diff != "" {
+			t.Fatalf("spans (-want +got):\n%s", diff)
+		}
+		op := spans[2]
+		attrs := attrMap(op.Attributes())
+		if got, want := attrs[semconv.GraphqlOperationNameKey], "Hello"; got != want {
+			t.Errorf("%s = %q; want %q", semconv.GraphqlOperationNameKey, got, want)
+		}
+		if got, want := attrs[semconv.GraphqlOperationTypeKey], "query"; got != want {
+			t.Errorf("%s = %q; want %q", semconv.GraphqlOperationTypeKey, got, want)
+		}
+		if got := attrs[DocumentHashKey]; len(got) != 64 {
+			t.Errorf("%s = %q; want SHA-256 hex", DocumentHashKey, got)
+		}
+		if _, ok := attrs[semconv.GraphqlDocumentKey]; ok {
+			t.Errorf("%s present without IncludeDocument", semconv.GraphqlDocumentKey)
+		}
+		for _, s := range spans[:2] {
+			if s.Parent().SpanID() != op.SpanContext().SpanID() {
+				t.Errorf("span %q is not a child of the operation span", s.Name())
+			}
+		}
+	})
+	t.Run("AnonymousMutationWithDocument", func(t *testing.T) {
+		srv, rec := newTestServer(t, &Options{IncludeDocument: true})
+		const query = `mutation { touch }`
+		srv.Execute(ctx, graphql.Request{Query: query})
+		spans := rec.Ended()
+		op := spans[len(spans)-1]
+		if got, want := op.Name(), "mutation"; got != want {
+			t.Errorf("operation span name = %q; want %q", got, want)
+		}
+		attrs := attrMap(op.Attributes())
+		if got := attrs[semconv.GraphqlDocumentKey]; got != query {
+			t.Errorf("%s = %q; want %q", semconv.GraphqlDocumentKey, got, query)
+		}
+	})
+	t.Run("ParseError", func(t *testing.T) {
+		srv, rec := newTestServer(t, nil)
+		srv.Execute(ctx, graphql.Request{Query: `{`})
+		spans := rec.Ended()
+		if diff := cmp.Diff([]string{"GraphQL Parse", "GraphQL Operation"}, spanNames(spans)); diff != "" {
+			t.Fatalf("spans (-want +got):\n%s", diff)
+		}
+		for _, s := range spans {
+			if s.Status().Code != codes.Error {
+				t.Errorf("span %q status = %v; want %v", s.Name(), s.Status().Code, codes.Error)
+			}
+		}
+	})
+	t.Run("FieldError", func(t *testing.T) {
+		srv, rec := newTestServer(t, nil)
+		srv.Execute(ctx, graphql.Request{Query: `{ fail }`})
+		spans := rec.Ended()
+		op := spans[len(spans)-1]
+		if op.Status().Code != codes.Error {
+			t.Errorf("status = %v; want %v", op.Status().Code, codes.Error)
+		}
+		if got, want := attrMap(op.Attributes())[ErrorCountKey], "1"; got != want {
+			t.Errorf("%s = %q; want %q", ErrorCountKey, got, want)
+		}
+		if len(op.Events()) != 1 {
+			t.Errorf("len(events) = %d; want 1", len(op.Events()))
+		}
+	})
+	t.Run("TrustedDocument", func(t *testing.T) {
+		rec := tracetest.NewSpanRecorder()
+		schema, err := graphql.ParseSchema(testSchema, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const doc = `query Hello { greet(name: "World") }`
+		srv, err := graphql.NewServerWithOptions(schema, testQuery{}, testMutation{}, &graphql.ServerOptions{
+			Tracer: NewTracer(&Options{
+				TracerProvider:  sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)),
+				IncludeDocument: true,
+			}),
+			TrustedDocuments: &graphql.TrustedDocumentOptions{
+				Documents: map[string]string{"abc": doc},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.Execute(ctx, graphql.Request{DocumentID: "abc"})
+		spans := rec.Ended()
+		if diff := cmp.Diff([]string{"GraphQL Validate", "query Hello"}, spanNames(spans)); diff != "" {
+			t.Fatalf("spans (-want +got):\n%s", diff)
+		}
+		attrs := attrMap(spans[1].Attributes())
+		if got := attrs[semconv.GraphqlDocumentKey]; got != doc {
+			t.Errorf("%s = %q; want %q", semconv.GraphqlDocumentKey, got, doc)
+		}
+		hash := sha256.Sum256([]byte(doc))
+		if got, want := attrs[DocumentHashKey], hex.EncodeToString(hash[:]); got != want {
+			t.Errorf("%s = %q; want %q", DocumentHashKey, got, want)
+		}
+	})
+	t.Run("ValidatedQuery", func(t *testing.T) {
+		srv, rec := newTestServer(t, nil)
+		query, errs := srv.Schema().Validate(`query Hi { greet(name: "World") }`)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		srv.Execute(ctx, graphql.Request{ValidatedQuery: query})
+		if diff := cmp.Diff([]string{"query Hi"}, spanNames(rec.Ended())); diff != "" {
+			t.Errorf("spans (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestFieldSpans(t *testing.T) {
+	ctx := context.Background()
+	t.Run("All", func(t *testing.T) {
+		srv, rec := newTestServer(t, &Options{FieldSpans: true})
+		srv.Execute(ctx, graphql.Request{Query: `{ greet(name: "World") fail }`})
+		spans := rec.Ended()
+		want := []string{"GraphQL Parse", "GraphQL Validate", "Query.greet", "Query.fail", "query"}
+		if diff := cmp.Diff(want, spanNames(spans)); diff != "" {
+			t.Fatalf("spans (-want +got):\n%s", diff)
+		}
+		greet := spans[2]
+		if greet.Parent().SpanID() != spans[4].SpanContext().SpanID() {
+			t.Error("field span is not a child of the operation span")
+		}
+		wantAttrs := map[attribute.Key]string{
+			FieldNameKey:       "greet",
+			FieldPathKey:       "greet",
+			FieldParentTypeKey: "Query",
+			FieldReturnTypeKey: "String!",
+		}
+		if diff := cmp.Diff(wantAttrs, attrMap(greet.Attributes())); diff != "" {
+			t.Errorf("greet attributes (-want +got):\n%s", diff)
+		}
+		if got := spans[3].Status().Code; got != codes.Error {
+			t.Errorf("fail status = %v; want %v", got, codes.Error)
+		}
+	})
+	t.Run("Threshold", func(t *testing.T) {
+		srv, rec := newTestServer(t, &Options{
+			FieldSpans:         true,
+			FieldSpanThreshold: 50 * time.Millisecond,
+		})
+		srv.Execute(ctx, graphql.Request{Query: `{ greet(name: "World") slow }`})
+		spans := rec.Ended()
+		want := []string{"GraphQL Parse", "GraphQL Validate", "Query.slow", "query"}
+		if diff := cmp.Diff(want, spanNames(spans)); diff != "" {
+			t.Fatalf("spans (-want +got):\n%s", diff)
+		}
+		slow := spans[2]
+		if d := slow.EndTime().Sub(slow.StartTime()); d < 100*time.Millisecond {
+			t.Errorf("slow span duration = %v; want >= 100ms", d)
+		}
+		if slow.Parent().SpanID() != spans[3].SpanContext().SpanID() {
+			t.Error("field span is not a child of the operation span")
+		}
+	})
+}
@@ -0,0 +1,348 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package relay
+
+import (
+	"context"
+	"encoding/base64"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// PageInfoType is the definition of the PageInfo type returned by
+// connections. It can be added to a schema's source.
+const PageInfoType = `type PageInfo {
+  hasPreviousPage: Boolean!
+  hasNextPage: Boolean!
+  startCursor: String
+  endCursor: String
+}
+`
+
+// Connection is a page of a list. Its fields follow the Relay cursor
+// connections specification, so it can be returned from a field with a type
+// like:
+//
+//	type UserConnection {
+//	  edges: [UserEdge!]!
+//	  nodes: [User!]!
+//	  pageInfo: PageInfo!
+//	  totalCount: Int
+//	}
+//
+//	type UserEdge {
+//	  node: User!
+//	  cursor: String!
+//	}
+type Connection struct {
+	Edges    []*Edge
+	PageInfo *PageInfo
+	// TotalCount is the number of nodes in the whole list. It is null for
+	// connections built with ConnectionFromKeyset.
+	TotalCount graphql.NullInt
+}
+
+// Nodes returns the nodes of the connection's edges.
+func (conn *Connection) Nodes() []interface{} {
+	nodes := make([]interface{}, 0, len(conn.Edges))
+	for _, e := range conn.Edges {
+		nodes = append(nodes, e.Node)
+	}
+	return nodes
+}
+
+// Edge is an element of a connection.
+type Edge struct {
+	Node   interface{}
+	Cursor string
+}
+
+// PageInfo describes the position of a connection's page in the whole list.
+type PageInfo struct {
+	HasPreviousPage bool
+	HasNextPage     bool
+	StartCursor     graphql.NullString
+	EndCursor       graphql.NullString
+}
+
+func newPageInfo(edges []*Edge) *PageInfo {
+	info := new(PageInfo)
+	if len(edges) > 0 {
+		info.StartCursor = graphql.NullString{S: edges[0].Cursor, Valid: true}
+		info.EndCursor = graphql.NullString{S: edges[len(edges)-1].Cursor, Valid: true}
+	}
+	return info
+}
+
+// ConnectionArgs holds the pagination arguments of a connection field.
+type ConnectionArgs struct {
+	First  graphql.NullInt
+	After  graphql.NullString
+	Last   graphql.NullInt
+	Before graphql.NullString
+}
+
+// ParseConnectionArgs reads the first, after, last, and before arguments
+// from a field's arguments and validates them. Other arguments are ignored.
+func ParseConnectionArgs(args map[string]graphql.Value) (ConnectionArgs, error) {
+	var cargs ConnectionArgs
+	if err := args["first"].Convert(&cargs.First); err != nil {
+		return ConnectionArgs{}, xerrors.Errorf("connection arguments: first: %w", err)
+	}
+	if err := args["after"].Convert(&cargs.After); err != nil {
+		return ConnectionArgs{}, xerrors.Errorf("connection arguments: after: %w", err)
+	}
+	if err := args["last"].Convert(&cargs.Last); err != nil {
+		return ConnectionArgs{}, xerrors.Errorf("connection arguments: last: %w", err)
+	}
+	if err := args["before"].Convert(&cargs.Before); err != nil {
+		return ConnectionArgs{}, xerrors.Errorf("connection arguments: before: %w", err)
+	}
+	if err := cargs.Validate(); err != nil {
+		return ConnectionArgs{}, err
+	}
+	return cargs, nil
+}
+
+// Validate returns an error if the arguments violate the rules of the Relay
+// specification: first and last must not be negative and must not both be
+// given.
+func (args ConnectionArgs) Validate() error {
+	if args.First.Valid && args.First.Int < 0 {
+		return xerrors.Errorf("connection arguments: first must not be negative (got %d)", args.First.Int)
+	}
+	if args.Last.Valid && args.Last.Int < 0 {
+		return xerrors.Errorf("connection arguments: last must not be negative (got %d)", args.Last.Int)
+	}
+	if args.First.Valid && args.Last.Valid {
+		return xerrors.New("connection arguments: first and last must not be used together")
+	}
+	return nil
+}
+
+// ConnectionFromSlice returns the page of a Go slice selected by the
+// arguments. It returns an error if slice is not a slice, the arguments are
+// invalid, or a cursor is malformed.
+func ConnectionFromSlice(slice interface{}, args ConnectionArgs) (*Connection, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, xerrors.Errorf("connection from slice: %T is not a slice", slice)
+	}
+	conn, err := ConnectionFromOffset(context.Background(), args, v.Len(), func(ctx context.Context, offset, limit int) (interface{}, error) {
+		return v.Slice(offset, offset+limit).Interface(), nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("connection from slice: %w", err)
+	}
+	return conn, nil
+}
+
+// OffsetFetcher returns a slice of up to limit nodes starting at the given
+// zero-based offset into the list.
+type OffsetFetcher func(ctx context.Context, offset, limit int) (nodes interface{}, err error)
+
+// ConnectionFromOffset returns the page of a list selected by the arguments,
+// using fetch to obtain the nodes. total is the number of nodes in the whole
+// list. Cursors encode positions in the list, so pages may skip or repeat
+// nodes if the list changes between requests.
+func ConnectionFromOffset(ctx context.Context, args ConnectionArgs, total int, fetch OffsetFetcher) (*Connection, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	start, end := 0, total
+	if args.After.Valid {
+		after, err := parseOffsetCursor(args.After.S)
+		if err != nil {
+			return nil, xerrors.Errorf("after: %w", err)
+		}
+		if after+1 > start {
+			start = after + 1
+		}
+	}
+	if args.Before.Valid {
+		before, err := parseOffsetCursor(args.Before.S)
+		if err != nil {
+			return nil, xerrors.Errorf("before: %w", err)
+		}
+		if before < end {
+			end = before
+		}
+	}
+	if start > end {
+		start = end
+	}
+	if args.First.Valid && end-start > int(args.First.Int) {
+		end = start + int(args.First.Int)
+	}
+	if args.Last.Valid && end-start > int(args.Last.Int) {
+		start = end - int(args.Last.Int)
+	}
+
+	conn := &Connection{TotalCount: graphql.NullInt{Int: int32(total), Valid: true}}
+	if end > start {
+		nodes, err := fetch(ctx, start, end-start)
+		if err != nil {
+			return nil, err
+		}
+		v := reflect.ValueOf(nodes)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, xerrors.Errorf("fetch returned %T instead of a slice", nodes)
+		}
+		n := v.Len()
+		if n > end-start {
+			n = end - start
+		}
+		conn.Edges = make([]*Edge, 0, n)
+		for i := 0; i < n; i++ {
+			conn.Edges = append(conn.Edges, &Edge{
+				Node:   v.Index(i).Interface(),
+				Cursor: offsetCursor(start + i),
+			})
+		}
+		end = start + n
+	}
+	conn.PageInfo = newPageInfo(conn.Edges)
+	conn.PageInfo.HasPreviousPage = start > 0
+	conn.PageInfo.HasNextPage = end < total
+	return conn, nil
+}
+
+// KeysetQuery describes the nodes requested from a KeysetFetcher.
+type KeysetQuery struct {
+	// After is the key that all returned nodes must sort after.
+	// If empty, the nodes start at the beginning of the list.
+	After string
+	// Before is the key that all returned nodes must sort before.
+	// If empty, the nodes run to the end of the list.
+	Before string
+	// Limit is the maximum number of nodes to return. Zero means no limit.
+	Limit int
+	// If FromEnd is true, then the fetcher should return the last nodes in the
+	// range instead of the first nodes. The nodes must still be returned in
+	// list order.
+	FromEnd bool
+}
+
+// KeysetNode is a node along with the key that determines its position in a
+// list.
+type KeysetNode struct {
+	Key  string
+	Node interface{}
+}
+
+// KeysetFetcher returns the nodes of a list that match the query, in order.
+type KeysetFetcher func(ctx context.Context, q KeysetQuery) ([]KeysetNode, error)
+
+// ConnectionFromKeyset returns the page of a list selected by the arguments,
+// using fetch to obtain the nodes. Cursors encode the keys of nodes, so pages
+// remain stable when the list changes between requests. To detect whether
+// there are more pages, fetch is asked for one more node than the page size.
+func ConnectionFromKeyset(ctx context.Context, args ConnectionArgs, fetch KeysetFetcher) (*Connection, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+	var q KeysetQuery
+	if args.After.Valid {
+		var err error
+		q.After, err = parseKeyCursor(args.After.S)
+		if err != nil {
+			return nil, xerrors.Errorf("after: %w", err)
+		}
+	}
+	if args.Before.Valid {
+		var err error
+		q.Before, err = parseKeyCursor(args.Before.S)
+		if err != nil {
+			return nil, xerrors.Errorf("before: %w", err)
+		}
+	}
+	pageSize := -1
+	switch {
+	case args.First.Valid:
+		pageSize = int(args.First.Int)
+		q.Limit = pageSize + 1
+	case args.Last.Valid:
+		pageSize = int(args.Last.Int)
+		q.Limit = pageSize + 1
+		q.FromEnd = true
+	}
+	nodes, err := fetch(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := pageSize >= 0 && len(nodes) > pageSize
+	if hasMore {
+		if q.FromEnd {
+			nodes = nodes[len(nodes)-pageSize:]
+		} else {
+			nodes = nodes[:pageSize]
+		}
+	}
+	conn := &Connection{Edges: make([]*Edge, 0, len(nodes))}
+	for _, n := range nodes {
+		conn.Edges = append(conn.Edges, &Edge{
+			Node:   n.Node,
+			Cursor: keyCursor(n.Key),
+		})
+	}
+	conn.PageInfo = newPageInfo(conn.Edges)
+	conn.PageInfo.HasPreviousPage = q.FromEnd && hasMore
+	conn.PageInfo.HasNextPage = !q.FromEnd && hasMore
+	return conn, nil
+}
+
+const (
+	offsetCursorPrefix = "offset:"
+	keyCursorPrefix    = "key:"
+)
+
+func offsetCursor(i int) string {
+	return base64.StdEncoding.EncodeToString([]byte(offsetCursorPrefix + strconv.Itoa(i)))
+}
+
+func parseOffsetCursor(cursor string) (int, error) {
+	s, err := parseCursor(cursor, offsetCursorPrefix)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i >= math.MaxInt32 {
+		return 0, xerrors.Errorf("invalid cursor %q", cursor)
+	}
+	return i, nil
+}
+
+func keyCursor(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(keyCursorPrefix + key))
+}
+
+func parseKeyCursor(cursor string) (string, error) {
+	return parseCursor(cursor, keyCursorPrefix)
+}
+
+func parseCursor(cursor, prefix string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), prefix) {
+		return "", xerrors.Errorf("invalid cursor %q", cursor)
+	}
+	return string(data[len(prefix):]), nil
+}
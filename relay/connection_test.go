@@ -0,0 +1,339 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package relay
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqltest"
+)
+
+var letters = []string{"A", "B", "C", "D", "E"}
+
+type pageSummary struct {
+	Nodes           []string
+	HasPreviousPage bool
+	HasNextPage     bool
+}
+
+func summarize(conn *Connection) pageSummary {
+	var s pageSummary
+	for _, e := range conn.Edges {
+		s.Nodes = append(s.Nodes, e.Node.(string))
+	}
+	s.HasPreviousPage = conn.PageInfo.HasPreviousPage
+	s.HasNextPage = conn.PageInfo.HasNextPage
+	return s
+}
+
+func first(n int32) graphql.NullInt { return graphql.NullInt{Int: n, Valid: true} }
+func last(n int32) graphql.NullInt  { return graphql.NullInt{Int: n, Valid: true} }
+
+func cursor(s string) graphql.NullString { return graphql.NullString{S: s, Valid: true} }
+
+func TestConnectionFromSlice(t *testing.T) {
+	tests := []struct {
+		name string
+		args ConnectionArgs
+		want pageSummary
+	}{
+		{
+			name: "All",
+			want: pageSummary{Nodes: letters},
+		},
+		{
+			name: "First",
+			args: ConnectionArgs{First: first(2)},
+			want: pageSummary{Nodes: []string{"A", "B"}, HasNextPage: true},
+		},
+		{
+			name: "FirstZero",
+			args: ConnectionArgs{First: first(0)},
+			want: pageSummary{HasNextPage: true},
+		},
+		{
+			name: "FirstTooMany",
+			args: ConnectionArgs{First: first(10)},
+			want: pageSummary{Nodes: letters},
+		},
+		{
+			name: "FirstAfter",
+			args: ConnectionArgs{First: first(2), After: cursor(offsetCursor(1))},
+			want: pageSummary{Nodes: []string{"C", "D"}, HasPreviousPage: true, HasNextPage: true},
+		},
+		{
+			name: "AfterEnd",
+			args: ConnectionArgs{After: cursor(offsetCursor(4))},
+			want: pageSummary{HasPreviousPage: true},
+		},
+		{
+			name: "Last",
+			args: ConnectionArgs{Last: last(2)},
+			want: pageSummary{Nodes: []string{"D", "E"}, HasPreviousPage: true},
+		},
+		{
+			name: "LastBefore",
+			args: ConnectionArgs{Last: last(2), Before: cursor(offsetCursor(3))},
+			want: pageSummary{Nodes: []string{"B", "C"}, HasPreviousPage: true, HasNextPage: true},
+		},
+		{
+			name: "AfterAndBefore",
+			args: ConnectionArgs{After: cursor(offsetCursor(0)), Before: cursor(offsetCursor(4))},
+			want: pageSummary{Nodes: []string{"B", "C", "D"}, HasPreviousPage: true, HasNextPage: true},
+		},
+		{
+			name: "AfterPastBefore",
+			args: ConnectionArgs{After: cursor(offsetCursor(3)), Before: cursor(offsetCursor(1))},
+			want: pageSummary{HasPreviousPage: true, HasNextPage: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, err := ConnectionFromSlice(letters, test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, summarize(conn)); diff != "" {
+				t.Errorf("page (-want +got):\n%s", diff)
+			}
+			if got := conn.TotalCount; !got.Valid || got.Int != int32(len(letters)) {
+				t.Errorf("TotalCount = %v; want %d", got, len(letters))
+			}
+			for i, e := range conn.Edges {
+				if i == 0 && conn.PageInfo.StartCursor.S != e.Cursor {
+					t.Errorf("StartCursor = %q; want %q", conn.PageInfo.StartCursor.S, e.Cursor)
+				}
+				if i == len(conn.Edges)-1 && conn.PageInfo.EndCursor.S != e.Cursor {
+					t.Errorf("EndCursor = %q; want %q", conn.PageInfo.EndCursor.S, e.Cursor)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args ConnectionArgs
+	}{
+		{name: "NegativeFirst", args: ConnectionArgs{First: first(-1)}},
+		{name: "NegativeLast", args: ConnectionArgs{Last: last(-1)}},
+		{name: "FirstAndLast", args: ConnectionArgs{First: first(1), Last: last(1)}},
+		{name: "BadAfter", args: ConnectionArgs{After: cursor("xyzzy")}},
+		{name: "BadBefore", args: ConnectionArgs{Before: cursor("xyzzy")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ConnectionFromSlice(letters, test.args); err == nil {
+				t.Error("ConnectionFromSlice did not return an error")
+			}
+			_, err := ConnectionFromKeyset(context.Background(), test.args, fetchLetters)
+			if err == nil {
+				t.Error("ConnectionFromKeyset did not return an error")
+			}
+		})
+	}
+	if _, err := ConnectionFromSlice("ABCDE", ConnectionArgs{}); err == nil {
+		t.Error("ConnectionFromSlice(string) did not return an error")
+	}
+	if _, err := ConnectionFromSlice(letters, ConnectionArgs{After: cursor(keyCursor("A"))}); err == nil {
+		t.Error("ConnectionFromSlice with keyset cursor did not return an error")
+	}
+	if _, err := ConnectionFromSlice(letters, ConnectionArgs{After: cursor(offsetCursor(math.MaxInt32))}); err == nil {
+		t.Error("ConnectionFromSlice with maximum offset cursor did not return an error")
+	}
+}
+
+// fetchLetters is a KeysetFetcher that uses the letters themselves as keys.
+func fetchLetters(ctx context.Context, q KeysetQuery) ([]KeysetNode, error) {
+	start := 0
+	if q.After != "" {
+		start = sort.SearchStrings(letters, q.After)
+		if start < len(letters) && letters[start] == q.After {
+			start++
+		}
+	}
+	end := len(letters)
+	if q.Before != "" {
+		end = sort.SearchStrings(letters, q.Before)
+	}
+	if start > end {
+		start = end
+	}
+	if q.Limit > 0 && end-start > q.Limit {
+		if q.FromEnd {
+			start = end - q.Limit
+		} else {
+			end = start + q.Limit
+		}
+	}
+	var nodes []KeysetNode
+	for _, l := range letters[start:end] {
+		nodes = append(nodes, KeysetNode{Key: l, Node: l})
+	}
+	return nodes, nil
+}
+
+func TestConnectionFromKeyset(t *testing.T) {
+	tests := []struct {
+		name string
+		args ConnectionArgs
+		want pageSummary
+	}{
+		{
+			name: "All",
+			want: pageSummary{Nodes: letters},
+		},
+		{
+			name: "First",
+			args: ConnectionArgs{First: first(2)},
+			want: pageSummary{Nodes: []string{"A", "B"}, HasNextPage: true},
+		},
+		{
+			name: "FirstExact",
+			args: ConnectionArgs{First: first(5)},
+			want: pageSummary{Nodes: letters},
+		},
+		{
+			name: "FirstAfter",
+			args: ConnectionArgs{First: first(2), After: cursor(keyCursor("B"))},
+			want: pageSummary{Nodes: []string{"C", "D"}, HasNextPage: true},
+		},
+		{
+			name: "Last",
+			args: ConnectionArgs{Last: last(2)},
+			want: pageSummary{Nodes: []string{"D", "E"}, HasPreviousPage: true},
+		},
+		{
+			name: "LastBefore",
+			args: ConnectionArgs{Last: last(3), Before: cursor(keyCursor("C"))},
+			want: pageSummary{Nodes: []string{"A", "B"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, err := ConnectionFromKeyset(context.Background(), test.args, fetchLetters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, summarize(conn)); diff != "" {
+				t.Errorf("page (-want +got):\n%s", diff)
+			}
+			if conn.TotalCount.Valid {
+				t.Errorf("TotalCount = %v; want null", conn.TotalCount)
+			}
+		})
+	}
+}
+
+func TestParseConnectionArgs(t *testing.T) {
+	schema, err := graphql.ParseSchema(PageInfoType+`
+		type Query {
+			letters(first: Int, after: String, last: Int, before: String, prefix: String): LetterConnection!
+		}
+
+		type LetterConnection {
+			edges: [LetterEdge!]!
+			nodes: [String!]!
+			pageInfo: PageInfo!
+			totalCount: Int
+		}
+
+		type LetterEdge {
+			node: String!
+			cursor: String!
+		}
+	`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := graphql.NewServer(schema, new(letterQuery), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("Page", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `query ($after: String) {
+				letters(first: 2, after: $after, prefix: "x") {
+					edges { node cursor }
+					nodes
+					pageInfo { hasPreviousPage hasNextPage startCursor endCursor }
+					totalCount
+				}
+			}`,
+			Variables: map[string]interface{}{"after": offsetCursor(0)},
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"letters": {
+				"edges": [
+					{"node": "B", "cursor": "`+offsetCursor(1)+`"},
+					{"node": "C", "cursor": "`+offsetCursor(2)+`"}
+				],
+				"nodes": ["B", "C"],
+				"pageInfo": {
+					"hasPreviousPage": true,
+					"hasNextPage": true,
+					"startCursor": "`+offsetCursor(1)+`",
+					"endCursor": "`+offsetCursor(2)+`"
+				},
+				"totalCount": 5
+			}
+		}`)
+	})
+	t.Run("Empty", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ letters(first: 0) { edges { node } pageInfo { startCursor endCursor } } }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"letters": {
+				"edges": [],
+				"pageInfo": {"startCursor": null, "endCursor": null}
+			}
+		}`)
+	})
+	t.Run("InvalidArgs", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ letters(first: 1, last: 1) { nodes } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Message:   "first and last must not be used together",
+				Path:      graphqltest.Path("letters"),
+				Locations: []graphql.Location{{Line: 1, Column: 3}},
+			},
+		})
+	})
+}
+
+type letterQuery struct{}
+
+func (*letterQuery) Letters(args map[string]graphql.Value) (*Connection, error) {
+	cargs, err := ParseConnectionArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return ConnectionFromSlice(letters, cargs)
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package relay_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/relay"
+)
+
+type Query struct {
+	nodes relay.NodeResolver
+}
+
+func (q *Query) Node(ctx context.Context, args map[string]graphql.Value) (interface{}, error) {
+	return q.nodes.Resolve(ctx, args["id"].Scalar())
+}
+
+func (q *Query) Users(args map[string]graphql.Value) (*relay.Connection, error) {
+	cargs, err := relay.ParseConnectionArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return relay.ConnectionFromSlice(users, cargs)
+}
+
+type User struct {
+	id   string
+	Name string
+}
+
+func (u *User) ID() string {
+	return relay.GlobalID("User", u.id)
+}
+
+var users = []*User{
+	{id: "1", Name: "Alice"},
+	{id: "2", Name: "Bob"},
+	{id: "3", Name: "Carol"},
+}
+
+func ExampleNodeResolver() {
+	schema, err := graphql.ParseSchema(relay.PageInfoType+`
+		type Query {
+			node(id: ID!): Node
+			users(first: Int, after: String, last: Int, before: String): UserConnection!
+		}
+
+		union Node = User
+
+		type User {
+			id: ID!
+			name: String!
+		}
+
+		type UserConnection {
+			nodes: [User!]!
+			pageInfo: PageInfo!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	query := &Query{
+		nodes: relay.NodeResolver{
+			"User": func(ctx context.Context, id string) (interface{}, error) {
+				for _, u := range users {
+					if u.id == id {
+						return u, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+	}
+	srv, err := graphql.NewServer(schema, query, nil)
+	if err != nil {
+		// handle error
+	}
+	response := srv.Execute(context.Background(), graphql.Request{
+		Query: `{ node(id: "VXNlcjoy") { ... on User { id name } } }`,
+	})
+	output, _ := json.Marshal(response)
+	fmt.Println(string(output))
+	// Output:
+	// {"data":{"node":{"id":"VXNlcjoy","name":"Bob"}}}
+}
+
+func ExampleConnectionFromSlice() {
+	// Equivalent to users(first: 2).
+	conn, err := relay.ConnectionFromSlice(users, relay.ConnectionArgs{
+		First: graphql.NullInt{Int: 2, Valid: true},
+	})
+	if err != nil {
+		// handle error
+	}
+	for _, e := range conn.Edges {
+		fmt.Println(e.Node.(*User).Name)
+	}
+	fmt.Println("Has next page:", conn.PageInfo.HasNextPage)
+	// Output:
+	// Alice
+	// Bob
+	// Has next page: true
+}
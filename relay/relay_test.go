@@ -0,0 +1,90 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package relay
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGlobalID(t *testing.T) {
+	tests := []struct {
+		typeName string
+		id       string
+		globalID string
+	}{
+		// Values from graphql-relay-js.
+		{typeName: "User", id: "1", globalID: "VXNlcjox"},
+		{typeName: "Faction", id: "2", globalID: "RmFjdGlvbjoy"},
+		{typeName: "Post", id: "a:b", globalID: "UG9zdDphOmI="},
+		{typeName: "Post", id: "", globalID: "UG9zdDo="},
+	}
+	for _, test := range tests {
+		if got := GlobalID(test.typeName, test.id); got != test.globalID {
+			t.Errorf("GlobalID(%q, %q) = %q; want %q", test.typeName, test.id, got, test.globalID)
+		}
+		typeName, id, err := ParseGlobalID(test.globalID)
+		if err != nil || typeName != test.typeName || id != test.id {
+			t.Errorf("ParseGlobalID(%q) = %q, %q, %v; want %q, %q, <nil>", test.globalID, typeName, id, err, test.typeName, test.id)
+		}
+	}
+}
+
+func TestParseGlobalIDErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"!!!",
+		"VXNlcg==", // "User"
+		"OjE=",     // ":1"
+	}
+	for _, globalID := range tests {
+		if typeName, id, err := ParseGlobalID(globalID); err == nil {
+			t.Errorf("ParseGlobalID(%q) = %q, %q, <nil>; want error", globalID, typeName, id)
+		}
+	}
+}
+
+func TestNodeResolver(t *testing.T) {
+	type user struct{ id string }
+	r := NodeResolver{
+		"User": func(ctx context.Context, id string) (interface{}, error) {
+			if id != "1" {
+				return nil, nil
+			}
+			return &user{id: id}, nil
+		},
+	}
+	ctx := context.Background()
+
+	got, err := r.Resolve(ctx, GlobalID("User", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := got.(*user); !ok || u.id != "1" {
+		t.Errorf("Resolve(User:1) = %#v; want user 1", got)
+	}
+	got, err = r.Resolve(ctx, GlobalID("User", "2"))
+	if got != nil || err != nil {
+		t.Errorf("Resolve(User:2) = %#v, %v; want <nil>, <nil>", got, err)
+	}
+	if _, err := r.Resolve(ctx, GlobalID("Post", "1")); err == nil {
+		t.Error("Resolve(Post:1) did not return an error")
+	}
+	if _, err := r.Resolve(ctx, "bogus"); err == nil {
+		t.Error("Resolve(bogus) did not return an error")
+	}
+}
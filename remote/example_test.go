@@ -0,0 +1,90 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package remote_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/remote"
+)
+
+func ExampleGateway() {
+	// In production, an HTTPExecutor would send requests to the remote
+	// service. Here, the remote service runs in the same process.
+	accountsSchema, err := graphql.ParseSchema(`
+		type Query {
+			user(id: ID!): User
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+	`, nil)
+	if err != nil {
+		// handle error
+	}
+	accountsServer, err := graphql.NewServer(accountsSchema, new(Accounts), nil)
+	if err != nil {
+		// handle error
+	}
+
+	g, err := remote.NewGateway(`
+		type Query {
+			hello: String!
+			user(id: ID!): User @remote(executor: "accounts")
+		}
+
+		type User {
+			id: ID!
+			name: String!
+		}
+	`, nil, map[string]remote.Executor{
+		"accounts": remote.ServerExecutor{Server: accountsServer},
+	})
+	if err != nil {
+		// handle error
+	}
+	srv, err := g.NewServer(&struct{ Hello string }{Hello: "world"}, nil)
+	if err != nil {
+		// handle error
+	}
+	response := srv.Execute(context.Background(), graphql.Request{
+		Query: `{ hello user(id: "1") { name } }`,
+	})
+	output, _ := json.Marshal(response)
+	fmt.Println(string(output))
+	// Output:
+	// {"data":{"hello":"world","user":{"name":"Alice"}}}
+}
+
+type Accounts struct{}
+
+func (*Accounts) User(args map[string]graphql.Value) *User {
+	if args["id"].Scalar() != "1" {
+		return nil
+	}
+	return &User{ID: "1", Name: "Alice"}
+}
+
+type User struct {
+	ID   string
+	Name string
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// HTTPExecutor sends requests to a GraphQL service over HTTP, using POST
+// requests with JSON bodies.
+type HTTPExecutor struct {
+	// URL is the GraphQL endpoint of the remote service.
+	URL string
+	// Client is the HTTP client used to make requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+	// Header specifies additional headers to send with each request.
+	Header http.Header
+}
+
+// Execute sends a request to the service.
+func (e *HTTPExecutor) Execute(ctx context.Context, req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, xerrors.Errorf("execute remote request: %w", err)
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, xerrors.Errorf("execute remote request: %w", err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	for k, v := range e.Header {
+		httpReq.Header[k] = append([]string(nil), v...)
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	httpReq.Header.Set("Accept", "application/json")
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, xerrors.Errorf("execute remote request: %w", err)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("execute remote request: %w", err)
+	}
+	contentType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if contentType != "application/json" {
+		return nil, xerrors.Errorf("execute remote request: %s returned %s (Content-Type %q)", e.URL, httpResp.Status, httpResp.Header.Get("Content-Type"))
+	}
+	resp := new(Response)
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return nil, xerrors.Errorf("execute remote request: %s: %w", e.URL, err)
+	}
+	return resp, nil
+}
+
+// ServerExecutor sends requests to a server in the same process. It is useful
+// for testing gateways without running the remote services.
+type ServerExecutor struct {
+	Server *graphql.Server
+}
+
+// Execute executes the request on the server.
+func (e ServerExecutor) Execute(ctx context.Context, req *Request) (*Response, error) {
+	vars := make(map[string]graphql.Input, len(req.Variables))
+	for k, v := range req.Variables {
+		var input graphql.Input
+		if err := json.Unmarshal(v, &input); err != nil {
+			return nil, xerrors.Errorf("execute request: variable %s: %w", k, err)
+		}
+		vars[k] = input
+	}
+	gqlResp := e.Server.Execute(ctx, graphql.Request{
+		Query:     req.Query,
+		Variables: vars,
+	})
+	data, err := json.Marshal(gqlResp.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("execute request: %w", err)
+	}
+	return &Response{
+		Data:   data,
+		Errors: gqlResp.Errors,
+	}, nil
+}
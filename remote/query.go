@@ -0,0 +1,199 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package remote
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// typeNameField is the name of the meta-field that returns an object's type.
+const typeNameField = "__typename"
+
+// keyAliasPrefix is prepended to the names of key fields that the gateway adds
+// to remote queries to resolve delegated fields below them.
+const keyAliasPrefix = "_remoteKey_"
+
+// queryBuilder writes a query document for a delegated field.
+type queryBuilder struct {
+	g       *Gateway
+	sb      strings.Builder
+	varDefs []string
+	vars    map[string]json.RawMessage
+}
+
+func newQueryBuilder(g *Gateway) *queryBuilder {
+	return &queryBuilder{g: g}
+}
+
+// request returns a request for the query document with the given operation
+// type.
+func (b *queryBuilder) request(op string) *Request {
+	s := op
+	if len(b.varDefs) > 0 {
+		s += "(" + strings.Join(b.varDefs, ", ") + ")"
+	}
+	return &Request{
+		Query:     s + " { " + b.sb.String() + " }",
+		Variables: b.vars,
+	}
+}
+
+// queryArg is an argument passed to a field as a variable.
+type queryArg struct {
+	name  string
+	typ   string
+	value json.RawMessage
+}
+
+// fieldArgs returns the non-null arguments of a field.
+func (b *queryBuilder) fieldArgs(defn *graphql.FieldDefinition, values map[string]graphql.Value) []queryArg {
+	var args []queryArg
+	for _, arg := range defn.Args {
+		val := values[arg.Name]
+		if val.IsNull() {
+			continue
+		}
+		data, err := json.Marshal(val)
+		if err != nil {
+			// Values from validated requests can always be marshaled.
+			panic(err)
+		}
+		args = append(args, queryArg{
+			name:  arg.Name,
+			typ:   arg.Type.String(),
+			value: data,
+		})
+	}
+	return args
+}
+
+// field writes a field selection. Arguments are passed as variables.
+func (b *queryBuilder) field(key, name string, args []queryArg, typ *graphql.Type, sel *graphql.SelectionSet) {
+	if key != name {
+		b.sb.WriteString(key)
+		b.sb.WriteString(": ")
+	}
+	b.sb.WriteString(name)
+	for i, arg := range args {
+		varName := "_" + strconv.Itoa(len(b.varDefs))
+		b.varDefs = append(b.varDefs, "$"+varName+": "+arg.typ)
+		if b.vars == nil {
+			b.vars = make(map[string]json.RawMessage)
+		}
+		b.vars[varName] = arg.value
+		if i == 0 {
+			b.sb.WriteString("(")
+		} else {
+			b.sb.WriteString(", ")
+		}
+		b.sb.WriteString(arg.name)
+		b.sb.WriteString(": $")
+		b.sb.WriteString(varName)
+	}
+	if len(args) > 0 {
+		b.sb.WriteString(")")
+	}
+	typ = namedType(typ)
+	switch typ.Kind() {
+	case graphql.ObjectKind:
+		b.sb.WriteString(" { ")
+		b.selectionSet(typ, sel)
+		b.sb.WriteString("}")
+	case graphql.UnionKind:
+		// The type name is needed to pick the Go type of the value.
+		b.sb.WriteString(" { " + typeNameField + " ")
+		for _, pt := range typ.PossibleTypes() {
+			if !hasFieldsForType(sel, pt.Name()) {
+				continue
+			}
+			b.sb.WriteString("... on ")
+			b.sb.WriteString(pt.Name())
+			b.sb.WriteString(" { ")
+			b.selectionSet(pt, sel)
+			b.sb.WriteString("} ")
+		}
+		b.sb.WriteString("}")
+	}
+}
+
+// selectionSet writes the fields in the selection set that apply to the given
+// object type. Delegated fields are replaced by their keys.
+func (b *queryBuilder) selectionSet(typ *graphql.Type, sel *graphql.SelectionSet) {
+	var keys map[string]bool
+	for i := 0; i < sel.Len(); i++ {
+		f := sel.Field(i)
+		if !f.ForType(typ.Name()) {
+			continue
+		}
+		if f.Name() == typeNameField {
+			if f.Key() != typeNameField {
+				b.sb.WriteString(f.Key())
+				b.sb.WriteString(": ")
+			}
+			b.sb.WriteString(typeNameField)
+			b.sb.WriteString(" ")
+			continue
+		}
+		if d := b.g.delegated[typ.Name()][f.Name()]; d != nil && d.remoteField != "" {
+			if !keys[d.key] {
+				b.sb.WriteString(keyAliasPrefix + d.key + ": " + d.key + " ")
+				if keys == nil {
+					keys = make(map[string]bool)
+				}
+				keys[d.key] = true
+			}
+			continue
+		}
+		defn := typ.Field(f.Name())
+		args := make(map[string]graphql.Value, len(defn.Args))
+		for _, arg := range defn.Args {
+			args[arg.Name] = f.Arg(arg.Name)
+		}
+		b.field(f.Key(), f.Name(), b.fieldArgs(defn, args), defn.Type, f.SelectionSet())
+		b.sb.WriteString(" ")
+	}
+}
+
+func hasFieldsForType(sel *graphql.SelectionSet, typeName string) bool {
+	for i := 0; i < sel.Len(); i++ {
+		f := sel.Field(i)
+		if f.ForType(typeName) && f.Name() != typeNameField {
+			return true
+		}
+	}
+	return false
+}
+
+// namedType strips any list and non-null wrappers from a type.
+func namedType(typ *graphql.Type) *graphql.Type {
+	for typ.OfType() != nil {
+		typ = typ.OfType()
+	}
+	return typ
+}
+
+// isLeafType reports whether typ is a scalar or enum type, possibly non-null.
+func isLeafType(typ *graphql.Type) bool {
+	if typ.Kind() == graphql.NonNullKind {
+		typ = typ.OfType()
+	}
+	return typ.Kind() == graphql.ScalarKind || typ.Kind() == graphql.EnumKind
+}
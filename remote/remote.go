@@ -0,0 +1,516 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package remote serves a schema whose fields are partly resolved by other
+// GraphQL services.
+//
+// A gateway schema is written like any other schema, but may mark fields with
+// the @remote directive to delegate them to a named Executor:
+//
+//	type Query {
+//	  hello: String!
+//	  user(id: ID!): User @remote(executor: "accounts")
+//	}
+//
+//	type User {
+//	  id: ID!
+//	  name: String!
+//	  reviews: [Review!]! @remote(executor: "reviews", field: "reviewsByUser")
+//	}
+//
+// Marking the Query or Mutation type itself delegates all of its fields that
+// are not marked with their own @remote directive. The remote service must
+// serve the delegated fields with compatible types. Types that are only
+// reached through delegated fields do not need Go implementations.
+//
+// When executing a delegated field of the Query or Mutation type, the gateway
+// sends the remote service a query for the field with the same arguments and
+// selection set, passing the arguments as variables. The returned data is
+// grafted into the response, and errors from the remote service are reported
+// at the corresponding paths in the gateway's response. Errors that the
+// remote service does not report at a path below the field are reported on
+// the delegated field.
+//
+// Fields of other object types are delegated by key. The gateway reads the
+// parent object's key field, named by the directive's key argument ("id" by
+// default), and queries the remote service's Query field named by the field
+// argument (the delegated field's name by default). The remote field takes
+// the key as an argument with the key field's name, followed by the
+// delegated field's arguments. In the example above, the gateway would
+// resolve User.reviews with a query like:
+//
+//	query($_0: ID!) { reviewsByUser(id: $_0) { ... } }
+//
+// The key field must not be delegated and must have a scalar or enum type.
+// @remote can only be used on the Query and Mutation types themselves, not
+// on other types.
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/ast"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// directiveDefinition declares the @remote directive.
+const directiveDefinition = `
+directive @remote(executor: String!, field: String, key: String) on FIELD_DEFINITION | OBJECT
+`
+
+// defaultKeyField is the key field used for delegated fields of non-root
+// types when the @remote directive does not name one.
+const defaultKeyField = "id"
+
+// placeholderField is added to the local Query type if all of its fields are
+// delegated, since object types must have at least one field.
+const placeholderField = "remotePlaceholder: Boolean\n"
+
+// An Executor sends GraphQL requests to a remote service. Execute should only
+// return an error if it could not obtain a response. Errors reported by the
+// service should be returned in the response.
+type Executor interface {
+	Execute(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Request is a GraphQL request sent to a remote service.
+type Request struct {
+	Query     string                     `json:"query"`
+	Variables map[string]json.RawMessage `json:"variables,omitempty"`
+}
+
+// Response is a GraphQL response received from a remote service.
+type Response struct {
+	Data   json.RawMessage          `json:"data"`
+	Errors []*graphql.ResponseError `json:"errors,omitempty"`
+}
+
+// Gateway is a parsed gateway schema along with the executors for its
+// delegated fields.
+type Gateway struct {
+	schema *graphql.Schema
+	// base is the schema without the delegated fields. It is used to check and
+	// resolve the application's root objects.
+	base      *graphql.Schema
+	executors map[string]Executor
+	// delegated maps type names to field names to delegations.
+	delegated map[string]map[string]*delegation
+	// nested is true if any fields of non-root types are delegated. Objects
+	// provided by the application are only wrapped if it is set.
+	nested bool
+}
+
+// delegation describes how a delegated field is resolved.
+type delegation struct {
+	executor string
+	// remoteField is the remote Query field that resolves a delegated field
+	// of a non-root type. It is empty for fields of root types.
+	remoteField string
+	// key is the field of the parent object whose value is passed to
+	// remoteField.
+	key string
+}
+
+// NewGateway parses a gateway schema. executors maps the names used in @remote
+// directives to the executors that serve them.
+func NewGateway(source string, opts *graphql.SchemaOptions, executors map[string]Executor) (*Gateway, error) {
+	fullSource := source + "\n" + directiveDefinition
+	schema, err := graphql.ParseSchema(fullSource, opts)
+	if err != nil {
+		return nil, xerrors.Errorf("new gateway: %w", err)
+	}
+	g := &Gateway{
+		schema:    schema,
+		executors: executors,
+		delegated: make(map[string]map[string]*delegation),
+	}
+	roots := map[*graphql.Type]bool{schema.QueryType(): true}
+	if m := schema.MutationType(); m != nil {
+		roots[m] = true
+	}
+	for _, typ := range schema.Types() {
+		if typ.Kind() != graphql.ObjectKind {
+			continue
+		}
+		typeExecutor := ""
+		if d := graphql.FindDirective(typ.Directives(), "remote"); d != nil {
+			if !roots[typ] {
+				return nil, xerrors.Errorf("new gateway: %s: @remote can only be used on the Query and Mutation types", typ.Name())
+			}
+			typeExecutor = d.Args["executor"].Scalar()
+		}
+		fields := make(map[string]*delegation)
+		for _, f := range typ.Fields() {
+			d := &delegation{executor: typeExecutor}
+			dir := graphql.FindDirective(f.Directives, "remote")
+			if dir != nil {
+				d.executor = dir.Args["executor"].Scalar()
+			}
+			if d.executor == "" {
+				continue
+			}
+			if executors[d.executor] == nil {
+				return nil, xerrors.Errorf("new gateway: %s.%s: no executor named %q", typ.Name(), f.Name, d.executor)
+			}
+			if roots[typ] {
+				if dir != nil && (!dir.Args["field"].IsNull() || !dir.Args["key"].IsNull()) {
+					return nil, xerrors.Errorf("new gateway: %s.%s: field and key can only be used on fields of non-root types", typ.Name(), f.Name)
+				}
+			} else {
+				d.remoteField = f.Name
+				if v := dir.Args["field"]; !v.IsNull() {
+					d.remoteField = v.Scalar()
+				}
+				d.key = defaultKeyField
+				if v := dir.Args["key"]; !v.IsNull() {
+					d.key = v.Scalar()
+				}
+			}
+			fields[f.Name] = d
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		g.delegated[typ.Name()] = fields
+		if roots[typ] {
+			continue
+		}
+		g.nested = true
+		for _, f := range typ.Fields() {
+			name, d := f.Name, fields[f.Name]
+			if d == nil {
+				continue
+			}
+			key := typ.Field(d.key)
+			if key == nil || fields[d.key] != nil {
+				return nil, xerrors.Errorf("new gateway: %s.%s: key %q is not a local field of %s", typ.Name(), name, d.key, typ.Name())
+			}
+			if !isLeafType(key.Type) {
+				return nil, xerrors.Errorf("new gateway: %s.%s: key %s.%s must be a scalar or enum", typ.Name(), name, typ.Name(), d.key)
+			}
+		}
+	}
+
+	baseSource, err := g.baseSource(fullSource)
+	if err != nil {
+		return nil, xerrors.Errorf("new gateway: %w", err)
+	}
+	g.base, err = graphql.ParseSchema(baseSource, opts)
+	if err != nil {
+		return nil, xerrors.Errorf("new gateway: %w", err)
+	}
+	return g, nil
+}
+
+// baseSource removes the delegated fields from the schema source. Removed text
+// is replaced with spaces so that positions in the source are preserved.
+func (g *Gateway) baseSource(fullSource string) (string, error) {
+	doc, errs := ast.Parse(fullSource)
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+	src := []byte(fullSource)
+	blank := func(start, end int) {
+		for i := start; i < end; i++ {
+			if src[i] != '\n' {
+				src[i] = ' '
+			}
+		}
+	}
+	var insertPos int
+	for i, defn := range doc.Definitions {
+		if defn.Type == nil || defn.Type.Object == nil {
+			continue
+		}
+		obj := defn.Type.Object
+		fields := g.delegated[obj.Name.Value]
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < len(obj.Fields.Defs) {
+			for j, f := range obj.Fields.Defs {
+				if fields[f.Name.Value] == nil {
+					continue
+				}
+				end := int(obj.Fields.RBrace)
+				if j+1 < len(obj.Fields.Defs) {
+					end = fieldStart(obj.Fields.Defs[j+1])
+				}
+				blank(fieldStart(f), end)
+			}
+			continue
+		}
+		if obj.Name.Value == g.schema.QueryType().Name() {
+			// A schema must have a query type, so replace its fields.
+			blank(fieldStart(obj.Fields.Defs[0]), int(obj.Fields.RBrace))
+			insertPos = int(obj.Fields.RBrace)
+			continue
+		}
+		end := len(src)
+		if i+1 < len(doc.Definitions) {
+			end = int(doc.Definitions[i+1].Start())
+		}
+		blank(int(defn.Start()), end)
+	}
+	if insertPos == 0 {
+		return string(src), nil
+	}
+	return string(src[:insertPos]) + placeholderField + string(src[insertPos:]), nil
+}
+
+func fieldStart(f *ast.FieldDefinition) int {
+	if f.Description != nil {
+		return int(f.Description.Start)
+	}
+	return int(f.Name.Start)
+}
+
+// Schema returns the gateway's schema.
+func (g *Gateway) Schema() *graphql.Schema {
+	return g.schema
+}
+
+// NewServer returns a new server for the gateway. query and mutation follow
+// the same rules as graphql.NewServer, except that they must not be functions
+// and they only need to provide the fields that are not delegated. If all
+// fields of a type are delegated, then the corresponding argument must be nil.
+func (g *Gateway) NewServer(query, mutation interface{}) (*graphql.Server, error) {
+	queryType := g.schema.QueryType().Name()
+	baseQuery := query
+	if g.allDelegated(g.schema.QueryType()) {
+		if query != nil {
+			return nil, xerrors.New("new gateway server: all query fields are delegated, but query object given")
+		}
+		baseQuery = placeholder{}
+	}
+	var mutationType string
+	baseMutation := mutation
+	if m := g.schema.MutationType(); m != nil {
+		mutationType = m.Name()
+		if g.allDelegated(m) {
+			if mutation != nil {
+				return nil, xerrors.New("new gateway server: all mutation fields are delegated, but mutation object given")
+			}
+			baseMutation = nil
+		}
+	}
+	// Check the root objects against the schema they will be resolved with.
+	if _, err := graphql.NewServer(g.base, baseQuery, baseMutation); err != nil {
+		return nil, xerrors.Errorf("new gateway server: %w", err)
+	}
+	queryRoot := &root{
+		g:         g,
+		typeName:  queryType,
+		operation: "query",
+		local:     query,
+	}
+	var mutationRoot interface{}
+	if mutationType != "" {
+		mutationRoot = &root{
+			g:         g,
+			typeName:  mutationType,
+			operation: "mutation",
+			local:     mutation,
+		}
+	}
+	srv, err := graphql.NewServer(g.schema, queryRoot, mutationRoot)
+	if err != nil {
+		return nil, xerrors.Errorf("new gateway server: %w", err)
+	}
+	return srv, nil
+}
+
+func (g *Gateway) allDelegated(typ *graphql.Type) bool {
+	return len(g.delegated[typ.Name()]) == len(typ.Fields())
+}
+
+// placeholder is the Go value used to check a base query type that only has
+// the placeholder field.
+type placeholder struct {
+	RemotePlaceholder bool
+}
+
+// root is a query or mutation object of a gateway server.
+type root struct {
+	g         *Gateway
+	typeName  string
+	operation string
+	local     interface{}
+}
+
+// ResolveField delegates fields to their executors or to the application's
+// root object.
+func (r *root) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	d := r.g.delegated[r.typeName][req.Name]
+	if d == nil {
+		return r.g.resolveLocal(ctx, r.local, r.typeName, req)
+	}
+	field := r.g.schema.Type(r.typeName).Field(req.Name)
+	b := newQueryBuilder(r.g)
+	b.field(req.Name, req.Name, b.fieldArgs(field, req.Args), field.Type, req.Selection)
+	return r.g.delegate(ctx, d.executor, b.request(r.operation), req.Name, field.Type, req.Selection)
+}
+
+// resolveLocal resolves a field on a Go value provided by the application.
+func (g *Gateway) resolveLocal(ctx context.Context, obj interface{}, typeName string, req graphql.FieldRequest) (interface{}, error) {
+	v, err := g.base.ResolveField(ctx, obj, typeName, req)
+	if err != nil || !g.nested {
+		return v, err
+	}
+	return g.wrapLocal(g.schema.Type(typeName).Field(req.Name).Type, v), nil
+}
+
+// wrapLocal wraps the objects in a Go value of the given type so that their
+// delegated fields are resolved by the gateway.
+func (g *Gateway) wrapLocal(typ *graphql.Type, v interface{}) interface{} {
+	if v == nil || isNull(v) {
+		return v
+	}
+	switch typ.Kind() {
+	case graphql.NonNullKind:
+		return g.wrapLocal(typ.OfType(), v)
+	case graphql.ListKind:
+		list := reflect.ValueOf(v)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			return v
+		}
+		elems := make([]interface{}, list.Len())
+		for i := range elems {
+			elems[i] = g.wrapLocal(typ.OfType(), list.Index(i).Interface())
+		}
+		return elems
+	case graphql.ObjectKind:
+		return &localObject{g: g, typ: typ, value: v}
+	case graphql.UnionKind:
+		t, ok := v.(graphql.Typer)
+		if !ok {
+			return v
+		}
+		objType := g.schema.Type(t.GraphQLType())
+		if objType == nil || !isPossibleType(typ, objType) {
+			return v
+		}
+		return &localObject{g: g, typ: objType, value: v}
+	default:
+		return v
+	}
+}
+
+// localObject is an object provided by the application whose fields may be
+// delegated.
+type localObject struct {
+	g     *Gateway
+	typ   *graphql.Type
+	value interface{}
+}
+
+// GraphQLType returns the name of the object's type.
+func (obj *localObject) GraphQLType() string {
+	return obj.typ.Name()
+}
+
+// ResolveField delegates fields to their executors or resolves them on the
+// application's value.
+func (obj *localObject) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	d := obj.g.delegated[obj.typ.Name()][req.Name]
+	if d == nil {
+		return obj.g.resolveLocal(ctx, obj.value, obj.typ.Name(), req)
+	}
+	key, err := obj.g.base.ResolveField(ctx, obj.value, obj.typ.Name(), graphql.FieldRequest{Name: d.key})
+	if err != nil {
+		return nil, xerrors.Errorf("key %s: %w", d.key, err)
+	}
+	if key == nil || isNull(key) {
+		return nil, nil
+	}
+	keyData, err := json.Marshal(key)
+	if err != nil {
+		return nil, xerrors.Errorf("key %s: %w", d.key, err)
+	}
+	return obj.g.delegateByKey(ctx, obj.typ, d, keyData, req)
+}
+
+// delegateByKey executes a delegated field of a non-root type on a remote
+// service, passing the parent object's key.
+func (g *Gateway) delegateByKey(ctx context.Context, parent *graphql.Type, d *delegation, key json.RawMessage, req graphql.FieldRequest) (interface{}, error) {
+	field := parent.Field(req.Name)
+	b := newQueryBuilder(g)
+	args := []queryArg{{
+		name:  d.key,
+		typ:   parent.Field(d.key).Type.String(),
+		value: key,
+	}}
+	args = append(args, b.fieldArgs(field, req.Args)...)
+	b.field(d.remoteField, d.remoteField, args, field.Type, req.Selection)
+	return g.delegate(ctx, d.executor, b.request("query"), d.remoteField, field.Type, req.Selection)
+}
+
+// delegate sends a request for a single field to a remote service and
+// converts the field's value in the response. key is the field's response key
+// in the remote response.
+func (g *Gateway) delegate(ctx context.Context, executor string, req *Request, key string, typ *graphql.Type, sel *graphql.SelectionSet) (interface{}, error) {
+	resp, err := g.executors[executor].Execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if len(resp.Data) > 0 {
+		dec := json.NewDecoder(strings.NewReader(string(resp.Data)))
+		dec.UseNumber()
+		if err := dec.Decode(&data); err != nil {
+			return nil, xerrors.Errorf("remote response: %w", err)
+		}
+	}
+	if data == nil {
+		// The request failed as a whole.
+		if len(resp.Errors) > 0 {
+			return nil, &graphql.ResponseError{Message: resp.Errors[0].Message}
+		}
+		return nil, xerrors.New("remote response has no data")
+	}
+	for _, e := range resp.Errors {
+		if len(e.Path) == 0 || e.Path[0].Field != key {
+			// The error can't be placed below the field, so it applies to the
+			// field as a whole.
+			return nil, &graphql.ResponseError{Message: e.Message}
+		}
+	}
+	res := &result{
+		g:      g,
+		key:    key,
+		errors: resp.Errors,
+	}
+	return res.convert(typ, sel, nil, nil, data[key])
+}
+
+// isNull reports whether a Go value provided by the application represents
+// null.
+func isNull(v interface{}) bool {
+	if n, ok := v.(graphql.Nullable); ok && n.IsGraphQLNull() {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,731 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+	"zombiezen.com/go/graphql-server/graphqlhttp"
+	"zombiezen.com/go/graphql-server/graphqltest"
+)
+
+const sharedTypes = `
+type User {
+  id: ID!
+  name: String!
+  age: Int
+  score: Float
+  admin: Boolean!
+  role: Role!
+  friends: [User!]!
+  status: String
+  secret: String!
+}
+
+enum Role { ADMIN, MEMBER }
+
+union SearchResult = User | Post
+
+type Post {
+  title: String!
+}
+`
+
+const accountsSDL = `
+type Query {
+  user(id: ID!): User
+  search(text: String!): [SearchResult!]!
+}
+
+type Mutation {
+  rename(id: ID!, name: String!): User!
+}
+` + sharedTypes
+
+const gatewaySDL = `
+type Query {
+  hello: String!
+  user(id: ID!): User @remote(executor: "accounts")
+  search(text: String!): [SearchResult!]! @remote(executor: "accounts")
+}
+
+type Mutation @remote(executor: "accounts") {
+  rename(id: ID!, name: String!): User!
+}
+` + sharedTypes
+
+type accountsQuery struct {
+	mu    sync.Mutex
+	users map[string]*accountsUser
+}
+
+func (q *accountsQuery) User(args map[string]graphql.Value) *accountsUser {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.users[args["id"].Scalar()]
+}
+
+func (q *accountsQuery) Search(args map[string]graphql.Value) []interface{} {
+	return []interface{}{
+		q.users["1"],
+		&accountsPost{Title: "Hello, " + args["text"].Scalar()},
+	}
+}
+
+type accountsMutation struct {
+	q *accountsQuery
+}
+
+func (m *accountsMutation) Rename(args map[string]graphql.Value) (*accountsUser, error) {
+	m.q.mu.Lock()
+	defer m.q.mu.Unlock()
+	u := m.q.users[args["id"].Scalar()]
+	if u == nil {
+		return nil, xerrors.New("no such user")
+	}
+	u.Name = args["name"].Scalar()
+	return u, nil
+}
+
+type accountsUser struct {
+	ID      string
+	Name    string
+	Age     graphql.NullInt
+	Score   float64
+	Admin   bool
+	Role    string
+	Friends []*accountsUser
+}
+
+func (u *accountsUser) GraphQLType() string { return "User" }
+
+func (u *accountsUser) Status() (graphql.NullString, error) {
+	return graphql.NullString{}, xerrors.New("status unavailable")
+}
+
+func (u *accountsUser) Secret() (string, error) {
+	return "", xerrors.New("access denied")
+}
+
+type accountsPost struct {
+	Title string
+}
+
+func (p *accountsPost) GraphQLType() string { return "Post" }
+
+func newAccountsServer(tb testing.TB) *graphql.Server {
+	tb.Helper()
+	schema, err := graphql.ParseSchema(accountsSDL, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	alice := &accountsUser{ID: "1", Name: "Alice", Age: graphql.NullInt{Int: 30, Valid: true}, Score: 1.5, Admin: true, Role: "ADMIN"}
+	bob := &accountsUser{ID: "2", Name: "Bob", Role: "MEMBER"}
+	alice.Friends = []*accountsUser{bob}
+	bob.Friends = []*accountsUser{alice}
+	q := &accountsQuery{users: map[string]*accountsUser{"1": alice, "2": bob}}
+	srv, err := graphql.NewServer(schema, q, &accountsMutation{q})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+// recordingExecutor records the requests sent to an executor.
+type recordingExecutor struct {
+	Executor
+	mu       sync.Mutex
+	requests []*Request
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, req *Request) (*Response, error) {
+	e.mu.Lock()
+	e.requests = append(e.requests, req)
+	e.mu.Unlock()
+	return e.Executor.Execute(ctx, req)
+}
+
+func (e *recordingExecutor) queries() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var queries []string
+	for _, req := range e.requests {
+		queries = append(queries, req.Query)
+	}
+	e.requests = nil
+	return queries
+}
+
+type gatewayQuery struct {
+	Hello string
+}
+
+func TestGateway(t *testing.T) {
+	ctx := context.Background()
+	accounts := &recordingExecutor{Executor: ServerExecutor{newAccountsServer(t)}}
+	g, err := NewGateway(gatewaySDL, nil, map[string]Executor{"accounts": accounts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := g.NewServer(&gatewayQuery{Hello: "world"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ hello }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{"hello": "world"}`)
+		if got := accounts.queries(); len(got) > 0 {
+			t.Errorf("remote queries = %q; want none", got)
+		}
+	})
+	t.Run("Delegated", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `query ($id: ID!) {
+				hello
+				me: user(id: $id) {
+					id
+					displayName: name
+					age score admin role
+					friends { name }
+					...Friends
+				}
+			}
+			fragment Friends on User {
+				friends { id }
+			}`,
+			Variables: map[string]interface{}{"id": "1"},
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"hello": "world",
+			"me": {
+				"id": "1",
+				"displayName": "Alice",
+				"age": 30,
+				"score": 1.5,
+				"admin": true,
+				"role": "ADMIN",
+				"friends": [{"name": "Bob", "id": "2"}]
+			}
+		}`)
+		want := []string{
+			`query($_0: ID!) { user(id: $_0) { id displayName: name age score admin role friends { name id } } }`,
+		}
+		if diff := cmp.Diff(want, accounts.queries()); diff != "" {
+			t.Errorf("remote queries (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Null", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ user(id: "404") { name } }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{"user": null}`)
+		accounts.queries()
+	})
+	t.Run("Union", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{
+				search(text: "x") {
+					... on User { name }
+					... on Post { kind: __typename title }
+				}
+			}`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"search": [
+				{"name": "Alice"},
+				{"kind": "Post", "title": "Hello, x"}
+			]
+		}`)
+		want := []string{
+			`query($_0: String!) { search(text: $_0) { __typename ... on User { name } ... on Post { kind: __typename title } } }`,
+		}
+		if diff := cmp.Diff(want, accounts.queries()); diff != "" {
+			t.Errorf("remote queries (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("Mutation", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `mutation { rename(id: "2", name: "Robert") { id name } }`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{"rename": {"id": "2", "name": "Robert"}}`)
+		want := []string{
+			`mutation($_0: ID!, $_1: String!) { rename(id: $_0, name: $_1) { id name } }`,
+		}
+		if diff := cmp.Diff(want, accounts.queries()); diff != "" {
+			t.Errorf("remote queries (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("NullableFieldError", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ user(id: "1") { name state: status } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Message:   "status unavailable",
+				Path:      graphqltest.Path("user", "state"),
+				Locations: []graphql.Location{{Line: 1, Column: 24}},
+			},
+		})
+		graphqltest.AssertData(t, resp, `{"user": {"name": "Alice", "state": null}}`)
+		accounts.queries()
+	})
+	t.Run("PropagatedError", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{ hello user(id: "1") { name friends { secret } } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Message:   "access denied",
+				Path:      graphqltest.Path("user", "friends", 0, "secret"),
+				Locations: []graphql.Location{{Line: 1, Column: 9}},
+			},
+		})
+		// secret and friends are non-null, so the remote service returns a
+		// null user.
+		graphqltest.AssertData(t, resp, `{"hello": "world", "user": null}`)
+		accounts.queries()
+	})
+	t.Run("MutationError", func(t *testing.T) {
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `mutation { rename(id: "404", name: "Nobody") { id } }`,
+		})
+		graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+			{
+				Message:   "no such user",
+				Path:      graphqltest.Path("rename"),
+				Locations: []graphql.Location{{Line: 1, Column: 12}},
+			},
+		})
+		accounts.queries()
+	})
+}
+
+func TestGatewayRequestErrors(t *testing.T) {
+	ctx := context.Background()
+	// The remote service does not have a search field, so the request fails
+	// validation.
+	schema, err := graphql.ParseSchema(`type Query { user(id: ID!): User }`+sharedTypes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteSrv, err := graphql.NewServer(schema, new(accountsQuery), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGateway(gatewaySDL, nil, map[string]Executor{
+		"accounts": ServerExecutor{remoteSrv},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := g.NewServer(&gatewayQuery{Hello: "world"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+		Query: `{ hello search(text: "x") { __typename } }`,
+	})
+	graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+		{
+			Message:   "search",
+			Path:      graphqltest.Path("search"),
+			Locations: []graphql.Location{{Line: 1, Column: 9}},
+		},
+	})
+
+	g, err = NewGateway(gatewaySDL, nil, map[string]Executor{
+		"accounts": executorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			return nil, xerrors.New("connection refused")
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err = g.NewServer(&gatewayQuery{Hello: "world"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+		Query: `{ hello user(id: "1") { name } }`,
+	})
+	graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+		{
+			Message:   "connection refused",
+			Path:      graphqltest.Path("user"),
+			Locations: []graphql.Location{{Line: 1, Column: 9}},
+		},
+	})
+	graphqltest.AssertData(t, resp, `{"hello": "world", "user": null}`)
+}
+
+type executorFunc func(ctx context.Context, req *Request) (*Response, error)
+
+func (f executorFunc) Execute(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+func TestHTTPExecutor(t *testing.T) {
+	ctx := context.Background()
+	var gotHeader string
+	handler := graphqlhttp.NewHandler(newAccountsServer(t))
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Authorization")
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpSrv.Close()
+	e := &HTTPExecutor{
+		URL:    httpSrv.URL,
+		Client: httpSrv.Client(),
+		Header: http.Header{"Authorization": {"Bearer xyzzy"}},
+	}
+
+	resp, err := e.Execute(ctx, &Request{
+		Query:     `query($id: ID!) { user(id: $id) { name status } }`,
+		Variables: map[string]json.RawMessage{"id": json.RawMessage(`"1"`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(resp.Data), `{"user":{"name":"Alice","status":null}}`; got != want {
+		t.Errorf("data = %s; want %s", got, want)
+	}
+	if len(resp.Errors) != 1 || len(resp.Errors[0].Path) != 2 {
+		t.Errorf("errors = %v; want 1 error at user.status", resp.Errors)
+	}
+	if want := "Bearer xyzzy"; gotHeader != want {
+		t.Errorf("Authorization = %q; want %q", gotHeader, want)
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	e = &HTTPExecutor{URL: notFound.URL, Client: notFound.Client()}
+	if _, err := e.Execute(ctx, &Request{Query: `{ user(id: "1") { name } }`}); err == nil {
+		t.Error("Execute on non-GraphQL endpoint did not return an error")
+	}
+}
+
+func TestAllDelegated(t *testing.T) {
+	accounts := ServerExecutor{newAccountsServer(t)}
+	g, err := NewGateway(`
+		type Query @remote(executor: "accounts") {
+			user(id: ID!): User
+		}
+	`+sharedTypes, nil, map[string]Executor{"accounts": accounts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NewServer(&gatewayQuery{}, nil); err == nil {
+		t.Error("NewServer with query object did not return an error")
+	}
+	srv, err := g.NewServer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := graphqltest.Execute(context.Background(), t, srv, graphqltest.Operation{
+		Query: `{ user(id: "2") { name } }`,
+	})
+	graphqltest.AssertErrors(t, resp, nil)
+	graphqltest.AssertData(t, resp, `{"user": {"name": "Bob"}}`)
+}
+
+func TestNewGatewayErrors(t *testing.T) {
+	executors := map[string]Executor{"accounts": ServerExecutor{}}
+	tests := []struct {
+		name string
+		sdl  string
+	}{
+		{
+			name: "UnknownExecutor",
+			sdl:  `type Query { user(id: ID!): User @remote(executor: "users") }` + sharedTypes,
+		},
+		{
+			name: "MissingKey",
+			sdl: `
+				type Query { hello: String }
+				type Foo { bar: String @remote(executor: "accounts") }
+			`,
+		},
+		{
+			name: "DelegatedKey",
+			sdl: `
+				type Query { hello: String }
+				type Foo {
+					id: ID! @remote(executor: "accounts")
+					bar: String @remote(executor: "accounts")
+				}
+			`,
+		},
+		{
+			name: "ObjectKey",
+			sdl: `
+				type Query { hello: String }
+				type Foo {
+					baz: Foo
+					bar: String @remote(executor: "accounts", key: "baz")
+				}
+			`,
+		},
+		{
+			name: "RootFieldWithKey",
+			sdl:  `type Query { user(id: ID!): User @remote(executor: "accounts", key: "id") }` + sharedTypes,
+		},
+		{
+			name: "NonRootType",
+			sdl: `
+				type Query { hello: String }
+				type Foo @remote(executor: "accounts") { bar: String }
+			`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewGateway(test.sdl, nil, executors); err == nil {
+				t.Error("NewGateway did not return an error")
+			}
+		})
+	}
+}
+
+func TestGatewayMissingLocalField(t *testing.T) {
+	g, err := NewGateway(gatewaySDL, nil, map[string]Executor{"accounts": ServerExecutor{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.NewServer(&struct{ Goodbye string }{}, nil); err == nil {
+		t.Error("NewServer with missing local field did not return an error")
+	}
+}
+
+const reviewsSDL = `
+type Query {
+  reviewsByUser(id: ID!, first: Int): [Review!]!
+}
+
+type Review {
+  body: String!
+}
+`
+
+type reviewsQuery struct{}
+
+func (reviewsQuery) ReviewsByUser(args map[string]graphql.Value) []*review {
+	n := 2
+	if first := args["first"]; !first.IsNull() {
+		n, _ = strconv.Atoi(first.Scalar())
+	}
+	reviews := make([]*review, n)
+	for i := range reviews {
+		reviews[i] = &review{Body: fmt.Sprintf("review %d of %s", i+1, args["id"].Scalar())}
+	}
+	return reviews
+}
+
+type review struct {
+	Body string
+}
+
+func newReviewsServer(tb testing.TB) *graphql.Server {
+	tb.Helper()
+	schema, err := graphql.ParseSchema(reviewsSDL, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv, err := graphql.NewServer(schema, reviewsQuery{}, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return srv
+}
+
+func TestNestedDelegation(t *testing.T) {
+	ctx := context.Background()
+	reviews := &recordingExecutor{Executor: ServerExecutor{newReviewsServer(t)}}
+
+	t.Run("RemoteParent", func(t *testing.T) {
+		accounts := &recordingExecutor{Executor: ServerExecutor{newAccountsServer(t)}}
+		g, err := NewGateway(`
+			type Query {
+			  user(id: ID!): User @remote(executor: "accounts")
+			}
+
+			type User {
+			  id: ID!
+			  name: String!
+			  friends: [User!]!
+			  reviews(first: Int): [Review!]! @remote(executor: "reviews", field: "reviewsByUser")
+			}
+
+			type Review {
+			  body: String!
+			}
+		`, nil, map[string]Executor{"accounts": accounts, "reviews": reviews})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv, err := g.NewServer(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{
+				user(id: "1") {
+					name
+					reviews(first: 1) { body }
+					friends { reviews { text: body } }
+				}
+			}`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"user": {
+				"name": "Alice",
+				"reviews": [{"body": "review 1 of 1"}],
+				"friends": [{
+					"reviews": [
+						{"text": "review 1 of 2"},
+						{"text": "review 2 of 2"}
+					]
+				}]
+			}
+		}`)
+		wantAccounts := []string{
+			`query($_0: ID!) { user(id: $_0) { name _remoteKey_id: id friends { _remoteKey_id: id } } }`,
+		}
+		if diff := cmp.Diff(wantAccounts, accounts.queries()); diff != "" {
+			t.Errorf("accounts queries (-want +got):\n%s", diff)
+		}
+		wantReviews := []string{
+			`query($_0: ID!) { reviewsByUser(id: $_0) { text: body } }`,
+			`query($_0: ID!, $_1: Int) { reviewsByUser(id: $_0, first: $_1) { body } }`,
+		}
+		if diff := cmp.Diff(wantReviews, reviews.queries(), cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+			t.Errorf("reviews queries (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("LocalParent", func(t *testing.T) {
+		g, err := NewGateway(`
+			type Query {
+			  me: Person
+			  nobody: Person
+			  people: [Person!]!
+			}
+
+			type Person {
+			  id: ID!
+			  name: String!
+			  reviews: [Review!]! @remote(executor: "reviews", field: "reviewsByUser")
+			}
+
+			type Review {
+			  body: String!
+			}
+		`, nil, map[string]Executor{"reviews": reviews})
+		if err != nil {
+			t.Fatal(err)
+		}
+		alice := &person{ID: "1", Name: "Alice"}
+		srv, err := g.NewServer(&localQuery{
+			Me:     alice,
+			People: []*person{alice, {ID: "2", Name: "Bob"}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := graphqltest.Execute(ctx, t, srv, graphqltest.Operation{
+			Query: `{
+				me { name reviews { body } }
+				nobody { reviews { body } }
+				people { name }
+			}`,
+		})
+		graphqltest.AssertErrors(t, resp, nil)
+		graphqltest.AssertData(t, resp, `{
+			"me": {
+				"name": "Alice",
+				"reviews": [{"body": "review 1 of 1"}, {"body": "review 2 of 1"}]
+			},
+			"nobody": null,
+			"people": [{"name": "Alice"}, {"name": "Bob"}]
+		}`)
+		want := []string{
+			`query($_0: ID!) { reviewsByUser(id: $_0) { body } }`,
+		}
+		if diff := cmp.Diff(want, reviews.queries()); diff != "" {
+			t.Errorf("reviews queries (-want +got):\n%s", diff)
+		}
+	})
+}
+
+type localQuery struct {
+	Me     *person
+	Nobody *person
+	People []*person
+}
+
+type person struct {
+	ID   string
+	Name string
+}
+
+func TestGatewayUnplacedErrors(t *testing.T) {
+	g, err := NewGateway(gatewaySDL, nil, map[string]Executor{
+		"accounts": executorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			return &Response{
+				Data:   json.RawMessage(`{"user": {"name": "Alice"}}`),
+				Errors: []*graphql.ResponseError{{Message: "rate limited"}},
+			}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := g.NewServer(&gatewayQuery{Hello: "world"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := graphqltest.Execute(context.Background(), t, srv, graphqltest.Operation{
+		Query: `{ hello user(id: "1") { name } }`,
+	})
+	graphqltest.AssertErrors(t, resp, []*graphql.ResponseError{
+		{
+			Message:   "rate limited",
+			Path:      graphqltest.Path("user"),
+			Locations: []graphql.Location{{Line: 1, Column: 9}},
+		},
+	})
+	graphqltest.AssertData(t, resp, `{"hello": "world", "user": null}`)
+}
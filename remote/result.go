@@ -0,0 +1,266 @@
+// Copyright 2019 Ross Light
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/xerrors"
+	"zombiezen.com/go/graphql-server/graphql"
+)
+
+// result holds the response to a delegated field.
+type result struct {
+	g *Gateway
+	// key is the response key of the delegated field in the remote response.
+	key    string
+	errors []*graphql.ResponseError
+}
+
+// convert converts a JSON value from the remote response into a Go value that
+// the gateway server can use as the given type. fieldPath is the path of the
+// field being resolved, relative to the delegated field, and path is the path
+// of the value itself, which may be below fieldPath for list elements.
+//
+// If the value is null and the remote service reported an error for it or for
+// a value below it, then convert returns the first such error with a path
+// relative to fieldPath. Since a field's value cannot be returned along with an
+// error, any error in a list element fails the whole list.
+func (res *result) convert(typ *graphql.Type, sel *graphql.SelectionSet, fieldPath, path []graphql.PathSegment, v interface{}) (interface{}, error) {
+	if v == nil {
+		if err := res.errorAt(fieldPath, path); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	switch typ.Kind() {
+	case graphql.NonNullKind:
+		return res.convert(typ.OfType(), sel, fieldPath, path, v)
+	case graphql.ListKind:
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, xerrors.Errorf("remote response: %v is not a list", formatPath(res.key, path))
+		}
+		elems := make([]interface{}, len(list))
+		for i := range list {
+			var err error
+			elemPath := appendPath(path, graphql.PathSegment{ListIndex: i})
+			elems[i], err = res.convert(typ.OfType(), sel, fieldPath, elemPath, list[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return elems, nil
+	case graphql.ObjectKind, graphql.UnionKind:
+		data, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, xerrors.Errorf("remote response: %v is not an object", formatPath(res.key, path))
+		}
+		objType := typ
+		if typ.Kind() == graphql.UnionKind {
+			typeName, _ := data[typeNameField].(string)
+			objType = res.g.schema.Type(typeName)
+			if objType == nil || !isPossibleType(typ, objType) {
+				return nil, xerrors.Errorf("remote response: %v has unknown type %q", formatPath(res.key, path), typeName)
+			}
+		}
+		return &object{
+			res:  res,
+			typ:  objType,
+			sel:  sel,
+			path: path,
+			data: data,
+		}, nil
+	default:
+		return convertScalar(typ, v)
+	}
+}
+
+// convertScalar converts a JSON scalar into a Go value of the kind the gateway
+// server expects for the given scalar or enum type.
+func convertScalar(typ *graphql.Type, v interface{}) (interface{}, error) {
+	switch typ.Name() {
+	case "Int":
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, xerrors.Errorf("remote response: %v is not an Int", v)
+		}
+		i, err := strconv.ParseInt(string(n), 10, 32)
+		if err != nil {
+			return nil, xerrors.Errorf("remote response: %v is not an Int", v)
+		}
+		return int32(i), nil
+	case "Float":
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, xerrors.Errorf("remote response: %v is not a Float", v)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, xerrors.Errorf("remote response: %v is not a Float", v)
+		}
+		return f, nil
+	case "Boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, xerrors.Errorf("remote response: %v is not a Boolean", v)
+		}
+		return b, nil
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return nil, xerrors.Errorf("remote response: cannot use %T as %v", v, typ)
+	}
+}
+
+// errorAt returns the first remote error at or below path, with its path
+// made relative to fieldPath. It returns nil if there is no such error.
+func (res *result) errorAt(fieldPath, path []graphql.PathSegment) error {
+	for _, e := range res.errors {
+		if len(e.Path) == 0 || e.Path[0].Field != res.key {
+			continue
+		}
+		remotePath := e.Path[1:]
+		if !hasPathPrefix(remotePath, path) {
+			continue
+		}
+		var rel []graphql.PathSegment
+		if len(remotePath) > len(fieldPath) {
+			rel = append(rel, remotePath[len(fieldPath):]...)
+		}
+		return &graphql.ResponseError{
+			Message: e.Message,
+			Path:    rel,
+		}
+	}
+	return nil
+}
+
+// object is an object from a remote response.
+type object struct {
+	res *result
+	typ *graphql.Type
+	// sel is the selection set that the object was requested with.
+	sel  *graphql.SelectionSet
+	path []graphql.PathSegment
+	data map[string]interface{}
+}
+
+// GraphQLType returns the name of the object's type.
+func (obj *object) GraphQLType() string {
+	return obj.typ.Name()
+}
+
+// ResolveField returns the value of a field from the remote response or
+// delegates the field to another executor.
+func (obj *object) ResolveField(ctx context.Context, req graphql.FieldRequest) (interface{}, error) {
+	if d := obj.res.g.delegated[obj.typ.Name()][req.Name]; d != nil && d.remoteField != "" {
+		key, ok := obj.data[keyAliasPrefix+d.key]
+		if !ok {
+			return nil, xerrors.Errorf("key %s.%s was not requested from remote service", obj.typ.Name(), d.key)
+		}
+		if key == nil {
+			return nil, nil
+		}
+		keyData, err := json.Marshal(key)
+		if err != nil {
+			return nil, xerrors.Errorf("key %s.%s: %w", obj.typ.Name(), d.key, err)
+		}
+		return obj.res.g.delegateByKey(ctx, obj.typ, d, keyData, req)
+	}
+	defn := obj.typ.Field(req.Name)
+	f := obj.find(defn, req)
+	if f == nil {
+		return nil, xerrors.Errorf("field %s.%s was not requested from remote service", obj.typ.Name(), req.Name)
+	}
+	fieldPath := appendPath(obj.path, graphql.PathSegment{Field: f.Key()})
+	return obj.res.convert(defn.Type, req.Selection, fieldPath, fieldPath, obj.data[f.Key()])
+}
+
+// find returns the field in the object's selection set that corresponds to the
+// request. Fields with the same name and arguments produce the same value, so
+// any one of them may be used.
+func (obj *object) find(defn *graphql.FieldDefinition, req graphql.FieldRequest) *graphql.SelectedField {
+	if defn == nil {
+		return nil
+	}
+	for i := 0; i < obj.sel.Len(); i++ {
+		f := obj.sel.Field(i)
+		if f.Name() != req.Name || f.SelectionSet() != req.Selection || !f.ForType(obj.typ.Name()) {
+			continue
+		}
+		sameArgs := true
+		for _, arg := range defn.Args {
+			if f.Arg(arg.Name).String() != req.Args[arg.Name].String() {
+				sameArgs = false
+				break
+			}
+		}
+		if sameArgs {
+			return f
+		}
+	}
+	return nil
+}
+
+func isPossibleType(union, typ *graphql.Type) bool {
+	for _, pt := range union.PossibleTypes() {
+		if pt == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPathPrefix(path, prefix []graphql.PathSegment) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendPath(path []graphql.PathSegment, seg graphql.PathSegment) []graphql.PathSegment {
+	newPath := make([]graphql.PathSegment, 0, len(path)+1)
+	newPath = append(newPath, path...)
+	return append(newPath, seg)
+}
+
+func formatPath(key string, path []graphql.PathSegment) string {
+	s := key
+	for _, seg := range path {
+		if seg.Field != "" {
+			s += "." + seg.Field
+		} else {
+			s += fmt.Sprintf("[%d]", seg.ListIndex)
+		}
+	}
+	return s
+}